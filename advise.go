@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+func adviseCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("advise", flag.ContinueOnError)
+	maxCandidates := fs.Int("max-candidates", 0, "only consider this many of the newest versions, every newer version if 0")
+	prerelease := fs.Bool("prerelease", false, "also consider pre-release versions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: dep-inspector [flags] advise [-max-candidates n] [-prerelease] path/of/module[@version]")
+	}
+	dep, version, ok := strings.Cut(fs.Arg(0), "@")
+	if !ok {
+		version = curVersion
+	}
+	current, err := d.checkVersion(dep, version)
+	if err != nil {
+		return err
+	}
+
+	versions, err := d.moduleVersions(ctx, dep)
+	if err != nil {
+		return fmt.Errorf("listing versions of %s: %w", dep, err)
+	}
+	candidates := upgradeCandidates(current, versions, *prerelease, *maxCandidates)
+	if len(candidates) == 0 {
+		fmt.Printf("%s is the newest version of %s\n", current, dep)
+		return nil
+	}
+
+	return d.adviseUpgrade(ctx, os.Stdout, dep, current, candidates)
+}
+
+// upgradeCandidates returns the versions newer than current that can
+// be upgraded to, newest first. Pre-release versions are only included
+// if prerelease is true, and only the limit newest versions are returned
+// if limit is positive.
+func upgradeCandidates(current string, versions []string, prerelease bool, limit int) []string {
+	var candidates []string
+	for _, v := range versions {
+		if semver.Compare(v, current) <= 0 {
+			continue
+		}
+		if semver.Prerelease(v) != "" && !prerelease {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	semver.Sort(candidates)
+	slices.Reverse(candidates)
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// adviseUpgrade inspects the current version of a dependency and then
+// candidate versions from newest to oldest, and recommends the first
+// one that doesn't add high or critical severity capabilities or
+// violate policy compared to the current version. Why each candidate
+// was rejected is written to w.
+func (d *depInspector) adviseUpgrade(ctx context.Context, w io.Writer, dep, current string, candidates []string) error {
+	curFindings, err := d.inspectDep(ctx, d.oldModBackupFiles, dep, current, false, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, current), err)
+	}
+	fmt.Fprintf(w, "Current version: %s\n", makeVersionStr(dep, current))
+
+	for _, candidate := range candidates {
+		// start from the original go.mod and go.sum, the previous
+		// candidate changed them
+		if err := d.resetModFiles(); err != nil {
+			return fmt.Errorf("restoring go.mod: %w", err)
+		}
+		findings, err := d.inspectDep(ctx, d.newModBackupFiles, dep, candidate, true, knownFindings(curFindings))
+		if ctx.Err() != nil {
+			return fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, candidate), context.Cause(ctx))
+		}
+		var blockers []string
+		if err != nil {
+			blockers = append(blockers, fmt.Sprintf("couldn't be inspected: %v", err))
+		} else {
+			blockers = d.upgradeBlockers(&savedResults{Old: curFindings, New: findings})
+		}
+
+		if len(blockers) == 0 {
+			fmt.Fprintf(w, "%s: adds no high severity capabilities and violates no policies\n", candidate)
+			fmt.Fprintf(w, "Recommended: upgrade %s to %s\n", dep, candidate)
+			return nil
+		}
+		fmt.Fprintf(w, "%s: %s\n", candidate, strings.Join(blockers, "; "))
+	}
+
+	fmt.Fprintf(w, "No newer version is recommended, stay at %s\n", current)
+	return nil
+}
+
+// upgradeBlockers returns why upgrading to the new version of results
+// isn't recommended: the high and critical severity capabilities it
+// adds and the policies it violates.
+func (d *depInspector) upgradeBlockers(res *savedResults) []string {
+	var blockers []string
+	violations := d.checkPolicy(res)
+
+	res = d.prepareResults(res)
+	capCounts := make(map[string]int)
+	var capNames []string
+	for _, c := range compareFindings(res.Old, res.New).addedCaps {
+		if compareSeverity(c.Severity, severityHigh) < 0 {
+			continue
+		}
+		name := strings.TrimPrefix(c.Capability, "CAPABILITY_")
+		if capCounts[name] == 0 {
+			capNames = append(capNames, name)
+		}
+		capCounts[name]++
+	}
+	if len(capNames) != 0 {
+		slices.Sort(capNames)
+		for i, name := range capNames {
+			capNames[i] = fmt.Sprintf("%s (%d)", name, capCounts[name])
+		}
+		blockers = append(blockers, "adds high severity capabilities "+strings.Join(capNames, ", "))
+	}
+	for _, v := range violations {
+		blockers = append(blockers, "violates policy "+v.String())
+	}
+	if res.New.Metadata.Incomplete != "" {
+		blockers = append(blockers, "findings are incomplete: "+res.New.Metadata.Incomplete)
+	}
+
+	return blockers
+}
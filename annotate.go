@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const golangPURLPrefix = "pkg:golang/"
+
+// sbomModule is a Go module version referenced by an SBOM component.
+type sbomModule struct {
+	path    string
+	version string
+}
+
+// annotateSBOMCmd adds findings to the Go module components of an
+// existing CycloneDX or SPDX JSON SBOM. SBOMs are decoded generically
+// so fields dep-inspector doesn't know about are preserved.
+func annotateSBOMCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector [flags] annotate-sbom sbom.json")
+	}
+
+	contents, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading SBOM: %w", err)
+	}
+	var sbom map[string]any
+	if err := json.Unmarshal(contents, &sbom); err != nil {
+		return fmt.Errorf("decoding SBOM: %w", err)
+	}
+
+	var (
+		componentsKey string
+		annotate      func(component map[string]any, findings *depFindings)
+		findModule    func(component map[string]any) (sbomModule, bool)
+	)
+	switch {
+	case sbom["bomFormat"] == "CycloneDX":
+		componentsKey = "components"
+		annotate = annotateCycloneDXComponent
+		findModule = func(component map[string]any) (sbomModule, bool) {
+			purl, _ := component["purl"].(string)
+			return parseGolangPURL(purl)
+		}
+	case sbom["spdxVersion"] != nil:
+		componentsKey = "packages"
+		annotate = annotateSPDXPackage
+		findModule = spdxPackageModule
+	default:
+		return errors.New("unknown SBOM format: only CycloneDX and SPDX JSON SBOMs are supported")
+	}
+
+	components, _ := sbom[componentsKey].([]any)
+	// multiple components can reference the same module version, only
+	// inspect each once
+	inspected := make(map[sbomModule]*depFindings)
+	for _, c := range components {
+		component, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		mod, ok := findModule(component)
+		if !ok || mod.path == d.parsedModFile.Module.Mod.Path {
+			continue
+		}
+
+		findings, ok := inspected[mod]
+		if !ok {
+			findings, err = d.inspectSBOMModule(ctx, mod)
+			if err != nil {
+				log.Printf("skipping %s: %v", makeVersionStr(mod.path, mod.version), err)
+			}
+			d.severities.apply(findings)
+			d.risk.apply(findings)
+			inspected[mod] = findings
+		}
+		if findings != nil {
+			annotate(component, findings)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if d.outputFile != "" {
+		f, err := os.Create(d.outputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sbom); err != nil {
+		return fmt.Errorf("encoding SBOM: %w", err)
+	}
+
+	return nil
+}
+
+func (d *depInspector) inspectSBOMModule(ctx context.Context, mod sbomModule) (*depFindings, error) {
+	// start from the original go.mod and go.sum, inspecting the
+	// previous module may have changed them
+	if err := d.resetModFiles(); err != nil {
+		return nil, fmt.Errorf("restoring go.mod: %w", err)
+	}
+	ver, err := d.checkVersion(mod.path, mod.version)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("inspecting %s", makeVersionStr(mod.path, ver))
+	return d.inspectDep(ctx, d.newModBackupFiles, mod.path, ver, true, nil)
+}
+
+func annotateCycloneDXComponent(component map[string]any, findings *depFindings) {
+	props, _ := component["properties"].([]any)
+	for _, prop := range findingProperties(findings) {
+		props = append(props, map[string]any{
+			"name":  prop.Name,
+			"value": prop.Value,
+		})
+	}
+	component["properties"] = props
+}
+
+// annotateSPDXPackage adds findings to an SPDX package as an
+// annotation, SPDX packages have no equivalent of CycloneDX properties.
+func annotateSPDXPackage(pkg map[string]any, findings *depFindings) {
+	var comment strings.Builder
+	for i, prop := range findingProperties(findings) {
+		if i > 0 {
+			comment.WriteString("\n")
+		}
+		fmt.Fprintf(&comment, "%s=%s", prop.Name, prop.Value)
+	}
+
+	annotations, _ := pkg["annotations"].([]any)
+	pkg["annotations"] = append(annotations, map[string]any{
+		"annotationDate": findings.Metadata.Time.UTC().Format(time.RFC3339),
+		"annotationType": "OTHER",
+		"annotator":      "Tool: dep-inspector-" + findings.Metadata.Version,
+		"comment":        comment.String(),
+	})
+}
+
+// spdxPackageModule finds the Go module an SPDX package refers to from
+// its package URL external reference.
+func spdxPackageModule(pkg map[string]any) (sbomModule, bool) {
+	refs, _ := pkg["externalRefs"].([]any)
+	for _, r := range refs {
+		ref, ok := r.(map[string]any)
+		if !ok || ref["referenceType"] != "purl" {
+			continue
+		}
+		locator, _ := ref["referenceLocator"].(string)
+		if mod, ok := parseGolangPURL(locator); ok {
+			return mod, true
+		}
+	}
+
+	return sbomModule{}, false
+}
+
+// parseGolangPURL parses a package URL of a Go module in the form
+// pkg:golang/path@version. Package URLs without versions are ignored,
+// there is no version to inspect.
+func parseGolangPURL(purl string) (sbomModule, bool) {
+	rest, ok := strings.CutPrefix(purl, golangPURLPrefix)
+	if !ok {
+		return sbomModule{}, false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	i := strings.LastIndex(rest, "@")
+	if i == -1 {
+		return sbomModule{}, false
+	}
+
+	modPath, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return sbomModule{}, false
+	}
+	version, err := url.PathUnescape(rest[i+1:])
+	if err != nil || version == "" {
+		return sbomModule{}, false
+	}
+
+	return sbomModule{path: modPath, version: version}, true
+}
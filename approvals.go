@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// approvalList is an organization's list of approved dependency
+// versions. It is YAML or JSON, and can be a file or served over HTTP.
+type approvalList struct {
+	Approved []approval `yaml:"approved"`
+}
+
+// approval records who approved a dependency version and why.
+type approval struct {
+	Module     string `yaml:"module"`
+	Version    string `yaml:"version"`
+	ApprovedBy string `yaml:"approved-by"`
+	Date       string `yaml:"date,omitempty"`
+	Notes      string `yaml:"notes,omitempty"`
+	// FindingsDigest identifies the findings that were approved
+	FindingsDigest string `yaml:"findings-digest,omitempty"`
+	// ZipHash is the go.sum hash of the module zip that was approved
+	ZipHash string `yaml:"zip-hash,omitempty"`
+}
+
+// approvalStatus is whether a dependency version is on the approved
+// versions list.
+type approvalStatus struct {
+	// Entry is the version's approval, or nil if it isn't approved
+	Entry *approval
+	// Current is false if the findings that were approved differ from
+	// the findings being reported
+	Current bool
+	// ContentChanged is true if the version was approved, but the
+	// approval doesn't apply as the module's content has changed since
+	ContentChanged bool
+}
+
+// loadApprovals reads the approved versions list from a file or an
+// HTTP endpoint.
+func loadApprovals(ctx context.Context, src string) (*approvalList, error) {
+	var (
+		contents []byte
+		err      error
+	)
+	if isURL(src) {
+		contents, err = fetchApprovals(ctx, src)
+	} else {
+		contents, err = os.ReadFile(src)
+		// the list is created when the first version is approved
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("approved versions list %s doesn't exist yet", src)
+			return &approvalList{}, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading approved versions: %w", err)
+	}
+
+	var list approvalList
+	if err := yaml.Unmarshal(contents, &list); err != nil {
+		return nil, fmt.Errorf("parsing approved versions: %w", err)
+	}
+	for _, a := range list.Approved {
+		if a.Module == "" || a.Version == "" {
+			return nil, errors.New("parsing approved versions: approvals require a module and version")
+		}
+	}
+
+	return &list, nil
+}
+
+// isURL returns true if src is an HTTP endpoint instead of a file.
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+func fetchApprovals(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// find returns the approval of a dependency version, or nil if it
+// isn't approved.
+func (l *approvalList) find(dep, version string) *approval {
+	for i, a := range l.Approved {
+		if a.Module == dep && a.Version == version {
+			return &l.Approved[i]
+		}
+	}
+	return nil
+}
+
+// approvalStatus returns whether the version of findings is approved,
+// or nil if no approved versions list was configured.
+func (d *depInspector) approvalStatus(findings *depFindings) *approvalStatus {
+	if d.approvals == nil {
+		return nil
+	}
+
+	status := &approvalStatus{
+		Entry: d.approvals.find(findings.Dep, findings.Version),
+	}
+	if status.Entry != nil && contentChanged(status.Entry.ZipHash, findings.ZipHash) {
+		status.Entry = nil
+		status.ContentChanged = true
+	}
+	if status.Entry != nil {
+		digest := status.Entry.FindingsDigest
+		status.Current = digest == "" || digest == findingsDigest(findings)
+	}
+	return status
+}
+
+// checkApproval returns a policy violation if -fail-unapproved was
+// passed and the version of results isn't approved.
+func (d *depInspector) checkApproval(res *savedResults) []policyViolation {
+	if !d.failUnapproved || d.approvals == nil {
+		return nil
+	}
+	status := d.approvalStatus(res.New)
+	if status.Entry != nil {
+		return nil
+	}
+
+	rule := res.New.Version + " is not approved"
+	if status.ContentChanged {
+		rule = "the content of " + res.New.Version + " changed since it was approved"
+	}
+	return []policyViolation{{
+		Dep:     res.New.Dep,
+		Rule:    rule,
+		Version: res.New.Version,
+	}}
+}
+
+func approveCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	notes := fs.String("notes", "", "notes about the approval, such as who reviewed the version")
+	approvedBy := fs.String("approved-by", "", "who approved the version, defaults to the email address git is configured with")
+	findingsPath := fs.String("findings", "", "findings that were reviewed, defaults to the latest findings of the version in the result store")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: dep-inspector [flags] approve [-notes notes] [-approved-by name] [-findings findings.json] path/of/module@version")
+	}
+	dep, version, ok := strings.Cut(fs.Arg(0), "@")
+	if !ok || dep == "" || version == "" {
+		return fmt.Errorf("%q is not a module version, must be path/of/module@version", fs.Arg(0))
+	}
+
+	writeList := d.approvedVersions != "" && !isURL(d.approvedVersions)
+	if d.store == nil && !writeList {
+		return errors.New("approve requires -store or an -approved-versions file to record the approval in")
+	}
+
+	findings, err := d.approvalFindings(ctx, dep, version, *findingsPath)
+	if err != nil {
+		return err
+	}
+	if *approvedBy == "" {
+		*approvedBy = d.approverName(ctx)
+	}
+	if *approvedBy == "" {
+		return errors.New("-approved-by is required")
+	}
+
+	// digest the findings reports would show
+	res := d.prepareResults(&savedResults{New: findings})
+	a := approval{
+		Module:         dep,
+		Version:        version,
+		ApprovedBy:     *approvedBy,
+		Date:           time.Now().UTC().Format(time.DateOnly),
+		Notes:          *notes,
+		FindingsDigest: findingsDigest(res.New),
+		ZipHash:        res.New.ZipHash,
+	}
+	if d.store != nil {
+		if err := d.store.recordApproval(ctx, a); err != nil {
+			return err
+		}
+	}
+	if writeList {
+		if err := addApproval(ctx, d.approvedVersions, a); err != nil {
+			return err
+		}
+	}
+	log.Printf("approved %s", makeVersionStr(dep, version))
+
+	return nil
+}
+
+// approvalFindings returns the findings of a dependency version that
+// is being approved, either from a file of saved findings or the
+// latest inspection of it in the result store.
+func (d *depInspector) approvalFindings(ctx context.Context, dep, version, path string) (*depFindings, error) {
+	if path != "" {
+		res, err := d.loadSavedResults(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if res.New.Dep != dep || res.New.Version != version {
+			return nil, fmt.Errorf("%s has findings of %s, not %s", path, makeVersionStr(res.New.Dep, res.New.Version), makeVersionStr(dep, version))
+		}
+		return res.New, nil
+	}
+
+	if d.store != nil {
+		inspection, ok, err := d.store.latest(ctx, dep, version)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return inspection.Findings, nil
+		}
+	}
+	return nil, fmt.Errorf("no findings of %s found, pass -findings or inspect it with -store first", makeVersionStr(dep, version))
+}
+
+// approverName returns the email address git is configured with, or
+// the name of the current user.
+func (d *depInspector) approverName(ctx context.Context) string {
+	var out bytes.Buffer
+	if err := d.runCommand(ctx, &out, "git", "config", "user.email"); err == nil {
+		if email := strings.TrimSpace(out.String()); email != "" {
+			return email
+		}
+	}
+	return os.Getenv("USER")
+}
+
+// addApproval adds an approval to the approved versions list at path,
+// replacing any previous approval of the same version.
+func addApproval(ctx context.Context, path string, a approval) error {
+	list, err := loadApprovals(ctx, path)
+	if err != nil {
+		return err
+	}
+	if existing := list.find(a.Module, a.Version); existing != nil {
+		*existing = a
+	} else {
+		list.Approved = append(list.Approved, a)
+	}
+
+	contents, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding approved versions: %w", err)
+	}
+	return writeFile(path, bytes.NewReader(contents))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+// htmlAsset is CSS or JavaScript that is inlined in HTML reports by a
+// template, or written to a separate file if -html-assets is passed.
+type htmlAsset struct {
+	// tmpl is the template that inlines the asset in a tag
+	tmpl string
+	tag  string
+	file string
+	// link is the HTML that links to the asset's file
+	link string
+}
+
+var htmlAssets = []htmlAsset{
+	{
+		tmpl: "style.tmpl",
+		tag:  "style",
+		file: "dep-inspector.css",
+		link: `<link rel="stylesheet" href="dep-inspector.css">`,
+	},
+	{
+		tmpl: "sort.tmpl",
+		tag:  "script",
+		file: "dep-inspector.js",
+		link: `<script src="dep-inspector.js"></script>`,
+	},
+	{
+		tmpl: "print.tmpl",
+		tag:  "script",
+		file: "dep-inspector-print.js",
+		link: `<script src="dep-inspector-print.js"></script>`,
+	},
+	{
+		tmpl: "panes.tmpl",
+		tag:  "script",
+		file: "dep-inspector-panes.js",
+		link: `<script src="dep-inspector-panes.js"></script>`,
+	},
+}
+
+// linkAssets returns a copy of tmpl where templates of assets link to
+// the assets' files instead of inlining them.
+func linkAssets(tmpl *template.Template) (*template.Template, error) {
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("error cloning output template: %w", err)
+	}
+	for _, asset := range htmlAssets {
+		if tmpl.Lookup(asset.tmpl) == nil {
+			continue
+		}
+		if _, err := tmpl.New(asset.tmpl).Parse(asset.link); err != nil {
+			return nil, fmt.Errorf("error parsing output template: %w", err)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// assetContents returns the contents of an asset without the tag its
+// template inlines it in.
+func assetContents(asset htmlAsset) ([]byte, error) {
+	contents, err := tmplFS.ReadFile("output/" + asset.tmpl)
+	if err != nil {
+		return nil, err
+	}
+	contents = bytes.TrimSpace(contents)
+	contents = bytes.TrimPrefix(contents, []byte("<"+asset.tag+">"))
+	contents = bytes.TrimSuffix(contents, []byte("</"+asset.tag+">"))
+
+	return append(bytes.TrimLeft(contents, "\n"), '\n'), nil
+}
+
+// writeHTMLAssets writes the files of every asset to dir.
+func writeHTMLAssets(dir string) error {
+	for _, asset := range htmlAssets {
+		contents, err := assetContents(asset)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dir, asset.file), bytes.NewReader(contents)); err != nil {
+			return fmt.Errorf("writing %s: %w", asset.file, err)
+		}
+	}
+
+	return nil
+}
+
+// addHTMLAssets adds the files of every asset to a zip archive.
+func addHTMLAssets(zw *zip.Writer) error {
+	for _, asset := range htmlAssets {
+		contents, err := assetContents(asset)
+		if err != nil {
+			return err
+		}
+		if err := addZipFile(zw, asset.file, bytes.NewReader(contents)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// findingsPredicateType is the in-toto predicate type of findings
+	// attestations
+	findingsPredicateType = "https://github.com/capnspacehook/dep-inspector/findings/v1"
+
+	bundleExt = ".sigstore.json"
+)
+
+// findingsPredicate is the in-toto predicate of findings attestations.
+// The subject of the statement is the findings file, the predicate
+// records how it was produced.
+type findingsPredicate struct {
+	Tool      attestationTool `json:"tool"`
+	Timestamp time.Time       `json:"timestamp"`
+	// Module is the main module dependencies were inspected from
+	Module string `json:"module,omitempty"`
+	// Inspected are the dependency versions the findings are of
+	Inspected []string `json:"inspected"`
+}
+
+type attestationTool struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	ToolVersions map[string]string `json:"toolVersions,omitempty"`
+}
+
+func (d *depInspector) newFindingsPredicate(metadata reportMetadata, findings ...*depFindings) findingsPredicate {
+	pred := findingsPredicate{
+		Tool: attestationTool{
+			Name:         "dep-inspector",
+			Version:      metadata.Version,
+			ToolVersions: metadata.ToolVersions,
+		},
+		Timestamp: metadata.Time,
+		Inspected: []string{},
+	}
+	if d.parsedModFile != nil {
+		pred.Module = d.parsedModFile.Module.Mod.Path
+	}
+	for _, f := range findings {
+		if f != nil {
+			pred.Inspected = append(pred.Inspected, makeVersionStr(f.Dep, f.Version))
+		}
+	}
+
+	return pred
+}
+
+// attestFindings signs an in-toto attestation of a findings file with
+// cosign, keyless via sigstore. The sigstore bundle is written next to
+// the findings file.
+func (d *depInspector) attestFindings(ctx context.Context, path string, pred findingsPredicate) error {
+	predFile, err := os.CreateTemp("", tempPrefix)
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(predFile.Name())
+	if err := json.NewEncoder(predFile).Encode(pred); err != nil {
+		predFile.Close()
+		return fmt.Errorf("encoding attestation predicate: %w", err)
+	}
+	if err := predFile.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	bundlePath := path + bundleExt
+	log.Printf("signing attestation of %s", path)
+	err = d.runCommand(ctx, nil,
+		"cosign", "attest-blob", "--yes",
+		"--type", findingsPredicateType,
+		"--predicate", predFile.Name(),
+		"--bundle", bundlePath,
+		path,
+	)
+	if err != nil {
+		return fmt.Errorf("signing attestation: %w", err)
+	}
+	log.Printf("wrote attestation bundle to %s", bundlePath)
+
+	return nil
+}
+
+type inTotoStatement struct {
+	Type          string            `json:"_type"`
+	Subject       []inTotoSubject   `json:"subject"`
+	PredicateType string            `json:"predicateType"`
+	Predicate     findingsPredicate `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// sigstoreBundle is the subset of cosign's bundle formats needed to
+// find the signed in-toto statement. Newer sigstore bundles contain a
+// DSSE envelope, older cosign bundles contain a base64 encoded
+// envelope as the signature.
+type sigstoreBundle struct {
+	DSSEEnvelope    *dsseEnvelope `json:"dsseEnvelope"`
+	Base64Signature string        `json:"base64Signature"`
+}
+
+type dsseEnvelope struct {
+	Payload     string `json:"payload"`
+	PayloadType string `json:"payloadType"`
+}
+
+func verifyCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dep-inspector [flags] verify findings.json...")
+	}
+
+	for _, path := range args {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading findings: %w", err)
+		}
+		pred, err := d.verifyFindings(ctx, path, contents)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: verified, produced by dep-inspector %s at %s", path, pred.Tool.Version, pred.Timestamp.Format(time.RFC3339))
+		if pred.Module != "" {
+			fmt.Printf(" in module %s", pred.Module)
+		}
+		fmt.Printf(" inspecting %s\n", strings.Join(pred.Inspected, ", "))
+	}
+
+	return nil
+}
+
+// readInput reads a findings file and, if -verify was passed, verifies
+// its attestation. The file is only read once so the contents that are
+// used are the contents that were verified.
+func (d *depInspector) readInput(ctx context.Context, path string) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading findings: %w", err)
+	}
+	if d.verify {
+		if _, err := d.verifyFindings(ctx, path, contents); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// verifyFindings verifies the signature and certificate identity of a
+// findings file's attestation with cosign, and checks that the
+// attestation was produced by dep-inspector for contents, the contents
+// of the file that will be used.
+func (d *depInspector) verifyFindings(ctx context.Context, path string, contents []byte) (*findingsPredicate, error) {
+	if d.certIdentity == "" || d.certOIDCIssuer == "" {
+		return nil, errors.New("verifying attestations requires -certificate-identity and -certificate-oidc-issuer")
+	}
+
+	bundlePath := path + bundleExt
+	err := d.runCommand(ctx, nil,
+		"cosign", "verify-blob-attestation",
+		"--type", findingsPredicateType,
+		"--bundle", bundlePath,
+		"--certificate-identity", d.certIdentity,
+		"--certificate-oidc-issuer", d.certOIDCIssuer,
+		path,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("verifying attestation of %s: %w", path, err)
+	}
+
+	// cosign has verified the signature, now make sure the statement
+	// is what dep-inspector would produce
+	stmt, err := readAttestedStatement(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.PredicateType != findingsPredicateType {
+		return nil, fmt.Errorf("attestation of %s has predicate type %q, not %q", path, stmt.PredicateType, findingsPredicateType)
+	}
+	if stmt.Predicate.Tool.Name != "dep-inspector" {
+		return nil, fmt.Errorf("attestation of %s was produced by %q, not dep-inspector", path, stmt.Predicate.Tool.Name)
+	}
+
+	hash := sha256.Sum256(contents)
+	digest := hex.EncodeToString(hash[:])
+	if !slices.ContainsFunc(stmt.Subject, func(s inTotoSubject) bool {
+		return s.Digest["sha256"] == digest
+	}) {
+		return nil, fmt.Errorf("attestation of %s is not of its contents", path)
+	}
+
+	return &stmt.Predicate, nil
+}
+
+func readAttestedStatement(bundlePath string) (*inTotoStatement, error) {
+	contents, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading attestation bundle: %w", err)
+	}
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(contents, &bundle); err != nil {
+		return nil, fmt.Errorf("decoding attestation bundle: %w", err)
+	}
+
+	envelope := bundle.DSSEEnvelope
+	if envelope == nil {
+		sig, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+		if err != nil {
+			return nil, fmt.Errorf("decoding attestation bundle signature: %w", err)
+		}
+		envelope = new(dsseEnvelope)
+		if err := json.Unmarshal(sig, envelope); err != nil {
+			return nil, fmt.Errorf("decoding attestation envelope: %w", err)
+		}
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding attestation payload: %w", err)
+	}
+
+	var stmt inTotoStatement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return nil, fmt.Errorf("decoding in-toto statement: %w", err)
+	}
+
+	return &stmt, nil
+}
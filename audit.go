@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// auditProblem is something that needs to be done about a required
+// dependency before it can be trusted.
+type auditProblem struct {
+	Dep     string
+	Version string
+	Problem string
+}
+
+func auditCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: dep-inspector -store path.db [-approved-versions list] audit")
+	}
+	if d.store == nil {
+		return errors.New("a result store must be specified with -store")
+	}
+
+	goSum, err := goSumEntries(d.modFilePath)
+	if err != nil {
+		return err
+	}
+
+	var problems []auditProblem
+	for _, req := range d.parsedModFile.Require {
+		zipHash := moduleZipHash(goSum, req.Mod.Path, req.Mod.Version)
+		depProblems, err := d.auditDep(ctx, req.Mod.Path, req.Mod.Version, zipHash)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, depProblems...)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("all %d required dependencies have been inspected and are within policy\n", len(d.parsedModFile.Require))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprint(tw, "Dependency\tVersion\tProblem\n")
+	for _, p := range problems {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Dep, p.Version, p.Problem)
+	}
+
+	return tw.Flush()
+}
+
+// auditDep returns the problems of the required version of a
+// dependency: whether it was never inspected, only inspected at other
+// versions, isn't approved or violates policy. Findings are only used
+// if they were of the module content zipHash identifies.
+func (d *depInspector) auditDep(ctx context.Context, dep, version, zipHash string) ([]auditProblem, error) {
+	problem := func(format string, a ...any) auditProblem {
+		return auditProblem{
+			Dep:     dep,
+			Version: version,
+			Problem: fmt.Sprintf(format, a...),
+		}
+	}
+
+	inspection, ok, err := d.store.latest(ctx, dep, version)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		inspections, err := d.store.history(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		if len(inspections) == 0 {
+			return []auditProblem{problem("never inspected")}, nil
+		}
+		return []auditProblem{problem("only inspected at %s", inspections[0].Version)}, nil
+	}
+	if contentChanged(inspection.Findings.ZipHash, zipHash) {
+		return []auditProblem{problem("content changed since inspected, module zip hash was %s", inspection.Findings.ZipHash)}, nil
+	}
+
+	var problems []auditProblem
+	res := d.prepareResults(&savedResults{New: inspection.Findings})
+	if status := d.approvalStatus(res.New); status != nil {
+		switch {
+		case status.ContentChanged:
+			problems = append(problems, problem("content changed since approved"))
+		case status.Entry == nil:
+			problems = append(problems, problem("not approved"))
+		case !status.Current:
+			problems = append(problems, problem("findings changed since approved by %s", status.Entry.ApprovedBy))
+		}
+	}
+	for _, violation := range d.checkPolicy(res) {
+		d.policyViolated = true
+		// unapproved versions were already listed
+		if violation.Version != "" {
+			continue
+		}
+		problems = append(problems, problem("violates policy: %s", violation.Rule))
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+var goImportRe = regexp.MustCompile(`<meta\s+name="go-import"\s+content="([^"]+)"`)
+
+type netrcEntry struct {
+	machine  string
+	login    string
+	password string
+	// isDefault is true for the 'default' entry, which is used for
+	// machines that don't have an entry
+	isDefault bool
+}
+
+// loadNetrc parses the .netrc file dep-inspector was configured with,
+// or the default .netrc file the go command would use. A missing
+// default .netrc file is not an error.
+func (d *depInspector) loadNetrc() error {
+	path := d.netrcPath
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		base := ".netrc"
+		if runtime.GOOS == "windows" {
+			base = "_netrc"
+		}
+		path = filepath.Join(home, base)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading .netrc file: %w", err)
+	}
+	d.netrc = parseNetrc(string(contents))
+
+	return nil
+}
+
+func parseNetrc(contents string) []netrcEntry {
+	var (
+		entries []netrcEntry
+		entry   netrcEntry
+		inMacro bool
+	)
+
+	addEntry := func() {
+		if entry.machine != "" || entry.isDefault {
+			entries = append(entries, entry)
+		}
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(contents))
+	for sc.Scan() {
+		line := sc.Text()
+		// macro definitions end at the first empty line
+		if inMacro {
+			if line == "" {
+				inMacro = false
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			// 'default' is the only token that isn't followed by a
+			// value
+			if fields[i] == "default" {
+				addEntry()
+				entry = netrcEntry{isDefault: true}
+				continue
+			}
+			if i+1 == len(fields) {
+				break
+			}
+			token, value := fields[i], fields[i+1]
+			i++
+			switch token {
+			case "machine":
+				addEntry()
+				entry = netrcEntry{machine: value}
+			case "login":
+				entry.login = value
+			case "password":
+				entry.password = value
+			case "macdef":
+				// the rest of the line is part of the macro
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	addEntry()
+
+	return entries
+}
+
+// credentialsFor returns credentials for a host from .netrc or, if
+// enabled, from git's configured credential helpers. Entries of .netrc
+// for the host are always used, but like curl the 'default' entry is
+// used if no entry matches, so it and git's credentials are only sent
+// to private hosts. No credentials are used if GOAUTH is off.
+func (d *depInspector) credentialsFor(ctx context.Context, host string, private bool) (string, string, bool) {
+	if d.goEnv["GOAUTH"] == "off" {
+		return "", "", false
+	}
+	for _, entry := range d.netrc {
+		if !entry.isDefault && entry.machine == host {
+			return entry.login, entry.password, true
+		}
+	}
+	if !private {
+		return "", "", false
+	}
+	for _, entry := range d.netrc {
+		if entry.isDefault {
+			return entry.login, entry.password, true
+		}
+	}
+	if !d.gitCredentials {
+		return "", "", false
+	}
+
+	var (
+		input  = fmt.Sprintf("protocol=https\nhost=%s\n\n", host)
+		output bytes.Buffer
+	)
+	cmd, errBuf := d.buildCommand(ctx, &output, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input)
+	// never prompt the user, only credentials that are already stored
+	// should be used
+	cmd.Env = setEnv(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		if d.verbose {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				err = formatCmdErr(ctx, cmd, err, errBuf)
+			}
+			log.Printf("no git credentials found for %s: %v", host, err)
+		}
+		return "", "", false
+	}
+
+	var user, pass string
+	for _, line := range strings.Split(output.String(), "\n") {
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			user = val
+		case "password":
+			pass = val
+		}
+	}
+
+	return user, pass, user != "" || pass != ""
+}
+
+// isPrivate returns true if a module path or host matches GOPRIVATE
+// or GONOPROXY.
+func (d *depInspector) isPrivate(target string) bool {
+	return module.MatchPrefixPatterns(d.goEnv["GOPRIVATE"], target) ||
+		module.MatchPrefixPatterns(d.goEnv["GONOPROXY"], target)
+}
+
+// discoverRepoURL finds the repository URL of a module from its
+// go-import meta tag, authenticating the request if credentials for
+// the module's host are available and the module is private.
+func (d *depInspector) discoverRepoURL(ctx context.Context, modPath string) (string, error) {
+	reqURL := "https://" + modPath + "?go-get=1"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if user, pass, ok := d.credentialsFor(ctx, req.URL.Hostname(), d.isPrivate(modPath)); ok {
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching go-import meta tag: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching go-import meta tag: %s returned %s", reqURL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("reading go-import meta tag: %w", err)
+	}
+
+	for _, m := range goImportRe.FindAllSubmatch(body, -1) {
+		fields := strings.Fields(string(m[1]))
+		if len(fields) != 3 {
+			continue
+		}
+		prefix, vcsType, repoRoot := fields[0], fields[1], fields[2]
+		if vcsType == "mod" || (modPath != prefix && !strings.HasPrefix(modPath, prefix+"/")) {
+			continue
+		}
+		return repoRoot, nil
+	}
+
+	return "", fmt.Errorf("no go-import meta tag found for %s", modPath)
+}
+
+// normalizeRemoteURL converts SSH remotes, such as
+// git@github.com:owner/repo.git and ssh://git@host/owner/repo, to the
+// equivalent HTTPS URL so links to source can be generated.
+func normalizeRemoteURL(remote string) string {
+	if user, rest, ok := strings.Cut(remote, "@"); ok && !strings.Contains(user, "/") && !strings.Contains(remote, "://") {
+		host, repoPath, ok := strings.Cut(rest, ":")
+		if ok {
+			remote = "https://" + host + "/" + strings.TrimPrefix(repoPath, "/")
+		}
+	}
+	if u, err := url.Parse(remote); err == nil && (u.Scheme == "ssh" || u.Scheme == "git+ssh" || u.Scheme == "git") {
+		u.Scheme = "https"
+		u.User = nil
+		// SSH ports don't carry over to HTTPS
+		u.Host = u.Hostname()
+		remote = u.String()
+	}
+
+	return strings.TrimSuffix(remote, ".git")
+}
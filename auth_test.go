@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     []netrcEntry
+	}{
+		{
+			name:     "single line",
+			contents: "machine example.com login user password pass\n",
+			want: []netrcEntry{
+				{machine: "example.com", login: "user", password: "pass"},
+			},
+		},
+		{
+			name: "multiple lines",
+			contents: `machine a.example.com
+	login a
+	password pa
+machine b.example.com login b password pb
+`,
+			want: []netrcEntry{
+				{machine: "a.example.com", login: "a", password: "pa"},
+				{machine: "b.example.com", login: "b", password: "pb"},
+			},
+		},
+		{
+			name:     "default",
+			contents: "machine example.com login user password pass\ndefault login anon password secret\n",
+			want: []netrcEntry{
+				{machine: "example.com", login: "user", password: "pass"},
+				{login: "anon", password: "secret", isDefault: true},
+			},
+		},
+		{
+			name:     "default on the same line as a machine",
+			contents: "default login anon password secret machine example.com login user password pass",
+			want: []netrcEntry{
+				{login: "anon", password: "secret", isDefault: true},
+				{machine: "example.com", login: "user", password: "pass"},
+			},
+		},
+		{
+			name: "macro definitions are skipped",
+			contents: `machine a.example.com login a password pa macdef init
+machine evil.example.com login evil password evil
+cd /pub
+
+machine b.example.com login b password pb
+`,
+			want: []netrcEntry{
+				{machine: "a.example.com", login: "a", password: "pa"},
+				{machine: "b.example.com", login: "b", password: "pb"},
+			},
+		},
+		{
+			name:     "macro definition at the end",
+			contents: "machine example.com login user password pass\nmacdef init\nmachine evil.example.com login evil password evil\n",
+			want: []netrcEntry{
+				{machine: "example.com", login: "user", password: "pass"},
+			},
+		},
+		{
+			name:     "token without a value",
+			contents: "machine example.com login user password",
+			want: []netrcEntry{
+				{machine: "example.com", login: "user"},
+			},
+		},
+		{
+			name:     "empty",
+			contents: "",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseNetrc(tt.contents)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseNetrc() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
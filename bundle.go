@@ -0,0 +1,140 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// isZipPath returns true if a report should be bundled into a zip
+// archive with the raw data it was rendered from.
+func isZipPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".zip")
+}
+
+// bundleReport creates a zip archive of a rendered report, the
+// findings it was rendered from and the metadata of the tools that
+// produced them. If -source-diff was passed, a diff of the source code
+// of the compared versions is included too.
+func (d *depInspector) bundleReport(ctx context.Context, zipPath string, res *savedResults, report io.Reader) (io.Reader, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	// files are named after the archive so they are recognizable
+	// after being extracted
+	name := strings.TrimSuffix(filepath.Base(zipPath), filepath.Ext(zipPath))
+	if err := addZipFile(zw, name+formatExts[d.format], report); err != nil {
+		return nil, err
+	}
+	if d.htmlAssets {
+		if err := addHTMLAssets(zw); err != nil {
+			return nil, err
+		}
+	}
+	findings, err := jsonOutput(res)
+	if err != nil {
+		return nil, err
+	}
+	if err := addZipFile(zw, name+"-findings.json", findings); err != nil {
+		return nil, err
+	}
+	metadata, err := json.MarshalIndent(res.New.Metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding metadata: %w", err)
+	}
+	if err := addZipFile(zw, name+"-metadata.json", bytes.NewReader(metadata)); err != nil {
+		return nil, err
+	}
+	if d.sourceDiff && res.Old != nil {
+		var diff bytes.Buffer
+		// the source code of versions may not be in the module cache
+		// if results were loaded from files
+		if err := d.diffModuleSource(ctx, &diff, res.Old, res.New); err != nil {
+			log.Printf("not bundling source diff: %v", err)
+		} else if err := addZipFile(zw, name+".diff", &diff); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("creating zip archive: %w", err)
+	}
+	return &buf, nil
+}
+
+func addZipFile(zw *zip.Writer, name string, r io.Reader) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("adding %s to zip archive: %w", name, err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("adding %s to zip archive: %w", name, err)
+	}
+	return nil
+}
+
+// diffModuleSource writes a unified diff of the source code of two
+// module versions in the module cache.
+func (d *depInspector) diffModuleSource(ctx context.Context, w io.Writer, oldFindings, newFindings *depFindings) error {
+	return d.gitDiffModules(ctx, w, oldFindings, newFindings, "--no-color")
+}
+
+// gitDiffModules writes the output of git diff run with flags on the
+// source code of two module versions in the module cache.
+func (d *depInspector) gitDiffModules(ctx context.Context, w io.Writer, oldFindings, newFindings *depFindings, flags ...string) error {
+	oldDir, err := moduleCacheDir(oldFindings.Dep, oldFindings.Version)
+	if err != nil {
+		return err
+	}
+	newDir, err := moduleCacheDir(newFindings.Dep, newFindings.Version)
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{oldDir, newDir} {
+		if _, err := os.Stat(filepath.Join(d.modCache, dir)); err != nil {
+			return fmt.Errorf("source code not found: %w", err)
+		}
+	}
+
+	// diff relative paths so the diff doesn't contain the path of the
+	// module cache
+	args := append([]string{"git", "diff", "--no-index"}, flags...)
+	args = append(args, oldDir, newDir)
+	cmd, errBuf := d.buildCommand(ctx, w, args...)
+	cmd.Dir = d.modCache
+	err = cmd.Run()
+	// git diff exits with 1 if there are differences
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil
+	}
+	if err != nil {
+		return formatCmdErr(ctx, cmd, err, errBuf)
+	}
+	return nil
+}
+
+// moduleCacheDir returns the directory of a module version relative to
+// the module cache.
+func moduleCacheDir(dep, version string) (string, error) {
+	escPath, err := module.EscapePath(dep)
+	if err != nil {
+		return "", err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return makeVersionStr(escPath, escVer), nil
+}
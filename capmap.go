@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+)
+
+// builtinCapMap is the Map of rules of capslock's built-in capability
+// map.
+const builtinCapMap = "builtin"
+
+// capMapRule is the capability map rule that assigned a capability to
+// the final function of its call path.
+type capMapRule struct {
+	// Map is the path of the embedded or user-supplied capability map
+	// the rule is in, or 'builtin' if it is from capslock's built-in
+	// capability map
+	Map  string
+	Line int    `json:",omitempty"`
+	Rule string `json:",omitempty"`
+
+	capability string
+}
+
+func (r *capMapRule) String() string {
+	if r.Map == builtinCapMap {
+		return "capslock's built-in capability map"
+	}
+	return fmt.Sprintf("%s:%d: %s", r.Map, r.Line, r.Rule)
+}
+
+// capMapSource is a capability map passed to capslock.
+type capMapSource struct {
+	name     string
+	contents []byte
+	// user is true if the map was passed with -capability-map
+	user bool
+}
+
+// capabilityMaps are the embedded capability maps and the ones passed
+// with -capability-map, which take precedence over capslock's built-in
+// capability map.
+type capabilityMaps struct {
+	files     []capMapSource
+	funcRules map[string]*capMapRule
+	pkgRules  map[string]*capMapRule
+}
+
+// loadCapMaps reads the embedded capability maps and the user-supplied
+// capability maps at paths. Rules of user-supplied maps take precedence
+// over embedded ones.
+func loadCapMaps(paths []string) (*capabilityMaps, error) {
+	m := &capabilityMaps{
+		funcRules: make(map[string]*capMapRule),
+		pkgRules:  make(map[string]*capMapRule),
+	}
+	// the embedded file system can always be read
+	_ = fs.WalkDir(capMaps, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := fs.ReadFile(capMaps, path)
+		if err != nil {
+			return err
+		}
+		m.add(capMapSource{name: path, contents: contents})
+		return nil
+	})
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading capability map: %w", err)
+		}
+		m.add(capMapSource{name: path, contents: contents, user: true})
+	}
+
+	return m, nil
+}
+
+// add adds the rules of a capability map, replacing rules of the same
+// functions and packages of maps that were added before.
+func (m *capabilityMaps) add(f capMapSource) {
+	m.files = append(m.files, f)
+
+	s := bufio.NewScanner(bytes.NewReader(f.contents))
+	for line := 1; s.Scan(); line++ {
+		fields := capMapFields(s.Text())
+		// malformed rules are reported by capslock
+		if len(fields) != 3 {
+			continue
+		}
+		rule := &capMapRule{
+			Map:        f.name,
+			Line:       line,
+			Rule:       strings.Join(fields, " "),
+			capability: fields[2],
+		}
+		switch fields[0] {
+		case "func":
+			m.funcRules[fields[1]] = rule
+		case "package":
+			m.pkgRules[fields[1]] = rule
+		}
+	}
+}
+
+// capMapFields returns the fields of a line of a capability map with
+// any comment removed.
+func capMapFields(line string) []string {
+	line, _, _ = strings.Cut(line, "#")
+	return strings.Fields(line)
+}
+
+// ruleFor returns the capability map rule that assigned a capability.
+// Rules of functions take precedence over rules of their package.
+func (m *capabilityMaps) ruleFor(c *capability) *capMapRule {
+	if len(c.Path) == 0 {
+		return &capMapRule{Map: builtinCapMap}
+	}
+	finalCall := c.Path[len(c.Path)-1].Name
+	rule, ok := m.funcRules[finalCall]
+	if !ok {
+		rule, ok = m.pkgRules[funcPackage(receiverReplacer.Replace(finalCall))]
+	}
+	if !ok || rule.capability != c.Capability {
+		return &capMapRule{Map: builtinCapMap}
+	}
+
+	ruleCopy := *rule
+	return &ruleCopy
+}
+
+// write writes every capability map so they can be passed to capslock
+// as one file.
+func (m *capabilityMaps) write(w io.Writer) error {
+	for _, f := range m.files {
+		if _, err := w.Write(f.contents); err != nil {
+			return err
+		}
+		// don't join the last line of a map with the first of the next
+		if len(f.contents) != 0 && f.contents[len(f.contents)-1] != '\n' {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// configHashes returns the hashes of the embedded configs of the
+// analysis tools and the user-supplied capability maps, keyed by their
+// paths.
+func (d *depInspector) configHashes() map[string]string {
+	hashes := configHashes()
+	if d.capMaps == nil {
+		return hashes
+	}
+
+	hashes = maps.Clone(hashes)
+	for _, f := range d.capMaps.files {
+		if f.user {
+			sum := sha256.Sum256(f.contents)
+			hashes[f.name] = hex.EncodeToString(sum[:])
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/tools/go/packages"
+)
+
+const capmapUsage = "usage: dep-inspector [flags] capmap check [-fixture dir] [map.cm...] | capmap stubs path/of/module@version"
+
+// capMapProblem is a problem with a rule of a user-supplied capability
+// map.
+type capMapProblem struct {
+	file string
+	line int
+	// warning is true if capslock accepts the rule but it probably
+	// doesn't do what was intended
+	warning bool
+	msg     string
+}
+
+func (p capMapProblem) String() string {
+	level := "error"
+	if p.warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.file, p.line, level, p.msg)
+}
+
+// capMapRef is a function or package a rule of a user-supplied
+// capability map assigns a capability to.
+type capMapRef struct {
+	file string
+	line int
+	kind string
+	name string
+}
+
+func capmapCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) == 0 {
+		return errors.New(capmapUsage)
+	}
+	switch args[0] {
+	case "check":
+		return capmapCheckCmd(ctx, d, args[1:])
+	case "stubs":
+		return capmapStubsCmd(ctx, d, args[1:])
+	default:
+		return errors.New(capmapUsage)
+	}
+}
+
+func capmapCheckCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("capmap check", flag.ContinueOnError)
+	fixture := fs.String("fixture", "", "directory of a Go module to run capslock on with the capability maps to show which capabilities their rules assign")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = d.capMapPaths
+	}
+	if len(paths) == 0 {
+		return errors.New(capmapUsage)
+	}
+
+	m, err := loadCapMaps(paths)
+	if err != nil {
+		return err
+	}
+	problems, err := d.checkCapMaps(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	var errCount int
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+		if !p.warning {
+			errCount++
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "no problems found in %s\n", strings.Join(paths, ", "))
+	}
+	if *fixture != "" && errCount == 0 {
+		if err := d.runCapMapFixture(ctx, w, m, *fixture); err != nil {
+			w.Flush()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if errCount != 0 {
+		return fmt.Errorf("found %d errors in capability maps", errCount)
+	}
+	return nil
+}
+
+// checkCapMaps checks the rules of user-supplied capability maps for
+// syntax errors, unknown capabilities, rules that conflict with rules
+// of other maps, and functions and packages that don't exist.
+func (d *depInspector) checkCapMaps(ctx context.Context, m *capabilityMaps) ([]capMapProblem, error) {
+	type seenRule struct {
+		file       string
+		line       int
+		capability string
+		user       bool
+	}
+	var (
+		problems []capMapProblem
+		refs     []capMapRef
+		// seen is the rules of maps checked so far, keyed by their
+		// kind and name
+		seen = make(map[string]seenRule)
+	)
+	for _, f := range m.files {
+		s := bufio.NewScanner(bytes.NewReader(f.contents))
+		for line := 1; s.Scan(); line++ {
+			fields := capMapFields(s.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			problem := func(warning bool, format string, args ...any) {
+				problems = append(problems, capMapProblem{
+					file:    f.name,
+					line:    line,
+					warning: warning,
+					msg:     fmt.Sprintf(format, args...),
+				})
+			}
+
+			// embedded maps are correct, they are only needed to find
+			// conflicting rules
+			if f.user {
+				if len(fields) != 3 {
+					problem(false, "expected 'func|package name CAPABILITY', got %q", strings.Join(fields, " "))
+					continue
+				}
+				if fields[0] != "func" && fields[0] != "package" {
+					problem(false, "unknown rule kind %q, must be func or package", fields[0])
+					continue
+				}
+				if _, ok := defaultCapSeverities[fields[2]]; !ok {
+					problem(false, "unknown capability %q", fields[2])
+					continue
+				}
+			}
+
+			key := fields[0] + " " + fields[1]
+			prev, ok := seen[key]
+			seen[key] = seenRule{file: f.name, line: line, capability: fields[2], user: f.user}
+			if !f.user {
+				continue
+			}
+			refs = append(refs, capMapRef{file: f.name, line: line, kind: fields[0], name: fields[1]})
+
+			switch {
+			case !ok:
+			case prev.capability == fields[2]:
+				problem(true, "duplicate of rule at %s:%d", prev.file, prev.line)
+			case prev.user:
+				problem(false, "conflicts with rule at %s:%d, which assigns %s", prev.file, prev.line, prev.capability)
+			default:
+				problem(true, "overrides embedded rule at %s:%d, which assigns %s", prev.file, prev.line, prev.capability)
+			}
+		}
+	}
+
+	refProblems, err := d.checkCapMapRefs(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, refProblems...)
+	slices.SortStableFunc(problems, func(a, b capMapProblem) int {
+		if a.file != b.file {
+			return strings.Compare(a.file, b.file)
+		}
+		return a.line - b.line
+	})
+
+	return problems, nil
+}
+
+// checkCapMapRefs checks that the functions and packages rules assign
+// capabilities to exist, loading them from the main module. Functions
+// and packages that don't exist are only warned about, they may exist
+// in other versions of Go or the module they are in.
+func (d *depInspector) checkCapMapRefs(ctx context.Context, refs []capMapRef) ([]capMapProblem, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	pkgPaths := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.kind == "package" {
+			pkgPaths[ref.name] = true
+		} else {
+			pkgPaths[funcPackage(receiverReplacer.Replace(ref.name))] = true
+		}
+	}
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     d.workDir,
+		Env:     d.commandEnv(),
+	}
+	patterns := maps.Keys(pkgPaths)
+	slices.Sort(patterns)
+	loaded, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+	// packages that failed to load aren't included
+	pkgDecls := make(map[string]map[string]bool)
+	for _, pkg := range loaded {
+		if len(pkg.Errors) != 0 {
+			continue
+		}
+		decls, err := declaredFuncs(pkg)
+		if err != nil {
+			return nil, err
+		}
+		pkgDecls[pkg.PkgPath] = decls
+	}
+
+	var problems []capMapProblem
+	for _, ref := range refs {
+		pkgPath := ref.name
+		if ref.kind == "func" {
+			pkgPath = funcPackage(receiverReplacer.Replace(ref.name))
+		}
+		problem := capMapProblem{file: ref.file, line: ref.line, warning: true}
+
+		decls, ok := pkgDecls[pkgPath]
+		switch {
+		case !ok:
+			problem.msg = fmt.Sprintf("package %s isn't in the standard library or a dependency of the main module", pkgPath)
+		case ref.kind == "func" && !capMapFuncExists(decls, pkgPath, ref.name):
+			problem.msg = fmt.Sprintf("%s isn't a function or method of package %s", ref.name, pkgPath)
+		default:
+			continue
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems, nil
+}
+
+// capMapFuncExists returns true if a function named the way capability
+// maps name them, such as 'os.Getenv' or '(*net/http.Client).Do', is
+// in decls, the declared functions of the package at pkgPath.
+func capMapFuncExists(decls map[string]bool, pkgPath, name string) bool {
+	// type parameters and closures aren't part of declared names
+	if start, end := strings.Index(name, "["), strings.Index(name, "]"); start != -1 && end > start {
+		name = name[:start] + name[end+1:]
+	}
+	name, _, _ = strings.Cut(name, "$")
+
+	if strings.HasPrefix(name, "(") {
+		recv, method, ok := strings.Cut(name[1:], ").")
+		if !ok {
+			return false
+		}
+		recv = strings.TrimPrefix(strings.TrimPrefix(recv, "*"), pkgPath+".")
+		return decls[recv+"."+method]
+	}
+	return decls[strings.TrimPrefix(name, pkgPath+".")]
+}
+
+// declaredFuncs returns the names of functions, methods and interface
+// methods declared in the files of pkg for every platform, methods are
+// named 'Type.Method'. Source is parsed instead of type checked so
+// packages of any Go version can be checked.
+func declaredFuncs(pkg *packages.Package) (map[string]bool, error) {
+	decls := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range append(slices.Clip(pkg.GoFiles), pkg.IgnoredFiles...) {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil && len(decl.Recv.List) != 0 {
+					name = recvTypeName(decl.Recv.List[0].Type) + "." + name
+				}
+				decls[name] = true
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					iface, ok := typeSpec.Type.(*ast.InterfaceType)
+					if !ok {
+						continue
+					}
+					for _, method := range iface.Methods.List {
+						for _, name := range method.Names {
+							decls[typeSpec.Name.Name+"."+name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// recvTypeName returns the name of the type of a method receiver.
+func recvTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return recvTypeName(expr.X)
+	case *ast.IndexExpr:
+		return recvTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(expr.X)
+	case *ast.ParenExpr:
+		return recvTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// runCapMapFixture runs capslock on the packages of the module in dir
+// with capability maps m, and prints the capabilities rules of
+// user-supplied maps assigned.
+func (d *depInspector) runCapMapFixture(ctx context.Context, w io.Writer, m *capabilityMaps, dir string) error {
+	fixture := *d
+	fixture.workDir = dir
+	fixture.capMaps = m
+	results, err := fixture.runCapslock(ctx, "", dir, []string{"./..."})
+	if err != nil {
+		return err
+	}
+
+	var matched int
+	fmt.Fprintf(w, "\nCapabilities assigned in %s:\n", dir)
+	for _, c := range results.CapabilityInfo {
+		user := slices.ContainsFunc(m.files, func(f capMapSource) bool {
+			return f.user && f.name == c.MapRule.Map
+		})
+		if !user {
+			continue
+		}
+		matched++
+
+		fmt.Fprintf(w, "\n  %s\n", c.MapRule)
+		for _, call := range c.Path {
+			fmt.Fprintf(w, "    %s", call.Name)
+			if call.Site.Filename != "" {
+				fmt.Fprintf(w, " at %s:%s", call.Site.Filename, call.Site.Line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if matched == 0 {
+		fmt.Fprintln(w, "  none, no rules of the capability maps matched calls of the fixture")
+	}
+
+	return nil
+}
+
+func capmapStubsCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector [flags] capmap stubs path/of/module@version")
+	}
+	dep, version, ok := strings.Cut(args[0], "@")
+	if !ok || dep == "" || version == "" {
+		return fmt.Errorf("%q is not a module version, must be path/of/module@version", args[0])
+	}
+	version, err := d.checkVersion(dep, version)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := d.setupDep(ctx, d.newModBackupFiles, dep, version, true)
+	if err != nil {
+		return err
+	}
+	versionStr := makeVersionStr(dep, version)
+	results, err := d.findCapabilities(ctx, dep, versionStr, pkgs)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeCapMapStubs(&buf, versionStr, results.CapabilityInfo, d.severities)
+	if d.outputFile == "" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+	return writeFile(d.outputFile, &buf)
+}
+
+// writeCapMapStubs writes a capability map with a rule for every
+// exported function the call paths of caps start at. Each function is
+// assigned the most severe capability it reaches, and the other
+// capabilities it reaches are listed in a comment so the rules can be
+// refined by hand.
+func writeCapMapStubs(w io.Writer, versionStr string, caps []*capability, severities *severityModel) {
+	// reached maps exported functions to the capabilities they reach,
+	// keyed by capability so each is only listed once
+	reached := make(map[string]map[string]*capability)
+	for _, c := range caps {
+		if len(c.Path) == 0 || !exportedFunc(c.Path[0].Name) {
+			continue
+		}
+		name := c.Path[0].Name
+		if reached[name] == nil {
+			reached[name] = make(map[string]*capability)
+		}
+		reached[name][c.Capability] = c
+	}
+
+	fmt.Fprintf(w, "# Capability map stubs of %s generated by dep-inspector.\n", versionStr)
+	fmt.Fprintln(w, "# Every exported function is assigned the most severe capability it")
+	fmt.Fprintln(w, "# reaches. Refine the rules, such as by marking functions that only")
+	fmt.Fprintln(w, "# use capabilities in ways that are safe as CAPABILITY_SAFE, and pass")
+	fmt.Fprintln(w, "# the map with -capability-map.")
+	if len(reached) == 0 {
+		fmt.Fprintln(w, "#\n# No exported functions reach any capabilities.")
+		return
+	}
+
+	funcs := maps.Keys(reached)
+	slices.SortFunc(funcs, func(a, b string) int {
+		aPkg, bPkg := funcPackage(receiverReplacer.Replace(a)), funcPackage(receiverReplacer.Replace(b))
+		if aPkg != bPkg {
+			return strings.Compare(aPkg, bPkg)
+		}
+		return strings.Compare(a, b)
+	})
+	var lastPkg string
+	for _, name := range funcs {
+		if pkg := funcPackage(receiverReplacer.Replace(name)); pkg != lastPkg {
+			fmt.Fprintf(w, "\n# %s\n", pkg)
+			lastPkg = pkg
+		}
+
+		funcCaps := maps.Values(reached[name])
+		slices.SortFunc(funcCaps, func(a, b *capability) int {
+			// most severe first
+			if c := compareSeverity(severities.capSeverity(b), severities.capSeverity(a)); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Capability, b.Capability)
+		})
+		if len(funcCaps) > 1 {
+			others := make([]string, 0, len(funcCaps)-1)
+			for _, c := range funcCaps[1:] {
+				others = append(others, c.Capability)
+			}
+			fmt.Fprintf(w, "# also reaches %s\n", strings.Join(others, ", "))
+		}
+		fmt.Fprintf(w, "func %s %s\n", name, funcCaps[0].Capability)
+	}
+}
+
+// exportedFunc returns true if a function named the way capability maps
+// name them is exported, and if it's a method, its receiver type is
+// exported too.
+func exportedFunc(name string) bool {
+	// closures can't be called from other packages
+	if strings.Contains(name, "$") {
+		return false
+	}
+	if start, end := strings.Index(name, "["), strings.Index(name, "]"); start != -1 && end > start {
+		name = name[:start] + name[end+1:]
+	}
+	name = receiverReplacer.Replace(name)
+	for _, ident := range strings.Split(strings.TrimPrefix(name, funcPackage(name)+"."), ".") {
+		if !token.IsExported(ident) {
+			return false
+		}
+	}
+	return true
+}
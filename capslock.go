@@ -1,23 +1,29 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 )
 
 //go:embed configs/capslock
 var capMaps embed.FS
 
+// capTypeDirect is the type of capabilities the dependency's own code
+// uses, as opposed to ones only its dependencies use.
+const capTypeDirect = "CAPABILITY_TYPE_DIRECT"
+
+// capslockGranularities are the granularities capslock can report
+// capabilities at, from coarsest to finest.
+var capslockGranularities = []string{"package", "intermediate", "function"}
+
 type capslockResult struct {
 	CapabilityInfo []*capability
 	ModuleInfo     []capModule
@@ -29,6 +35,28 @@ type capability struct {
 	Path           []functionCall
 	PackageDir     string
 	CapabilityType string
+	// Severity is set from the configured severity model when
+	// rendering reports
+	Severity string `json:",omitempty"`
+	// Triage is the decision reviewers made about the capability, set
+	// from triage decisions when rendering reports
+	Triage *triageDecision `json:",omitempty"`
+	// Comments are reviewers' comments about the capability, set from
+	// comments when rendering reports
+	Comments []findingComment `json:",omitempty"`
+	// MapRule is the capability map rule that assigned the capability
+	// to the final function of Path
+	MapRule *capMapRule `json:",omitempty"`
+	// Classification is the weaknesses the capability is an exposure
+	// surface for, set when rendering reports if -classify is passed
+	Classification *classification `json:",omitempty"`
+	// Generated is true if the capability's call path starts in a
+	// generated file, set when rendering reports
+	Generated bool `json:",omitempty"`
+	// ReportedVia are the dependencies whose findings included this
+	// capability when it was reported by more than one dependency in
+	// the same run
+	ReportedVia []string `json:",omitempty"`
 }
 
 type functionCall struct {
@@ -48,20 +76,28 @@ type capModule struct {
 }
 
 func (d *depInspector) findCapabilities(ctx context.Context, dep, versionStr string, pkgs loadedPackages) (*capslockResult, error) {
-	allPkgs := dep + "/..."
-	var depPkgs []string
+	depPkgs, err := d.capslockPackages(dep, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	return d.runCapslock(ctx, dep, versionStr, depPkgs)
+}
+
+// capslockPackages returns the packages of dep capslock is run on,
+// the packages that are imported unless -a or -unused-dep was passed.
+func (d *depInspector) capslockPackages(dep string, pkgs loadedPackages) ([]string, error) {
 	if d.inspectAllPkgs || d.unusedDep {
-		depPkgs = []string{allPkgs}
-	} else {
-		importedPkgs, err := listImportedPackages(dep, pkgs)
-		if err != nil {
-			return nil, err
-		}
-		depPkgs = importedPkgs
+		return []string{dep + "/..."}, nil
 	}
+	return listImportedPackages(dep, pkgs)
+}
 
-	// write embedded capability maps to a temporary file to it can
-	// be used by capslock
+// runCapslock finds the capabilities of packages of module with
+// capslock. Capabilities in ignored packages and files are dropped as
+// they are decoded.
+func (d *depInspector) runCapslock(ctx context.Context, module, versionStr string, pkgs []string) (*capslockResult, error) {
+	// write embedded and user-supplied capability maps to a temporary
+	// file so it can be used by capslock
 	cfgDir, err := os.MkdirTemp("", tempPrefix)
 	if err != nil {
 		return nil, fmt.Errorf("creating temporary directory: %w", err)
@@ -73,45 +109,58 @@ func (d *depInspector) findCapabilities(ctx context.Context, dep, versionStr str
 		return nil, fmt.Errorf("creating temporary file: %w", err)
 	}
 
-	err = fs.WalkDir(capMaps, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		f, err := capMaps.Open(path)
-		if err != nil {
-			return fmt.Errorf("opening embedded capability map: %w", err)
-		}
-		defer f.Close()
-
-		_, err = io.Copy(capMapFile, f)
-		if err != nil {
-			return fmt.Errorf("writing to temporary file: %w", err)
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("walking embedded capability maps: %w", err)
+	if err := d.capMaps.write(capMapFile); err != nil {
+		capMapFile.Close()
+		return nil, fmt.Errorf("writing capability maps to temporary file: %w", err)
 	}
 	if err := capMapFile.Close(); err != nil {
 		return nil, fmt.Errorf("closing temporary file: %w", err)
 	}
 
 	log.Printf("finding capabilities of %s with capslock", versionStr)
-	var output bytes.Buffer
-	cmd := []string{"capslock", "-packages", strings.Join(depPkgs, ","), "-capability_map", capMapFile.Name(), "-output=json"}
-	err = d.runCommand(ctx, &output, cmd...)
-	if err != nil {
+	var results capslockResult
+	decode := func(dec *json.Decoder) error {
+		err := decodeObject(dec, func(name string) error {
+			switch name {
+			case "CapabilityInfo":
+				return decodeArray(dec, func() error {
+					var c capability
+					if err := dec.Decode(&c); err != nil {
+						return err
+					}
+					if !d.filter.ignoredCap(module, &c) {
+						c.MapRule = d.capMaps.ruleFor(&c)
+						results.CapabilityInfo = append(results.CapabilityInfo, &c)
+					}
+					return nil
+				})
+			case "ModuleInfo":
+				return dec.Decode(&results.ModuleInfo)
+			default:
+				return skipValue(dec)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("decoding results from capslock: %w", err)
+		}
+		return nil
+	}
+	cmd := []string{"capslock", "-packages", strings.Join(pkgs, ","), "-capability_map", capMapFile.Name(), "-output=json"}
+	if d.granularity != "" {
+		cmd = append(cmd, "-granularity", d.granularity)
+	}
+	if d.noInitSummary {
+		cmd = append(cmd, "-noinitsummary")
+	}
+	if d.buildTags != "" {
+		cmd = append(cmd, "-buildtags", d.buildTags)
+	}
+	start := time.Now()
+	if err := d.runAnalyzer(ctx, decode, nil, cmd...); err != nil {
 		return nil, err
 	}
+	d.metrics.observeAnalyzer("capslock", start)
 
-	var results capslockResult
-	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
-		return nil, fmt.Errorf("decoding results from capslock: %w", err)
-	}
 	results.CapabilityInfo = slices.Clip(results.CapabilityInfo)
 	slices.SortFunc(results.CapabilityInfo, compareCaps)
 
@@ -158,41 +207,3 @@ func compareCaps(a, b *capability) int {
 
 	return 0
 }
-
-func capsEqual(a, b *capability) bool {
-	if a.PackageDir != b.PackageDir {
-		return false
-	}
-	if a.PackageName != b.PackageName {
-		return false
-	}
-	if a.Capability != b.Capability {
-		return false
-	}
-	if a.CapabilityType != b.CapabilityType {
-		return false
-	}
-	if len(a.Path) != len(b.Path) {
-		return false
-	}
-
-	for i := range a.Path {
-		if a.Path[i].Name != b.Path[i].Name {
-			return false
-		}
-
-		callA := a.Path[i].Site
-		callB := a.Path[i].Site
-		if callA.Filename != callB.Filename {
-			return false
-		}
-		if callA.Line != callB.Line {
-			return false
-		}
-		if callA.Column != callB.Column {
-			return false
-		}
-	}
-
-	return true
-}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// chatMessage is a short notification message about the results of an
+// inspection, formatted differently depending on the chat service.
+type chatMessage struct {
+	dep        string
+	oldVersion string
+	newVersion string
+	caps       []string
+	reportURL  string
+}
+
+func newChatMessage(res *savedResults, reportURL string) chatMessage {
+	msg := chatMessage{
+		dep:        res.New.Dep,
+		newVersion: res.New.Version,
+		reportURL:  reportURL,
+	}
+
+	caps := res.New.Caps.CapabilityInfo
+	if res.Old != nil {
+		msg.oldVersion = res.Old.Version
+		caps = compareFindings(res.Old, res.New).addedCaps
+	}
+	for _, c := range caps {
+		msg.caps = append(msg.caps, strings.TrimPrefix(c.Capability, "CAPABILITY_"))
+	}
+	slices.Sort(msg.caps)
+	msg.caps = slices.Compact(msg.caps)
+
+	return msg
+}
+
+// text formats the message, link formats a link to the report in the
+// markup of the chat service.
+func (m chatMessage) text(link func(text, url string) string) string {
+	var sb strings.Builder
+	if m.oldVersion != "" {
+		fmt.Fprintf(&sb, "New version %s of %s ", m.newVersion, m.dep)
+		if len(m.caps) == 0 {
+			sb.WriteString("adds no new capabilities")
+		} else {
+			fmt.Fprintf(&sb, "adds %s capabilities", joinWords(m.caps))
+		}
+		fmt.Fprintf(&sb, " compared to %s", m.oldVersion)
+	} else {
+		fmt.Fprintf(&sb, "%s ", makeVersionStr(m.dep, m.newVersion))
+		if len(m.caps) == 0 {
+			sb.WriteString("has no capabilities")
+		} else {
+			fmt.Fprintf(&sb, "has %s capabilities", joinWords(m.caps))
+		}
+	}
+	if m.reportURL != "" {
+		sb.WriteString(". ")
+		sb.WriteString(link("View report", m.reportURL))
+	}
+
+	return sb.String()
+}
+
+// joinWords joins words in a list that reads naturally in a sentence.
+func joinWords(words []string) string {
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		return words[0]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// notifyChat posts a message summarizing results to the configured
+// Slack and Discord webhooks.
+func (d *depInspector) notifyChat(ctx context.Context, res *savedResults, reportURL string) error {
+	msg := newChatMessage(res, reportURL)
+
+	var errs []error
+	if d.slackWebhook != "" {
+		payload := struct {
+			Text string `json:"text"`
+		}{
+			Text: msg.text(func(text, url string) string {
+				return fmt.Sprintf("<%s|%s>", url, text)
+			}),
+		}
+		if err := postChatMessage(ctx, d.slackWebhook, payload); err != nil {
+			errs = append(errs, fmt.Errorf("posting Slack message: %w", err))
+		}
+	}
+	if d.discordWebhook != "" {
+		payload := struct {
+			Content string `json:"content"`
+		}{
+			Content: msg.text(func(text, url string) string {
+				return fmt.Sprintf("[%s](%s)", text, url)
+			}),
+		}
+		if err := postChatMessage(ctx, d.discordWebhook, payload); err != nil {
+			errs = append(errs, fmt.Errorf("posting Discord message: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func postChatMessage(ctx context.Context, webhookURL string, payload any) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// don't leak the webhook URL, it contains a secret token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("returned %s: %s", resp.Status, msg)
+	}
+
+	return nil
+}
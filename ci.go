@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// baselineFindings are the findings of every dependency of the main
+// module. CI on the main branch can save them so pull requests can be
+// compared against them, only inspecting dependencies that changed.
+type baselineFindings struct {
+	Module   string
+	Metadata reportMetadata
+	Deps     []*depFindings
+}
+
+func baselineCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: dep-inspector [flags] baseline")
+	}
+
+	base := d.inspectRequiredDeps(ctx)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(base); err != nil {
+		return fmt.Errorf("encoding baseline: %w", err)
+	}
+	if d.outputFile == "" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+	if err := writeFile(d.outputFile, &buf); err != nil {
+		return err
+	}
+	if d.sign {
+		return d.attestFindings(ctx, d.outputFile, d.newFindingsPredicate(base.Metadata, base.Deps...))
+	}
+
+	return nil
+}
+
+// inspectRequiredDeps inspects the required version of every
+// dependency of the main module.
+func (d *depInspector) inspectRequiredDeps(ctx context.Context) *baselineFindings {
+	base := &baselineFindings{
+		Module:   d.parsedModFile.Module.Mod.Path,
+		Metadata: d.buildMetadata(),
+	}
+	for _, req := range d.parsedModFile.Require {
+		log.Printf("inspecting %s", makeVersionStr(req.Mod.Path, req.Mod.Version))
+		// go.mod doesn't need to be changed to inspect the current
+		// version, so the original go.mod backup is restored
+		findings, err := d.inspectDep(ctx, d.modBackupFiles, req.Mod.Path, req.Mod.Version, false, nil)
+		if err != nil {
+			log.Printf("skipping %s: %v", req.Mod.Path, err)
+			continue
+		}
+		base.Deps = append(base.Deps, findings)
+	}
+
+	return base
+}
+
+// loadBaseline reads and, if -verify was passed, verifies a saved
+// baseline of the main module modPath.
+func (d *depInspector) loadBaseline(ctx context.Context, path, modPath string) (*baselineFindings, error) {
+	contents, err := d.readInput(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var base baselineFindings
+	if err := json.Unmarshal(contents, &base); err != nil {
+		return nil, fmt.Errorf("decoding baseline: %w", err)
+	}
+	if base.Module != modPath {
+		return nil, fmt.Errorf("baseline is of module %s, not %s", base.Module, modPath)
+	}
+
+	return &base, nil
+}
+
+func compareBaselineCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector [flags] compare-baseline baseline.json")
+	}
+
+	base, err := d.loadBaseline(ctx, args[0], d.parsedModFile.Module.Mod.Path)
+	if err != nil {
+		return err
+	}
+
+	baseDeps := make(map[string]*depFindings, len(base.Deps))
+	for _, findings := range base.Deps {
+		baseDeps[findings.Dep] = findings
+	}
+
+	goSum, err := goSumEntries(d.modFilePath)
+	if err != nil {
+		return err
+	}
+
+	var depsToInspect []changedDep
+	required := make(map[string]bool, len(d.parsedModFile.Require))
+	for _, req := range d.parsedModFile.Require {
+		required[req.Mod.Path] = true
+		oldFindings, ok := baseDeps[req.Mod.Path]
+		if ok && oldFindings.Version == req.Mod.Version {
+			// findings of the baseline can't be reused if the content
+			// of the version changed
+			if !contentChanged(oldFindings.ZipHash, moduleZipHash(goSum, req.Mod.Path, req.Mod.Version)) {
+				continue
+			}
+			log.Printf("the content of %s changed since the baseline was created, inspecting it again", makeVersionStr(req.Mod.Path, req.Mod.Version))
+		}
+
+		changed := changedDep{
+			dep:    req.Mod.Path,
+			newVer: req.Mod.Version,
+		}
+		if ok {
+			changed.oldVer = oldFindings.Version
+		}
+		depsToInspect = append(depsToInspect, changed)
+	}
+	for dep := range baseDeps {
+		if !required[dep] {
+			log.Printf("%s is no longer required", dep)
+		}
+	}
+	if len(depsToInspect) == 0 {
+		log.Println("no dependencies changed compared to the baseline")
+		return nil
+	}
+
+	d.multipleReports = len(depsToInspect) > 1
+	var (
+		results []*savedResults
+		errs    []error
+	)
+	for _, changed := range depsToInspect {
+		log.Printf("inspecting %s", changed.dep)
+		newFindings, err := d.inspectDep(ctx, d.modBackupFiles, changed.dep, changed.newVer, false, knownFindings(baseDeps[changed.dep]))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inspecting %s: %w", makeVersionStr(changed.dep, changed.newVer), err))
+			continue
+		}
+
+		// dependencies that aren't in the baseline were added, so
+		// they have no old findings to compare against
+		res := &savedResults{
+			Old: baseDeps[changed.dep],
+			New: newFindings,
+		}
+		if err := d.outputResults(ctx, res); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, res)
+	}
+	// pages of multiple reports need an index
+	if d.pages && d.multipleReports {
+		if err := d.writeRollup(ctx, results, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
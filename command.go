@@ -1,38 +1,87 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// cmdWaitDelay is how long to wait for the output of a command to be
+// closed after it was killed.
+const cmdWaitDelay = 5 * time.Second
+
 func (d *depInspector) runGoCommand(ctx context.Context, args ...string) error {
-	env := make([]string, len(goEnvVars))
-	for _, envVar := range goEnvVars {
-		env = append(env, fmt.Sprintf("%s=%s", envVar, os.Getenv(envVar)))
-	}
+	return d.runCommand(ctx, nil, args...)
+}
 
-	cmd, errBuf := d.buildCommand(ctx, nil, env, args...)
+func (d *depInspector) runCommand(ctx context.Context, writer io.Writer, args ...string) error {
+	cmd, errBuf := d.buildCommand(ctx, writer, args...)
 	if err := cmd.Run(); err != nil {
-		return formatCmdErr(cmd, err, errBuf)
+		return formatCmdErr(ctx, cmd, err, errBuf)
 	}
 	return nil
 }
 
-func (d *depInspector) runCommand(ctx context.Context, writer io.Writer, args ...string) error {
-	cmd, errBuf := d.buildCommand(ctx, writer, nil, args...)
-	if err := cmd.Run(); err != nil {
-		return formatCmdErr(cmd, err, errBuf)
+// runAnalyzer runs an analysis tool and decodes its JSON output with
+// decode while it's written, so large outputs aren't buffered in memory.
+// Exit codes in okCodes aren't errors, linters exit with them when they
+// find issues. If -jobs was passed, it waits until fewer than that many
+// analysis tools are running.
+func (d *depInspector) runAnalyzer(ctx context.Context, decode func(*json.Decoder) error, okCodes []int, args ...string) error {
+	if d.analyzerSlots != nil {
+		select {
+		case d.analyzerSlots <- struct{}{}:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+		defer func() { <-d.analyzerSlots }()
 	}
-	return nil
+
+	if d.confinedEnv != "" {
+		args = append([]string{d.executable}, args...)
+	}
+	cmd, errBuf := d.buildCommand(ctx, nil, args...)
+	cmd.Env = setEnv(d.commandEnv(), d.analyzerEnv()...)
+	if d.confinedEnv != "" {
+		cmd.Env = setEnv(cmd.Env, d.confinedEnv)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return formatCmdErr(ctx, cmd, err, errBuf)
+	}
+
+	decodeErr := decode(json.NewDecoder(bufio.NewReader(stdout)))
+	// read the rest of the output so the command doesn't block writing
+	// it if decoding failed
+	if _, err := io.Copy(io.Discard, stdout); err != nil && decodeErr == nil {
+		decodeErr = fmt.Errorf("reading output: %w", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || !slices.Contains(okCodes, exitErr.ExitCode()) {
+			return formatCmdErr(ctx, cmd, err, errBuf)
+		}
+	}
+	return decodeErr
 }
 
-func (d *depInspector) buildCommand(ctx context.Context, writer io.Writer, env []string, args ...string) (*exec.Cmd, *bytes.Buffer) {
+func (d *depInspector) buildCommand(ctx context.Context, writer io.Writer, args ...string) (*exec.Cmd, *bytes.Buffer) {
 	var cmd *exec.Cmd
 	if len(args) == 1 {
 		cmd = exec.CommandContext(ctx, args[0])
@@ -41,9 +90,20 @@ func (d *depInspector) buildCommand(ctx context.Context, writer io.Writer, env [
 	}
 
 	var errBuf bytes.Buffer
-	cmd.Env = env
+	cmd.Dir = d.workDir
+	// the go command compiles and runs code of dependencies so it only
+	// gets the environment it needs. Other tools, such as git and ssh,
+	// get the whole environment so credential helpers and agents work
+	if args[0] == "go" {
+		cmd.Env = d.commandEnv()
+	} else {
+		cmd.Env = os.Environ()
+	}
 	cmd.Stdout = writer
 	cmd.Stderr = &errBuf
+	// if the command is killed because ctx is done, don't wait for
+	// processes it started that are still holding its output open
+	cmd.WaitDelay = cmdWaitDelay
 
 	if d.verbose {
 		log.Printf("running command: %q", cmd)
@@ -52,7 +112,119 @@ func (d *depInspector) buildCommand(ctx context.Context, writer io.Writer, env [
 	return cmd, &errBuf
 }
 
-func formatCmdErr(cmd *exec.Cmd, err error, errBuf *bytes.Buffer) error {
+// commandEnvVars are the environment variables the go command and
+// analysis tools inherit in addition to goEnvVars. They locate the Go
+// toolchain, its caches and configuration, configure cgo, tune the Go
+// runtime and allow fetching private modules over ssh.
+// Other variables, such as tokens, aren't passed to them.
+var commandEnvVars = []string{
+	"HOME",
+	"PATH",
+	"TMPDIR",
+	// Windows equivalents of the above
+	"USERPROFILE",
+	"LOCALAPPDATA",
+	"APPDATA",
+	"SystemRoot",
+	"TEMP",
+	"TMP",
+	"XDG_CACHE_HOME",
+	"XDG_CONFIG_HOME",
+	"GOROOT",
+	"GOPATH",
+	"GOENV",
+	"GOCACHE",
+	"GOMODCACHE",
+	"GOTOOLCHAIN",
+	"GOWORK",
+	"GOSUMDB",
+	"GOINSECURE",
+	"GOVCS",
+	"CGO_ENABLED",
+	"CC",
+	"CXX",
+	"GOGC",
+	"GOMEMLIMIT",
+	"GOMAXPROCS",
+	"GOLANGCI_LINT_CACHE",
+	"STATICCHECK_CACHE",
+	"NETRC",
+	"SSH_AUTH_SOCK",
+	"GIT_SSH_COMMAND",
+}
+
+// commandEnv returns the environment the go command and analysis tools
+// are run with. Go settings that were configured are explicitly set so
+// go, capslock and the linters all resolve and load modules the same
+// way.
+func (d *depInspector) commandEnv() []string {
+	var env []string
+	for _, name := range append(commandEnvVars, goEnvVars...) {
+		if val, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+val)
+		}
+	}
+	if d.goProxy != "" {
+		env = setEnv(env, "GOPROXY="+d.goProxy)
+	}
+	if d.goPrivate != "" {
+		env = setEnv(env, "GOPRIVATE="+d.goPrivate)
+	}
+	if d.goFlags != "" {
+		env = setEnv(env, "GOFLAGS="+d.goFlags)
+	}
+	if d.netrcPath != "" {
+		env = setEnv(env, "NETRC="+d.netrcPath)
+	}
+	if d.goAuth != "" {
+		env = setEnv(env, "GOAUTH="+d.goAuth)
+	}
+	if d.cacheOnly {
+		env = setEnv(env, d.cacheOnlyEnv()...)
+	}
+
+	return env
+}
+
+// setEnv sets variables of an environment, replacing the values of
+// variables that are already set so no variable is set twice.
+func setEnv(env []string, vars ...string) []string {
+	for _, kv := range vars {
+		name, _, _ := strings.Cut(kv, "=")
+		i := slices.IndexFunc(env, func(existing string) bool {
+			return strings.HasPrefix(existing, name+"=")
+		})
+		if i == -1 {
+			env = append(env, kv)
+		} else {
+			env[i] = kv
+		}
+	}
+	return env
+}
+
+// analyzerEnv returns the environment analysis tools are run with in
+// addition to commandEnv. When -jobs limits how many run at once, the
+// CPUs are split between them so they don't contend for them, unless
+// GOMAXPROCS was set. GOGC and GOMEMLIMIT are inherited as-is.
+func (d *depInspector) analyzerEnv() []string {
+	if d.jobs <= 0 {
+		return nil
+	}
+	if _, ok := os.LookupEnv("GOMAXPROCS"); ok {
+		return nil
+	}
+	procs := max(runtime.NumCPU()/d.jobs, 1)
+	return []string{"GOMAXPROCS=" + strconv.Itoa(procs)}
+}
+
+// formatCmdErr adds what a failed command wrote to stderr to its error.
+// If the command was killed because ctx is done, such as when -timeout
+// was exceeded, the error says why so what was running is clear.
+func formatCmdErr(ctx context.Context, cmd *exec.Cmd, err error, errBuf *bytes.Buffer) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("running %s: %w\n%s", cmd, context.Cause(ctx), errBuf)
+	}
 	var execErr *exec.ExitError
 	if errors.As(err, &execErr) {
 		return fmt.Errorf("running %s: %s\n%w", cmd, errBuf, err)
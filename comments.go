@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// findingComment is a reviewer's comment about a finding, such as why
+// a capability is expected. Comments are keyed by the finding's
+// fingerprint so they are shown with the finding in every later report.
+type findingComment struct {
+	Module      string `yaml:"module"`
+	Fingerprint string `yaml:"fingerprint"`
+	Author      string `yaml:"author"`
+	Date        string `yaml:"date,omitempty"`
+	Text        string `yaml:"text"`
+}
+
+// commentList is the comments about findings of an organization. It is
+// YAML or JSON, and is usually committed next to the main module so
+// comments are shared.
+type commentList struct {
+	Comments []findingComment `yaml:"comments"`
+}
+
+// loadComments reads comments about findings from a file.
+func loadComments(path string) (*commentList, error) {
+	contents, err := os.ReadFile(path)
+	// the file is created when the first comment is added
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("comments file %s doesn't exist yet", path)
+		return &commentList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading comments: %w", err)
+	}
+
+	var list commentList
+	if err := yaml.Unmarshal(contents, &list); err != nil {
+		return nil, fmt.Errorf("parsing comments: %w", err)
+	}
+	for _, c := range list.Comments {
+		if c.Module == "" || c.Fingerprint == "" || c.Text == "" {
+			return nil, errors.New("parsing comments: comments require a module, fingerprint and text")
+		}
+	}
+
+	return &list, nil
+}
+
+// apply sets the comments of every finding that was commented on.
+func (l *commentList) apply(findings *depFindings) {
+	if l == nil || findings == nil {
+		return
+	}
+	for _, c := range findings.Caps.CapabilityInfo {
+		c.Comments = l.find(findings.Dep, capFindingFingerprint(c))
+	}
+	for _, issue := range findings.Issues {
+		issue.Comments = l.find(findings.Dep, issueFingerprint(findings.Dep, issue))
+	}
+}
+
+// find returns the comments about a finding of dep, oldest first.
+func (l *commentList) find(dep, fingerprint string) []findingComment {
+	var comments []findingComment
+	for _, c := range l.Comments {
+		if c.Module == dep && c.Fingerprint == fingerprint {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+// addComment appends a comment to the comments file at path.
+func addComment(path string, c findingComment) error {
+	list, err := loadComments(path)
+	if err != nil {
+		return err
+	}
+	list.Comments = append(list.Comments, c)
+
+	contents, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding comments: %w", err)
+	}
+	return writeFile(path, bytes.NewReader(contents))
+}
+
+func commentCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("comment", flag.ContinueOnError)
+	author := fs.String("author", "", "who wrote the comment, defaults to the email address git is configured with")
+	findingsPath := fs.String("findings", "", "results or inspection ID to search for the finding, defaults to the latest findings of every version in the result store")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || fs.Arg(1) == "" {
+		return errors.New("usage: dep-inspector [flags] comment [-author name] [-findings results.json|inspection-id] fingerprint text")
+	}
+	if d.store == nil && d.commentsPath == "" {
+		return errors.New("comment requires -store or a -comments file to record the comment in")
+	}
+
+	sources, err := d.findingSources(ctx, *findingsPath)
+	if err != nil {
+		return err
+	}
+	f, err := findFinding(sources, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *author == "" {
+		*author = d.approverName(ctx)
+	}
+	if *author == "" {
+		return errors.New("-author is required")
+	}
+
+	c := findingComment{
+		Module:      f.findings.Dep,
+		Fingerprint: f.fingerprint,
+		Author:      *author,
+		Date:        time.Now().UTC().Format(time.DateOnly),
+		Text:        fs.Arg(1),
+	}
+	if d.store != nil {
+		if err := d.store.recordComment(ctx, c); err != nil {
+			return err
+		}
+	}
+	if d.commentsPath != "" {
+		if err := addComment(d.commentsPath, c); err != nil {
+			return err
+		}
+	}
+	log.Printf("commented on finding %s of %s", f.fingerprint, f.findings.Dep)
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the contents of a dep-inspector config file. Every
+// setting can also be set with a flag, flags that are explicitly passed
+// take precedence over settings in the config file.
+type config struct {
+	GoProxy   string        `yaml:"goproxy"`
+	GoPrivate string        `yaml:"goprivate"`
+	GoFlags   string        `yaml:"goflags"`
+	CacheOnly bool          `yaml:"cache-only"`
+	Runner    string        `yaml:"runner"`
+	Confine   bool          `yaml:"confine"`
+	Jobs      int           `yaml:"jobs"`
+	Timeout   time.Duration `yaml:"timeout"`
+
+	Netrc          string `yaml:"netrc"`
+	GoAuth         string `yaml:"goauth"`
+	GitCredentials bool   `yaml:"git-credentials"`
+
+	Severities  severityConfig `yaml:"severities"`
+	Risk        riskConfig     `yaml:"risk"`
+	MinSeverity string         `yaml:"min-severity"`
+	OnlyCaps    string         `yaml:"only-caps"`
+	DirectOnly  bool           `yaml:"direct-only"`
+	CollectCaps string         `yaml:"capabilities"`
+	Classify    bool           `yaml:"classify"`
+
+	AllowModifiedCache bool `yaml:"allow-modified-cache"`
+	CompareVCS         bool `yaml:"compare-vcs"`
+	CompareProxies     bool `yaml:"compare-proxies"`
+	FindCopies         bool `yaml:"find-copies"`
+	FindDownloadExec   bool `yaml:"find-download-exec"`
+
+	StdlibFrames  string `yaml:"stdlib-frames"`
+	GeneratedCode string `yaml:"generated-code"`
+
+	IgnorePackages []string `yaml:"ignore-packages"`
+	IgnoreFiles    []string `yaml:"ignore-files"`
+	CapabilityMaps []string `yaml:"capability-maps"`
+
+	CapslockGranularity   string `yaml:"capslock-granularity"`
+	CapslockNoInitSummary bool   `yaml:"capslock-noinitsummary"`
+	CapslockBuildTags     string `yaml:"capslock-buildtags"`
+
+	Contributors bool `yaml:"contributors"`
+	Ownership    bool `yaml:"ownership"`
+	CheckProxies bool `yaml:"check-proxies"`
+	ReleaseNotes bool `yaml:"release-notes"`
+	Vulns        bool `yaml:"vulns"`
+
+	FailOn   []string      `yaml:"fail-on"`
+	Licenses licensePolicy `yaml:"licenses"`
+	Reviews  reviewsConfig `yaml:"reviews"`
+
+	ApprovedVersions string `yaml:"approved-versions"`
+	FailUnapproved   bool   `yaml:"fail-unapproved"`
+	Triage           string `yaml:"triage"`
+	Comments         string `yaml:"comments"`
+
+	Webhooks      []string `yaml:"webhooks"`
+	WebhookSecret string   `yaml:"webhook-secret"`
+
+	Upload      string `yaml:"upload"`
+	Sign        bool   `yaml:"sign"`
+	JSONSidecar bool   `yaml:"json-sidecar"`
+	SourceDiff  bool   `yaml:"source-diff"`
+	NoMinify    bool   `yaml:"no-minify"`
+	HTMLAssets  bool   `yaml:"html-assets"`
+	Pages       bool   `yaml:"pages"`
+
+	Verify         bool   `yaml:"verify"`
+	CertIdentity   string `yaml:"certificate-identity"`
+	CertOIDCIssuer string `yaml:"certificate-oidc-issuer"`
+
+	SlackWebhook   string `yaml:"slack-webhook"`
+	DiscordWebhook string `yaml:"discord-webhook"`
+}
+
+func loadConfig(path string) (*config, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal(contents, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+func (d *depInspector) applyConfig(cfg *config) {
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	configValue(setFlags, "goproxy", &d.goProxy, cfg.GoProxy)
+	configValue(setFlags, "goprivate", &d.goPrivate, cfg.GoPrivate)
+	configValue(setFlags, "goflags", &d.goFlags, cfg.GoFlags)
+	configValue(setFlags, "cache-only", &d.cacheOnly, cfg.CacheOnly)
+	configValue(setFlags, "runner", &d.runner, cfg.Runner)
+	configValue(setFlags, "confine", &d.confine, cfg.Confine)
+	configValue(setFlags, "jobs", &d.jobs, cfg.Jobs)
+	configValue(setFlags, "timeout", &d.timeout, cfg.Timeout)
+	configValue(setFlags, "netrc", &d.netrcPath, cfg.Netrc)
+	configValue(setFlags, "goauth", &d.goAuth, cfg.GoAuth)
+	configValue(setFlags, "git-credentials", &d.gitCredentials, cfg.GitCredentials)
+	configValue(setFlags, "min-severity", &d.minSeverity, cfg.MinSeverity)
+	configValue(setFlags, "only-caps", &d.onlyCaps, cfg.OnlyCaps)
+	configValue(setFlags, "direct-only", &d.directOnly, cfg.DirectOnly)
+	configValue(setFlags, "capabilities", &d.collectCaps, cfg.CollectCaps)
+	configValue(setFlags, "classify", &d.classify, cfg.Classify)
+	configValue(setFlags, "allow-modified-cache", &d.allowModCache, cfg.AllowModifiedCache)
+	configValue(setFlags, "compare-vcs", &d.compareVCS, cfg.CompareVCS)
+	configValue(setFlags, "compare-proxies", &d.compareProxies, cfg.CompareProxies)
+	configValue(setFlags, "find-copies", &d.findCopies, cfg.FindCopies)
+	configValue(setFlags, "find-download-exec", &d.downloadExec, cfg.FindDownloadExec)
+	configValue(setFlags, "stdlib-frames", &d.stdlibFrames, cfg.StdlibFrames)
+	configValue(setFlags, "generated-code", &d.generatedCode, cfg.GeneratedCode)
+	configValue(setFlags, "capslock-granularity", &d.granularity, cfg.CapslockGranularity)
+	configValue(setFlags, "capslock-noinitsummary", &d.noInitSummary, cfg.CapslockNoInitSummary)
+	configValue(setFlags, "capslock-buildtags", &d.buildTags, cfg.CapslockBuildTags)
+	configValue(setFlags, "contributors", &d.contributors, cfg.Contributors)
+	configValue(setFlags, "ownership", &d.ownership, cfg.Ownership)
+	configValue(setFlags, "check-proxies", &d.checkProxies, cfg.CheckProxies)
+	configValue(setFlags, "release-notes", &d.releaseNotes, cfg.ReleaseNotes)
+	configValue(setFlags, "vulns", &d.vulns, cfg.Vulns)
+	configValue(setFlags, "approved-versions", &d.approvedVersions, cfg.ApprovedVersions)
+	configValue(setFlags, "fail-unapproved", &d.failUnapproved, cfg.FailUnapproved)
+	configValue(setFlags, "triage", &d.triagePath, cfg.Triage)
+	configValue(setFlags, "comments", &d.commentsPath, cfg.Comments)
+	configValue(setFlags, "webhook-secret", &d.webhookSecret, cfg.WebhookSecret)
+	configValue(setFlags, "upload", &d.upload, cfg.Upload)
+	configValue(setFlags, "sign", &d.sign, cfg.Sign)
+	configValue(setFlags, "json-sidecar", &d.jsonSidecar, cfg.JSONSidecar)
+	configValue(setFlags, "source-diff", &d.sourceDiff, cfg.SourceDiff)
+	configValue(setFlags, "no-minify", &d.noMinify, cfg.NoMinify)
+	configValue(setFlags, "html-assets", &d.htmlAssets, cfg.HTMLAssets)
+	configValue(setFlags, "pages", &d.pages, cfg.Pages)
+	configValue(setFlags, "verify", &d.verify, cfg.Verify)
+	configValue(setFlags, "certificate-identity", &d.certIdentity, cfg.CertIdentity)
+	configValue(setFlags, "certificate-oidc-issuer", &d.certOIDCIssuer, cfg.CertOIDCIssuer)
+	configValue(setFlags, "slack-webhook", &d.slackWebhook, cfg.SlackWebhook)
+	configValue(setFlags, "discord-webhook", &d.discordWebhook, cfg.DiscordWebhook)
+	if !setFlags["webhook"] && len(cfg.Webhooks) != 0 {
+		d.webhooks = cfg.Webhooks
+	}
+	if !setFlags["ignore-pkg"] && len(cfg.IgnorePackages) != 0 {
+		d.ignorePkgs = cfg.IgnorePackages
+	}
+	if !setFlags["ignore-file"] && len(cfg.IgnoreFiles) != 0 {
+		d.ignoreFiles = cfg.IgnoreFiles
+	}
+	if !setFlags["capability-map"] && len(cfg.CapabilityMaps) != 0 {
+		d.capMapPaths = cfg.CapabilityMaps
+	}
+	if !setFlags["fail-on"] && len(cfg.FailOn) != 0 {
+		d.failOn = cfg.FailOn
+	}
+}
+
+// configValue sets dst to val if val is not the zero value and the
+// flag that also controls dst was not explicitly passed.
+func configValue[T comparable](setFlags map[string]bool, flagName string, dst *T, val T) {
+	var zero T
+	if setFlags[flagName] || val == zero {
+		return
+	}
+	*dst = val
+}
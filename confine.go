@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// confineEnvVar is set to the policy analysis tools are confined with
+// when dep-inspector runs them through itself. It confines itself and
+// then executes the tool, which inherits the restrictions.
+const confineEnvVar = "DEP_INSPECTOR_CONFINE"
+
+// confinePolicy is what confined analysis tools can access. Network
+// sockets can never be created.
+type confinePolicy struct {
+	// ReadOnly are files and directories that can be read and executed
+	ReadOnly []string
+	// ReadWrite are files and directories that can also be written to
+	ReadWrite []string
+}
+
+// newConfinePolicy returns the policy analysis tools are confined with.
+// They can read the Go toolchain, the analysis tools, the module cache,
+// the main module and system libraries, and only write to temporary
+// directories and build and analysis caches.
+func (d *depInspector) newConfinePolicy(ctx context.Context) (*confinePolicy, error) {
+	var output strings.Builder
+	if err := d.runCommand(ctx, &output, "go", "env", "GOROOT", "GOCACHE"); err != nil {
+		return nil, fmt.Errorf("getting GOROOT and GOCACHE: %w", err)
+	}
+	goRoot, goCache, _ := strings.Cut(trimNewline(output.String()), "\n")
+
+	policy := &confinePolicy{
+		ReadOnly: []string{
+			goRoot,
+			d.modCache,
+			filepath.Dir(d.modFilePath),
+			"/etc",
+			"/lib",
+			"/lib64",
+			"/usr",
+		},
+		ReadWrite: []string{
+			os.TempDir(),
+			goCache,
+			"/dev/null",
+		},
+	}
+	for _, tool := range append([]string{"go"}, analysisTools...) {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			return nil, err
+		}
+		policy.ReadOnly = append(policy.ReadOnly, filepath.Dir(path))
+	}
+	// golangci-lint and staticcheck cache results here
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		policy.ReadWrite = append(policy.ReadWrite, cacheDir)
+	}
+
+	return policy, nil
+}
+
+// confineEnv returns the environment variable that makes dep-inspector
+// confine itself with policy before executing a command.
+func confineEnv(policy *confinePolicy) (string, error) {
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("encoding confinement policy: %w", err)
+	}
+	return confineEnvVar + "=" + string(encoded), nil
+}
+
+// execConfined confines dep-inspector with an encoded policy and
+// executes a command in its place. It only returns if that fails.
+func execConfined(encodedPolicy string, args []string) int {
+	var policy confinePolicy
+	if err := json.Unmarshal([]byte(encodedPolicy), &policy); err != nil {
+		log.Printf("error: decoding confinement policy: %v", err)
+		return 1
+	}
+	if len(args) == 0 {
+		log.Println("error: no command to confine")
+		return 1
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 1
+	}
+	// commands the executed command runs are confined by inheriting
+	// the restrictions, they aren't run through dep-inspector
+	os.Unsetenv(confineEnvVar)
+
+	if err := confineExec(&policy, path, args); err != nil {
+		log.Printf("error: confining %s: %v", args[0], err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,201 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const confineSupported = true
+
+const (
+	// landlockReadAccess is the access read-only paths are granted
+	landlockReadAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+	// landlockFileAccess is the access that can be granted to files,
+	// the rest only applies to directories
+	landlockFileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// confineExec restricts the current thread with landlock and a seccomp
+// filter and executes path from it, so the command is confined too.
+// The restrictions are inherited by every process it starts and can't
+// be lifted.
+func confineExec(policy *confinePolicy, path string, args []string) error {
+	// landlock and seccomp restrict the calling thread, which has to be
+	// the one that executes the command
+	runtime.LockOSThread()
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	if err := landlockRestrict(policy); err != nil {
+		return err
+	}
+	if err := seccompDenySockets(); err != nil {
+		return err
+	}
+
+	return unix.Exec(path, args, os.Environ())
+}
+
+// landlockRestrict restricts filesystem access to the paths of policy.
+// Paths that don't exist are skipped.
+func landlockRestrict(policy *confinePolicy) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("landlock isn't supported by this kernel: %w", errno)
+	}
+	// access rights are handled by the newest landlock ABI the kernel
+	// supports, otherwise creating the ruleset fails
+	handled := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("creating landlock ruleset: %w", errno)
+	}
+	rulesetFD := int(fd)
+	defer unix.Close(rulesetFD)
+
+	addRules := func(paths []string, access uint64) error {
+		for _, path := range paths {
+			if err := landlockAllow(rulesetFD, path, access&handled); err != nil {
+				return fmt.Errorf("allowing access to %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+	if err := addRules(policy.ReadOnly, landlockReadAccess); err != nil {
+		return err
+	}
+	if err := addRules(policy.ReadWrite, handled); err != nil {
+		return err
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(rulesetFD), 0, 0); errno != 0 {
+		return fmt.Errorf("enforcing landlock ruleset: %w", errno)
+	}
+	return nil
+}
+
+// landlockAllow adds a rule to a landlock ruleset that grants access
+// to path and everything beneath it.
+func landlockAllow(rulesetFD int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return err
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileAccess
+	}
+
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFD), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// seccompDenySockets installs the seccomp filter seccompFilter returns
+// for the architecture dep-inspector is running on.
+func seccompDenySockets() error {
+	var arch uint32
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	}
+
+	filter := seccompFilter(arch)
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return fmt.Errorf("installing seccomp filter: %w", err)
+	}
+	return nil
+}
+
+// seccompFilter returns the seccomp BPF program seccompDenySockets
+// installs for the audit architecture arch. Creating sockets other than
+// Unix sockets fails with EACCES. io_uring can create sockets without
+// the socket syscall so it can't be set up either. Syscalls of other
+// architectures, such as x32 syscalls on amd64, kill the process.
+func seccompFilter(arch uint32) []unix.SockFilter {
+	const (
+		// offsets of fields of struct seccomp_data
+		offsetNr   = 0
+		offsetArch = 4
+		offsetArg0 = 16
+		// syscall numbers with this bit set are x32 syscalls
+		x32SyscallBit = 0x40000000
+	)
+	load := func(offset uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset}
+	}
+	jump := func(op uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_JMP | op | unix.BPF_K, K: k, Jt: jt, Jf: jf}
+	}
+	ret := func(k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: k}
+	}
+	return []unix.SockFilter{
+		load(offsetArch),
+		jump(unix.BPF_JEQ, arch, 1, 0),
+		ret(unix.SECCOMP_RET_KILL_PROCESS),
+		load(offsetNr),
+		jump(unix.BPF_JGE, x32SyscallBit, 0, 1),
+		ret(unix.SECCOMP_RET_KILL_PROCESS),
+		jump(unix.BPF_JEQ, unix.SYS_IO_URING_SETUP, 3, 0),
+		jump(unix.BPF_JEQ, unix.SYS_SOCKET, 0, 3),
+		// the domain of socket is its first argument
+		load(offsetArg0),
+		jump(unix.BPF_JEQ, unix.AF_UNIX, 1, 0),
+		ret(unix.SECCOMP_RET_ERRNO | uint32(unix.EACCES)),
+		ret(unix.SECCOMP_RET_ALLOW),
+	}
+}
@@ -0,0 +1,125 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// runSeccompFilter runs a seccomp BPF program against a syscall and
+// returns the action the kernel would take. Only the instructions
+// seccompFilter uses are supported.
+func runSeccompFilter(t *testing.T, filter []unix.SockFilter, arch, nr, arg0 uint32) uint32 {
+	t.Helper()
+
+	// struct seccomp_data
+	data := map[uint32]uint32{0: nr, 4: arch, 16: arg0}
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			val, ok := data[ins.K]
+			if !ok {
+				t.Fatalf("instruction %d loads unknown offset %d", pc, ins.K)
+			}
+			acc = val
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			if acc >= ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_RET | unix.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("instruction %d has unsupported code %#x", pc, ins.Code)
+		}
+	}
+
+	t.Fatal("filter ended without returning")
+	return 0
+}
+
+func TestSeccompFilter(t *testing.T) {
+	const (
+		arch          = unix.AUDIT_ARCH_X86_64
+		x32SyscallBit = 0x40000000
+
+		allow  = unix.SECCOMP_RET_ALLOW
+		kill   = unix.SECCOMP_RET_KILL_PROCESS
+		denied = unix.SECCOMP_RET_ERRNO | uint32(unix.EACCES)
+	)
+	tests := []struct {
+		name string
+		arch uint32
+		nr   uint32
+		arg0 uint32
+		want uint32
+	}{
+		{
+			name: "other syscall",
+			arch: arch,
+			nr:   unix.SYS_READ,
+			want: allow,
+		},
+		{
+			name: "unix socket",
+			arch: arch,
+			nr:   unix.SYS_SOCKET,
+			arg0: unix.AF_UNIX,
+			want: allow,
+		},
+		{
+			name: "inet socket",
+			arch: arch,
+			nr:   unix.SYS_SOCKET,
+			arg0: unix.AF_INET,
+			want: denied,
+		},
+		{
+			name: "inet6 socket",
+			arch: arch,
+			nr:   unix.SYS_SOCKET,
+			arg0: unix.AF_INET6,
+			want: denied,
+		},
+		{
+			name: "io_uring",
+			arch: arch,
+			nr:   unix.SYS_IO_URING_SETUP,
+			want: denied,
+		},
+		{
+			name: "x32 syscall",
+			arch: arch,
+			nr:   x32SyscallBit | unix.SYS_SOCKET,
+			arg0: unix.AF_INET,
+			want: kill,
+		},
+		{
+			name: "other architecture",
+			arch: unix.AUDIT_ARCH_I386,
+			nr:   unix.SYS_READ,
+			want: kill,
+		},
+	}
+
+	filter := seccompFilter(arch)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runSeccompFilter(t, filter, tt.arch, tt.nr, tt.arg0)
+			if got != tt.want {
+				t.Errorf("filter returned %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux || !(amd64 || arm64)
+
+package main
+
+import "errors"
+
+const confineSupported = false
+
+func confineExec(*confinePolicy, string, []string) error {
+	return errors.New("confining analysis tools is only supported on Linux on amd64 and arm64")
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// sensitiveFileCategories are categories of files that deserve extra
+// scrutiny when changed by a new contributor, keyed by words found in
+// the file's path.
+var sensitiveFileCategories = map[string][]string{
+	"crypto":  {"crypto", "cipher", "tls", "x509", "ssh", "pgp", "openpgp", "sign", "signature", "verify", "hash", "rand", "key", "keys", "cert", "certs"},
+	"network": {"net", "http", "https", "dns", "socket", "dial", "proxy", "transport", "tcp", "udp", "grpc", "url"},
+	"build":   {"makefile", "gnumakefile", "dockerfile", "configure", "build", "scripts", "script", ".github", ".circleci"},
+}
+
+// buildFileExts are extensions of files that are part of the build or
+// contain code that isn't Go.
+var buildFileExts = []string{".sh", ".bash", ".mk", ".ps1", ".bat", ".m4", ".s", ".c", ".h", ".syso"}
+
+// contributorChanges are the authors of the commits between compared
+// versions of a dependency.
+type contributorChanges struct {
+	Commits int
+	// Authors are sorted by the number of commits they authored
+	Authors []*contributor
+}
+
+type contributor struct {
+	Name    string
+	Email   string
+	Commits int
+	// FirstTime is true if the author had no commits before the old
+	// version
+	FirstTime bool
+	// SensitiveFiles are crypto, network or build related files the
+	// author changed, with the category of each in parenthesis
+	SensitiveFiles []string
+}
+
+// Flagged returns true if the contributor should be looked at closely.
+func (c *contributor) Flagged() bool {
+	return c.FirstTime && len(c.SensitiveFiles) != 0
+}
+
+// findContributors finds the authors of commits between two versions
+// of a dependency by cloning its repository.
+func (d *depInspector) findContributors(ctx context.Context, dep, oldVer, newVer string) (*contributorChanges, error) {
+	clone, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(clone)
+
+	oldURL, err := d.findModuleURL(ctx, dep, oldVer, clone)
+	if err != nil {
+		return nil, err
+	}
+	newURL, err := d.findModuleURL(ctx, dep, newVer, clone)
+	if err != nil {
+		return nil, err
+	}
+	repoURL, subdir := repoRoot(oldURL)
+	oldRev, newRev := moduleRev(oldURL, subdir), moduleRev(newURL, subdir)
+
+	// only commits and trees are needed to find authors and the files
+	// they changed
+	gitDir := clone + "/repo.git"
+	if err := d.runCommand(ctx, nil, "git", "clone", "--quiet", "--bare", "--filter=blob:none", repoURL, gitDir); err != nil {
+		return nil, fmt.Errorf("cloning %s: %w", repoURL, err)
+	}
+
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "git", "--git-dir", gitDir, "log", "--format=%ae", oldRev); err != nil {
+		return nil, fmt.Errorf("listing commits of %s: %w", oldRev, err)
+	}
+	prevAuthors := make(map[string]bool)
+	for _, email := range strings.Fields(output.String()) {
+		prevAuthors[strings.ToLower(email)] = true
+	}
+
+	output.Reset()
+	args := []string{"git", "--git-dir", gitDir, "log", "--no-renames", "--name-only", "--format=%x00%an%x00%ae", oldRev + ".." + newRev}
+	if subdir != "" {
+		// only commits that changed the module are interesting
+		args = append(args, "--", subdir)
+	}
+	if err := d.runCommand(ctx, &output, args...); err != nil {
+		return nil, fmt.Errorf("listing commits between %s and %s: %w", oldRev, newRev, err)
+	}
+
+	changes := &contributorChanges{}
+	authors := make(map[string]*contributor)
+	var author *contributor
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		// commits start with a line of the author's name and email
+		// separated by NUL bytes, followed by the files they changed
+		if fields := strings.Split(line, "\x00"); len(fields) == 3 {
+			changes.Commits++
+			email := strings.ToLower(fields[2])
+			author = authors[email]
+			if author == nil {
+				author = &contributor{
+					Name:      fields[1],
+					Email:     fields[2],
+					FirstTime: !prevAuthors[email],
+				}
+				authors[email] = author
+			}
+			author.Commits++
+			continue
+		}
+		if author == nil {
+			continue
+		}
+		file := line
+		if subdir != "" {
+			var ok bool
+			file, ok = strings.CutPrefix(line, subdir+"/")
+			if !ok {
+				// the file isn't part of the module
+				continue
+			}
+		}
+		if category := sensitiveFileCategory(file); category != "" {
+			file = fmt.Sprintf("%s (%s)", file, category)
+			if !slices.Contains(author.SensitiveFiles, file) {
+				author.SensitiveFiles = append(author.SensitiveFiles, file)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading commits: %w", err)
+	}
+
+	for _, author := range authors {
+		slices.Sort(author.SensitiveFiles)
+		changes.Authors = append(changes.Authors, author)
+	}
+	slices.SortFunc(changes.Authors, func(a, b *contributor) int {
+		if c := cmp.Compare(b.Commits, a.Commits); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Email, b.Email)
+	})
+
+	return changes, nil
+}
+
+// repoRoot returns the URL of the repository a module is in and the
+// directory of the module in the repository.
+func repoRoot(modURL moduleURL) (string, string) {
+	repoURL := *modURL.url
+	if repoURL.Host == "go.googlesource.com" {
+		return repoURL.String(), ""
+	}
+
+	// repositories of other hosts are at /owner/repo
+	elems := strings.Split(strings.Trim(repoURL.Path, "/"), "/")
+	if len(elems) <= 2 {
+		return repoURL.String(), ""
+	}
+	repoURL.Path = "/" + path.Join(elems[:2]...)
+	subdir := elems[2:]
+	// major version suffixes aren't directories in the repository if
+	// the module is in a subdirectory
+	if v2PlusRe.MatchString(subdir[len(subdir)-1]) {
+		subdir = subdir[:len(subdir)-1]
+	}
+
+	return repoURL.String(), path.Join(subdir...)
+}
+
+// moduleRev returns the git revision of a module version. Tags of
+// modules in subdirectories are prefixed with the subdirectory.
+func moduleRev(modURL moduleURL, subdir string) string {
+	if modURL.verIsCommit {
+		return modURL.version
+	}
+	return path.Join(subdir, modURL.version)
+}
+
+// sensitiveFileCategory returns the category of a sensitive file or an
+// empty string if the file isn't sensitive.
+func sensitiveFileCategory(file string) string {
+	if slices.Contains(buildFileExts, strings.ToLower(path.Ext(file))) {
+		return "build"
+	}
+	if base := path.Base(file); base == "go.mod" || base == "go.sum" {
+		return "build"
+	}
+
+	// split the path into directory and file names and the words in
+	// them, 'net/http_client.go' is 'net', 'http_client.go', 'http',
+	// 'client' and 'go'
+	var words []string
+	for _, elem := range strings.Split(strings.ToLower(file), "/") {
+		words = append(words, elem)
+		words = append(words, strings.FieldsFunc(elem, func(r rune) bool {
+			return r == '_' || r == '-' || r == '.'
+		})...)
+	}
+	for _, category := range []string{"crypto", "network", "build"} {
+		for _, word := range words {
+			if slices.Contains(sensitiveFileCategories[category], word) {
+				return category
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// evidence of how copied code was detected
+	copyEvidenceImport  = "import comment"
+	copyEvidenceContent = "identical files"
+
+	// minCopiedFiles is how many Go files of a directory must be
+	// identical to files of another module's package for the directory
+	// to be considered a copy, so small common files such as doc.go
+	// aren't enough
+	minCopiedFiles = 2
+)
+
+// importCommentRe matches the canonical import path of a package clause
+// import comment, such as 'package yaml // import "gopkg.in/yaml.v3"'.
+var importCommentRe = regexp.MustCompile(`^package\s+\w+\s*(?://\s*import\s+"([^"]+)"|/\*\s*import\s+"([^"]+)"\s*\*/)`)
+
+// copiedCode is a directory of a dependency that is a copy of code of
+// another module. Copies aren't seen by vulnerability scanners that
+// only look at go.sum, and silently duplicate the risk of the module
+// they were copied from.
+type copiedCode struct {
+	// Dir is the directory of the copy relative to the dependency's
+	// module root
+	Dir string
+	// Upstream is the module or package the code was copied from
+	Upstream string
+	// Version is the version of Upstream that was copied, if known
+	Version string `json:",omitempty"`
+	// Similarity is the percentage of Go files of Dir that are
+	// identical to files of Upstream, only set if the copy was found
+	// by comparing files
+	Similarity int `json:",omitempty"`
+	// Evidence is how the copy was found
+	Evidence string
+}
+
+// findCopiedCode finds directories of a dependency version that are
+// copies of other modules, such as third_party directories. Module zips
+// never include vendor directories, so copies are found from
+// directories whose Go files are identical to files of versions of
+// modules in go.sum that are in the module cache, and from import
+// comments that name another module.
+func (d *depInspector) findCopiedCode(dep, version string, goSum []string) ([]copiedCode, error) {
+	depDir, err := moduleDirPath(d.modCache, dep, version)
+	if err != nil {
+		return nil, err
+	}
+	dirFiles, err := goFileHashes(depDir)
+	if err != nil {
+		return nil, fmt.Errorf("hashing files of %s: %w", makeVersionStr(dep, version), err)
+	}
+
+	copies, err := d.contentCopies(dep, dirFiles, goSum)
+	if err != nil {
+		return nil, err
+	}
+	copies = append(copies, importCommentCopies(dep, depDir, dirFiles)...)
+
+	// a directory found in more than one way is only reported once,
+	// preferring the evidence that identifies the version
+	slices.SortStableFunc(copies, func(a, b copiedCode) int {
+		return strings.Compare(a.Dir, b.Dir)
+	})
+	copies = slices.CompactFunc(copies, func(a, b copiedCode) bool {
+		return a.Dir == b.Dir
+	})
+	return copies, nil
+}
+
+// goFileHashes returns the SHA-256 hashes of the Go files of every
+// directory of a module, keyed by the directory relative to the module
+// root and then by file name.
+func goFileHashes(modDir string) (map[string]map[string]string, error) {
+	dirs := make(map[string]map[string]string)
+	err := filepath.WalkDir(modDir, func(file string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() || filepath.Ext(file) != ".go" {
+			return err
+		}
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(modDir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if dirs[rel] == nil {
+			dirs[rel] = make(map[string]string)
+		}
+		sum := sha256.Sum256(contents)
+		dirs[rel][de.Name()] = string(sum[:])
+		return nil
+	})
+	return dirs, err
+}
+
+// importCommentCopies returns directories whose package clause has an
+// import comment naming a package that isn't part of the dependency.
+func importCommentCopies(dep, modDir string, dirFiles map[string]map[string]string) []copiedCode {
+	var copies []copiedCode
+	for dir, files := range dirFiles {
+		names := maps.Keys(files)
+		slices.Sort(names)
+		for _, name := range names {
+			importPath := importComment(filepath.Join(modDir, filepath.FromSlash(dir), name))
+			if importPath == "" || importPath == dep || strings.HasPrefix(importPath, dep+"/") {
+				continue
+			}
+			copies = append(copies, copiedCode{
+				Dir:      dir,
+				Upstream: importPath,
+				Evidence: copyEvidenceImport,
+			})
+			break
+		}
+	}
+	return copies
+}
+
+// importComment returns the canonical import path of a Go file's
+// package clause import comment, or an empty string if it has none.
+func importComment(file string) string {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	// the import comment is on the same line as the package clause
+	line := contents[fset.Position(f.Package).Offset:]
+	line, _, _ = bytes.Cut(line, []byte("\n"))
+	m := importCommentRe.FindSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	if len(m[1]) != 0 {
+		return string(m[1])
+	}
+	return string(m[2])
+}
+
+// upstreamPkg is a package of a version of a module code may have been
+// copied from.
+type upstreamPkg struct {
+	mod     string
+	version string
+	dir     string
+}
+
+// contentCopies returns directories of a dependency whose Go files are
+// mostly identical to the files of a package of a version of another
+// module in go.sum. Every version of those modules in the module cache
+// is compared, as the copied version is often not the one that is
+// required.
+func (d *depInspector) contentCopies(dep string, dirFiles map[string]map[string]string, goSum []string) ([]copiedCode, error) {
+	// index the packages of other modules by the hashes of their files
+	index := make(map[string][]upstreamPkg)
+	for _, modPath := range goSumModules(goSum) {
+		if modPath == dep {
+			continue
+		}
+		escPath, err := module.EscapePath(modPath)
+		if err != nil {
+			continue
+		}
+		versionDirs, err := filepath.Glob(filepath.Join(d.modCache, escPath+"@*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, versionDir := range versionDirs {
+			escVer := strings.TrimPrefix(filepath.Base(versionDir), filepath.Base(escPath)+"@")
+			version, err := module.UnescapeVersion(escVer)
+			if err != nil {
+				continue
+			}
+			pkgFiles, err := goFileHashes(versionDir)
+			if err != nil {
+				return nil, fmt.Errorf("hashing files of %s: %w", makeVersionStr(modPath, version), err)
+			}
+			for dir, files := range pkgFiles {
+				pkg := upstreamPkg{mod: modPath, version: version, dir: dir}
+				for _, hash := range files {
+					index[hash] = append(index[hash], pkg)
+				}
+			}
+		}
+	}
+
+	var copies []copiedCode
+	for dir, files := range dirFiles {
+		matches := make(map[upstreamPkg]int)
+		for _, hash := range files {
+			for _, pkg := range index[hash] {
+				matches[pkg]++
+			}
+		}
+
+		var (
+			best      upstreamPkg
+			bestCount int
+		)
+		for pkg, count := range matches {
+			// prefer the lowest version that matches as well, as later
+			// versions often have the same files
+			if count > bestCount || count == bestCount && upstreamLess(pkg, best) {
+				best, bestCount = pkg, count
+			}
+		}
+		if bestCount < minCopiedFiles || bestCount*2 < len(files) {
+			continue
+		}
+		copies = append(copies, copiedCode{
+			Dir:        dir,
+			Upstream:   path.Join(best.mod, best.dir),
+			Version:    best.version,
+			Similarity: bestCount * 100 / len(files),
+			Evidence:   copyEvidenceContent,
+		})
+	}
+	return copies, nil
+}
+
+// upstreamLess orders packages of module versions by module path,
+// version and then directory.
+func upstreamLess(a, b upstreamPkg) bool {
+	if a.mod != b.mod {
+		return a.mod < b.mod
+	}
+	if c := semver.Compare(a.version, b.version); c != 0 {
+		return c < 0
+	}
+	return a.dir < b.dir
+}
+
+// goSumModules returns the paths of the modules in go.sum.
+func goSumModules(goSum []string) []string {
+	var mods []string
+	for _, entry := range goSum {
+		mod, _, ok := strings.Cut(entry, " ")
+		if ok {
+			mods = append(mods, mod)
+		}
+	}
+	slices.Sort(mods)
+	return slices.Compact(mods)
+}
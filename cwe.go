@@ -0,0 +1,162 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// classification is the CWE weaknesses and OWASP Top 10 categories a
+// finding is related to.
+type classification struct {
+	CWE   []string `json:",omitempty"`
+	OWASP []string `json:",omitempty"`
+}
+
+// capCWEs are the weaknesses the code paths behind capabilities are an
+// exposure surface for. A capability doesn't mean the weakness exists,
+// only that untrusted input reaching it could exploit the weakness.
+var capCWEs = map[string][]string{
+	"CAPABILITY_ARBITRARY_EXECUTION": {"CWE-94"},
+	"CAPABILITY_CGO":                 {"CWE-695"},
+	"CAPABILITY_EXEC":                {"CWE-78"},
+	"CAPABILITY_FILES":               {"CWE-22"},
+	"CAPABILITY_MODIFY_SYSTEM_STATE": {"CWE-15"},
+	"CAPABILITY_NETWORK":             {"CWE-918"},
+	"CAPABILITY_READ_SYSTEM_STATE":   {"CWE-526"},
+	"CAPABILITY_REFLECT":             {"CWE-470"},
+	"CAPABILITY_SYSTEM_CALLS":        {"CWE-695"},
+	"CAPABILITY_UNSAFE_POINTER":      {"CWE-242"},
+}
+
+// gosecCWEs are the weaknesses gosec rules check for, keyed by rule
+// code.
+var gosecCWEs = map[string][]string{
+	"G101": {"CWE-798"},
+	"G102": {"CWE-200"},
+	"G103": {"CWE-242"},
+	"G104": {"CWE-703"},
+	"G106": {"CWE-322"},
+	"G107": {"CWE-88"},
+	"G108": {"CWE-200"},
+	"G109": {"CWE-190"},
+	"G110": {"CWE-409"},
+	"G111": {"CWE-22"},
+	"G112": {"CWE-400"},
+	"G114": {"CWE-676"},
+	"G201": {"CWE-89"},
+	"G202": {"CWE-89"},
+	"G203": {"CWE-79"},
+	"G204": {"CWE-78"},
+	"G301": {"CWE-276"},
+	"G302": {"CWE-276"},
+	"G303": {"CWE-377"},
+	"G304": {"CWE-22"},
+	"G305": {"CWE-22"},
+	"G306": {"CWE-276"},
+	"G307": {"CWE-703"},
+	"G401": {"CWE-326"},
+	"G402": {"CWE-295"},
+	"G403": {"CWE-310"},
+	"G404": {"CWE-338"},
+	"G501": {"CWE-327"},
+	"G502": {"CWE-327"},
+	"G503": {"CWE-327"},
+	"G504": {"CWE-327"},
+	"G505": {"CWE-327"},
+	"G601": {"CWE-118"},
+	"G602": {"CWE-118"},
+}
+
+// linterCWEs are the weaknesses linters that only check for one kind of
+// problem detect.
+var linterCWEs = map[string][]string{
+	"bidichk":       {"CWE-451"},
+	"errcheck":      {"CWE-703"},
+	"rowserrcheck":  {"CWE-703"},
+	"sqlclosecheck": {"CWE-404"},
+}
+
+// cweOWASP are the OWASP Top 10 2021 categories weaknesses are part of.
+var cweOWASP = map[string]string{
+	"CWE-15":  "A05:2021 Security Misconfiguration",
+	"CWE-22":  "A01:2021 Broken Access Control",
+	"CWE-78":  "A03:2021 Injection",
+	"CWE-79":  "A03:2021 Injection",
+	"CWE-88":  "A03:2021 Injection",
+	"CWE-89":  "A03:2021 Injection",
+	"CWE-94":  "A03:2021 Injection",
+	"CWE-200": "A01:2021 Broken Access Control",
+	"CWE-242": "A04:2021 Insecure Design",
+	"CWE-276": "A01:2021 Broken Access Control",
+	"CWE-295": "A07:2021 Identification and Authentication Failures",
+	"CWE-310": "A02:2021 Cryptographic Failures",
+	"CWE-322": "A07:2021 Identification and Authentication Failures",
+	"CWE-326": "A02:2021 Cryptographic Failures",
+	"CWE-327": "A02:2021 Cryptographic Failures",
+	"CWE-338": "A02:2021 Cryptographic Failures",
+	"CWE-377": "A01:2021 Broken Access Control",
+	"CWE-470": "A03:2021 Injection",
+	"CWE-798": "A07:2021 Identification and Authentication Failures",
+	"CWE-918": "A10:2021 Server-Side Request Forgery",
+}
+
+// classifyCWEs returns the classification of weaknesses, or nil if
+// there are none.
+func classifyCWEs(cwes []string) *classification {
+	if len(cwes) == 0 {
+		return nil
+	}
+
+	class := &classification{CWE: cwes}
+	for _, cwe := range cwes {
+		if category, ok := cweOWASP[cwe]; ok && !slices.Contains(class.OWASP, category) {
+			class.OWASP = append(class.OWASP, category)
+		}
+	}
+	return class
+}
+
+func capClassification(c *capability) *classification {
+	return classifyCWEs(capCWEs[c.Capability])
+}
+
+func issueClassification(issue *lintIssue) *classification {
+	if issue.FromLinter == "gosec" {
+		// gosec issues start with the rule code, such as 'G204: Subprocess
+		// launched with variable'
+		code, _, _ := strings.Cut(issue.Text, ":")
+		return classifyCWEs(gosecCWEs[code])
+	}
+	return classifyCWEs(linterCWEs[issue.FromLinter])
+}
+
+// classify sets the classification of every finding.
+func classify(findings *depFindings) {
+	if findings == nil {
+		return
+	}
+	for _, c := range findings.Caps.CapabilityInfo {
+		c.Classification = capClassification(c)
+	}
+	for _, issue := range findings.Issues {
+		issue.Classification = issueClassification(issue)
+	}
+}
+
+// sarifTags returns the tags of a classification in the form GitHub
+// code scanning shows them, such as 'external/cwe/cwe-78'.
+func (c *classification) sarifTags() []string {
+	if c == nil {
+		return nil
+	}
+
+	tags := []string{"security"}
+	for _, cwe := range c.CWE {
+		tags = append(tags, "external/cwe/"+strings.ToLower(cwe))
+	}
+	for _, category := range c.OWASP {
+		id, _, _ := strings.Cut(category, " ")
+		tags = append(tags, "external/owasp/"+strings.ToLower(id))
+	}
+	return tags
+}
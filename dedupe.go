@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+)
+
+// dedupeCaps records which dependencies reported each capability that
+// is in the findings of more than one dependency in the same run.
+// Capabilities whose paths go through a shared module, such as a helper
+// module used by multiple changed dependencies, would otherwise be
+// shown once per dependency. Old and new findings are compared
+// separately so a capability one dependency only had before doesn't
+// hide it being added to another. Findings aren't removed so they are
+// still compared, counted and checked against policies; duplicates are
+// only collapsed when they are rendered.
+func dedupeCaps(results []*savedResults) {
+	var oldFindings, newFindings []*depFindings
+	for _, res := range results {
+		if res.Old != nil {
+			oldFindings = append(oldFindings, res.Old)
+		}
+		newFindings = append(newFindings, res.New)
+	}
+	markSharedCaps(oldFindings)
+	markSharedCaps(newFindings)
+}
+
+// markSharedCaps sets which dependencies reported each capability that
+// is in more than one of findings.
+func markSharedCaps(findings []*depFindings) {
+	reportedVia := make(map[string][]string)
+	for _, f := range findings {
+		for _, c := range f.Caps.CapabilityInfo {
+			fp, ok := capFingerprint(f.Dep, c)
+			if !ok {
+				continue
+			}
+			if deps := reportedVia[fp]; len(deps) == 0 || deps[len(deps)-1] != f.Dep {
+				reportedVia[fp] = append(deps, f.Dep)
+			}
+		}
+	}
+
+	for _, f := range findings {
+		for _, c := range f.Caps.CapabilityInfo {
+			c.ReportedVia = nil
+			if fp, ok := capFingerprint(f.Dep, c); ok && len(reportedVia[fp]) > 1 {
+				c.ReportedVia = reportedVia[fp]
+			}
+		}
+	}
+}
+
+// collapseSharedCaps returns the capabilities of dep that should be
+// shown, leaving out capabilities that are shown in the findings of the
+// first dependency that reported them.
+func collapseSharedCaps(dep string, caps []*capability) []*capability {
+	var shown []*capability
+	for _, c := range caps {
+		if len(c.ReportedVia) == 0 || c.ReportedVia[0] == dep {
+			shown = append(shown, c)
+		}
+	}
+	return shown
+}
+
+// capFingerprint identifies a capability by the part of its path that
+// is outside of dep. Only capabilities whose paths go through another
+// non-standard library module can be shared between dependencies.
+func capFingerprint(dep string, c *capability) (string, bool) {
+	start := -1
+	for i, call := range c.Path {
+		pkg := funcPackage(call.Name)
+		if pkg != dep && !strings.HasPrefix(pkg, dep+"/") && !isStdlibPackage(pkg) {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return "", false
+	}
+
+	var fp strings.Builder
+	fp.WriteString(c.Capability)
+	for i, call := range c.Path[start:] {
+		fp.WriteString("\n")
+		fp.WriteString(call.Name)
+		// the call site of the first function is in dep
+		if i != 0 {
+			fp.WriteString(" " + call.Site.Filename + ":" + call.Site.Line + ":" + call.Site.Column)
+		}
+	}
+
+	return fp.String(), true
+}
+
+// funcPackage returns the package path of a function name such as
+// 'example.com/pkg.Func' or '(*example.com/pkg.Type).Method'.
+func funcPackage(name string) string {
+	name = strings.TrimLeft(name, "(*")
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot != -1 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+const (
+	stdlibFramesShow     = "show"
+	stdlibFramesCollapse = "collapse"
+	stdlibFramesHide     = "hide"
+)
+
+var stdlibFramesModes = []string{stdlibFramesShow, stdlibFramesCollapse, stdlibFramesHide}
+
+// collapsedFrames returns how call i of a call path is shown when
+// consecutive calls through the standard library are collapsed or
+// hidden according to mode: 0 if it's shown, the number of calls that
+// are collapsed if it's the first of them, or -1 if it's skipped. The
+// first and last calls and the first call into the standard library
+// after a call from outside it are always shown.
+func collapsedFrames(calls []functionCall, i int, mode string) int {
+	collapsible := func(i int) bool {
+		return i > 0 && i < len(calls)-1 &&
+			isStdlibPackage(funcPackage(calls[i].Name)) &&
+			isStdlibPackage(funcPackage(calls[i-1].Name))
+	}
+	if mode == stdlibFramesShow || mode == "" || !collapsible(i) {
+		return 0
+	}
+	if mode == stdlibFramesHide || collapsible(i-1) {
+		return -1
+	}
+
+	n := 1
+	for collapsible(i + n) {
+		n++
+	}
+	// replacing a single call with a placeholder doesn't make the path
+	// any shorter
+	if n == 1 {
+		return 0
+	}
+	return n
+}
+
+// isStdlibPackage returns true if pkg is a standard library package,
+// the first element of their paths have no dots.
+func isStdlibPackage(pkg string) bool {
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// diffStat is how much the source code of compared versions differs,
+// like the summary git diff --stat prints.
+type diffStat struct {
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+}
+
+// moduleDiffStat counts the files changed and lines inserted and
+// deleted between the source code of two module versions in the module
+// cache.
+func (d *depInspector) moduleDiffStat(ctx context.Context, oldFindings, newFindings *depFindings) (*diffStat, error) {
+	var numstat bytes.Buffer
+	if err := d.gitDiffModules(ctx, &numstat, oldFindings, newFindings, "--numstat"); err != nil {
+		return nil, err
+	}
+	return parseNumstat(&numstat)
+}
+
+// parseNumstat parses the output of git diff --numstat. Binary files
+// are counted as changed without inserting or deleting lines.
+func parseNumstat(numstat *bytes.Buffer) (*diffStat, error) {
+	var stat diffStat
+	s := bufio.NewScanner(numstat)
+	for s.Scan() {
+		added, rest, ok := strings.Cut(s.Text(), "\t")
+		if !ok {
+			return nil, fmt.Errorf("malformed numstat line %q", s.Text())
+		}
+		deleted, _, ok := strings.Cut(rest, "\t")
+		if !ok {
+			return nil, fmt.Errorf("malformed numstat line %q", s.Text())
+		}
+
+		stat.FilesChanged++
+		if added == "-" && deleted == "-" {
+			continue
+		}
+		insertions, err := strconv.Atoi(added)
+		if err != nil {
+			return nil, fmt.Errorf("malformed numstat line %q: %w", s.Text(), err)
+		}
+		deletions, err := strconv.Atoi(deleted)
+		if err != nil {
+			return nil, fmt.Errorf("malformed numstat line %q: %w", s.Text(), err)
+		}
+		stat.Insertions += insertions
+		stat.Deletions += deletions
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading numstat: %w", err)
+	}
+
+	return &stat, nil
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"go/constant"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const (
+	// maxTaintRounds is how many times functions are analyzed again
+	// when functions that return network data are found
+	maxTaintRounds = 5
+
+	sinkExec       = "executed"
+	sinkPlugin     = "loaded as a plugin"
+	sinkExecutable = "written to an executable file"
+)
+
+var (
+	// networkSources are functions and methods that return data read
+	// from the network
+	networkSources = []string{
+		"net/http.Get", "net/http.Head", "net/http.Post", "net/http.PostForm",
+		"(*net/http.Client).Do", "(*net/http.Client).Get", "(*net/http.Client).Head",
+		"(*net/http.Client).Post", "(*net/http.Client).PostForm",
+		"net.Dial", "net.DialTCP", "net.DialTimeout", "net.DialUDP", "net.DialUnix",
+		"(*net.Dialer).Dial", "(*net.Dialer).DialContext",
+		"(net.Listener).Accept", "(*net.TCPListener).Accept", "(*net.TCPListener).AcceptTCP",
+	}
+	// execSinks are functions that execute or load their arguments,
+	// mapped to how
+	execSinks = map[string]string{
+		"os/exec.Command":        sinkExec,
+		"os/exec.CommandContext": sinkExec,
+		"os.StartProcess":        sinkExec,
+		"syscall.Exec":           sinkExec,
+		"syscall.ForkExec":       sinkExec,
+		"plugin.Open":            sinkPlugin,
+	}
+	// writeFileFuncs are functions that write the data of their second
+	// argument to a file with the permissions of their third argument
+	writeFileFuncs = []string{"os.WriteFile", "io/ioutil.WriteFile"}
+)
+
+// downloadExec is a flow of data from the network to code that
+// executes it, such as a binary that is downloaded and run. It is far
+// more alarming than the network and exec capabilities alone.
+type downloadExec struct {
+	// Source is the function the data was read from the network with
+	Source string
+	// Sink is what happens to the data
+	Sink string
+	// Func is the function the data reaches the sink in
+	Func string
+	Pos  sourcePos
+}
+
+// buildSSA builds loaded packages as SSA. The SSA builder panics on
+// syntax it doesn't support, such as language features newer than it.
+// The panic is returned as an error so only this analysis fails instead
+// of dep-inspector crashing. Packages are built serially in the calling
+// goroutine, the panic couldn't be recovered in goroutines the builder
+// starts itself.
+func buildSSA(loaded []*packages.Package) (prog *ssa.Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building SSA: %v", r)
+		}
+	}()
+
+	prog, _ = ssautil.Packages(loaded, ssa.InstantiateGenerics|ssa.BuildSerially)
+	prog.Build()
+	return prog, nil
+}
+
+// findDownloadExec finds where data read from the network is executed
+// or written to executable files in the loaded packages of a
+// dependency. The packages are built as SSA and a lightweight taint
+// analysis tracks values derived from network reads through the
+// dependency's functions, including functions that return them.
+func (d *depInspector) findDownloadExec(dep string, pkgs loadedPackages) ([]downloadExec, error) {
+	var (
+		patterns []string
+		modDir   string
+	)
+	for _, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Path == dep {
+			patterns = append(patterns, pkg.PkgPath)
+			modDir = pkg.Module.Dir
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	slices.Sort(patterns)
+
+	cfg := &packages.Config{
+		Mode: packages.LoadAllSyntax | packages.NeedModule,
+		Dir:  d.workDir,
+		Env:  d.commandEnv(),
+	}
+	loaded, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages: %w", err)
+	}
+	// packages with errors can't be built as SSA
+	loaded = slices.DeleteFunc(loaded, func(pkg *packages.Package) bool {
+		return len(pkg.Errors) != 0
+	})
+
+	prog, err := buildSSA(loaded)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg != nil && (fn.Pkg.Pkg.Path() == dep || strings.HasPrefix(fn.Pkg.Pkg.Path(), dep+"/")) {
+			funcs = append(funcs, fn)
+		}
+	}
+	slices.SortFunc(funcs, func(a, b *ssa.Function) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	t := &taintAnalysis{
+		prog:      prog,
+		modDir:    modDir,
+		tainted:   make(map[ssa.Value]string),
+		execFiles: make(map[ssa.Value]bool),
+		returns:   make(map[*ssa.Function]string),
+	}
+	for round := 0; round < maxTaintRounds; round++ {
+		t.changed = false
+		for _, fn := range funcs {
+			t.analyze(fn)
+		}
+		if !t.changed {
+			break
+		}
+	}
+
+	// flows are found again every round
+	slices.SortFunc(t.flows, func(a, b downloadExec) int {
+		if c := strings.Compare(a.Pos.File, b.Pos.File); c != 0 {
+			return c
+		}
+		if c := a.Pos.Line - b.Pos.Line; c != 0 {
+			return c
+		}
+		return strings.Compare(a.Source+a.Sink, b.Source+b.Sink)
+	})
+	return slices.CompactFunc(t.flows, func(a, b downloadExec) bool {
+		return a == b
+	}), nil
+}
+
+// taintAnalysis tracks values derived from data read from the network.
+type taintAnalysis struct {
+	prog   *ssa.Program
+	modDir string
+	// tainted maps values derived from the network to the function the
+	// data was read with
+	tainted map[ssa.Value]string
+	// execFiles are files opened with executable permissions
+	execFiles map[ssa.Value]bool
+	// returns are functions that return data read from the network
+	returns map[*ssa.Function]string
+	changed bool
+	flows   []downloadExec
+}
+
+// analyze propagates taint through a function until nothing changes.
+func (t *taintAnalysis) analyze(fn *ssa.Function) {
+	for {
+		changed := false
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				if t.propagate(fn, instr) {
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return
+		}
+		t.changed = true
+	}
+}
+
+// propagate taints the values an instruction derives from tainted
+// values and records flows into sinks. It returns true if anything was
+// newly tainted.
+func (t *taintAnalysis) propagate(fn *ssa.Function, instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.Call:
+		return t.call(fn, instr)
+	case *ssa.Store:
+		// data stored in memory taints the variable or buffer
+		if source, ok := t.tainted[instr.Val]; ok {
+			return t.taint(taintRoot(instr.Addr), source)
+		}
+		return false
+	case *ssa.Return:
+		for _, res := range instr.Results {
+			if source, ok := t.tainted[res]; ok {
+				if _, ok := t.returns[fn]; !ok {
+					t.returns[fn] = source
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	v, ok := instr.(ssa.Value)
+	if !ok {
+		return false
+	}
+	var changed bool
+	for _, op := range instr.Operands(nil) {
+		if op == nil || *op == nil {
+			continue
+		}
+		if source, ok := t.tainted[*op]; ok && t.taint(v, source) {
+			changed = true
+		}
+		if t.execFiles[*op] && isFileValue(v) && !t.execFiles[v] {
+			t.execFiles[v] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// call handles sources, sinks and propagation through calls.
+func (t *taintAnalysis) call(fn *ssa.Function, call *ssa.Call) bool {
+	common := call.Common()
+	name := calleeName(common)
+	args := common.Args
+	if common.IsInvoke() {
+		args = append([]ssa.Value{common.Value}, args...)
+	}
+
+	if slices.Contains(networkSources, name) {
+		return t.taint(call, name)
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		if source, ok := t.returns[callee]; ok {
+			return t.taint(call, source)
+		}
+	}
+
+	var (
+		source      string
+		hasExecFile bool
+	)
+	for _, arg := range args {
+		if s, ok := t.tainted[arg]; ok && source == "" {
+			source = s
+		}
+		if t.execFiles[arg] {
+			hasExecFile = true
+		}
+	}
+
+	switch {
+	case name == "os.OpenFile" && len(args) == 3 && isExecPerm(args[2]):
+		if !t.execFiles[call] {
+			t.execFiles[call] = true
+			return true
+		}
+	case source == "":
+	case execSinks[name] != "":
+		t.addFlow(fn, call, source, execSinks[name])
+	case slices.Contains(writeFileFuncs, name) && len(args) == 3 && isExecPerm(args[2]):
+		if _, ok := t.tainted[args[1]]; ok {
+			t.addFlow(fn, call, source, sinkExecutable)
+		}
+	case hasExecFile:
+		// such as io.Copy(f, resp.Body) or f.Write(data)
+		t.addFlow(fn, call, source, sinkExecutable)
+	}
+	if source == "" {
+		return false
+	}
+
+	// the results of calls with network data are derived from it, and
+	// buffers and writers passed with it are filled with it, such as
+	// io.ReadAll(resp.Body) and conn.Read(buf)
+	changed := t.taint(call, source)
+	for _, arg := range args {
+		if _, ok := t.tainted[arg]; !ok && isBuffer(arg) && t.taint(taintRoot(arg), source) {
+			changed = true
+		}
+	}
+	// parameters of the dependency's functions that are passed network
+	// data are tainted so it can be followed into them
+	if callee := common.StaticCallee(); callee != nil && len(callee.Params) == len(args) {
+		for i, arg := range args {
+			if source, ok := t.tainted[arg]; ok && t.taint(callee.Params[i], source) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (t *taintAnalysis) taint(v ssa.Value, source string) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := t.tainted[v]; ok {
+		return false
+	}
+	t.tainted[v] = source
+	return true
+}
+
+func (t *taintAnalysis) addFlow(fn *ssa.Function, call *ssa.Call, source, sink string) {
+	pos := t.prog.Fset.Position(call.Pos())
+	file, err := filepath.Rel(t.modDir, pos.Filename)
+	if err != nil {
+		file = pos.Filename
+	}
+	t.flows = append(t.flows, downloadExec{
+		Source: source,
+		Sink:   sink,
+		Func:   fn.String(),
+		Pos: sourcePos{
+			File: filepath.ToSlash(file),
+			Line: pos.Line,
+		},
+	})
+}
+
+// calleeName returns the qualified name of the function or method a
+// call calls, such as 'net/http.Get' or '(*net/http.Client).Do'.
+func calleeName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Method.FullName()
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		if origin := callee.Origin(); origin != nil {
+			callee = origin
+		}
+		return callee.String()
+	}
+	return ""
+}
+
+// taintRoot returns the variable or buffer a value refers to, so
+// writes through slices, interfaces and pointers taint it.
+func taintRoot(v ssa.Value) ssa.Value {
+	for {
+		switch x := v.(type) {
+		case *ssa.Slice:
+			v = x.X
+		case *ssa.MakeInterface:
+			v = x.X
+		case *ssa.ChangeType:
+			v = x.X
+		case *ssa.IndexAddr:
+			v = x.X
+		case *ssa.FieldAddr:
+			v = x.X
+		default:
+			return v
+		}
+	}
+}
+
+// isBuffer returns true if a value can be written to by a function it
+// is passed to.
+func isBuffer(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.Alloc, *ssa.Slice, *ssa.MakeInterface, *ssa.MakeSlice:
+		return true
+	}
+	return false
+}
+
+// isFileValue returns true if a value derived from a file is the file
+// itself, rather than data read from it.
+func isFileValue(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.Extract, *ssa.MakeInterface, *ssa.ChangeType, *ssa.Phi:
+		return true
+	}
+	return false
+}
+
+// isExecPerm returns true if a value is constant file permissions that
+// allow executing the file.
+func isExecPerm(v ssa.Value) bool {
+	c, ok := v.(*ssa.Const)
+	if !ok || c.Value == nil || c.Value.Kind() != constant.Int {
+		return false
+	}
+	perm, ok := constant.Uint64Val(c.Value)
+	return ok && perm&0o111 != 0
+}
+
+// downloadExecChanges are the flows of network data into execution a
+// new version of a dependency has that the old version didn't, and
+// that it no longer has.
+type downloadExecChanges struct {
+	Added   []downloadExec
+	Removed []downloadExec
+}
+
+// compareDownloadExec compares the flows of network data into
+// execution of two versions of a dependency.
+func compareDownloadExec(oldFacts, newFacts *sourceFacts) *downloadExecChanges {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	removed, _, added := processFindings(oldFacts.DownloadExec, newFacts.DownloadExec, func(flow downloadExec) string {
+		return flow.Func + " " + flow.Source + " " + flow.Sink
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &downloadExecChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"go/ast"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// envReadFuncs are functions that read the environment variable named
+// by their first argument, keyed by package path.
+var envReadFuncs = map[string][]string{
+	"os":      {"Getenv", "LookupEnv"},
+	"syscall": {"Getenv"},
+}
+
+// envStructTags are keys of struct tags that name the environment
+// variable a field is set from by env config libraries.
+var envStructTags = []string{"env", "envconfig"}
+
+// sensitiveEnvVarRe matches names of environment variables that
+// usually hold credentials.
+var sensitiveEnvVarRe = regexp.MustCompile(`(?i)(SECRET|TOKEN|PASSW(OR)?D|PASSPHRASE|CREDENTIAL|PRIVATE_KEY|ACCESS_KEY|API_KEY|AUTH)`)
+
+// envVarRead is an environment variable a dependency reads.
+type envVarRead struct {
+	Name string
+	// Sensitive is true if the name suggests the variable holds
+	// credentials
+	Sensitive bool `json:",omitempty"`
+	Uses      []sourcePos
+}
+
+// scanEnvVars finds the environment variables a file reads.
+func (s *sourceFacts) scanEnvVars(f *sourceFile) {
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.CallExpr:
+			s.scanEnvCall(f, n)
+		case *ast.Field:
+			if n.Tag == nil {
+				return true
+			}
+			tag, err := strconv.Unquote(n.Tag.Value)
+			if err != nil {
+				return true
+			}
+			for _, key := range envStructTags {
+				name, _, _ := strings.Cut(reflect.StructTag(tag).Get(key), ",")
+				if name != "" && name != "-" {
+					s.addEnvVar(name, f.pos(n))
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (s *sourceFacts) scanEnvCall(f *sourceFile, call *ast.CallExpr) {
+	pkgPath, name, ok := f.callee(call)
+	if !ok || len(call.Args) == 0 {
+		return
+	}
+
+	switch {
+	case slices.Contains(envReadFuncs[pkgPath], name):
+		if envName, ok := f.stringValue(call.Args[0]); ok {
+			s.addEnvVar(envName, f.pos(call))
+		} else {
+			s.DynamicEnvReads++
+		}
+	case pkgPath == "os" && (name == "ExpandEnv" || name == "Expand"):
+		// variables referenced in strings that are expanded, such as
+		// '$HOME/.config'
+		str, ok := f.stringValue(call.Args[0])
+		if !ok {
+			s.DynamicEnvReads++
+			return
+		}
+		os.Expand(str, func(envName string) string {
+			s.addEnvVar(envName, f.pos(call))
+			return ""
+		})
+	case strings.HasPrefix(pkgPath, "github.com/spf13/viper") && name == "BindEnv":
+		// viper.BindEnv(key) reads the upper case key, and
+		// viper.BindEnv(key, names...) reads the names
+		args := call.Args[1:]
+		upper := len(args) == 0
+		if upper {
+			args = call.Args
+		}
+		for _, arg := range args {
+			envName, ok := f.stringValue(arg)
+			if !ok {
+				s.DynamicEnvReads++
+				continue
+			}
+			if upper {
+				envName = strings.ToUpper(envName)
+			}
+			s.addEnvVar(envName, f.pos(call))
+		}
+	}
+}
+
+func (s *sourceFacts) addEnvVar(name string, pos sourcePos) {
+	i := slices.IndexFunc(s.EnvVars, func(env envVarRead) bool {
+		return env.Name == name
+	})
+	if i == -1 {
+		s.EnvVars = append(s.EnvVars, envVarRead{
+			Name:      name,
+			Sensitive: sensitiveEnvVarRe.MatchString(name),
+		})
+		i = len(s.EnvVars) - 1
+	}
+	s.EnvVars[i].Uses = append(s.EnvVars[i].Uses, pos)
+}
+
+// envVarChanges are the environment variables a new version of a
+// dependency reads that the old version didn't, and that it no longer
+// reads.
+type envVarChanges struct {
+	Added   []envVarRead
+	Removed []envVarRead
+}
+
+// compareEnvVars compares the environment variables two versions of a
+// dependency read.
+func compareEnvVars(oldFacts, newFacts *sourceFacts) *envVarChanges {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	removed, _, added := processFindings(oldFacts.EnvVars, newFacts.EnvVars, func(env envVarRead) string {
+		return env.Name
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &envVarChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// minFingerprintLen is the length of the shortest fingerprint prefix
+// explain accepts, shorter prefixes would match too many findings.
+const minFingerprintLen = 6
+
+// snippetContext is how many lines before and after a call site or
+// issue are shown.
+const snippetContext = 2
+
+// staticcheckLinters are linters whose checks are documented on
+// staticcheck's website.
+var staticcheckLinters = []string{"staticcheck", "gosimple", "stylecheck"}
+
+// receiverReplacer removes the parenthesis and pointer of method
+// receivers from function names.
+var receiverReplacer = strings.NewReplacer("*", "", "(", "", ")", "")
+
+// explainedFinding is a finding and the findings of the dependency
+// version it was found in.
+type explainedFinding struct {
+	findings    *depFindings
+	fingerprint string
+	cap         *capability
+	issue       *lintIssue
+}
+
+func explainCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	findingsPath := fs.String("findings", "", "results or inspection ID to search for the finding, defaults to the latest findings of every version in the result store")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: dep-inspector [flags] explain [-findings results.json|inspection-id] fingerprint")
+	}
+
+	sources, err := d.findingSources(ctx, *findingsPath)
+	if err != nil {
+		return err
+	}
+	f, err := findFinding(sources, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// source is read from the module cache
+	d.modCache, err = d.getGoModCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if f.cap != nil {
+		d.explainCap(ctx, w, f)
+	} else {
+		d.explainIssue(w, f)
+	}
+	return w.Flush()
+}
+
+// findingSources returns the findings to search for a finding in,
+// either from a file of saved results or an inspection ID, or the
+// latest findings of every dependency version in the result store.
+func (d *depInspector) findingSources(ctx context.Context, path string) ([]*depFindings, error) {
+	if path != "" {
+		res, err := d.loadResults(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		sources := []*depFindings{res.New}
+		if res.Old != nil {
+			sources = append(sources, res.Old)
+		}
+		return sources, nil
+	}
+	if d.store == nil {
+		return nil, errors.New("-findings or -store is required to search for the finding")
+	}
+
+	inspections, err := d.store.latestAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*depFindings, 0, len(inspections))
+	for _, inspection := range inspections {
+		sources = append(sources, inspection.Findings)
+	}
+	return sources, nil
+}
+
+// findFinding returns the finding whose fingerprint starts with prefix.
+// If it was found in more than one dependency version, the first one
+// of sources it was found in is returned.
+func findFinding(sources []*depFindings, prefix string) (*explainedFinding, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) < minFingerprintLen {
+		return nil, fmt.Errorf("fingerprint must be at least %d characters", minFingerprintLen)
+	}
+
+	var found *explainedFinding
+	check := func(f *explainedFinding) error {
+		if !strings.HasPrefix(f.fingerprint, prefix) {
+			return nil
+		}
+		if found == nil {
+			found = f
+			return nil
+		}
+		if found.fingerprint != f.fingerprint {
+			return fmt.Errorf("fingerprint %s is ambiguous, it matches %s and %s", prefix, found.fingerprint, f.fingerprint)
+		}
+		return nil
+	}
+
+	for _, findings := range sources {
+		if findings.Caps != nil {
+			for _, c := range findings.Caps.CapabilityInfo {
+				err := check(&explainedFinding{
+					findings:    findings,
+					fingerprint: capFindingFingerprint(c),
+					cap:         c,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, issue := range findings.Issues {
+			err := check(&explainedFinding{
+				findings:    findings,
+				fingerprint: issueFingerprint(findings.Dep, issue),
+				issue:       issue,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no finding with fingerprint %s found", prefix)
+	}
+
+	return found, nil
+}
+
+// explainCap prints every call of a capability's call path with the
+// source around each call site, and the capability map rule that
+// matched the final call.
+func (d *depInspector) explainCap(ctx context.Context, w io.Writer, f *explainedFinding) {
+	c := f.cap
+	fmt.Fprintf(w, "%s found in %s\n", c.Capability, makeVersionStr(f.findings.Dep, f.findings.Version))
+	fmt.Fprintf(w, "Fingerprint: %s\n", f.fingerprint)
+	fmt.Fprintf(w, "Package:     %s\n", c.PackageDir)
+	fmt.Fprintf(w, "Type:        %s\n", capTypeName(c.CapabilityType))
+	if c.Severity != "" {
+		fmt.Fprintf(w, "Severity:    %s\n", c.Severity)
+	}
+
+	fmt.Fprintln(w, "\nCall path:")
+	var goRoot string
+	for i, call := range c.Path {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, call.Name)
+		// the version is empty for the standard library, which links
+		// to the latest documentation
+		mod, _ := findPkgModule(f.findings, funcPackage(receiverReplacer.Replace(call.Name)))
+		fmt.Fprintf(w, "   docs: %s\n", funcDocURL(call.Name, mod.Version))
+		if i == 0 || call.Site.Filename == "" {
+			continue
+		}
+
+		// the call site is in the package of the calling function
+		callerPkg := funcPackage(receiverReplacer.Replace(c.Path[i-1].Name))
+		fmt.Fprintf(w, "   called at %s:%s\n", path.Join(callerPkg, call.Site.Filename), call.Site.Line)
+		if isStdlibPackage(callerPkg) && goRoot == "" {
+			var err error
+			goRoot, err = d.getGoRoot(ctx)
+			if err != nil {
+				fmt.Fprintf(w, "   source not available: %v\n", err)
+				continue
+			}
+		}
+		file, err := d.callerFile(f.findings, callerPkg, call.Site.Filename, goRoot)
+		if err == nil {
+			err = printSnippet(w, file, call.Site.Line)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "   source not available: %v\n", err)
+		}
+	}
+
+	fmt.Fprintln(w, "\nCapability map rule:")
+	rule := c.MapRule
+	// findings of older versions of dep-inspector don't record the rule
+	if rule == nil {
+		rule = d.capMaps.ruleFor(c)
+	}
+	if rule.Map == builtinCapMap {
+		fmt.Fprintf(w, "  %s is %s in %s\n", c.Path[len(c.Path)-1].Name, c.Capability, rule)
+	} else {
+		fmt.Fprintf(w, "  %s\n", rule)
+	}
+}
+
+// explainIssue prints a linter issue with the source around it and a
+// link to the documentation of the check that found it.
+func (d *depInspector) explainIssue(w io.Writer, f *explainedFinding) {
+	issue := f.issue
+	fmt.Fprintf(w, "%s issue found in %s\n", issue.FromLinter, makeVersionStr(f.findings.Dep, f.findings.Version))
+	fmt.Fprintf(w, "Fingerprint: %s\n", f.fingerprint)
+	fmt.Fprintf(w, "Position:    %s:%d:%d\n", path.Join(f.findings.Dep, issue.Pos.Filename), issue.Pos.Line, issue.Pos.Column)
+	if issue.Severity != "" {
+		fmt.Fprintf(w, "Severity:    %s\n", issue.Severity)
+	}
+	fmt.Fprintf(w, "\n%s\n\n", issue.Text)
+
+	file, err := moduleFile(d.modCache, f.findings.Dep, f.findings.Version, issue.Pos.Filename)
+	if err == nil {
+		err = printSnippet(w, file, strconv.Itoa(issue.Pos.Line))
+	}
+	if err != nil {
+		// fall back to the lines the linter reported
+		for _, line := range issue.SourceLines {
+			fmt.Fprintf(w, "   %s\n", line)
+		}
+	}
+
+	fmt.Fprintf(w, "\nLinter documentation: %s\n", linterDocURL(issue))
+}
+
+// callerFile returns the path of a file of pkg in GOROOT or the module
+// cache.
+func (d *depInspector) callerFile(findings *depFindings, pkg, filename, goRoot string) (string, error) {
+	if isStdlibPackage(pkg) {
+		return filepath.Join(goRoot, "src", filepath.FromSlash(pkg), filename), nil
+	}
+
+	mod, ok := findPkgModule(findings, pkg)
+	if !ok {
+		return "", fmt.Errorf("module of package %s not found", pkg)
+	}
+	return moduleFile(d.modCache, mod.Path, mod.Version, path.Join(strings.TrimPrefix(pkg, mod.Path), filename))
+}
+
+// moduleFile returns the path in the module cache of a file of a
+// module version.
+func moduleFile(modCache, modPath, version, filename string) (string, error) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return "", err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCache, makeVersionStr(escPath, escVer), filepath.FromSlash(filename)), nil
+}
+
+// findPkgModule returns the module pkg is in and its version.
+func findPkgModule(findings *depFindings, pkg string) (capModule, bool) {
+	mods := []capModule{{Path: findings.Dep, Version: findings.Version}}
+	if findings.Caps != nil {
+		mods = append(mods, findings.Caps.ModuleInfo...)
+	}
+
+	var found capModule
+	for _, mod := range mods {
+		if pkg != mod.Path && !strings.HasPrefix(pkg, mod.Path+"/") {
+			continue
+		}
+		// nested modules have longer paths
+		if len(mod.Path) > len(found.Path) {
+			found = mod
+		}
+	}
+	return found, found.Path != ""
+}
+
+// printSnippet prints the lines of a file around line, marking line.
+func printSnippet(w io.Writer, file, line string) error {
+	lineNum, err := strconv.Atoi(line)
+	if err != nil {
+		return fmt.Errorf("invalid line %q", line)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	first := max(lineNum-snippetContext, 1)
+	last := lineNum + snippetContext
+	width := len(strconv.Itoa(last))
+	s := bufio.NewScanner(f)
+	for n := 1; n <= last && s.Scan(); n++ {
+		if n < first {
+			continue
+		}
+		marker := " "
+		if n == lineNum {
+			marker = ">"
+		}
+		fmt.Fprintf(w, "   %s %*d | %s\n", marker, width, n, s.Text())
+	}
+	return s.Err()
+}
+
+// linterDocURL returns the URL of the documentation of the check that
+// found an issue.
+func linterDocURL(issue *lintIssue) string {
+	linter, code, _ := strings.Cut(issue.FromLinter, " ")
+	if !slices.Contains(staticcheckLinters, linter) {
+		return "https://golangci-lint.run/usage/linters/#" + linter
+	}
+	if code == "" {
+		// golangci-lint prefixes messages of staticcheck's checks
+		// with their code
+		code, _, _ = strings.Cut(issue.Text, ":")
+		if strings.Contains(code, " ") {
+			code = ""
+		}
+	}
+	return "https://staticcheck.dev/docs/checks/#" + code
+}
+
+// getGoRoot returns the GOROOT standard library source is in.
+func (d *depInspector) getGoRoot(ctx context.Context) (string, error) {
+	var sb strings.Builder
+	if err := d.runCommand(ctx, &sb, "go", "env", "GOROOT"); err != nil {
+		return "", fmt.Errorf("getting GOROOT: %w", err)
+	}
+	return trimNewline(sb.String()), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+)
+
+// fatalFuncs are functions that exit the process, keyed by package
+// path.
+var fatalFuncs = map[string][]string{
+	"log": {"Fatal", "Fatalf", "Fatalln"},
+	"os":  {"Exit"},
+}
+
+// fatalCall is a function a dependency's library packages call that
+// crashes or exits the process, which the programs that import them
+// can't recover from.
+type fatalCall struct {
+	// Func is 'panic' or the qualified name of the function, such as
+	// 'log.Fatal'
+	Func string
+	Uses []sourcePos
+}
+
+// scanFatalCalls finds calls of panic and functions that exit the
+// process. Main packages are skipped as only they decide when to exit.
+func (s *sourceFacts) scanFatalCalls(f *sourceFile) {
+	if f.file.Name.Name == "main" {
+		return
+	}
+
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+			s.addFatalCall("panic", f.pos(call))
+			return true
+		}
+		pkgPath, name, ok := f.callee(call)
+		if ok && slices.Contains(fatalFuncs[pkgPath], name) {
+			s.addFatalCall(pkgPath+"."+name, f.pos(call))
+		}
+		return true
+	})
+}
+
+func (s *sourceFacts) addFatalCall(fn string, pos sourcePos) {
+	i := slices.IndexFunc(s.FatalCalls, func(call fatalCall) bool {
+		return call.Func == fn
+	})
+	if i == -1 {
+		s.FatalCalls = append(s.FatalCalls, fatalCall{Func: fn})
+		i = len(s.FatalCalls) - 1
+	}
+	s.FatalCalls[i].Uses = append(s.FatalCalls[i].Uses, pos)
+}
+
+// fatalCallChange is how many times a function that crashes or exits
+// the process is called by two versions of a dependency.
+type fatalCallChange struct {
+	Func string
+	Old  int
+	New  int
+}
+
+// Delta returns how the number of calls changed, such as '+2'.
+func (c fatalCallChange) Delta() string {
+	return formatDelta(c.New - c.Old)
+}
+
+// compareFatalCalls returns the functions that crash or exit the
+// process whose number of calls changed between two versions of a
+// dependency.
+func compareFatalCalls(oldFacts, newFacts *sourceFacts) []fatalCallChange {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	counts := make(map[string]*fatalCallChange)
+	change := func(fn string) *fatalCallChange {
+		if counts[fn] == nil {
+			counts[fn] = &fatalCallChange{Func: fn}
+		}
+		return counts[fn]
+	}
+	for _, call := range oldFacts.FatalCalls {
+		change(call.Func).Old = len(call.Uses)
+	}
+	for _, call := range newFacts.FatalCalls {
+		change(call.Func).New = len(call.Uses)
+	}
+
+	var changes []fatalCallChange
+	for _, c := range counts {
+		if c.Old != c.New {
+			changes = append(changes, *c)
+		}
+	}
+	slices.SortFunc(changes, func(a, b fatalCallChange) int {
+		return strings.Compare(a.Func, b.Func)
+	})
+	return changes
+}
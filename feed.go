@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	feedFile = "feed.atom"
+	// maxFeedEntries is how many of the latest inspections are kept in
+	// the feed
+	maxFeedEntries = 100
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// readFeed reads the Atom feed at path so entries of previous runs are
+// kept, or creates a new feed if it doesn't exist.
+func readFeed(path, module, baseURL string) (*atomFeed, error) {
+	feed := &atomFeed{}
+	contents, err := os.ReadFile(path)
+	if err == nil {
+		if err := xml.Unmarshal(contents, feed); err != nil {
+			return nil, fmt.Errorf("decoding feed %s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	feed.Title = "Inspected dependencies of " + module
+	feed.ID = "urn:dep-inspector:" + module
+	feed.Author = atomAuthor{Name: "dep-inspector"}
+	feed.Links = nil
+	if baseURL != "" {
+		feed.Links = []atomLink{{Href: baseURL + "/" + feedFile, Rel: "self"}}
+	}
+
+	return feed, nil
+}
+
+// addEntry adds an entry for a completed inspection to the start of
+// the feed. Policy violations are listed in the entry's summary.
+func (f *atomFeed) addEntry(res *savedResults, reportURL string, violations []policyViolation) {
+	updated := res.New.Metadata.Time
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	entry := atomEntry{
+		Title:      newChatMessage(res, "").text(nil),
+		ID:         "urn:dep-inspector:" + makeVersionStr(res.New.Dep, res.New.Version),
+		Updated:    updated.UTC().Format(time.RFC3339),
+		Categories: []atomCategory{{Term: eventInspectionCompleted}},
+	}
+	if reportURL != "" {
+		entry.Links = []atomLink{{Href: reportURL, Rel: "alternate"}}
+	}
+
+	var summary strings.Builder
+	summary.WriteString(entry.Title)
+	if res.New.Risk != nil {
+		fmt.Fprintf(&summary, "\nRisk score: %d/100", res.New.Risk.Score)
+	}
+	if len(violations) != 0 {
+		entry.Categories = append(entry.Categories, atomCategory{Term: eventPolicyViolation})
+		summary.WriteString("\nPolicy violations:")
+		for _, violation := range violations {
+			fmt.Fprintf(&summary, "\n- %s", violation)
+		}
+	}
+	entry.Summary = summary.String()
+
+	// a version inspected again replaces its previous entry
+	f.Entries = slices.DeleteFunc(f.Entries, func(e atomEntry) bool {
+		return e.ID == entry.ID
+	})
+	f.Entries = append([]atomEntry{entry}, f.Entries...)
+	if len(f.Entries) > maxFeedEntries {
+		f.Entries = f.Entries[:maxFeedEntries]
+	}
+	f.Updated = entry.Updated
+}
+
+// write writes the feed to path.
+func (f *atomFeed) write(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return fmt.Errorf("encoding feed: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	return writeFile(path, &buf)
+}
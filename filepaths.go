@@ -0,0 +1,252 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+)
+
+// pathAccessFuncs are functions that access the filesystem paths passed
+// as the arguments at the indexes, keyed by package path and then
+// function name.
+var pathAccessFuncs = map[string]map[string][]int{
+	"os": {
+		"Chdir":      {0},
+		"Chmod":      {0},
+		"Chown":      {0},
+		"Chtimes":    {0},
+		"Create":     {0},
+		"CreateTemp": {0},
+		"DirFS":      {0},
+		"Lchown":     {0},
+		"Link":       {0, 1},
+		"Lstat":      {0},
+		"Mkdir":      {0},
+		"MkdirAll":   {0},
+		"MkdirTemp":  {0},
+		"Open":       {0},
+		"OpenFile":   {0},
+		"ReadDir":    {0},
+		"ReadFile":   {0},
+		"Readlink":   {0},
+		"Remove":     {0},
+		"RemoveAll":  {0},
+		"Rename":     {0, 1},
+		"Stat":       {0},
+		"Symlink":    {0, 1},
+		"Truncate":   {0},
+		"WriteFile":  {0},
+	},
+	"io/ioutil": {
+		"ReadDir":   {0},
+		"ReadFile":  {0},
+		"TempDir":   {0},
+		"TempFile":  {0},
+		"WriteFile": {0},
+	},
+	"path/filepath": {
+		"EvalSymlinks": {0},
+		"Glob":         {0},
+		"Walk":         {0},
+		"WalkDir":      {0},
+	},
+	"syscall": {
+		"Chmod":  {0},
+		"Mkdir":  {0},
+		"Open":   {0},
+		"Rmdir":  {0},
+		"Stat":   {0},
+		"Unlink": {0},
+	},
+}
+
+// sensitivePathRe matches paths that hold credentials or system state
+// libraries rarely have a reason to access.
+var sensitivePathRe = regexp.MustCompile(`^/etc/(passwd|shadow|group|sudoers|hosts|resolv\.conf)$|^/(proc|sys|dev|boot|root)(/|$)|(^|/)\.(ssh|aws|azure|kube|docker|gnupg|netrc|git-credentials|npmrc|pypirc|bash_history|zsh_history)(/|$)|(^|/)\.config/gcloud(/|$)|(^|/)id_(rsa|dsa|ecdsa|ed25519)`)
+
+// pathAccess is a filesystem path a dependency accesses.
+type pathAccess struct {
+	// Path is the path that is accessed. Parts that aren't constant
+	// are '*', the home directory is '~' and environment variables
+	// are '$NAME'.
+	Path string
+	// Sensitive is true if the path holds credentials or system state
+	Sensitive bool `json:",omitempty"`
+	Uses      []sourcePos
+}
+
+// scanPaths finds the constant filesystem paths a file accesses.
+func (s *sourceFacts) scanPaths(f *sourceFile) {
+	vars := pathVars(f)
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		pkgPath, name, ok := f.callee(call)
+		if !ok {
+			return true
+		}
+		for _, i := range pathAccessFuncs[pkgPath][name] {
+			if i >= len(call.Args) {
+				continue
+			}
+			p, ok := f.pathValue(call.Args[i], vars)
+			if ok && p != "" {
+				s.addPath(p, f.pos(call))
+			}
+		}
+		return true
+	})
+}
+
+// pathVars returns variables of a file that are assigned the home or
+// temporary directory or an environment variable, mapped to how they
+// are shown in paths. Scopes are ignored as variables that are reused
+// for other values are rare.
+func pathVars(f *sourceFile) map[string]string {
+	vars := make(map[string]string)
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		assign, ok := node.(*ast.AssignStmt)
+		if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+			return true
+		}
+		call, ok := assign.Rhs[0].(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := assign.Lhs[0].(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if p, ok := f.pathCall(call); ok {
+			vars[ident.Name] = p
+		}
+		return true
+	})
+	return vars
+}
+
+// pathValue returns the path an expression evaluates to, with parts
+// that aren't constant replaced with '*', and true if any part is
+// known.
+func (f *sourceFile) pathValue(expr ast.Expr, vars map[string]string) (string, bool) {
+	if s, ok := f.stringValue(expr); ok {
+		return s, true
+	}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if p, ok := vars[e.Name]; ok {
+			return p, true
+		}
+	case *ast.ParenExpr:
+		return f.pathValue(e.X, vars)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			break
+		}
+		x, xOK := f.pathValue(e.X, vars)
+		y, yOK := f.pathValue(e.Y, vars)
+		return x + y, xOK || yOK
+	case *ast.CallExpr:
+		if p, ok := f.pathCall(e); ok {
+			return p, true
+		}
+		pkgPath, name, ok := f.callee(e)
+		if !ok || name != "Join" || (pkgPath != "path/filepath" && pkgPath != "path") || e.Ellipsis.IsValid() {
+			break
+		}
+		var (
+			elems = make([]string, len(e.Args))
+			known bool
+		)
+		for i, arg := range e.Args {
+			var ok bool
+			elems[i], ok = f.pathValue(arg, vars)
+			known = known || ok
+		}
+		return path.Join(elems...), known
+	}
+	return "*", false
+}
+
+// pathCall returns how a call that returns a well known directory or
+// an environment variable is shown in paths.
+func (f *sourceFile) pathCall(call *ast.CallExpr) (string, bool) {
+	pkgPath, name, ok := f.callee(call)
+	if !ok || pkgPath != "os" {
+		return "", false
+	}
+	switch name {
+	case "UserHomeDir":
+		return "~", true
+	case "TempDir":
+		return "$TMPDIR", true
+	case "UserConfigDir":
+		return "~/.config", true
+	case "UserCacheDir":
+		return "~/.cache", true
+	case "Getenv":
+		if len(call.Args) != 1 {
+			return "", false
+		}
+		env, ok := f.stringValue(call.Args[0])
+		if !ok {
+			return "", false
+		}
+		if env == "HOME" {
+			return "~", true
+		}
+		return "$" + env, true
+	case "ExpandEnv":
+		if len(call.Args) != 1 {
+			return "", false
+		}
+		return f.stringValue(call.Args[0])
+	}
+	return "", false
+}
+
+func (s *sourceFacts) addPath(p string, pos sourcePos) {
+	i := slices.IndexFunc(s.Paths, func(access pathAccess) bool {
+		return access.Path == p
+	})
+	if i == -1 {
+		s.Paths = append(s.Paths, pathAccess{
+			Path:      p,
+			Sensitive: sensitivePathRe.MatchString(p),
+		})
+		i = len(s.Paths) - 1
+	}
+	s.Paths[i].Uses = append(s.Paths[i].Uses, pos)
+}
+
+// pathChanges are the filesystem paths a new version of a dependency
+// accesses that the old version didn't, and that it no longer
+// accesses.
+type pathChanges struct {
+	Added   []pathAccess
+	Removed []pathAccess
+}
+
+// comparePaths compares the filesystem paths two versions of a
+// dependency access.
+func comparePaths(oldFacts, newFacts *sourceFacts) *pathChanges {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	removed, _, added := processFindings(oldFacts.Paths, newFacts.Paths, func(access pathAccess) string {
+		return access.Path
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &pathChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
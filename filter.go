@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// findingsFilter restricts which findings are reported.
+type findingsFilter struct {
+	minSeverity string
+	// caps are the names of capabilities to report, all capabilities
+	// are reported if empty
+	caps []string
+	// directOnly is true if only capabilities used directly by the
+	// dependency's own code are reported
+	directOnly bool
+	// collectCaps are the names of capabilities that are collected
+	// from capslock, all capabilities are collected if empty
+	collectCaps []string
+	// hideGenerated is true if findings in generated files aren't
+	// reported
+	hideGenerated bool
+
+	// ignorePkgs match import paths of packages whose findings are
+	// ignored
+	ignorePkgs []*regexp.Regexp
+	// ignoreFiles are globs of files whose findings are ignored
+	ignoreFiles []string
+}
+
+// newFindingsFilter creates a filter from a minimum severity, a comma
+// separated list of capability names, with or without the CAPABILITY_
+// prefix, and whether only direct capabilities are reported.
+func newFindingsFilter(minSeverity, onlyCaps string, directOnly bool) (*findingsFilter, error) {
+	if minSeverity != "" {
+		if err := checkSeverity(minSeverity); err != nil {
+			return nil, fmt.Errorf("invalid minimum severity: %w", err)
+		}
+	}
+
+	f := &findingsFilter{
+		minSeverity: minSeverity,
+		caps:        parseCapNames(onlyCaps),
+		directOnly:  directOnly,
+	}
+
+	return f, nil
+}
+
+// collect restricts which capabilities are collected from capslock to
+// a comma separated list of capability names, with or without the
+// CAPABILITY_ prefix. Other capabilities are dropped as capslock's
+// output is read, so they aren't recorded anywhere.
+func (f *findingsFilter) collect(capNames string) error {
+	for _, name := range parseCapNames(capNames) {
+		if _, ok := defaultCapSeverities[name]; !ok {
+			return fmt.Errorf("unknown capability %q", name)
+		}
+		f.collectCaps = append(f.collectCaps, name)
+	}
+	return nil
+}
+
+// parseCapNames parses a comma separated list of capability names, with
+// or without the CAPABILITY_ prefix.
+func parseCapNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, "CAPABILITY_") {
+			name = "CAPABILITY_" + name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// ignore adds package patterns and file globs to ignore findings of.
+// Package patterns are import paths where '...' matches any string,
+// like patterns passed to go commands. File globs are matched against
+// paths relative to the root of the dependency; globs without a slash
+// are matched against every element of the path, so 'testdata' or
+// '*.pb.go' match anywhere.
+func (f *findingsFilter) ignore(pkgPatterns, fileGlobs []string) error {
+	for _, pattern := range pkgPatterns {
+		f.ignorePkgs = append(f.ignorePkgs, pkgPatternRegexp(pattern))
+	}
+	for _, glob := range fileGlobs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid ignore file glob %q: %w", glob, err)
+		}
+		f.ignoreFiles = append(f.ignoreFiles, glob)
+	}
+
+	return nil
+}
+
+// pkgPatternRegexp converts a package pattern to a regular expression.
+func pkgPatternRegexp(pattern string) *regexp.Regexp {
+	re := regexp.QuoteMeta(pattern)
+	// 'foo/...' matches foo as well as packages under it
+	if strings.HasSuffix(re, `/\.\.\.`) {
+		re = strings.TrimSuffix(re, `/\.\.\.`) + `(/.*)?`
+	}
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+	return regexp.MustCompile("^" + re + "$")
+}
+
+func (f *findingsFilter) empty() bool {
+	return f.minSeverity == "" && len(f.caps) == 0 && !f.directOnly && !f.hideGenerated && len(f.ignorePkgs) == 0 && len(f.ignoreFiles) == 0
+}
+
+// filterResults returns a copy of results with only findings that pass
+// the filter. Severities must already be set.
+func (f *findingsFilter) filterResults(res *savedResults) *savedResults {
+	if f.empty() {
+		return res
+	}
+	return &savedResults{
+		Old: f.filterFindings(res.Old),
+		New: f.filterFindings(res.New),
+	}
+}
+
+func (f *findingsFilter) filterFindings(findings *depFindings) *depFindings {
+	if findings == nil {
+		return nil
+	}
+
+	filtered := *findings
+	if findings.Caps != nil {
+		caps := *findings.Caps
+		caps.CapabilityInfo = slices.DeleteFunc(slices.Clone(caps.CapabilityInfo), func(c *capability) bool {
+			if len(f.caps) != 0 && !slices.Contains(f.caps, c.Capability) {
+				return true
+			}
+			if f.directOnly && c.CapabilityType != capTypeDirect {
+				return true
+			}
+			if f.hideGenerated && c.Generated {
+				return true
+			}
+			if f.ignoredCap(findings.Dep, c) {
+				return true
+			}
+			return !f.severe(c.Severity)
+		})
+		filtered.Caps = &caps
+	}
+	filtered.Issues = slices.DeleteFunc(slices.Clone(findings.Issues), func(issue *lintIssue) bool {
+		if f.ignoredIssue(findings.Dep, issue) {
+			return true
+		}
+		if f.hideGenerated && issue.Generated {
+			return true
+		}
+		return !f.severe(issue.Severity)
+	})
+
+	return &filtered
+}
+
+func (f *findingsFilter) severe(sev string) bool {
+	return f.minSeverity == "" || compareSeverity(sev, f.minSeverity) >= 0
+}
+
+// ignoredCap returns true if a capability of dep isn't collected or is
+// in an ignored package or file.
+func (f *findingsFilter) ignoredCap(dep string, c *capability) bool {
+	if f == nil {
+		return false
+	}
+	if len(f.collectCaps) != 0 && !slices.Contains(f.collectCaps, c.Capability) {
+		return true
+	}
+	return f.ignored(c.PackageDir, capFile(dep, c))
+}
+
+// ignoredIssue returns true if a linter issue of dep is in an ignored
+// package or file. The issue's filename must be relative to the root of
+// dep.
+func (f *findingsFilter) ignoredIssue(dep string, issue *lintIssue) bool {
+	if f == nil {
+		return false
+	}
+	pkg := dep
+	if dir := path.Dir(issue.Pos.Filename); dir != "." {
+		pkg = path.Join(pkg, dir)
+	}
+	return f.ignored(pkg, issue.Pos.Filename)
+}
+
+// ignored returns true if findings in a package or file should be
+// ignored. file is relative to the root of the dependency and may be
+// empty if it isn't known.
+func (f *findingsFilter) ignored(pkg, file string) bool {
+	for _, re := range f.ignorePkgs {
+		if re.MatchString(pkg) {
+			return true
+		}
+	}
+	if file == "" {
+		return false
+	}
+
+	elems := strings.Split(file, "/")
+	for _, glob := range f.ignoreFiles {
+		if !strings.Contains(glob, "/") {
+			for _, elem := range elems {
+				if ok, _ := path.Match(glob, elem); ok {
+					return true
+				}
+			}
+			continue
+		}
+		// match the file and every directory it's in, so 'third_party/foo'
+		// matches files under third_party/foo
+		for i := len(elems); i > 0; i-- {
+			if ok, _ := path.Match(glob, strings.Join(elems[:i], "/")); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// capFile returns the file relative to the root of dep where the path
+// of a capability starts, or an empty string if it isn't known.
+func capFile(dep string, c *capability) string {
+	// the first function of the path is in the dependency, the call it
+	// makes is the location in the dependency
+	if len(c.Path) < 2 || c.Path[1].Site.Filename == "" {
+		return ""
+	}
+	file := path.Base(c.Path[1].Site.Filename)
+	rel, ok := strings.CutPrefix(c.PackageDir, dep)
+	if !ok {
+		return file
+	}
+	return path.Join(strings.TrimPrefix(rel, "/"), file)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// forkCmd compares a module against a fork of it that has a different
+// module path.
+func forkCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: dep-inspector [flags] fork path/of/module@version path/of/fork@version")
+	}
+	orig, origVer, ok := strings.Cut(args[0], "@")
+	if !ok {
+		return fmt.Errorf("malformed module version string %q: no \"@\" present", args[0])
+	}
+	fork, forkVer, ok := strings.Cut(args[1], "@")
+	if !ok {
+		return fmt.Errorf("malformed module version string %q: no \"@\" present", args[1])
+	}
+	if orig == fork {
+		return errors.New("cannot compare: module and fork have the same path, compare versions of the module instead")
+	}
+
+	origVer, err := d.checkVersion(orig, origVer)
+	if err != nil {
+		return fmt.Errorf("checking module version: %w", err)
+	}
+	forkVer, err = d.checkVersion(fork, forkVer)
+	if err != nil {
+		return fmt.Errorf("checking fork version: %w", err)
+	}
+
+	// the fork isn't imported by the main module, so inspect every
+	// package of both modules to compare them fairly
+	d.unusedDep = true
+
+	origFindings, err := d.inspectDep(ctx, d.oldModBackupFiles, orig, origVer, false, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting %s: %w", makeVersionStr(orig, origVer), err)
+	}
+	// don't inspect the fork with the original module added to go.mod
+	if err := d.resetModFiles(); err != nil {
+		return fmt.Errorf("restoring go.mod: %w", err)
+	}
+	forkFindings, err := d.inspectDep(ctx, d.newModBackupFiles, fork, forkVer, true, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting %s: %w", makeVersionStr(fork, forkVer), err)
+	}
+
+	return d.outputResults(ctx, &savedResults{
+		Old: origFindings,
+		New: forkFindings,
+	})
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"slices"
+	"strconv"
+)
+
+const (
+	// what conditions that may hide behavior from analysis depend on
+	gateDate     = "date"
+	gateHostname = "hostname"
+	gateCI       = "CI detection"
+	gateLocale   = "locale"
+
+	// maxConditionLen is how much of a condition is shown in reports
+	maxConditionLen = 120
+	// integers between these are likely unix timestamps of dates a
+	// condition checks for, from 2008 to 2036, excluding common
+	// constants like 1e9 and math.MaxInt32
+	minTimestamp = 1_200_000_000
+	maxTimestamp = 2_100_000_000
+)
+
+var (
+	// ciEnvVars are environment variables CI services set that code
+	// can check to behave differently when it is being tested
+	ciEnvVars = []string{
+		"BUILDKITE", "BUILD_ID", "BUILD_NUMBER", "CI", "CIRCLECI", "CODEBUILD_BUILD_ID",
+		"CONTINUOUS_INTEGRATION", "DRONE", "GITHUB_ACTIONS", "GITLAB_CI", "JENKINS_URL",
+		"RUN_ID", "TEAMCITY_VERSION", "TF_BUILD", "TRAVIS",
+	}
+	// localeEnvVars are environment variables that set the locale
+	localeEnvVars = []string{"LANG", "LANGUAGE", "LC_ALL", "LC_CTYPE", "LC_MESSAGES", "TZ"}
+	// dateMethods are methods of time.Time that return parts of dates
+	dateMethods = []string{"Day", "Month", "Weekday", "Year", "YearDay"}
+	// locationMethods are methods of time.Time that return time zones
+	locationMethods = []string{"Location", "Zone"}
+)
+
+// gatedCondition is a condition that depends on the date, host, CI
+// environment or locale. Malicious code uses such conditions to only
+// run after a date or outside of CI and sandboxes, so it isn't seen
+// when the code is tested or analyzed.
+type gatedCondition struct {
+	// Kind is what the condition depends on
+	Kind string
+	// Condition is the source of the condition
+	Condition string
+	Uses      []sourcePos
+}
+
+// scanGating finds conditions of if and switch statements that depend
+// on the date, hostname, CI environment variables or locale. Variables
+// assigned from those values are tracked so conditions that use them
+// are found too, ignoring scopes.
+func (s *sourceFacts) scanGating(f *sourceFile) {
+	vars := make(map[string]string)
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			addGateVars(vars, n.Lhs, n.Rhs, f)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			addGateVars(vars, lhs, n.Values, f)
+		}
+		return true
+	})
+
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		var conds []ast.Expr
+		switch n := node.(type) {
+		case *ast.IfStmt:
+			conds = []ast.Expr{n.Cond}
+		case *ast.SwitchStmt:
+			if n.Tag != nil {
+				conds = []ast.Expr{n.Tag}
+				break
+			}
+			for _, stmt := range n.Body.List {
+				conds = append(conds, stmt.(*ast.CaseClause).List...)
+			}
+		}
+		for _, cond := range conds {
+			if kind := f.gateKind(cond, vars); kind != "" {
+				s.addGatedCondition(kind, f.conditionSource(cond), f.pos(cond))
+			}
+		}
+		return true
+	})
+}
+
+// addGateVars records variables that are assigned values conditions
+// may be gated on.
+func addGateVars(vars map[string]string, lhs, rhs []ast.Expr, f *sourceFile) {
+	for i, expr := range rhs {
+		kind := f.gateKind(expr, vars)
+		if kind == "" {
+			continue
+		}
+		// 'host, err := os.Hostname()' assigns every variable from
+		// one call
+		names := lhs
+		if len(rhs) == len(lhs) {
+			names = lhs[i : i+1]
+		}
+		for _, name := range names {
+			if ident, ok := name.(*ast.Ident); ok && ident.Name != "_" && ident.Name != "err" {
+				vars[ident.Name] = kind
+			}
+		}
+	}
+}
+
+// gateKind returns what an expression depends on if it may be used to
+// gate behavior, or an empty string if it doesn't.
+func (f *sourceFile) gateKind(expr ast.Expr, vars map[string]string) string {
+	var kind string
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if kind != "" {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.Ident:
+			kind = vars[n.Name]
+		case *ast.BasicLit:
+			if n.Kind != token.INT {
+				break
+			}
+			if i, err := strconv.ParseInt(n.Value, 0, 64); err == nil && i >= minTimestamp && i <= maxTimestamp {
+				kind = gateDate
+			}
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && f.imports[x.Name] == "time" && n.Sel.Name == "Local" {
+				kind = gateLocale
+			}
+		case *ast.CallExpr:
+			kind = f.gateCallKind(n)
+		}
+		return kind == ""
+	})
+	return kind
+}
+
+// gateCallKind returns what the value a call returns depends on if
+// behavior may be gated on it.
+func (f *sourceFile) gateCallKind(call *ast.CallExpr) string {
+	pkgPath, name, ok := f.callee(call)
+	if !ok {
+		// methods of time.Time, or of other types with the same names
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		switch {
+		case !ok:
+			return ""
+		case slices.Contains(dateMethods, sel.Sel.Name):
+			return gateDate
+		case slices.Contains(locationMethods, sel.Sel.Name):
+			return gateLocale
+		}
+		return ""
+	}
+
+	switch {
+	case pkgPath == "time" && name == "Date":
+		return gateDate
+	case pkgPath == "time" && (name == "Parse" || name == "ParseInLocation") && len(call.Args) >= 2:
+		if _, ok := f.stringValue(call.Args[1]); ok {
+			return gateDate
+		}
+	case pkgPath == "os" && name == "Hostname":
+		return gateHostname
+	case slices.Contains(envReadFuncs[pkgPath], name) && len(call.Args) != 0:
+		env, ok := f.stringValue(call.Args[0])
+		switch {
+		case !ok:
+		case slices.Contains(ciEnvVars, env):
+			return gateCI
+		case slices.Contains(localeEnvVars, env):
+			return gateLocale
+		}
+	}
+	return ""
+}
+
+// conditionSource returns the formatted source of a condition,
+// shortened if it is long.
+func (f *sourceFile) conditionSource(cond ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, f.fset, cond); err != nil {
+		return ""
+	}
+	src := []rune(buf.String())
+	if len(src) > maxConditionLen {
+		return string(src[:maxConditionLen]) + "…"
+	}
+	return string(src)
+}
+
+func (s *sourceFacts) addGatedCondition(kind, cond string, pos sourcePos) {
+	i := slices.IndexFunc(s.GatedConditions, func(gc gatedCondition) bool {
+		return gc.Kind == kind && gc.Condition == cond
+	})
+	if i == -1 {
+		s.GatedConditions = append(s.GatedConditions, gatedCondition{Kind: kind, Condition: cond})
+		i = len(s.GatedConditions) - 1
+	}
+	s.GatedConditions[i].Uses = append(s.GatedConditions[i].Uses, pos)
+}
+
+// gatingChanges are the gated conditions a new version of a dependency
+// has that the old version didn't, and that it no longer has.
+type gatingChanges struct {
+	Added   []gatedCondition
+	Removed []gatedCondition
+}
+
+// compareGating compares the gated conditions of two versions of a
+// dependency.
+func compareGating(oldFacts, newFacts *sourceFacts) *gatingChanges {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	removed, _, added := processFindings(oldFacts.GatedConditions, newFacts.GatedConditions, func(gc gatedCondition) string {
+		return gc.Kind + " " + gc.Condition
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &gatingChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
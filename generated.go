@@ -0,0 +1,79 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+)
+
+const (
+	// how findings in generated files are reported
+	generatedShow       = "show"
+	generatedGroup      = "group"
+	generatedDownweight = "downweight"
+	generatedHide       = "hide"
+)
+
+var generatedCodeModes = []string{generatedShow, generatedGroup, generatedDownweight, generatedHide}
+
+// generatedSuffixes are suffixes of names of files that are generated
+// even if they don't have a 'Code generated ... DO NOT EDIT.' comment,
+// such as protobuf files of old versions of protoc-gen-go.
+var generatedSuffixes = []string{".pb.go", ".pb.gw.go", ".pb.validate.go"}
+
+// isGeneratedFile returns true if a Go file was generated by a tool.
+func isGeneratedFile(name string, contents []byte) bool {
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, contents, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	return ast.IsGenerated(f)
+}
+
+// generatedFiles returns the generated files of the dependency,
+// relative to its root.
+func (f *depFindings) generatedFiles() map[string]bool {
+	files := make(map[string]bool)
+	for _, info := range f.PackageInfo {
+		dir := strings.TrimPrefix(strings.TrimPrefix(info.Path, f.Dep), "/")
+		for _, name := range info.Generated {
+			files[path.Join(dir, name)] = true
+		}
+	}
+	return files
+}
+
+// markGenerated marks findings in generated files. If mode is
+// generatedDownweight their severities are lowered to low so they
+// don't inflate risk scores. Severities must already be set.
+func markGenerated(findings *depFindings, mode string) {
+	if findings == nil {
+		return
+	}
+	generated := findings.generatedFiles()
+	if len(generated) == 0 {
+		return
+	}
+
+	for _, c := range findings.Caps.CapabilityInfo {
+		c.Generated = generated[capFile(findings.Dep, c)]
+		if c.Generated && mode == generatedDownweight {
+			c.Severity = severityLow
+		}
+	}
+	for _, issue := range findings.Issues {
+		issue.Generated = generated[issue.Pos.Filename]
+		if issue.Generated && mode == generatedDownweight {
+			issue.Severity = severityLow
+		}
+	}
+}
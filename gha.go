@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"text/template"
+)
+
+// maxSummaryCaps is the maximum number of capabilities listed in a
+// GitHub Actions job summary.
+const maxSummaryCaps = 10
+
+type ghaSummary struct {
+	Title       string
+	Totals      findingTotals
+	CapsHeading string
+	TopCaps     []*capability
+	MoreCaps    int
+}
+
+// writeGHASummary appends a Markdown summary of results to the GitHub
+// Actions job summary.
+func writeGHASummary(res *savedResults) error {
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return errors.New("writing GitHub Actions job summary: GITHUB_STEP_SUMMARY is not set")
+	}
+
+	summary, err := markdownSummary(res)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(summaryPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening GitHub Actions job summary: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(summary); err != nil {
+		return fmt.Errorf("writing GitHub Actions job summary: %w", err)
+	}
+
+	return nil
+}
+
+// markdownSummary renders a short Markdown summary of results with
+// totals and the most important capabilities.
+func markdownSummary(res *savedResults) ([]byte, error) {
+	dep := res.New.Dep
+	summary := ghaSummary{
+		Title:       "Findings for " + makeVersionStr(dep, res.New.Version),
+		Totals:      calculateTotals(res.New.Caps.CapabilityInfo, res.New.Issues),
+		CapsHeading: "Top capabilities",
+	}
+	caps := res.New.Caps.CapabilityInfo
+	if res.Old != nil {
+		compared := prepareCompareDepsResult(res.Old, res.New)
+		summary.Title = fmt.Sprintf("Comparing %s and %s", compared.OldVersionStr, compared.NewVersionStr)
+		summary.Totals = compared.Totals
+		summary.CapsHeading = "Top new capabilities"
+		caps = compareFindings(res.Old, res.New).addedCaps
+	}
+
+	// the most severe capabilities are listed first, then capabilities
+	// with the shortest call paths as they are the most direct
+	caps = slices.Clone(caps)
+	slices.SortFunc(caps, func(a, b *capability) int {
+		if c := compareSeverity(b.Severity, a.Severity); c != 0 {
+			return c
+		}
+		return compareCaps(a, b)
+	})
+	if len(caps) > maxSummaryCaps {
+		summary.MoreCaps = len(caps) - maxSummaryCaps
+		caps = caps[:maxSummaryCaps]
+	}
+	summary.TopCaps = caps
+
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"capType":     capTypeName,
+		"formatDelta": formatDelta,
+		"finalCall": func(c *capability) string {
+			return c.Path[len(c.Path)-1].Name
+		},
+	}).ParseFS(tmplFS, "output/gha-summary.md.tmpl", "output/totals.md.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing output template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "gha-summary.md.tmpl", summary); err != nil {
+		return nil, fmt.Errorf("error executing output template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/mod/modfile"
+)
+
+func gitDiffCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: dep-inspector [flags] git-diff old-ref new-ref")
+	}
+	oldRef, newRef := args[0], args[1]
+
+	oldModFile, err := d.loadModFilesAtRef(ctx, oldRef, d.oldModBackupFiles)
+	if err != nil {
+		return err
+	}
+	newModFile, err := d.loadModFilesAtRef(ctx, newRef, d.newModBackupFiles)
+	if err != nil {
+		return err
+	}
+
+	depsToInspect := findChangedDeps(oldModFile, newModFile)
+	for _, oldDep := range oldModFile.Require {
+		if !slices.ContainsFunc(newModFile.Require, func(newDep *modfile.Require) bool {
+			return newDep.Mod.Path == oldDep.Mod.Path
+		}) {
+			log.Printf("%s was removed in %s", oldDep.Mod.Path, newRef)
+		}
+	}
+	if len(depsToInspect) == 0 {
+		log.Printf("no dependencies changed between %s and %s", oldRef, newRef)
+		return nil
+	}
+	return d.inspectChangedDeps(ctx, depsToInspect)
+}
+
+// loadModFilesAtRef reads go.mod and go.sum of the main module at a git
+// ref into modBackupFiles, so dependencies will be inspected with the
+// requirements the module had at that ref.
+func (d *depInspector) loadModFilesAtRef(ctx context.Context, ref string, modBackupFiles *modFilePair) (*modfile.File, error) {
+	modDir := filepath.Dir(d.modFilePath)
+
+	var modContents bytes.Buffer
+	err := d.runCommand(ctx, &modContents, "git", "-C", modDir, "show", ref+":./go.mod")
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod at %s: %w", ref, err)
+	}
+	parsedModFile, err := modfile.Parse(ref+":go.mod", modContents.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod at %s: %w", ref, err)
+	}
+	// modules without dependencies don't have a go.sum
+	var sumContents bytes.Buffer
+	err = d.runCommand(ctx, &sumContents, "git", "-C", modDir, "show", ref+":./go.sum")
+	if err != nil {
+		log.Printf("go.sum not found at %s", ref)
+		sumContents.Reset()
+	}
+
+	modBackupFiles.modFile, err = os.CreateTemp("", "go.mod.bak")
+	if err != nil {
+		return nil, fmt.Errorf("creating backup go.mod file: %w", err)
+	}
+	modBackupFiles.sumFile, err = os.CreateTemp("", "go.sum.bak")
+	if err != nil {
+		return nil, fmt.Errorf("creating backup go.sum file: %w", err)
+	}
+	if _, err := modBackupFiles.modFile.Write(modContents.Bytes()); err != nil {
+		return nil, fmt.Errorf("writing go.mod: %w", err)
+	}
+	if _, err := modBackupFiles.sumFile.Write(sumContents.Bytes()); err != nil {
+		return nil, fmt.Errorf("writing go.sum: %w", err)
+	}
+
+	return parsedModFile, nil
+}
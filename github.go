@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+
+	// githubActionsLogin is the author of comments posted with the
+	// token GitHub Actions provides
+	githubActionsLogin = "github-actions[bot]"
+)
+
+var (
+	prRefRe = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	prURLRe = regexp.MustCompile(`^https://[^/]+/([\w.-]+)/([\w.-]+)/pull/(\d+)`)
+)
+
+type pullRequestRef struct {
+	owner  string
+	repo   string
+	number int
+}
+
+// parsePRRef parses a pull request reference in the form owner/repo#123
+// or a pull request URL.
+func parsePRRef(ref string) (pullRequestRef, error) {
+	m := prRefRe.FindStringSubmatch(ref)
+	if m == nil {
+		m = prURLRe.FindStringSubmatch(ref)
+	}
+	if m == nil {
+		return pullRequestRef{}, fmt.Errorf("malformed pull request reference %q: must be in the form owner/repo#123 or a pull request URL", ref)
+	}
+	number, err := strconv.Atoi(m[3])
+	if err != nil {
+		return pullRequestRef{}, fmt.Errorf("malformed pull request number: %w", err)
+	}
+
+	return pullRequestRef{
+		owner:  m[1],
+		repo:   m[2],
+		number: number,
+	}, nil
+}
+
+type issueComment struct {
+	ID   int64       `json:"id"`
+	Body string      `json:"body"`
+	User *githubUser `json:"user,omitempty"`
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// postPRComment posts a comment summarizing results on the configured
+// pull request. If a comment about the same dependency was already
+// posted by a previous run it is updated instead. Only comments posted
+// by the same user are updated, so comments by others that contain the
+// marker are left alone.
+func (d *depInspector) postPRComment(ctx context.Context, res *savedResults) error {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return errors.New("posting pull request comment: GITHUB_TOKEN is not set")
+	}
+	pr, err := parsePRRef(d.prComment)
+	if err != nil {
+		return err
+	}
+
+	summary, err := markdownSummary(res)
+	if err != nil {
+		return err
+	}
+	// the marker is used to find the comment again on later runs
+	marker := fmt.Sprintf("<!-- dep-inspector: %s -->", res.New.Dep)
+	var body strings.Builder
+	body.WriteString(marker)
+	body.WriteString("\n")
+	body.Write(summary)
+	if d.reportURL != "" {
+		fmt.Fprintf(&body, "\n[Full report](%s)\n", d.reportURL)
+	}
+
+	login := d.tokenLogin(ctx, token)
+	comments, err := listPRComments(ctx, token, pr)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if comment.User != nil && comment.User.Login == login && strings.HasPrefix(comment.Body, marker) {
+			path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", pr.owner, pr.repo, comment.ID)
+			return githubRequest(ctx, token, http.MethodPatch, path, issueComment{Body: body.String()}, nil)
+		}
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", pr.owner, pr.repo, pr.number)
+	return githubRequest(ctx, token, http.MethodPost, path, issueComment{Body: body.String()}, nil)
+}
+
+// tokenLogin returns the login of the user a token authenticates as.
+// Tokens GitHub Actions provides can't read the authenticated user, so
+// comments posted with them are assumed to be authored by
+// github-actions[bot].
+func (d *depInspector) tokenLogin(ctx context.Context, token string) string {
+	var user githubUser
+	if err := githubRequest(ctx, token, http.MethodGet, "/user", nil, &user); err != nil {
+		if d.verbose {
+			log.Printf("error getting user of GITHUB_TOKEN, assuming it's %s: %v", githubActionsLogin, err)
+		}
+		return githubActionsLogin
+	}
+	return user.Login
+}
+
+func listPRComments(ctx context.Context, token string, pr pullRequestRef) ([]issueComment, error) {
+	const perPage = 100
+
+	var comments []issueComment
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments?per_page=%d&page=%d", pr.owner, pr.repo, pr.number, perPage, page)
+		var pageComments []issueComment
+		if err := githubRequest(ctx, token, http.MethodGet, path, nil, &pageComments); err != nil {
+			return nil, fmt.Errorf("listing pull request comments: %w", err)
+		}
+		comments = append(comments, pageComments...)
+		if len(pageComments) < perPage {
+			return comments, nil
+		}
+	}
+}
+
+// githubRequest makes a request to the GitHub REST API, encoding reqBody
+// and decoding the response into respBody if they are not nil.
+func githubRequest(ctx context.Context, token, method, path string, reqBody, respBody any) error {
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	reqURL := strings.TrimSuffix(apiURL, "/") + path
+
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	// public repositories can be read without a token
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, msg)
+	}
+	if respBody != nil {
+		if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/vcs"
 	"github.com/samber/lo"
@@ -32,10 +33,33 @@ var (
 	//go:embed output/*
 	tmplFS          embed.FS
 	supportingTmpls = []string{
+		"output/cap-type-toggle.tmpl",
 		"output/capabilities.tmpl",
+		"output/comments.tmpl",
+		"output/copied-code.tmpl",
+		"output/download-exec.tmpl",
+		"output/env-vars.tmpl",
+		"output/fatal-calls.tmpl",
+		"output/gating.tmpl",
+		"output/go-sum.tmpl",
 		"output/linter-issues.tmpl",
+		"output/low-level.tmpl",
+		"output/metadata.tmpl",
+		"output/nav.tmpl",
+		"output/package-details.tmpl",
+		"output/package-page.tmpl",
+		"output/package-rollup.tmpl",
+		"output/packages.tmpl",
+		"output/panes.tmpl",
+		"output/paths.tmpl",
+		"output/platforms.tmpl",
+		"output/print.tmpl",
+		"output/proxies.tmpl",
+		"output/proxy-diff.tmpl",
 		"output/style.tmpl",
 		"output/totals.tmpl",
+		"output/triage.tmpl",
+		"output/vcs-diff.tmpl",
 	}
 
 	supportedHosts = []string{"github.com", "gitlab.com", "go.googlesource.com", "gittea.dev"}
@@ -49,10 +73,42 @@ type singleDepResult struct {
 
 	Findings findingResult
 	Packages []string
+	Licenses []string
+	Risk     *riskScore
+	// Vulns are the dependency's known vulnerabilities, only set if
+	// -vulns was passed
+	Vulns *vulnFindings
+	// Proxies are where private modules are served from, only set if
+	// -check-proxies was passed
+	Proxies []proxyAvailability
+	// VCSDiff is how the module zip differs from its repository, only
+	// set if -compare-vcs was passed
+	VCSDiff *vcsDiff
+	// ProxyDiff is how sources serve the module version, only set if
+	// -compare-proxies was passed
+	ProxyDiff *proxyDiff
+	// CopiedCode are directories that are copies of other modules,
+	// only set if -find-copies was passed
+	CopiedCode []copiedCode
+	// Source are facts found by parsing the dependency's source code
+	Source *sourceFacts
+	// Violations are the policy rules the findings violated
+	Violations []policyViolation
+	// Reviews are reviews of the version by trusted reviewers
+	Reviews  []verifiedReview
+	Approval *approvalStatus
+	// PackagePanes are the details of every package of the dependency
+	PackagePanes []packagePane
+	// Links are links to related pages if -pages was passed
+	Links    *pageLinks
+	Metadata reportMetadata
 }
 
 type moduleURL struct {
-	modPath     string
+	modPath string
+	// modVersion is the module version, version is the VCS revision
+	// it was made from
+	modVersion  string
 	version     string
 	verIsCommit bool
 	url         *url.URL
@@ -63,16 +119,177 @@ func (m moduleURL) isZero() bool {
 }
 
 type findingResult struct {
+	Dep    string
 	Caps   map[string][]*capability
 	Issues map[string][]*lintIssue
 	Totals findingTotals
 
 	CapMods []string
 	ModURLs map[string]moduleURL
+
+	// PackageLinks is true if package names should link to the panes
+	// of their packages, or their pages if PackagePages is true
+	PackageLinks bool
+	PackagePages bool
+}
+
+// PackageURL returns the URL of the pane or page of pkg, or an empty
+// string if package names shouldn't be linked.
+func (f findingResult) PackageURL(pkg string) string {
+	switch {
+	case !f.PackageLinks:
+		return ""
+	case f.PackagePages:
+		return packagePageLink(pkg)
+	default:
+		return "#pkg-" + pkg
+	}
+}
+
+// pkgCapRollup is the capabilities found in a package and how many
+// call paths reach each of them.
+type pkgCapRollup struct {
+	Package string
+	Caps    []capCount
+}
+
+type capCount struct {
+	Name     string
+	Severity string
+	Count    int
+}
+
+// PackageRollup returns the capabilities found in every package, most
+// severe capabilities first, so reports can show which capabilities
+// packages have before the call paths that reach them.
+func (f findingResult) PackageRollup() []pkgCapRollup {
+	counts := make(map[string]map[string]*capCount)
+	for name, caps := range f.Caps {
+		for _, c := range caps {
+			pkgCounts := counts[c.PackageDir]
+			if pkgCounts == nil {
+				pkgCounts = make(map[string]*capCount)
+				counts[c.PackageDir] = pkgCounts
+			}
+			count := pkgCounts[name]
+			if count == nil {
+				count = &capCount{Name: name, Severity: c.Severity}
+				pkgCounts[name] = count
+			}
+			count.Count++
+		}
+	}
+
+	rollup := make([]pkgCapRollup, 0, len(counts))
+	for pkg, pkgCounts := range counts {
+		r := pkgCapRollup{Package: pkg}
+		for _, count := range pkgCounts {
+			r.Caps = append(r.Caps, *count)
+		}
+		slices.SortFunc(r.Caps, func(a, b capCount) int {
+			if c := compareSeverity(b.Severity, a.Severity); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		rollup = append(rollup, r)
+	}
+	slices.SortFunc(rollup, func(a, b pkgCapRollup) int {
+		return strings.Compare(a.Package, b.Package)
+	})
+
+	return rollup
 }
 
-func (d *depInspector) singleDepHTMLOutput(ctx context.Context, dep, version string, pkgsInspected []string, capResult *capslockResult, issues []*lintIssue) (io.Reader, error) {
-	capMods, modURLs, err := findModuleURLs(capResult.ModuleInfo)
+// packagePane is a section of a report with the details and findings
+// of a single package.
+type packagePane struct {
+	packageInfo
+	SourceURL string
+	Findings  findingResult
+	// Page is the link to the package's page if -pages was passed
+	Page string
+}
+
+// buildPackagePanes groups findings by the package they were found in.
+func (d *depInspector) buildPackagePanes(findings *depFindings, capMods []string, modURLs map[string]moduleURL) []packagePane {
+	dep := findings.Dep
+	panes := make([]packagePane, 0, len(findings.PackageInfo))
+	for _, info := range findings.PackageInfo {
+		var (
+			caps   []*capability
+			issues []*lintIssue
+		)
+		for _, c := range findings.Caps.CapabilityInfo {
+			if c.PackageDir == info.Path {
+				caps = append(caps, c)
+			}
+		}
+		for _, issue := range findings.Issues {
+			if path.Join(dep, path.Dir(issue.Pos.Filename)) == info.Path {
+				issues = append(issues, issue)
+			}
+		}
+
+		pane := packagePane{
+			packageInfo: info,
+			Findings:    prepareFindingResult(dep, caps, issues, capMods, modURLs),
+		}
+		srcURL, err := d.packageSourceURL(dep, info.Path, modURLs[dep])
+		if err != nil {
+			log.Printf("error finding source URL of package %s: %v", info.Path, err)
+		}
+		pane.SourceURL = srcURL
+		panes = append(panes, pane)
+	}
+
+	return panes
+}
+
+// packageSourceURL returns the URL of the directory of a package on
+// its module's hosting provider, or an empty string if the provider is
+// unknown.
+func (d *depInspector) packageSourceURL(dep, pkg string, modURL moduleURL) (string, error) {
+	if modURL.isZero() {
+		return "", nil
+	}
+
+	newURL := *modURL.url
+	strippedPath, err := stripMajorVersionDir(modURL.modPath, modURL.version, newURL.Path, d.modCache)
+	if err != nil {
+		return "", err
+	}
+	newURL.Path = strippedPath
+	pkgDir := strings.TrimPrefix(strings.TrimPrefix(pkg, dep), "/")
+
+	// format the URL according to the hosting provider
+	switch newURL.Host {
+	case "github.com":
+		newURL.Path = path.Join(newURL.Path, "tree", modURL.version, pkgDir)
+	case "gitlab.com":
+		newURL.Path = path.Join(newURL.Path, "-", "tree", modURL.version, pkgDir)
+	case "go.googlesource.com":
+		if modURL.verIsCommit {
+			newURL.Path = path.Join(newURL.Path, "+", "refs", "tags", modURL.version, pkgDir)
+		} else {
+			newURL.Path = path.Join(newURL.Path, "+", modURL.version, pkgDir)
+		}
+	case "gittea.dev":
+		srcType := "tag"
+		if modURL.verIsCommit {
+			srcType = "commit"
+		}
+		newURL.Path = path.Join(newURL.Path, "src", srcType, modURL.version, pkgDir)
+	default:
+		return "", nil
+	}
+
+	return newURL.String(), nil
+}
+
+func (d *depInspector) singleDepHTMLOutput(ctx context.Context, findings *depFindings, extras *reportExtras) (io.Reader, error) {
+	dep := findings.Dep
+	capMods, modURLs, err := d.findModuleURLs(ctx, findings.Caps.ModuleInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -80,26 +297,49 @@ func (d *depInspector) singleDepHTMLOutput(ctx context.Context, dep, version str
 	if err != nil {
 		return nil, err
 	}
-	tmpl, err := d.loadTemplate("output/single-dep.tmpl", dep, capMods, goVer, stdlibURL)
+	tmpl, err := d.loadTemplate("output/single-dep.tmpl", capMods, goVer, stdlibURL)
 	if err != nil {
 		return nil, err
 	}
 
 	res := &singleDepResult{
 		Dep:              dep,
-		VersionStr:       makeVersionStr(dep, version),
+		VersionStr:       makeVersionStr(dep, findings.Version),
 		ModuleRemoteURLs: modURLs,
-		Packages:         pkgsInspected,
-		Findings:         prepareFindingResult(dep, capResult.CapabilityInfo, issues, capMods, modURLs),
+		Packages:         findings.Packages,
+		Licenses:         findings.Licenses,
+		Risk:             findings.Risk,
+		Vulns:            findings.Vulns,
+		Proxies:          findings.Proxies,
+		VCSDiff:          findings.VCSDiff,
+		ProxyDiff:        findings.ProxyDiff,
+		CopiedCode:       findings.CopiedCode,
+		Source:           findings.Source,
+		Violations:       extras.Violations,
+		Reviews:          extras.Reviews,
+		Approval:         extras.Approval,
+		Findings:         prepareFindingResult(dep, findings.Caps.CapabilityInfo, findings.Issues, capMods, modURLs),
+		PackagePanes:     d.buildPackagePanes(findings, capMods, modURLs),
+		Metadata:         findings.Metadata,
+	}
+	res.Findings.PackageLinks = len(res.PackagePanes) != 0
+	if d.pages {
+		res.Findings.PackagePages = true
+		res.Links = d.reportPageLinks(dep)
+		if err := d.writePackagePages(tmpl, findings, res.PackagePanes); err != nil {
+			return nil, err
+		}
 	}
 
-	return executeTemplate(tmpl, res)
+	return d.executeTemplate(tmpl, res)
 }
 
 type compareDepsResult struct {
 	Dep           string
 	OldVersionStr string
 	NewVersionStr string
+	// Overview is a sentence summarizing the most important changes
+	Overview string
 
 	OldFindings  findingResult
 	SameFindings findingResult
@@ -107,14 +347,58 @@ type compareDepsResult struct {
 	Totals       findingTotals
 	OldPackages  []string
 	NewPackages  []string
+	Modules      *moduleChanges
+	GoSum        *goSumChanges
+	Licenses     *licenseChange
+	Ownership    []ownershipChange
+	Proxies      *proxyChanges
+	OldRisk      *riskScore
+	NewRisk      *riskScore
+	NewVulns     *vulnFindings
+	NewVCSDiff   *vcsDiff
+	ProxyDiff    *proxyDiff
+	NewCopies    []copiedCode
+	EnvVars      *envVarChanges
+	Paths        *pathChanges
+	FatalCalls   []fatalCallChange
+	Platforms    *platformSummary
+	LowLevel     *lowLevelChanges
+	Gating       *gatingChanges
+	NetworkExec  *downloadExecChanges
+	Violations   []policyViolation
+	Reviews      []verifiedReview
+	Approval     *approvalStatus
+	// PackagePanes are the details of every package of the new version
+	PackagePanes []packagePane
+	// Links are links to related pages if -pages was passed
+	Links        *pageLinks
+	Contributors *contributorChanges
+	ReleaseNotes []releaseNotes
+	DiffStat     *diffStat
+	Metadata     reportMetadata
+
+	// OnlyChanges is true if findings that are the same between
+	// versions should be omitted
+	OnlyChanges bool
+	// BaselineMetadata describes how the old findings were produced if
+	// they were loaded from a previous run
+	BaselineMetadata *reportMetadata
 }
 
-func (d *depInspector) compareDepsHTMLOutput(ctx context.Context, dep, oldVer, newVer string, results *inspectResults) (io.Reader, error) {
-	oldCapMods, oldModURLs, err := findModuleURLs(results.oldCapMods)
+// RiskDelta returns how much the risk score changed between compared
+// versions.
+func (c *compareDepsResult) RiskDelta() string {
+	return riskDelta(c.OldRisk, c.NewRisk)
+}
+
+func (d *depInspector) compareDepsHTMLOutput(ctx context.Context, oldFindings, newFindings *depFindings, extras *reportExtras) (io.Reader, error) {
+	dep := newFindings.Dep
+	results := compareFindings(oldFindings, newFindings)
+	oldCapMods, oldModURLs, err := d.findModuleURLs(ctx, results.oldCapMods)
 	if err != nil {
 		return nil, err
 	}
-	newCapMods, newModURLs, err := findModuleURLs(results.newCapMods)
+	newCapMods, newModURLs, err := d.findModuleURLs(ctx, results.newCapMods)
 	if err != nil {
 		return nil, err
 	}
@@ -126,27 +410,91 @@ func (d *depInspector) compareDepsHTMLOutput(ctx context.Context, dep, oldVer, n
 	if err != nil {
 		return nil, err
 	}
-	tmpl, err := d.loadTemplate("output/compare-deps.tmpl", dep, capMods, goVer, stdlibURL)
+	tmpl, err := d.loadTemplate("output/compare-deps.tmpl", capMods, goVer, stdlibURL)
 	if err != nil {
 		return nil, err
 	}
 
-	res := &compareDepsResult{
-		Dep:           dep,
-		OldVersionStr: makeVersionStr(dep, oldVer),
-		NewVersionStr: makeVersionStr(dep, newVer),
-		OldFindings:   prepareFindingResult(dep, results.removedCaps, results.fixedIssues, oldCapMods, oldModURLs),
-		SameFindings:  prepareFindingResult(dep, results.sameCaps, results.staleIssues, newCapMods, newModURLs),
-		NewFindings:   prepareFindingResult(dep, results.addedCaps, results.newIssues, newCapMods, newModURLs),
-		NewPackages:   results.newPackages,
-		OldPackages:   results.oldPackages,
+	res := buildCompareDepsResult(oldFindings, newFindings, results)
+	res.OnlyChanges = res.OnlyChanges || d.onlyChanges
+	res.Violations = extras.Violations
+	res.Contributors = extras.Contributors
+	res.DiffStat = extras.DiffStat
+	res.ReleaseNotes = extras.ReleaseNotes
+	res.Reviews = extras.Reviews
+	res.Approval = extras.Approval
+	res.OldFindings = prepareFindingResult(oldFindings.Dep, results.removedCaps, results.fixedIssues, oldCapMods, oldModURLs)
+	res.SameFindings = prepareFindingResult(dep, results.sameCaps, results.staleIssues, newCapMods, newModURLs)
+	res.NewFindings = prepareFindingResult(dep, results.addedCaps, results.newIssues, newCapMods, newModURLs)
+	res.PackagePanes = d.buildPackagePanes(newFindings, newCapMods, newModURLs)
+	if len(res.PackagePanes) != 0 {
+		res.SameFindings.PackageLinks = true
+		res.NewFindings.PackageLinks = true
+	}
+	if d.pages {
+		res.SameFindings.PackagePages = true
+		res.NewFindings.PackagePages = true
+		res.Links = d.reportPageLinks(dep)
+		if err := d.writePackagePages(tmpl, newFindings, res.PackagePanes); err != nil {
+			return nil, err
+		}
 	}
 	buildCombinedTotals(res)
 
-	return executeTemplate(tmpl, res)
+	return d.executeTemplate(tmpl, res)
 }
 
-func (d *depInspector) loadTemplate(tmplPath, dep string, capMods []string, goVer string, stdlibURL *url.URL) (*template.Template, error) {
+// buildCompareDepsResult fills in the parts of a compareDepsResult
+// that are common to every output format.
+func buildCompareDepsResult(oldFindings, newFindings *depFindings, results *inspectResults) *compareDepsResult {
+	dep := newFindings.Dep
+	oldVer, newVer := oldFindings.Version, newFindings.Version
+	res := &compareDepsResult{
+		Dep:         dep,
+		NewPackages: results.newPackages,
+		OldPackages: results.oldPackages,
+		Modules:     compareModules(oldFindings.Modules, newFindings.Modules),
+		GoSum:       compareGoSums(oldFindings.GoSumHashes, newFindings.GoSumHashes),
+		Licenses:    compareLicenses(oldFindings.Licenses, newFindings.Licenses),
+		Ownership:   compareOwnership(oldFindings.Ownership, newFindings.Ownership),
+		Proxies:     compareProxies(oldFindings.Proxies, newFindings.Proxies),
+		OldRisk:     oldFindings.Risk,
+		NewRisk:     newFindings.Risk,
+		NewVulns:    newFindings.Vulns,
+		NewVCSDiff:  newFindings.VCSDiff,
+		ProxyDiff:   newFindings.ProxyDiff,
+		NewCopies:   newFindings.CopiedCode,
+		EnvVars:     compareEnvVars(oldFindings.Source, newFindings.Source),
+		Paths:       comparePaths(oldFindings.Source, newFindings.Source),
+		FatalCalls:  compareFatalCalls(oldFindings.Source, newFindings.Source),
+		Platforms:   newPlatforms(newFindings.Source),
+		LowLevel:    compareLowLevelAccess(oldFindings.Source, newFindings.Source),
+		Gating:      compareGating(oldFindings.Source, newFindings.Source),
+		NetworkExec: compareDownloadExec(oldFindings.Source, newFindings.Source),
+		Metadata:    newFindings.Metadata,
+	}
+	// when comparing a version against a previous inspection of the
+	// same version, only changes are interesting
+	if oldVer == newVer {
+		oldVer = fmt.Sprintf("%s (inspected %s)", oldVer, oldFindings.Metadata.Time.Local().Format(time.DateTime))
+		newVer = fmt.Sprintf("%s (inspected %s)", newVer, newFindings.Metadata.Time.Local().Format(time.DateTime))
+		res.OnlyChanges = true
+		res.BaselineMetadata = &oldFindings.Metadata
+	}
+	// the old findings may be of a different module when comparing
+	// against a fork
+	res.OldVersionStr = makeVersionStr(oldFindings.Dep, oldVer)
+	res.NewVersionStr = makeVersionStr(dep, newVer)
+	if oldFindings.Dep == dep {
+		res.Overview = changeOverview(oldFindings, newFindings, results, oldVer, newVer)
+	} else {
+		res.Overview = changeOverview(oldFindings, newFindings, results, res.OldVersionStr, res.NewVersionStr)
+	}
+
+	return res
+}
+
+func (d *depInspector) loadTemplate(tmplPath string, capMods []string, goVer string, stdlibURL *url.URL) (*template.Template, error) {
 	funcMap := map[string]any{
 		"getCapsByPkg": func(caps []*capability) map[string][]*capability {
 			return lo.GroupBy(caps, func(c *capability) string {
@@ -155,20 +503,24 @@ func (d *depInspector) loadTemplate(tmplPath, dep string, capMods []string, goVe
 		},
 		"getCapsByFinalCall": func(caps []*capability) map[string][]*capability {
 			return lo.GroupBy(caps, func(c *capability) string {
+				if c.Generated && d.generatedCode == generatedGroup {
+					return c.Path[len(c.Path)-1].Name + " (generated code)"
+				}
 				return c.Path[len(c.Path)-1].Name
 			})
 		},
-		"capType": func(capType string) string {
-			if capType == "CAPABILITY_TYPE_DIRECT" {
-				return "Direct"
-			}
-			return "Transitive"
-		},
+		"capType": capTypeName,
 		"getIssuesByLinter": func(issues []*lintIssue) map[string][]*lintIssue {
 			return lo.GroupBy(issues, func(i *lintIssue) string {
+				if i.Generated && d.generatedCode == generatedGroup {
+					return i.FromLinter + " (generated code)"
+				}
 				return i.FromLinter
 			})
 		},
+		"collapsedFrames": func(calls []functionCall, i int) int {
+			return collapsedFrames(calls, i, d.stdlibFrames)
+		},
 		"getPrevCallName": func(calls []functionCall, idx int) string {
 			return calls[idx-1].Name
 		},
@@ -219,7 +571,7 @@ func (d *depInspector) loadTemplate(tmplPath, dep string, capMods []string, goVe
 
 			return callSiteToURL(call.Site, modURL, pkg, d.modCache)
 		},
-		"issuePosToURL": func(pos token.Position, modURLs map[string]moduleURL) (string, error) {
+		"issuePosToURL": func(dep string, pos token.Position, modURLs map[string]moduleURL) (string, error) {
 			modURL, ok := modURLs[dep]
 			if !ok {
 				return "", fmt.Errorf("module URL for dep %s not found", dep)
@@ -239,13 +591,35 @@ func (d *depInspector) loadTemplate(tmplPath, dep string, capMods []string, goVe
 			// have the package prefixed
 			return callSiteToURL(site, modURLs[dep], "", d.modCache)
 		},
-		"formatDelta": func(delta int) string {
-			deltaStr := strconv.Itoa(delta)
-			if delta >= 0 {
-				deltaStr = "+" + deltaStr
+		"funcDocURL": func(name string, modURLs map[string]moduleURL) string {
+			pkg := funcPackage(strings.NewReplacer("*", "", "(", "", ")", "").Replace(name))
+			// don't leak the names of private packages
+			if module.MatchPrefixPatterns(d.goEnv["GOPRIVATE"], pkg) {
+				return ""
+			}
+
+			var version string
+			if i := slices.IndexFunc(capMods, func(mod string) bool {
+				return pkg == mod || strings.HasPrefix(pkg, mod+"/")
+			}); i != -1 {
+				version = modURLs[capMods[i]].modVersion
+			} else if isStdlibPackage(pkg) && strings.HasPrefix(goVer, "go") {
+				version = goVer
+			}
+			return funcDocURL(name, version)
+		},
+		"formatDelta": formatDelta,
+		"triageForm": func(dep, fingerprint string, decision *triageDecision) triageForm {
+			return triageForm{
+				URL:         d.triageURL,
+				Module:      dep,
+				Fingerprint: fingerprint,
+				Statuses:    triageStatuses,
+				Decision:    decision,
 			}
-			return deltaStr
 		},
+		"capFingerprint":   capFindingFingerprint,
+		"issueFingerprint": issueFingerprint,
 	}
 
 	tmpl, err := template.ParseFS(tmplFS, tmplPath)
@@ -261,7 +635,68 @@ func (d *depInspector) loadTemplate(tmplPath, dep string, capMods []string, goVe
 	return tmpl, nil
 }
 
-func findModuleURLs(capMods []capModule) ([]string, map[string]moduleURL, error) {
+// funcDocURL returns the URL of the pkg.go.dev documentation of a
+// function such as 'example.com/pkg.Func' or
+// '(*example.com/pkg.Type).Method'. If version is empty the latest
+// documentation is linked.
+func funcDocURL(name, version string) string {
+	name = strings.NewReplacer("*", "", "(", "", ")", "").Replace(name)
+	pkg := funcPackage(name)
+	symbol := strings.TrimPrefix(strings.TrimPrefix(name, pkg), ".")
+	// closures and instantiations of generic functions are documented
+	// by the function they are a part of
+	if i := strings.IndexAny(symbol, "$["); i != -1 {
+		symbol = symbol[:i]
+	}
+
+	docURL := &url.URL{
+		Scheme: "https",
+		Host:   "pkg.go.dev",
+		Path:   "/" + pkg,
+	}
+	if version != "" {
+		docURL.Path += "@" + version
+	}
+	// only exported identifiers are documented, link to the exported
+	// type of unexported methods
+	var anchor []string
+	for _, ident := range strings.Split(symbol, ".") {
+		if !token.IsExported(ident) {
+			break
+		}
+		anchor = append(anchor, ident)
+	}
+	docURL.Fragment = strings.Join(anchor, ".")
+
+	return docURL.String()
+}
+
+func capTypeName(capType string) string {
+	if capType == capTypeDirect {
+		return "Direct"
+	}
+	return "Transitive"
+}
+
+// capDisplayName returns the name of a capability as it is shown in
+// reports, such as 'System Calls' for 'CAPABILITY_SYSTEM_CALLS'.
+func capDisplayName(capability string) string {
+	capName := strings.ReplaceAll(strings.TrimPrefix(capability, "CAPABILITY_"), "_", " ")
+	//lint:ignore SA1019 the capability name will not have Unicode
+	// punctuation that causes issues for strings.ToLower so using
+	// it is fine
+	return strings.Title(strings.ToLower(capName))
+}
+
+func formatDelta(delta int) string {
+	deltaStr := strconv.Itoa(delta)
+	if delta >= 0 {
+		deltaStr = "+" + deltaStr
+	}
+	return deltaStr
+}
+
+func (d *depInspector) findModuleURLs(ctx context.Context, capMods []capModule) ([]string, map[string]moduleURL, error) {
 	local, err := os.MkdirTemp("", tempPrefix)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating temporary directory: %w", err)
@@ -274,8 +709,8 @@ func findModuleURLs(capMods []capModule) ([]string, map[string]moduleURL, error)
 		if err := os.Mkdir(localPath, 0o755); err != nil {
 			return nil, nil, fmt.Errorf("creating directory: %w", err)
 		}
-		modURL, err := findModuleURL(modInfo.Path, modInfo.Version, localPath)
-		if err != nil {
+		modURL, err := d.findModuleURL(ctx, modInfo.Path, modInfo.Version, localPath)
+		if err != nil && !d.cacheOnly {
 			log.Printf("error finding module URL: %v", err)
 			modURLs[modInfo.Path] = moduleURL{}
 		}
@@ -285,19 +720,31 @@ func findModuleURLs(capMods []capModule) ([]string, map[string]moduleURL, error)
 	return maps.Keys(modURLs), modURLs, nil
 }
 
-func findModuleURL(modPath, version, localPath string) (moduleURL, error) {
+func (d *depInspector) findModuleURL(ctx context.Context, modPath, version, localPath string) (moduleURL, error) {
 	remote := "https://" + modPath
 	if strings.HasPrefix(modPath, "golang.org/x/") {
 		remote = "https://github.com/golang/" + strings.TrimPrefix(modPath, "golang.org/x/")
-	}
-	if !strings.HasPrefix(modPath, "github.com/") && !strings.HasPrefix(modPath, "gitlab.com/") {
-		repo, err := vcs.NewRepo(remote, localPath)
+	} else if !strings.HasPrefix(modPath, "github.com/") && !strings.HasPrefix(modPath, "gitlab.com/") {
+		// discovering where modules are hosted needs network access
+		if d.cacheOnly {
+			return moduleURL{}, fmt.Errorf("finding the repository of %s is skipped with -cache-only", modPath)
+		}
+		// private modules may require authentication to discover
+		// where they are hosted, so try that first
+		repoURL, err := d.discoverRepoURL(ctx, modPath)
 		if err != nil {
-			return moduleURL{}, fmt.Errorf("error finding remote repository for dependency: %w", err)
+			if d.verbose {
+				log.Printf("error discovering repository of %s, falling back to VCS detection: %v", modPath, err)
+			}
+			repo, err := vcs.NewRepo(remote, localPath)
+			if err != nil {
+				return moduleURL{}, fmt.Errorf("error finding remote repository for dependency: %w", err)
+			}
+			repoURL = repo.Remote()
 		}
-		remote = repo.Remote()
+		remote = repoURL
 	}
-	remoteURL, err := url.Parse(remote)
+	remoteURL, err := url.Parse(normalizeRemoteURL(remote))
 	if err != nil {
 		return moduleURL{}, fmt.Errorf("parsing remote URL: %w", err)
 	}
@@ -306,6 +753,7 @@ func findModuleURL(modPath, version, localPath string) (moduleURL, error) {
 	}
 
 	// make the version not Go specific
+	modVersion := version
 	var verIsCommit bool
 	if module.IsPseudoVersion(version) {
 		version, err = module.PseudoVersionRev(version)
@@ -319,6 +767,7 @@ func findModuleURL(modPath, version, localPath string) (moduleURL, error) {
 
 	return moduleURL{
 		modPath:     modPath,
+		modVersion:  modVersion,
 		version:     version,
 		verIsCommit: verIsCommit,
 		url:         remoteURL,
@@ -346,16 +795,13 @@ func (d *depInspector) findStdlibURL(ctx context.Context) (string, *url.URL, err
 }
 
 func prepareFindingResult(dep string, caps []*capability, issues []*lintIssue, capMods []string, modURLs map[string]moduleURL) (f findingResult) {
-	f.Caps = lo.GroupBy(caps, func(c *capability) string {
-		capName := strings.ReplaceAll(strings.TrimPrefix(c.Capability, "CAPABILITY_"), "_", " ")
-		//lint:ignore SA1019 the capability name will not have Unicode
-		// punctuation that causes issues for strings.ToLower so using
-		// it is fine
-		return strings.Title(strings.ToLower(capName))
+	f.Caps = lo.GroupBy(collapseSharedCaps(dep, caps), func(c *capability) string {
+		return capDisplayName(c.Capability)
 	})
 	f.Issues = lo.GroupBy(issues, func(i *lintIssue) string {
 		return path.Join(dep, path.Dir(i.Pos.Filename))
 	})
+	f.Dep = dep
 	f.Totals = calculateTotals(caps, issues)
 
 	f.CapMods = capMods
@@ -364,8 +810,25 @@ func prepareFindingResult(dep string, caps []*capability, issues []*lintIssue, c
 	return f
 }
 
-func executeTemplate(tmpl *template.Template, data any) (io.Reader, error) {
+// executeTemplate renders an HTML report. The output is minified
+// unless -no-minify was passed.
+func (d *depInspector) executeTemplate(tmpl *template.Template, data any) (io.Reader, error) {
+	if d.htmlAssets {
+		var err error
+		tmpl, err = linkAssets(tmpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var buf bytes.Buffer
+	if d.noMinify {
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("error executing output template: %w", err)
+		}
+		return &buf, nil
+	}
+
 	min := minify.New()
 	min.AddFunc("text/html", html.Minify)
 	w := min.Writer("text/html", &buf)
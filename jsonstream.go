@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// decodeObject decodes a JSON object one field at a time. decodeField
+// is called with the name of every field, and must decode its value
+// from dec.
+func decodeObject(dec *json.Decoder, decodeField func(name string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected field name, got %v", tok)
+		}
+		if err := decodeField(name); err != nil {
+			return fmt.Errorf("decoding %s: %w", name, err)
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeArray decodes a JSON array one element at a time, so only the
+// elements that are kept are held in memory. decodeElem must decode an
+// element from dec. A null array is treated as empty.
+func decodeArray(dec *json.Decoder, decodeElem func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+	for dec.More() {
+		if err := decodeElem(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// skipValue discards the next JSON value.
+func skipValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
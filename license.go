@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/licensecheck"
+	"golang.org/x/mod/module"
+)
+
+const (
+	// licenseNone means a module has no license files
+	licenseNone = "NONE"
+	// licenseUnknown means a module has license files, but none of
+	// them could be identified
+	licenseUnknown = "NOASSERTION"
+
+	// minLicenseCoverage is the percentage of a license file that must
+	// match known licenses for them to be reported
+	minLicenseCoverage = 75
+)
+
+// licenseFileRe matches the names of files that contain licenses.
+var licenseFileRe = regexp.MustCompile(`(?i)^(licen[cs]e|copying|unlicense)([.-].*)?$`)
+
+// licenseChange is how the licenses of a dependency changed between
+// compared versions.
+type licenseChange struct {
+	Old []string
+	New []string
+}
+
+// moduleZipPath returns the path of the zip of a module version in the
+// module cache.
+func moduleZipPath(modCache, dep, version string) (string, error) {
+	escPath, err := module.EscapePath(dep)
+	if err != nil {
+		return "", err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCache, "cache", "download", escPath, "@v", escVer+".zip"), nil
+}
+
+// moduleLicenses detects the licenses of a module version from the
+// license files at the root of its zip in the module cache.
+func (d *depInspector) moduleLicenses(dep, version string) ([]string, error) {
+	zipPath, err := moduleZipPath(d.modCache, dep, version)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening module zip: %w", err)
+	}
+	defer zr.Close()
+
+	// files in module zips are prefixed with the module version
+	prefix := makeVersionStr(dep, version) + "/"
+	var files [][]byte
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || strings.Contains(name, "/") || !licenseFileRe.MatchString(name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		files = append(files, contents)
+	}
+
+	return detectLicenses(files), nil
+}
+
+// dirLicenses detects the licenses of a module from the license files
+// in its directory.
+func dirLicenses(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading module directory: %w", err)
+	}
+
+	var files [][]byte
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !licenseFileRe.MatchString(entry.Name()) {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name(), err)
+		}
+		files = append(files, contents)
+	}
+
+	return detectLicenses(files), nil
+}
+
+// detectLicenses returns the sorted SPDX identifiers of the licenses in
+// license files, licenseNone if there are no license files or
+// licenseUnknown if no licenses could be identified.
+func detectLicenses(files [][]byte) []string {
+	if len(files) == 0 {
+		return []string{licenseNone}
+	}
+
+	var ids []string
+	for _, contents := range files {
+		cov := licensecheck.Scan(contents)
+		if cov.Percent < minLicenseCoverage {
+			continue
+		}
+		for _, match := range cov.Match {
+			ids = append(ids, match.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return []string{licenseUnknown}
+	}
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
+
+// compareLicenses returns how licenses changed, or nil if they didn't
+// or the licenses of either version weren't detected.
+func compareLicenses(oldLicenses, newLicenses []string) *licenseChange {
+	if oldLicenses == nil || newLicenses == nil || slices.Equal(oldLicenses, newLicenses) {
+		return nil
+	}
+	return &licenseChange{
+		Old: oldLicenses,
+		New: newLicenses,
+	}
+}
+
+// licensePolicy restricts which licenses dependencies may have.
+type licensePolicy struct {
+	// Allowed are the SPDX identifiers of allowed licenses, any license
+	// is allowed if empty
+	Allowed []string `yaml:"allowed"`
+	// Forbidden are the SPDX identifiers of forbidden licenses
+	Forbidden []string `yaml:"forbidden"`
+}
+
+func (p licensePolicy) empty() bool {
+	return len(p.Allowed) == 0 && len(p.Forbidden) == 0
+}
+
+// violations returns the licenses of a module version that the policy
+// disallows. Licenses that weren't detected are disallowed if an allow
+// list is set, they can't be shown to be allowed.
+func (p licensePolicy) violations(mod, version string, licenses []string) []policyViolation {
+	if licenses == nil {
+		if len(p.Allowed) == 0 {
+			log.Printf("WARNING: licenses of %s could not be determined, they aren't checked against forbidden licenses", makeVersionStr(mod, version))
+			return nil
+		}
+		return []policyViolation{{
+			Dep:  makeVersionStr(mod, version),
+			Rule: "license could not be determined",
+		}}
+	}
+
+	containsLicense := func(ids []string, license string) bool {
+		return slices.ContainsFunc(ids, func(id string) bool {
+			// SPDX identifiers are case insensitive
+			return strings.EqualFold(id, license)
+		})
+	}
+
+	var violations []policyViolation
+	for _, license := range licenses {
+		var rule string
+		switch {
+		case containsLicense(p.Forbidden, license):
+			rule = fmt.Sprintf("license %s is forbidden", license)
+		case len(p.Allowed) != 0 && !containsLicense(p.Allowed, license):
+			rule = fmt.Sprintf("license %s is not allowed", license)
+		default:
+			continue
+		}
+		violations = append(violations, policyViolation{
+			Dep:     makeVersionStr(mod, version),
+			Rule:    rule,
+			License: license,
+		})
+	}
+
+	return violations
+}
+
+// checkLicenses evaluates the license policy against the licenses of
+// the dependency and, when comparing, modules that are newly required.
+func (d *depInspector) checkLicenses(res *savedResults) []policyViolation {
+	if d.licensePolicy.empty() {
+		return nil
+	}
+
+	violations := d.licensePolicy.violations(res.New.Dep, res.New.Version, res.New.Licenses)
+	if res.Old == nil {
+		return violations
+	}
+	changes := compareModules(res.Old.Modules, res.New.Modules)
+	if changes == nil {
+		return violations
+	}
+	for _, mod := range changes.Added {
+		path, version, _ := strings.Cut(mod, "@")
+		if path == res.New.Dep {
+			continue
+		}
+		licenses, err := d.moduleLicenses(path, version)
+		if err != nil {
+			// modules that only go.mod files were needed of are not
+			// downloaded, their licenses are treated as undetected
+			log.Printf("error detecting licenses of %s: %v", mod, err)
+		}
+		violations = append(violations, d.licensePolicy.violations(path, version, licenses)...)
+	}
+
+	return violations
+}
@@ -10,73 +10,254 @@ import (
 	"go/token"
 	"log"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/mod/module"
 )
 
-const golangciCfgName = ".golangci.yml"
+const (
+	golangciCfgName = ".golangci.yml"
+	// golangciCfgEmbedPath is the path of the embedded golangci-lint
+	// config
+	golangciCfgEmbedPath = "configs/golangci-lint/golangci.yml"
+)
 
 //go:embed configs/golangci-lint/golangci.yml
 var golangciCfgContents []byte
 
-type golangciResult struct {
-	Issues []*lintIssue
-}
-
 type lintIssue struct {
 	FromLinter  string
 	Text        string
 	SourceLines []string
 	Pos         token.Position
+	// Severity is set from the configured severity model when
+	// rendering reports
+	Severity string `json:",omitempty"`
+	// Triage is the decision reviewers made about the issue, set from
+	// triage decisions when rendering reports
+	Triage *triageDecision `json:",omitempty"`
+	// Comments are reviewers' comments about the issue, set from
+	// comments when rendering reports
+	Comments []findingComment `json:",omitempty"`
+	// Classification is the weaknesses the issue is related to, set
+	// when rendering reports if -classify is passed
+	Classification *classification `json:",omitempty"`
+	// Generated is true if the issue is in a generated file, set when
+	// rendering reports
+	Generated bool `json:",omitempty"`
 }
 
-func (d *depInspector) lintDepVersion(ctx context.Context, dep, version string, pkgs loadedPackages) ([]*lintIssue, error) {
-	var golangciLintDirs []string
-	var staticcheckDirs []string
+// lintDepVersion lints the packages of a dependency version. If prev
+// are findings of another version of the dependency, issues of
+// packages whose files didn't change since are reused instead of
+// linting the packages again.
+func (d *depInspector) lintDepVersion(ctx context.Context, dep, version string, pkgs loadedPackages, prev *depFindings) ([]*lintIssue, error) {
+	targets, err := d.findLintTargets(dep, version, pkgs, prev)
+	if err != nil {
+		return nil, err
+	}
 	versionStr := makeVersionStr(dep, version)
+	if targets.reusedPkgs != 0 {
+		log.Printf("reusing linter issues of %d packages of %s unchanged since %s", targets.reusedPkgs, versionStr, prev.Version)
+	}
+	reusedIssues := targets.reusedIssues
+	if len(targets.golangciLintDirs) == 0 {
+		slices.SortFunc(reusedIssues, compareIssues)
+		return reusedIssues, nil
+	}
+
+	issues, err := d.runLinters(ctx, dep, versionStr, targets.golangciLintDirs, targets.staticcheckPkgs, func(filename string) (string, error) {
+		return trimFilename(filename, d.modCache)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reusedIssues) == 0 {
+		return issues, nil
+	}
+	issues = append(issues, reusedIssues...)
+	slices.SortFunc(issues, compareIssues)
+
+	return issues, nil
+}
+
+// lintTargets are what the linters are run on when linting a
+// dependency version.
+type lintTargets struct {
+	golangciLintDirs []string
+	staticcheckPkgs  []string
+	// reusedIssues are the issues of packages that didn't change since
+	// a previous version, they aren't linted again
+	reusedIssues []*lintIssue
+	reusedPkgs   int
+}
 
+// findLintTargets returns the directories golangci-lint and the
+// packages staticcheck have to lint of a dependency version. Packages
+// whose issues can be reused from prev are left out.
+func (d *depInspector) findLintTargets(dep, version string, pkgs loadedPackages, prev *depFindings) (*lintTargets, error) {
+	var targets lintTargets
 	if d.inspectAllPkgs || d.unusedDep {
 		escPath, err := module.EscapePath(dep)
 		if err != nil {
 			return nil, err
 		}
 		path := filepath.Join(d.modCache, escPath)
-		golangciLintDirs = []string{fmt.Sprintf("%s@%s%c...", path, version, filepath.Separator)}
-		staticcheckDirs = []string{dep + "/..."}
-	} else {
-		escDep, err := module.EscapePath(dep)
+		targets.golangciLintDirs = []string{fmt.Sprintf("%s@%s%c...", path, version, filepath.Separator)}
+		targets.staticcheckPkgs = []string{dep + "/..."}
+		return &targets, nil
+	}
+
+	escDep, err := module.EscapePath(dep)
+	if err != nil {
+		return nil, err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	escVerStr := makeVersionStr(escDep, escVer)
+	prevDir := d.prevLintDir(dep, version, prev)
+
+	for _, pkg := range pkgs {
+		if !strings.HasPrefix(pkg.PkgPath, dep) {
+			continue
+		}
+
+		pkgPath := strings.TrimPrefix(pkg.PkgPath, dep)
+		dir := filepath.Join(d.modCache, escVerStr, pkgPath)
+		if prevDir != "" && !slices.Contains(targets.golangciLintDirs, dir) && sameDirFiles(dir, filepath.Join(prevDir, pkgPath)) {
+			targets.reusedIssues = append(targets.reusedIssues, pkgIssues(prev.Issues, pkgPath)...)
+			targets.reusedPkgs++
+			continue
+		}
+
+		if !slices.Contains(targets.golangciLintDirs, dir) {
+			targets.golangciLintDirs = append(targets.golangciLintDirs, dir)
+		}
+		if !slices.Contains(targets.staticcheckPkgs, pkg.PkgPath) {
+			targets.staticcheckPkgs = append(targets.staticcheckPkgs, pkg.PkgPath)
+		}
+	}
+
+	return &targets, nil
+}
+
+// prevLintDir returns the module cache directory of the version of
+// prev if its linter issues can be reused when linting version of dep,
+// or an empty string if not. Issues are only reused if they were found
+// by the same linters with the same config.
+func (d *depInspector) prevLintDir(dep, version string, prev *depFindings) string {
+	// the module cache only has the current content of a version
+	if prev == nil || prev.Dep != dep || prev.Version == version {
+		return ""
+	}
+	// issues that weren't found can't be reused
+	if prev.Metadata.Incomplete != "" {
+		return ""
+	}
+	for _, tool := range []string{"golangci-lint", "staticcheck"} {
+		if prev.Metadata.ToolVersions[tool] != d.toolVersions[tool] {
+			return ""
+		}
+	}
+	if prev.Metadata.ConfigHashes[golangciCfgEmbedPath] != configHashes()[golangciCfgEmbedPath] {
+		return ""
+	}
+
+	escDep, err := module.EscapePath(dep)
+	if err != nil {
+		return ""
+	}
+	escVer, err := module.EscapeVersion(prev.Version)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(d.modCache, makeVersionStr(escDep, escVer))
+	if _, err := os.Stat(dir); err != nil {
+		return ""
+	}
+	return dir
+}
+
+// sameDirFiles returns true if two directories contain files with the
+// same names and contents. Subdirectories are not compared, as they
+// are other packages.
+func sameDirFiles(dirA, dirB string) bool {
+	filesA, err := dirFiles(dirA)
+	if err != nil {
+		return false
+	}
+	filesB, err := dirFiles(dirB)
+	if err != nil {
+		return false
+	}
+	if !slices.Equal(filesA, filesB) {
+		return false
+	}
+
+	for _, name := range filesA {
+		contentsA, err := os.ReadFile(filepath.Join(dirA, name))
 		if err != nil {
-			return nil, err
+			return false
 		}
-		escVer, err := module.EscapeVersion(version)
+		contentsB, err := os.ReadFile(filepath.Join(dirB, name))
 		if err != nil {
-			return nil, err
+			return false
+		}
+		if !bytes.Equal(contentsA, contentsB) {
+			return false
 		}
-		escVerStr := makeVersionStr(escDep, escVer)
+	}
 
-		for _, pkg := range pkgs {
-			if !strings.HasPrefix(pkg.PkgPath, dep) {
-				continue
-			}
+	return true
+}
 
-			pkgPath := strings.TrimPrefix(pkg.PkgPath, dep)
-			dir := filepath.Join(d.modCache, escVerStr, pkgPath)
+// dirFiles returns the sorted names of the files in a directory.
+func dirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
 
-			if !slices.Contains(golangciLintDirs, dir) {
-				golangciLintDirs = append(golangciLintDirs, dir)
-			}
-			if !slices.Contains(staticcheckDirs, pkg.PkgPath) {
-				staticcheckDirs = append(staticcheckDirs, pkg.PkgPath)
-			}
+// pkgIssues returns copies of the issues in the files of a package.
+// pkgPath is the path of the package relative to its module.
+func pkgIssues(issues []*lintIssue, pkgPath string) []*lintIssue {
+	pkgDir := strings.TrimPrefix(pkgPath, "/")
+	if pkgDir == "" {
+		pkgDir = "."
+	}
+
+	var found []*lintIssue
+	for _, issue := range issues {
+		if path.Dir(issue.Pos.Filename) != pkgDir {
+			continue
 		}
+		reused := *issue
+		reused.SourceLines = slices.Clone(issue.SourceLines)
+		found = append(found, &reused)
 	}
+	return found
+}
 
+// runLinters lints directories with golangci-lint and packages with
+// staticcheck. trimFilename makes the absolute filenames of issues
+// relative to the root of module. Issues in ignored packages and files
+// are dropped.
+func (d *depInspector) runLinters(ctx context.Context, module, versionStr string, golangciLintDirs, staticcheckDirs []string, trimFilename func(string) (string, error)) ([]*lintIssue, error) {
 	var (
 		issuesCh = make(chan []*lintIssue, 2)
 		errCh    = make(chan error, 2)
@@ -88,22 +269,26 @@ func (d *depInspector) lintDepVersion(ctx context.Context, dep, version string,
 		defer wg.Done()
 
 		log.Printf("linting %s with golangci-lint", versionStr)
+		start := time.Now()
 		issues, err := d.golangciLint(ctx, golangciLintDirs)
 		if err != nil {
 			errCh <- fmt.Errorf("linting with golangci-lint: %w", err)
 			return
 		}
+		d.metrics.observeAnalyzer("golangci-lint", start)
 		issuesCh <- issues
 	}()
 	go func() {
 		defer wg.Done()
 
 		log.Printf("linting %s with staticcheck", versionStr)
+		start := time.Now()
 		issues, err := d.staticcheckLint(ctx, staticcheckDirs)
 		if err != nil {
 			errCh <- fmt.Errorf("linting with staticcheck: %w", err)
 			return
 		}
+		d.metrics.observeAnalyzer("staticcheck", start)
 		issuesCh <- issues
 	}()
 
@@ -118,21 +303,26 @@ func (d *depInspector) lintDepVersion(ctx context.Context, dep, version string,
 		return nil, errors.Join(linterErrs...)
 	}
 
-	// sort issues by linter and file
 	issues := append(<-issuesCh, <-issuesCh...)
-	issues = slices.Clip(issues)
-	slices.SortFunc(issues, compareIssues)
-
 	for i := range issues {
 		filename := issues[i].Pos.Filename
 		filename, err := filepath.Abs(filename)
 		if err != nil {
 			return nil, fmt.Errorf("making path absolute: %w", err)
 		}
-		issues[i].Pos.Filename, err = trimFilename(filename, d.modCache)
+		issues[i].Pos.Filename, err = trimFilename(filename)
 		if err != nil {
 			return nil, err
 		}
+	}
+	issues = slices.DeleteFunc(issues, func(issue *lintIssue) bool {
+		return d.filter.ignoredIssue(module, issue)
+	})
+	// sort issues by linter and file
+	issues = slices.Clip(issues)
+	slices.SortFunc(issues, compareIssues)
+
+	for i := range issues {
 
 		// make leading whitespace of source code lines uniform
 		for j := range issues[i].SourceLines {
@@ -158,25 +348,35 @@ func (d *depInspector) golangciLint(ctx context.Context, dirs []string) ([]*lint
 		return nil, fmt.Errorf("writing golangci-lint config file: %w", err)
 	}
 
-	var output bytes.Buffer
-	cmd := []string{"golangci-lint", "run", "-c", golangciCfgPath, "--out-format=json"}
-	cmd = append(cmd, dirs...)
-	err = d.runCommand(ctx, &output, cmd...)
-	if err != nil {
-		// golangci-lint will exit with 1 if any linters returned issues,
-		// but that doesn't mean it itself failed
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() != 1 {
-			return nil, err
+	var issues []*lintIssue
+	decode := func(dec *json.Decoder) error {
+		err := decodeObject(dec, func(name string) error {
+			if name != "Issues" {
+				return skipValue(dec)
+			}
+			return decodeArray(dec, func() error {
+				var issue lintIssue
+				if err := dec.Decode(&issue); err != nil {
+					return err
+				}
+				issues = append(issues, &issue)
+				return nil
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("decoding golangci-lint results: %w", err)
 		}
+		return nil
 	}
-
-	var results golangciResult
-	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
-		return nil, fmt.Errorf("decoding golangci-lint results: %w", err)
+	cmd := []string{"golangci-lint", "run", "-c", golangciCfgPath, "--out-format=json"}
+	cmd = append(cmd, dirs...)
+	// golangci-lint will exit with 1 if any linters returned issues,
+	// but that doesn't mean it itself failed
+	if err := d.runAnalyzer(ctx, decode, []int{1}, cmd...); err != nil {
+		return nil, err
 	}
 
-	return results.Issues, nil
+	return issues, nil
 }
 
 type staticcheckIssue struct {
@@ -193,50 +393,42 @@ type staticcheckPosition struct {
 }
 
 func (d *depInspector) staticcheckLint(ctx context.Context, dirs []string) ([]*lintIssue, error) {
-	var lintBuf bytes.Buffer
-	cmd := []string{"staticcheck", "-checks=SA1*,SA2*,SA4*,SA5*,SA9*", "-f=json", "-tests=false"}
-	cmd = append(cmd, dirs...)
-	err := d.runCommand(ctx, &lintBuf, cmd...)
-	if err != nil {
-		// staticcheck will exit with 1 if any issues are found, but
-		// that doesn't mean it itself failed
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() != 1 {
-			return nil, err
+	var issues []*lintIssue
+	decode := func(dec *json.Decoder) error {
+		for dec.More() {
+			var sIssue staticcheckIssue
+			if err := dec.Decode(&sIssue); err != nil {
+				return fmt.Errorf("decoding staticcheck results: %w", err)
+			}
+			issue := &lintIssue{
+				FromLinter: "staticcheck " + sIssue.Code,
+				Text:       trimLinterMsg(sIssue.Message),
+				Pos: token.Position{
+					Filename: sIssue.Location.File,
+					Offset:   sIssue.End.Column, // ?
+					Line:     sIssue.Location.Line,
+					Column:   sIssue.Location.Column,
+				},
+			}
+			var err error
+			issue.SourceLines, err = getSrcLinesFromFile(
+				sIssue.Location.File,
+				sIssue.Location.Line,
+				sIssue.End.Line,
+			)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, issue)
 		}
+		return nil
 	}
-
-	var sIssues []staticcheckIssue
-	dec := json.NewDecoder(&lintBuf)
-	for dec.More() {
-		var issue staticcheckIssue
-		if err := dec.Decode(&issue); err != nil {
-			return nil, fmt.Errorf("decoding staticcheck results: %w", err)
-		}
-		sIssues = append(sIssues, issue)
-	}
-
-	issues := make([]*lintIssue, len(sIssues))
-	for i, sIssue := range sIssues {
-		issue := &lintIssue{
-			FromLinter: "staticcheck " + sIssue.Code,
-			Text:       trimLinterMsg(sIssue.Message),
-			Pos: token.Position{
-				Filename: sIssue.Location.File,
-				Offset:   sIssue.End.Column, // ?
-				Line:     sIssue.Location.Line,
-				Column:   sIssue.Location.Column,
-			},
-		}
-		issue.SourceLines, err = getSrcLinesFromFile(
-			sIssue.Location.File,
-			sIssue.Location.Line,
-			sIssue.End.Line,
-		)
-		if err != nil {
-			return nil, err
-		}
-		issues[i] = issue
+	cmd := []string{"staticcheck", "-checks=SA1*,SA2*,SA4*,SA5*,SA9*", "-f=json", "-tests=false"}
+	cmd = append(cmd, dirs...)
+	// staticcheck will exit with 1 if any issues are found, but that
+	// doesn't mean it itself failed
+	if err := d.runAnalyzer(ctx, decode, []int{1}, cmd...); err != nil {
+		return nil, err
 	}
 
 	return issues, nil
@@ -289,39 +481,11 @@ func compareIssues(a, b *lintIssue) int {
 	return 0
 }
 
-func issuesEqual(dep string, a, b *lintIssue) bool {
-	if a.FromLinter != b.FromLinter || a.Text != b.Text {
-		return false
-	}
-	if a.Pos.Line != b.Pos.Line {
-		return false
-	}
-	if a.Pos.Column != b.Pos.Column {
-		return false
-	}
-	if len(a.SourceLines) != len(b.SourceLines) {
-		return false
-	}
-
-	// compare paths after the module version
-	filenameA := getDepRelPath(dep, a.Pos.Filename)
-	filenameB := getDepRelPath(dep, b.Pos.Filename)
-	if filenameA != filenameB {
-		return false
-	}
-
-	// compare source code lines with leading and trailing whitespace
-	// removed; if only whitespace changed between old and new versions
-	// the line(s) are semantically the same
-	for i := range a.SourceLines {
-		srcLineA := strings.TrimSpace(a.SourceLines[i])
-		srcLineB := strings.TrimSpace(b.SourceLines[i])
-		if srcLineA != srcLineB {
-			return false
-		}
-	}
-
-	return true
+// issueMatchKey identifies a linter issue when comparing versions.
+// Issues are the same if their linter, message, file and source lines
+// match and they are on the same line and column.
+func issueMatchKey(dep string, issue *lintIssue) string {
+	return fmt.Sprintf("%s:%d:%d", issueFingerprint(dep, issue), issue.Pos.Line, issue.Pos.Column)
 }
 
 func getDepRelPath(dep, path string) string {
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// kinds of low level system access
+	lowLevelSyscall = "raw syscall"
+	lowLevelDLL     = "DLL procedure"
+	lowLevelNetlink = "netlink"
+	lowLevelDevice  = "device"
+)
+
+var (
+	// rawSyscallFuncs are functions that make the system call whose
+	// number is their first argument, keyed by package path
+	rawSyscallFuncs = map[string][]string{
+		"syscall": {
+			"Syscall", "Syscall6", "Syscall9", "Syscall12", "Syscall15", "Syscall18", "SyscallN",
+			"RawSyscall", "RawSyscall6",
+		},
+		"golang.org/x/sys/unix": {
+			"Syscall", "Syscall6", "SyscallNoError", "RawSyscall", "RawSyscall6", "RawSyscallNoError",
+		},
+	}
+	// dllFuncs are functions that load Windows DLLs or their
+	// procedures, whose name is their first argument
+	dllFuncs = map[string][]string{
+		"syscall":                  {"LoadDLL", "MustLoadDLL", "NewLazyDLL"},
+		"golang.org/x/sys/windows": {"LoadDLL", "MustLoadDLL", "NewLazyDLL", "NewLazySystemDLL", "LoadLibrary"},
+	}
+	// netlinkPkgs are packages that talk to the kernel over netlink
+	netlinkPkgs = []string{
+		"github.com/mdlayher/netlink",
+		"github.com/vishvananda/netlink",
+		"github.com/jsimonetti/rtnetlink",
+	}
+)
+
+// lowLevelAccess is system access that bypasses the standard library,
+// so the capabilities capslock reports for it say little about what it
+// does.
+type lowLevelAccess struct {
+	Kind string
+	// Detail is the function or package used, and the syscall, DLL or
+	// device if known, such as 'unix.Syscall(SYS_PTRACE)'
+	Detail string
+	Uses   []sourcePos
+}
+
+// scanLowLevelAccess finds raw syscalls, Windows DLL calls and netlink
+// sockets.
+func (s *sourceFacts) scanLowLevelAccess(f *sourceFile) {
+	for _, imp := range f.file.Imports {
+		impPath, err := strconv.Unquote(imp.Path.Value)
+		if err == nil && slices.Contains(netlinkPkgs, impPath) {
+			s.addLowLevelAccess(lowLevelNetlink, impPath, f.pos(imp))
+		}
+	}
+
+	ast.Inspect(f.file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.CallExpr:
+			pkgPath, name, ok := f.callee(n)
+			if !ok || len(n.Args) == 0 {
+				return true
+			}
+			qualName := path.Base(pkgPath) + "." + name
+			switch {
+			case slices.Contains(rawSyscallFuncs[pkgPath], name):
+				s.addLowLevelAccess(lowLevelSyscall, qualName+"("+f.syscallNumber(n.Args[0])+")", f.pos(n))
+			case slices.Contains(dllFuncs[pkgPath], name):
+				dll, ok := f.stringValue(n.Args[0])
+				if !ok {
+					dll = "*"
+				}
+				s.addLowLevelAccess(lowLevelDLL, qualName+"("+dll+")", f.pos(n))
+			}
+		case *ast.SelectorExpr:
+			// sockets of the AF_NETLINK family
+			x, ok := n.X.(*ast.Ident)
+			if !ok || n.Sel.Name != "AF_NETLINK" {
+				return true
+			}
+			if pkgPath := f.imports[x.Name]; pkgPath == "syscall" || pkgPath == "golang.org/x/sys/unix" {
+				s.addLowLevelAccess(lowLevelNetlink, x.Name+".AF_NETLINK", f.pos(n))
+			}
+		}
+		return true
+	})
+}
+
+// syscallNumber returns the name or number of the syscall an
+// expression refers to, or '*' if it can't be found. Variables are
+// returned as-is as they are usually constants.
+func (f *sourceFile) syscallNumber(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.SelectorExpr:
+		// unix.SYS_PTRACE
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			return e.Value
+		}
+	case *ast.CallExpr:
+		// uintptr(unix.SYS_PTRACE)
+		if len(e.Args) == 1 {
+			return f.syscallNumber(e.Args[0])
+		}
+	}
+	return "*"
+}
+
+// addDeviceAccess adds the paths under /dev that were found as low
+// level access.
+func (s *sourceFacts) addDeviceAccess() {
+	for _, access := range s.Paths {
+		if strings.HasPrefix(access.Path, "/dev/") {
+			for _, pos := range access.Uses {
+				s.addLowLevelAccess(lowLevelDevice, access.Path, pos)
+			}
+		}
+	}
+}
+
+func (s *sourceFacts) addLowLevelAccess(kind, detail string, pos sourcePos) {
+	i := slices.IndexFunc(s.LowLevel, func(access lowLevelAccess) bool {
+		return access.Kind == kind && access.Detail == detail
+	})
+	if i == -1 {
+		s.LowLevel = append(s.LowLevel, lowLevelAccess{Kind: kind, Detail: detail})
+		i = len(s.LowLevel) - 1
+	}
+	s.LowLevel[i].Uses = append(s.LowLevel[i].Uses, pos)
+}
+
+// lowLevelChanges are the kinds of low level system access a new
+// version of a dependency has that the old version didn't, and that it
+// no longer has.
+type lowLevelChanges struct {
+	Added   []lowLevelAccess
+	Removed []lowLevelAccess
+}
+
+// compareLowLevelAccess compares the low level system access of two
+// versions of a dependency.
+func compareLowLevelAccess(oldFacts, newFacts *sourceFacts) *lowLevelChanges {
+	if oldFacts == nil || newFacts == nil {
+		return nil
+	}
+
+	removed, _, added := processFindings(oldFacts.LowLevel, newFacts.LowLevel, func(access lowLevelAccess) string {
+		return access.Kind + " " + access.Detail
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &lowLevelChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
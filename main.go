@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -15,8 +16,9 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
-	"github.com/pkg/browser"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/semver"
 )
@@ -28,9 +30,18 @@ const (
 	tempPrefix = "dep-inspector"
 )
 
+// goEnvVars are the Go environment variables that affect how
+// dependencies are fetched and loaded. Their effective values are
+// recorded in report metadata.
 var goEnvVars = []string{
-	"HOME",
-	"PATH",
+	"GOARCH",
+	"GOAUTH",
+	"GOFLAGS",
+	"GONOPROXY",
+	"GONOSUMDB",
+	"GOOS",
+	"GOPRIVATE",
+	"GOPROXY",
 }
 
 func usage() {
@@ -50,6 +61,101 @@ To compare dependency versions:
 'current' can be used instead of a version if you wish to inspect or
 compare the current version of a dependency.
 
+To find the newest version of a dependency that is safe to upgrade
+to, which adds no high severity capabilities and violates no policies
+compared to the current version:
+
+	dep-inspector [flags] advise path/of/module[@version]
+
+To inspect the packages of the main module itself:
+
+	dep-inspector [flags] self
+
+To compare the packages of the main module between two git refs:
+
+	dep-inspector [flags] self old-ref new-ref
+
+To compare a module against a fork of it with a different module path:
+
+	dep-inspector [flags] fork path/of/module@version path/of/fork@version
+
+To list past inspections of a dependency recorded in a result store:
+
+	dep-inspector -store path.db history path/of/module
+
+To render a report from saved JSON results or a result store entry
+without re-running analysis:
+
+	dep-inspector [flags] report results.json
+	dep-inspector -store path.db [flags] report inspection-id
+
+To combine the results of comparisons that were split between
+workers with -shard into one report:
+
+	dep-inspector [flags] merge results.json...
+
+To compare two sets of saved results:
+
+	dep-inspector [flags] diff old-results.json new-results.json
+
+To show the call path, source and documentation of a single finding:
+
+	dep-inspector [flags] explain [-findings results.json|inspection-id] fingerprint
+
+To mark a finding as accepted, needing a fix or a false positive so
+later reports show the decision:
+
+	dep-inspector -store path.db [flags] triage [-note note] fingerprint accepted|needs-fix|false-positive
+
+To comment on a finding so later reports show the comment with it:
+
+	dep-inspector -store path.db [flags] comment fingerprint text
+
+To check capability map files for mistakes, and optionally show which
+capabilities their rules assign in a fixture module:
+
+	dep-inspector [flags] capmap check [-fixture dir] [map.cm...]
+
+To write a capability map with a rule for every exported function of a
+dependency that reaches a capability, as a starting point for a custom
+map:
+
+	dep-inspector [flags] [-o file.cm] capmap stubs path/of/module@version
+
+To save the findings of every dependency as a baseline, and later only
+inspect dependencies that changed compared to that baseline:
+
+	dep-inspector -o baseline.json baseline
+	dep-inspector [flags] compare-baseline baseline.json
+
+To write a CycloneDX SBOM of the main module with the findings of each
+dependency, inspecting dependencies or using a saved baseline:
+
+	dep-inspector [flags] sbom [baseline.json]
+
+To add findings to the Go modules in an existing CycloneDX or SPDX JSON
+SBOM, inspecting each module at the version recorded in the SBOM:
+
+	dep-inspector [flags] annotate-sbom sbom.json
+
+To verify the attestations of signed findings or baseline files:
+
+	dep-inspector -certificate-identity id -certificate-oidc-issuer url verify findings.json...
+
+To compare every dependency that changed between two git refs of the
+main module:
+
+	dep-inspector [flags] git-diff old-ref new-ref
+
+To run a server that inspects dependencies submitted over a REST API:
+
+	dep-inspector [flags] serve -listen :8080
+
+To watch dependencies for new versions and inspect them as they are
+released:
+
+	dep-inspector [flags] watch [-interval 1h] [path/of/module...]
+
 %s accepts the following flags:
 
 `[1:], projectName)
@@ -61,6 +167,11 @@ For more information, see https://github.com/capnspacehook/dep-inspector.
 }
 
 func main() {
+	// analysis tools are run through dep-inspector when they are
+	// confined
+	if policy, ok := os.LookupEnv(confineEnvVar); ok {
+		os.Exit(execConfined(policy, os.Args[1:]))
+	}
 	os.Exit(mainRetCode())
 }
 
@@ -69,12 +180,121 @@ type depInspector struct {
 	unusedDep        bool
 	upgradeTransDeps bool
 	outputFile       string
+	format           string
+	ghaSummary       bool
+	prComment        string
+	reportURL        string
+	webhooks         stringsFlag
+	webhookSecret    string
+	upload           string
+	sign             bool
+	reviewSources    stringsFlag
+	approvedVersions string
+	failUnapproved   bool
+	triagePath       string
+	commentsPath     string
+	verify           bool
+	certIdentity     string
+	certOIDCIssuer   string
+	slackWebhook     string
+	discordWebhook   string
+	minSeverity      string
+	onlyCaps         string
+	collectCaps      string
+	directOnly       bool
+	classify         bool
+	compareVCS       bool
+	compareProxies   bool
+	findCopies       bool
+	downloadExec     bool
+	generatedCode    string
+	allowModCache    bool
+	stdlibFrames     string
+	ignorePkgs       stringsFlag
+	ignoreFiles      stringsFlag
+	capMapPaths      stringsFlag
+	granularity      string
+	noInitSummary    bool
+	buildTags        string
+	summary          bool
+	onlyChanges      bool
+	failOn           stringsFlag
+	depth            int
+	shard            string
+	contributors     bool
+	ownership        bool
+	checkProxies     bool
+	releaseNotes     bool
+	vulns            bool
+	jsonSidecar      bool
+	sourceDiff       bool
+	noMinify         bool
+	htmlAssets       bool
+	pages            bool
 	verbose          bool
 
-	modFilePath   string
+	goProxy   string
+	goPrivate string
+	goFlags   string
+	cacheOnly bool
+	runner    string
+	confine   bool
+	jobs      int
+	timeout   time.Duration
+	resume    bool
+	dryRun    bool
+
+	storePath string
+	diffLast  bool
+
+	netrcPath      string
+	goAuth         string
+	gitCredentials bool
+	netrc          []netrcEntry
+
+	modFilePath string
+	// workDir is the directory commands are run in, the current
+	// directory if empty
+	workDir       string
 	sumFilePath   string
 	parsedModFile *modfile.File
 	modCache      string
+	goEnv         map[string]string
+	toolVersions  map[string]string
+	// runFlags are the flags that were passed, they are recorded in
+	// report metadata
+	runFlags []string
+	pkgCache *pkgLoadCache
+	// confinedEnv is set to the environment variable that confines
+	// analysis tools if -confine was passed
+	confinedEnv string
+	// executable is the path of dep-inspector, analysis tools are run
+	// through it when they are confined
+	executable string
+	// analyzerSlots limits how many analysis tools run at once, it is
+	// nil if they aren't limited
+	analyzerSlots chan struct{}
+	store         *resultStore
+	severities    *severityModel
+	risk          *riskModel
+	filter        *findingsFilter
+	capMaps       *capabilityMaps
+	policyRules   []policyRule
+	licensePolicy licensePolicy
+	reviews       *reviewSources
+	approvals     *approvalList
+	triage        *triageList
+	comments      *commentList
+	// triageURL is where HTML reports post triage decisions, only set
+	// in server mode
+	triageURL string
+	metrics   *inspectorMetrics
+
+	// multipleReports is true if reports of multiple dependencies
+	// will be written in this run
+	multipleReports bool
+	// policyViolated is true if findings violated a policy rule
+	policyViolated bool
 
 	modBackupFiles    *modFilePair
 	oldModBackupFiles *modFilePair
@@ -93,6 +313,7 @@ func (m *modFilePair) Close() error {
 func mainRetCode() int {
 	var (
 		de           depInspector
+		configPath   string
 		printVersion bool
 	)
 
@@ -100,10 +321,79 @@ func mainRetCode() int {
 	flag.BoolVar(&de.inspectAllPkgs, "a", false, "inspect all packages of the dependency, not just those that are used")
 	flag.BoolVar(&de.unusedDep, "unused-dep", false, "inspect dependency that is not used in this module")
 	flag.BoolVar(&de.upgradeTransDeps, "u", false, "upgrade transitive dependencies and inspect them as well")
-	flag.StringVar(&de.outputFile, "o", "", "file to write output HTML to")
+	flag.IntVar(&de.depth, "depth", -1, "when comparing versions, only inspect changed dependencies up to this many levels of requirements away: 1 only inspects the dependency, 2 also its changed requirements and so on. Every changed dependency is inspected if negative")
+	flag.StringVar(&de.shard, "shard", "", "when comparing, only inspect this part of the changed dependencies in the form i/n, such as 2/4. Results of every part can be combined with the merge subcommand")
+	flag.StringVar(&de.outputFile, "o", "", "file to write output to")
+	flag.StringVar(&de.format, "format", formatHTML, "output format: html, json, markdown or sarif")
+	flag.BoolVar(&de.ghaSummary, "gha-summary", false, "also write a Markdown summary to $GITHUB_STEP_SUMMARY")
+	flag.StringVar(&de.prComment, "pr-comment", "", "post or update a summary comment on a GitHub pull request (owner/repo#123 or URL), requires GITHUB_TOKEN to be set")
+	flag.StringVar(&de.reportURL, "report-url", "", "URL of the full report to link to in pull request comments")
+	flag.Var(&de.webhooks, "webhook", "URL to post a JSON payload to when an inspection completes in server or watch mode, can be passed multiple times")
+	flag.StringVar(&de.webhookSecret, "webhook-secret", "", "secret used to sign webhook payloads with HMAC-SHA256")
+	flag.StringVar(&de.upload, "upload", "", "upload reports and JSON results to object storage: s3://bucket/prefix, gs://bucket/prefix or az://account/container/prefix")
+	flag.BoolVar(&de.sign, "sign", false, "sign an in-toto attestation of JSON findings or baselines with cosign, requires -o")
+	flag.BoolVar(&de.compareVCS, "compare-vcs", false, "compare the files of module zips with their repositories at the tag or commit they were made from and report differences")
+	flag.BoolVar(&de.compareProxies, "compare-proxies", false, "fetch module versions from every configured proxy, the public proxy and their repositories and report differences")
+	flag.BoolVar(&de.findCopies, "find-copies", false, "find directories of dependencies that are copies of other modules in go.sum, such as vendored third_party directories")
+	flag.BoolVar(&de.downloadExec, "find-download-exec", false, "find network data that dependencies execute or write to executable files, which loads and type checks dependencies again and is slow")
+	flag.BoolVar(&de.allowModCache, "allow-modified-cache", false, "annotate reports instead of failing when the module cache's copies of modules were modified since they were downloaded")
+	flag.BoolVar(&de.verify, "verify", false, "verify attestations of findings and baseline files before using them")
+	flag.StringVar(&de.certIdentity, "certificate-identity", "", "identity findings attestations must be signed by")
+	flag.StringVar(&de.certOIDCIssuer, "certificate-oidc-issuer", "", "OIDC issuer of the identity findings attestations must be signed by")
+	flag.StringVar(&de.slackWebhook, "slack-webhook", "", "Slack incoming webhook URL to post messages to when an inspection completes in server or watch mode")
+	flag.StringVar(&de.discordWebhook, "discord-webhook", "", "Discord webhook URL to post messages to when an inspection completes in server or watch mode")
+	flag.StringVar(&de.minSeverity, "min-severity", "", "only report findings of at least this severity: low, medium, high or critical")
+	flag.StringVar(&de.stdlibFrames, "stdlib-frames", stdlibFramesShow, "how consecutive calls through the standard library in call paths of capabilities are shown in reports: show, collapse or hide")
+	flag.StringVar(&de.generatedCode, "generated-code", generatedShow, "how findings in generated files are reported: show, group them apart from other findings, downweight them to low severity or hide them")
+	flag.BoolVar(&de.directOnly, "direct-only", false, "only report capabilities the dependency's own code uses directly, not ones only reached through its dependencies")
+	flag.BoolVar(&de.classify, "classify", false, "tag findings with the CWE weaknesses and OWASP Top 10 categories they relate to in JSON and SARIF output")
+	flag.StringVar(&de.collectCaps, "capabilities", "", "only collect these comma separated capabilities from capslock, such as NETWORK,EXEC,CGO. Other capabilities aren't recorded in JSON findings or the result store")
+	flag.StringVar(&de.onlyCaps, "only-caps", "", "only report these comma separated capabilities, such as NETWORK,EXEC")
+	flag.Var(&de.ignorePkgs, "ignore-pkg", "ignore findings in packages matching this pattern, such as example.com/dep/internal/gen/..., can be passed multiple times")
+	flag.Var(&de.ignoreFiles, "ignore-file", "ignore findings in files matching this glob, such as *.pb.go or testdata, can be passed multiple times")
+	flag.StringVar(&de.granularity, "capslock-granularity", "", "granularity capslock reports capabilities at: package, intermediate or function. Defaults to capslock's default")
+	flag.BoolVar(&de.noInitSummary, "capslock-noinitsummary", false, "pass -noinitsummary to capslock so capabilities of package initialization are reported with full call paths instead of being summarized")
+	flag.StringVar(&de.buildTags, "capslock-buildtags", "", "comma separated build tags capslock loads packages with")
+	flag.Var(&de.capMapPaths, "capability-map", "capability map file with rules that take precedence over capslock's built-in and dep-inspector's embedded maps, can be passed multiple times")
+	flag.StringVar(&de.approvedVersions, "approved-versions", "", "file or HTTP URL of a list of approved dependency versions to show approvals from in reports")
+	flag.BoolVar(&de.failUnapproved, "fail-unapproved", false, "treat inspecting a version that isn't on the -approved-versions list or approved in the result store as a policy violation")
+	flag.StringVar(&de.triagePath, "triage", "", "file to record triage decisions about findings in and show them in reports from")
+	flag.StringVar(&de.commentsPath, "comments", "", "file to record comments about findings in and show them in reports from")
+	flag.Var(&de.reviewSources, "review-source", "directory, HTTP endpoint or git repository prefixed with 'git+' to show reviews of trusted reviewers from in reports, can be passed multiple times")
+	flag.BoolVar(&de.jsonSidecar, "json-sidecar", false, "when writing an HTML, Markdown or SARIF report to a file, also write a JSON summary of totals, metadata and finding fingerprints next to it")
+	flag.BoolVar(&de.sourceDiff, "source-diff", false, "when writing a zip archive or pages with -o, include a diff of the source code of the compared versions")
+	flag.BoolVar(&de.noMinify, "no-minify", false, "don't minify HTML reports, which makes them easier to read when customizing templates")
+	flag.BoolVar(&de.htmlAssets, "html-assets", false, "write the CSS and JavaScript of HTML reports to separate files next to the output file instead of inlining them")
+	flag.BoolVar(&de.pages, "pages", false, "write HTML reports as a directory of interlinked overview, dependency, package and diff pages to the directory passed with -o")
+	flag.BoolVar(&de.summary, "summary", false, "only output totals of findings and how they changed, not the findings themselves")
+	flag.BoolVar(&de.onlyChanges, "only-changes", false, "when comparing, omit findings that are the same in both versions from reports")
+	flag.BoolVar(&de.contributors, "contributors", false, "when comparing, list the authors of commits between the versions by cloning the dependency's repository")
+	flag.BoolVar(&de.ownership, "ownership", false, "check for changes of the dependency's repository and release signing key, requires network access")
+	flag.BoolVar(&de.checkProxies, "check-proxies", false, "check which proxies serve private modules and if public modules with the same paths have higher versions, requires network access")
+	flag.BoolVar(&de.releaseNotes, "release-notes", false, "when comparing, include the release notes of versions between the compared versions from GitHub or GitLab")
+	flag.BoolVar(&de.vulns, "vulns", false, "query OSV for known vulnerabilities of inspected dependencies, requires network access")
+	flag.Var(&de.failOn, "fail-on", "exit with code 3 if findings match a policy rule such as 'added.caps.NETWORK > 0', can be passed multiple times")
 	flag.BoolVar(&de.verbose, "v", false, "print commands being run and verbose information")
+	flag.StringVar(&configPath, "config", "", "path of config file to load settings from")
+	flag.StringVar(&de.goProxy, "goproxy", "", "GOPROXY to use when fetching and loading modules")
+	flag.StringVar(&de.goPrivate, "goprivate", "", "GOPRIVATE module path patterns to use when fetching and loading modules")
+	flag.StringVar(&de.goFlags, "goflags", "", "GOFLAGS to pass to all go commands and analysis tools")
+	flag.BoolVar(&de.cacheOnly, "cache-only", false, "never access the network, module versions must already be in the module cache or a file:// -goproxy")
+	flag.StringVar(&de.runner, "runner", "", "run the analysis elsewhere and report findings here: docker[:image] runs it in disposable containers without network access with the module cache mounted read-only, ssh:[user@]host runs it on a remote host that only gets go.mod and go.sum")
+	flag.BoolVar(&de.confine, "confine", false, "on Linux, confine analysis tools with landlock and seccomp so they can only write to temporary directories and caches, only read the module cache, Go toolchain and main module, and can't open network sockets")
+	flag.DurationVar(&de.timeout, "timeout", 0, "stop and fail if inspecting takes longer than this, such as 30m. Commands that were running are reported and go.mod and go.sum are restored. No timeout if 0")
+	flag.BoolVar(&de.dryRun, "dry-run", false, "print the module versions that would be downloaded, how go.mod would change and which packages would be analyzed without running any analysis tools")
+	flag.BoolVar(&de.resume, "resume", false, "when comparing versions, reuse the findings of changed dependencies a previous run with the same flags inspected before it was interrupted")
+	flag.IntVar(&de.jobs, "jobs", 0, "maximum number of analysis tools to run at once, CPUs are split between them unless GOMAXPROCS is set. Unlimited if 0")
+	flag.StringVar(&de.netrcPath, "netrc", "", "path of .netrc file with credentials for private modules")
+	flag.StringVar(&de.goAuth, "goauth", "", "GOAUTH to use when fetching private modules")
+	flag.BoolVar(&de.gitCredentials, "git-credentials", false, "use git credential helpers to authenticate when resolving private module repositories")
+	flag.StringVar(&de.storePath, "store", "", "SQLite database to record inspection results in")
+	flag.BoolVar(&de.diffLast, "diff-last", false, "only report findings that changed since the last inspection recorded in the result store")
 	flag.BoolVar(&printVersion, "version", false, "print version and build information and exit")
 	flag.Parse()
+	de.runFlags = passedFlags()
+	de.pkgCache = newPkgLoadCache()
 
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -115,13 +405,205 @@ func mainRetCode() int {
 		return 0
 	}
 
-	if narg := flag.NArg(); narg != 1 && narg != 3 {
-		usage()
+	if _, ok := subcommands[flag.Arg(0)]; !ok {
+		if narg := flag.NArg(); narg != 1 && narg != 3 {
+			usage()
+			return 2
+		}
+	}
+
+	var cfg config
+	if configPath != "" {
+		loadedCfg, err := loadConfig(configPath)
+		if err != nil {
+			log.Printf("error: %v", err)
+			return 1
+		}
+		cfg = *loadedCfg
+		de.applyConfig(&cfg)
+	}
+	var err error
+	de.severities, err = newSeverityModel(cfg.Severities)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	de.risk, err = newRiskModel(cfg.Risk)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	de.filter, err = newFindingsFilter(de.minSeverity, de.onlyCaps, de.directOnly)
+	if err == nil {
+		err = de.filter.ignore(de.ignorePkgs, de.ignoreFiles)
+	}
+	if err == nil {
+		err = de.filter.collect(de.collectCaps)
+	}
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	if !slices.Contains(generatedCodeModes, de.generatedCode) {
+		log.Printf("error: unknown -generated-code mode %q, must be one of %s", de.generatedCode, strings.Join(generatedCodeModes, ", "))
+		return 2
+	}
+	de.filter.hideGenerated = de.generatedCode == generatedHide
+	if !slices.Contains(stdlibFramesModes, de.stdlibFrames) {
+		log.Printf("error: unknown -stdlib-frames mode %q, must be one of %s", de.stdlibFrames, strings.Join(stdlibFramesModes, ", "))
+		return 2
+	}
+	if de.granularity != "" && !slices.Contains(capslockGranularities, de.granularity) {
+		log.Printf("error: unknown capslock granularity %q, must be one of %s", de.granularity, strings.Join(capslockGranularities, ", "))
+		return 2
+	}
+	de.capMaps, err = loadCapMaps(de.capMapPaths)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	if !slices.Contains(outputFormats, de.format) {
+		log.Printf("error: unknown output format %q", de.format)
+		return 2
+	}
+	de.policyRules, err = parsePolicyRules(de.failOn)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	de.licensePolicy = cfg.Licenses
+	de.reviews, err = newReviewSources(de.reviewSources, cfg.Reviews)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return 2
+	}
+	if de.cacheOnly {
+		if conflicts := de.cacheOnlyConflicts(); len(conflicts) != 0 {
+			log.Printf("error: -cache-only can't be used with flags that need network access: %s", strings.Join(conflicts, ", "))
+			return 2
+		}
+		if flag.Arg(0) == "serve" || flag.Arg(0) == "watch" {
+			log.Printf("error: -cache-only can't be used with %s", flag.Arg(0))
+			return 2
+		}
+	}
+	if de.runner != "" {
+		kind, _, err := parseRunner(de.runner)
+		if err != nil {
+			log.Printf("error: %v", err)
+			return 2
+		}
+		if conflicts := de.runnerConflicts(); kind == runnerDocker && len(conflicts) != 0 {
+			log.Printf("error: -runner docker can't be used with flags that need network access while analyzing: %s", strings.Join(conflicts, ", "))
+			return 2
+		}
+		if _, ok := subcommands[flag.Arg(0)]; ok {
+			log.Println("error: -runner can only be used when inspecting or comparing dependency versions")
+			return 2
+		}
+		if de.dryRun || de.resume {
+			log.Println("error: -runner can't be used with -dry-run or -resume")
+			return 2
+		}
+	}
+	if de.shard != "" {
+		if _, err := parseShard(de.shard); err != nil {
+			log.Printf("error: %v", err)
+			return 2
+		}
+		if flag.NArg() == 1 {
+			log.Println("error: -shard can only be used when comparing dependency versions")
+			return 2
+		}
+	}
+	if de.confine && !confineSupported {
+		log.Println("error: -confine is only supported on Linux on amd64 and arm64")
+		return 2
+	}
+	if de.jobs < 0 {
+		log.Println("error: -jobs must not be negative")
+		return 2
+	}
+	if _, ok := subcommands[flag.Arg(0)]; ok && de.dryRun {
+		log.Println("error: -dry-run can only be used when inspecting or comparing dependency versions")
+		return 2
+	}
+	if de.timeout < 0 {
+		log.Println("error: -timeout must not be negative")
+		return 2
+	}
+	if de.timeout > 0 && (flag.Arg(0) == "serve" || flag.Arg(0) == "watch") {
+		log.Printf("error: -timeout cannot be used with %s", flag.Arg(0))
+		return 2
+	}
+	if de.jobs > 0 {
+		de.analyzerSlots = make(chan struct{}, de.jobs)
+	}
+	if de.failUnapproved && de.approvedVersions == "" && de.storePath == "" {
+		log.Println("error: -fail-unapproved requires -approved-versions or -store")
+		return 2
+	}
+	if de.summary && de.format == formatSARIF {
+		log.Println("error: -summary does not support sarif output")
+		return 2
+	}
+	if de.prComment != "" {
+		if _, err := parsePRRef(de.prComment); err != nil {
+			log.Printf("error: %v", err)
+			return 2
+		}
+	}
+
+	if de.sign && de.outputFile == "" {
+		log.Println("error: -sign requires -o")
+		return 2
+	}
+	if de.jsonSidecar && de.outputFile == "" {
+		log.Println("error: -json-sidecar requires -o")
+		return 2
+	}
+	if de.jsonSidecar && de.format == formatJSON {
+		log.Println("error: -json-sidecar is redundant with -format json")
+		return 2
+	}
+	if de.sourceDiff && !isZipPath(de.outputFile) && !de.pages {
+		log.Println("error: -source-diff requires -o with a .zip file or -pages")
+		return 2
+	}
+	if de.pages && (de.outputFile == "" || de.format != formatHTML) {
+		log.Println("error: -pages requires -o and -format html")
+		return 2
+	}
+	if isPDFPath(de.outputFile) && (de.format != formatHTML || de.htmlAssets) {
+		log.Println("error: -o with a .pdf file requires -format html and can't be used with -html-assets")
+		return 2
+	}
+	if de.pages && (isZipPath(de.outputFile) || de.htmlAssets) {
+		log.Println("error: -pages can't be used with a .zip file or -html-assets")
+		return 2
+	}
+	if de.htmlAssets && (de.outputFile == "" || de.format != formatHTML) {
+		log.Println("error: -html-assets requires -o and -format html")
 		return 2
 	}
+	if de.sign && de.summary {
+		log.Println("error: -sign signs findings, which -summary does not output")
+		return 2
+	}
+	if de.upload != "" {
+		if _, err := parseUploadDest(de.upload); err != nil {
+			log.Printf("error: %v", err)
+			return 2
+		}
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := interruptContext()
 	defer cancel()
+	if de.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, de.timeout, fmt.Errorf("timed out after %s", de.timeout))
+		defer cancelTimeout()
+	}
 
 	if err := mainErr(ctx, &de); err != nil {
 		var exitErr errJustExit
@@ -131,15 +613,132 @@ func mainRetCode() int {
 		log.Printf("error: %v", err)
 		return 1
 	}
+	if de.policyViolated {
+		return exitPolicyViolation
+	}
 
 	return 0
 }
 
+// interruptContext returns a context that is canceled when an
+// interrupt or termination signal is received, so results found so far
+// can be written and go.mod and go.sum restored. Only the first signal
+// is handled, another one exits immediately.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			log.Printf("received %s, stopping; send it again to exit immediately", sig)
+			cancel(fmt.Errorf("received %s", sig))
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+
+	return ctx, func() { cancel(nil) }
+}
+
 type errJustExit int
 
 func (e errJustExit) Error() string { return fmt.Sprintf("exit: %d", e) }
 
 func mainErr(ctx context.Context, de *depInspector) (ret error) {
+	if de.storePath != "" {
+		store, err := openStore(ctx, de.storePath)
+		if err != nil {
+			return err
+		}
+		de.store = store
+		defer func() {
+			ret = errors.Join(ret, store.Close())
+		}()
+	}
+	if de.approvedVersions != "" {
+		approvals, err := loadApprovals(ctx, de.approvedVersions)
+		if err != nil {
+			return err
+		}
+		de.approvals = approvals
+	}
+	if de.store != nil {
+		// versions approved with the approve subcommand
+		stored, err := de.store.approvals(ctx)
+		if err != nil {
+			return err
+		}
+		if len(stored) != 0 {
+			if de.approvals == nil {
+				de.approvals = &approvalList{}
+			}
+			de.approvals.Approved = append(de.approvals.Approved, stored...)
+		}
+	}
+	if de.triagePath != "" {
+		triage, err := loadTriage(de.triagePath)
+		if err != nil {
+			return err
+		}
+		de.triage = triage
+	}
+	if de.store != nil {
+		// findings triaged with the triage subcommand or server
+		stored, err := de.store.triageDecisions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(stored) != 0 {
+			if de.triage == nil {
+				de.triage = &triageList{}
+			}
+			for _, t := range stored {
+				de.triage.add(t)
+			}
+		}
+	}
+	if de.commentsPath != "" {
+		comments, err := loadComments(de.commentsPath)
+		if err != nil {
+			return err
+		}
+		de.comments = comments
+	}
+	if de.store != nil {
+		// comments added with the comment subcommand
+		stored, err := de.store.comments(ctx)
+		if err != nil {
+			return err
+		}
+		if len(stored) != 0 {
+			if de.comments == nil {
+				de.comments = &commentList{}
+			}
+			// comments recorded in both the store and the
+			// -comments file are only shown once
+			for _, c := range stored {
+				if !slices.Contains(de.comments.Comments, c) {
+					de.comments.Comments = append(de.comments.Comments, c)
+				}
+			}
+		}
+	}
+
+	if de.diffLast {
+		if de.store == nil {
+			return errors.New("-diff-last requires a result store to be specified with -store")
+		}
+		if flag.NArg() != 1 {
+			return errors.New("-diff-last can only be used when inspecting a single dependency version")
+		}
+	}
+
+	sub, isSubcommand := subcommands[flag.Arg(0)]
+	if isSubcommand && !sub.needsModule {
+		return sub.run(ctx, de, flag.Args()[1:])
+	}
+
 	if err := de.init(ctx); err != nil {
 		return err
 	}
@@ -149,6 +748,10 @@ func mainErr(ctx context.Context, de *depInspector) (ret error) {
 		ret = errors.Join(ret, restoreErr, closeErr)
 	}()
 
+	if isSubcommand {
+		return sub.run(ctx, de, flag.Args()[1:])
+	}
+
 	if flag.NArg() == 1 {
 		depVer := flag.Arg(0)
 		dep, ver, ok := strings.Cut(depVer, "@")
@@ -162,6 +765,17 @@ func mainErr(ctx context.Context, de *depInspector) (ret error) {
 		if err != nil {
 			return err
 		}
+		if de.cacheOnly {
+			if err := de.checkCached(dep, ver); err != nil {
+				return err
+			}
+		}
+		if de.dryRun {
+			return de.dryRunSingle(ctx, os.Stdout, dep, ver)
+		}
+		if de.runner != "" {
+			return de.inspectWithRunner(ctx, dep, ver)
+		}
 
 		return de.inspectSingleDepVersion(ctx, dep, ver)
 	}
@@ -181,6 +795,18 @@ func mainErr(ctx context.Context, de *depInspector) (ret error) {
 	if semver.Compare(oldVer, newVer) == 1 {
 		return fmt.Errorf("cannot compare: %q is greater than %q. old version must be less than new version", oldVer, newVer)
 	}
+	if de.cacheOnly {
+		if err := de.checkCached(dep, oldVer, newVer); err != nil {
+			return err
+		}
+	}
+
+	if de.dryRun {
+		return de.dryRunCompare(ctx, os.Stdout, dep, oldVer, newVer)
+	}
+	if de.runner != "" {
+		return de.inspectWithRunner(ctx, dep, oldVer, newVer)
+	}
 
 	return de.compareDepVersionsRecursively(ctx, dep, oldVer, newVer)
 }
@@ -207,6 +833,28 @@ func (d *depInspector) init(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	d.goEnv, err = d.getGoEnv(ctx)
+	if err != nil {
+		return err
+	}
+	if err := d.loadNetrc(); err != nil {
+		return err
+	}
+	if d.confine {
+		policy, err := d.newConfinePolicy(ctx)
+		if err != nil {
+			return err
+		}
+		d.confinedEnv, err = confineEnv(policy)
+		if err != nil {
+			return err
+		}
+		d.executable, err = os.Executable()
+		if err != nil {
+			return fmt.Errorf("finding dep-inspector executable: %w", err)
+		}
+	}
+	d.toolVersions = d.getToolVersions(ctx)
 
 	return nil
 }
@@ -260,57 +908,158 @@ func (d *depInspector) checkVersion(dep, ver string) (string, error) {
 }
 
 func (d *depInspector) inspectSingleDepVersion(ctx context.Context, dep, version string) error {
-	capResult, lintIssues, pkgsInspected, err := d.inspectDep(ctx, d.newModBackupFiles, dep, version, true)
-	if err != nil {
-		return err
+	res := new(savedResults)
+	if d.diffLast {
+		lastInspection, ok, err := d.store.latest(ctx, dep, version)
+		if err != nil {
+			return err
+		}
+		if ok {
+			res.Old = lastInspection.Findings
+		} else {
+			log.Printf("no previous inspection of %s recorded, reporting all findings", makeVersionStr(dep, version))
+		}
 	}
 
-	r, err := d.singleDepHTMLOutput(ctx, dep, version, pkgsInspected, capResult, lintIssues)
-	if err != nil {
-		return err
+	findings, inspectErr := d.inspectDep(ctx, d.newModBackupFiles, dep, version, true, nil)
+	if findings == nil {
+		return inspectErr
+	}
+	res.New = findings
+	if res.Old != nil && contentChanged(res.Old.ZipHash, res.New.ZipHash) {
+		log.Printf("warning: the content of %s changed since it was last inspected, its module zip hash was %s and is now %s",
+			makeVersionStr(dep, version), res.Old.ZipHash, res.New.ZipHash)
 	}
 
-	if d.outputFile != "" {
-		outFile, err := os.Create(d.outputFile)
-		if err != nil {
-			return err
-		}
-		defer outFile.Close()
-		_, err = io.Copy(outFile, r)
+	if err := d.outputResults(uninterruptedContext(ctx), res); err != nil {
 		return err
 	}
+	if inspectErr != nil {
+		return fmt.Errorf("findings are incomplete: %w", inspectErr)
+	}
+	return nil
+}
 
-	err = browser.OpenReader(r)
-	if err != nil {
-		return err
+// uninterruptedContext returns a context to finish recording and
+// writing results with. If ctx is done because dep-inspector was
+// interrupted, the results that were found are still written.
+func uninterruptedContext(ctx context.Context) context.Context {
+	if ctx.Err() != nil {
+		return context.WithoutCancel(ctx)
 	}
+	return ctx
+}
 
-	return nil
+// prevFindings returns the findings of another version of a dependency
+// being inspected, waiting for them if they are still being found. It
+// returns nil if there are none.
+type prevFindings func() *depFindings
+
+// knownFindings returns a prevFindings of findings that were already
+// found, or nil if findings is nil.
+func knownFindings(findings *depFindings) prevFindings {
+	if findings == nil {
+		return nil
+	}
+	return func() *depFindings {
+		return findings
+	}
 }
 
-func (d *depInspector) inspectDep(ctx context.Context, modBackupFiles *modFilePair, dep, version string, newDepVer bool) (*capslockResult, []*lintIssue, []string, error) {
+// inspectDep finds the capabilities and linter issues of a dependency
+// version. If prev returns findings of another version of the
+// dependency, linter issues of packages that didn't change since are
+// reused. If ctx is done before every analyzer finished but some did,
+// the incomplete findings are returned along with an error.
+func (d *depInspector) inspectDep(ctx context.Context, modBackupFiles *modFilePair, dep, version string, newDepVer bool, prev prevFindings) (findings *depFindings, ret error) {
+	defer func() {
+		d.metrics.observeInspection(findings, ret)
+	}()
+
 	versionStr := makeVersionStr(dep, version)
-	if err := d.setupDepVersion(ctx, modBackupFiles, versionStr, newDepVer); err != nil {
-		return nil, nil, nil, fmt.Errorf("setting up dependency: %w", err)
+	d.metrics.observeModCacheLookup(d.modCache, dep, version)
+	pkgs, err := d.setupDep(ctx, modBackupFiles, dep, version, newDepVer)
+	if err != nil {
+		return nil, err
 	}
 
-	modPath := d.parsedModFile.Module.Mod.Path
-	pkgs, err := listPackages(modPath)
+	modules, err := requiredModules(d.modFilePath)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	// if -unused-dep wasn't passed make sure the dependency is actually
-	// dependency or running tools will fail
-	if !d.unusedDep {
-		var depIsUsed bool
-		for _, pkg := range pkgs {
-			if pkg.Module != nil && pkg.Module.Path == dep {
-				depIsUsed = true
-				break
-			}
+	goSum, err := goSumEntries(d.modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	modifiedModules, err := d.checkModCache(ctx, dep, version, goSum)
+	if err != nil {
+		return nil, err
+	}
+	// licenses can't be detected if the dependency is replaced with a
+	// local directory, but that shouldn't prevent inspecting it
+	licenses, err := d.moduleLicenses(dep, version)
+	if err != nil {
+		log.Printf("error detecting licenses of %s: %v", versionStr, err)
+	}
+	var size int64
+	if zipPath, err := moduleZipPath(d.modCache, dep, version); err == nil {
+		if info, err := os.Stat(zipPath); err == nil {
+			size = info.Size()
 		}
-		if !depIsUsed {
-			return nil, nil, nil, fmt.Errorf("%s is not used in %s, run again with the -unused-dep flag", versionStr, modPath)
+	}
+	var vulns *vulnFindings
+	if d.vulns {
+		vulns, err = findVulns(ctx, dep, version)
+		if err != nil {
+			log.Printf("error finding vulnerabilities of %s: %v", versionStr, err)
+		}
+	}
+	var ownership *ownershipSignals
+	if d.ownership {
+		ownership, err = d.findOwnership(ctx, dep, version)
+		if err != nil {
+			log.Printf("error finding ownership of %s: %v", versionStr, err)
+		}
+	}
+	var proxies []proxyAvailability
+	if d.checkProxies {
+		proxies, err = d.findProxies(ctx, dep, version, modules)
+		if err != nil {
+			log.Printf("error checking proxies of %s: %v", versionStr, err)
+		}
+	}
+	source, err := scanDepSource(dep, d.targetPlatform(), pkgs)
+	if err != nil {
+		log.Printf("error scanning the source of %s: %v", versionStr, err)
+	} else if d.downloadExec {
+		source.DownloadExec, err = d.findDownloadExec(dep, pkgs)
+		if err != nil {
+			log.Printf("error finding downloaded code that is executed in %s: %v", versionStr, err)
+		}
+	}
+	var copies []copiedCode
+	if d.findCopies {
+		copies, err = d.findCopiedCode(dep, version, goSum)
+		if err != nil {
+			log.Printf("error finding copied code in %s: %v", versionStr, err)
+		}
+	}
+	var zipDiff *vcsDiff
+	if d.compareVCS {
+		zipDiff, err = d.compareZipToVCS(ctx, dep, version)
+		if err != nil {
+			log.Printf("error comparing the module zip of %s with its repository: %v", versionStr, err)
+		} else if zipDiff.Differs() {
+			log.Printf("WARNING: module zip of %s differs from %s at %s", versionStr, zipDiff.Repository, zipDiff.Revision)
+		}
+	}
+	var proxyDiff *proxyDiff
+	if d.compareProxies {
+		proxyDiff, err = d.compareProxyFetches(ctx, dep, version, moduleZipHash(goSum, dep, version))
+		if err != nil {
+			log.Printf("error comparing how proxies serve %s: %v", versionStr, err)
+		} else if proxyDiff.Differs() {
+			log.Printf("WARNING: %s is served differently by proxies or its repository", versionStr)
 		}
 	}
 
@@ -335,7 +1084,11 @@ func (d *depInspector) inspectDep(ctx context.Context, modBackupFiles *modFilePa
 	go func() {
 		defer wg.Done()
 
-		issues, err := d.lintDepVersion(ctx, dep, version, pkgs)
+		var prevVer *depFindings
+		if prev != nil {
+			prevVer = prev()
+		}
+		issues, err := d.lintDepVersion(ctx, dep, version, pkgs, prevVer)
 		if err != nil {
 			errCh <- fmt.Errorf("linting dependency: %w", err)
 			return
@@ -350,8 +1103,25 @@ func (d *depInspector) inspectDep(ctx context.Context, modBackupFiles *modFilePa
 	for err := range errCh {
 		inspectErrs = append(inspectErrs, err)
 	}
-	if len(inspectErrs) != 0 {
-		return nil, nil, nil, errors.Join(inspectErrs...)
+	// if inspecting was interrupted, the findings of the analyzers
+	// that finished are still returned
+	if len(inspectErrs) == 2 || len(inspectErrs) != 0 && ctx.Err() == nil {
+		return nil, errors.Join(inspectErrs...)
+	}
+	var (
+		caps    = &capslockResult{}
+		issues  []*lintIssue
+		missing []string
+	)
+	select {
+	case caps = <-capsCh:
+	default:
+		missing = append(missing, "capabilities")
+	}
+	select {
+	case issues = <-issuesCh:
+	default:
+		missing = append(missing, "linter issues")
 	}
 
 	var pkgsInspected []string
@@ -366,8 +1136,75 @@ func (d *depInspector) inspectDep(ctx context.Context, modBackupFiles *modFilePa
 		}
 	}
 	slices.Sort(pkgsInspected)
+	pkgInfo, err := describePackages(dep, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	findings = &depFindings{
+		Dep:         dep,
+		Version:     version,
+		Caps:        caps,
+		Issues:      issues,
+		Packages:    pkgsInspected,
+		PackageInfo: pkgInfo,
+		Modules:     modules,
+		GoSumHashes: goSumHashes(goSum, modules),
+		Licenses:    licenses,
+		Ownership:   ownership,
+		Proxies:     proxies,
+		VCSDiff:     zipDiff,
+		ProxyDiff:   proxyDiff,
+		CopiedCode:  copies,
+		Source:      source,
+		Size:        size,
+		ZipHash:     moduleZipHash(goSum, dep, version),
+		Vulns:       vulns,
+		Metadata:    d.buildMetadata(),
+	}
+	findings.Metadata.ModifiedModules = modifiedModules
+	if len(missing) != 0 {
+		findings.Metadata.Incomplete = fmt.Sprintf("%v, %s of %s weren't found", context.Cause(ctx), strings.Join(missing, " and "), versionStr)
+		return findings, errors.Join(inspectErrs...)
+	}
+	if d.store != nil {
+		if err := d.store.record(uninterruptedContext(ctx), findings); err != nil {
+			return nil, err
+		}
+	}
+
+	return findings, nil
+}
+
+// setupDep sets up go.mod with a dependency version and loads the
+// packages of the main module with it.
+func (d *depInspector) setupDep(ctx context.Context, modBackupFiles *modFilePair, dep, version string, newDepVer bool) (loadedPackages, error) {
+	versionStr := makeVersionStr(dep, version)
+	if err := d.setupDepVersion(ctx, modBackupFiles, versionStr, newDepVer); err != nil {
+		return nil, fmt.Errorf("setting up dependency: %w", err)
+	}
+
+	modPath := d.parsedModFile.Module.Mod.Path
+	pkgs, err := d.loadPackages(modPath, "")
+	if err != nil {
+		return nil, err
+	}
+	// if -unused-dep wasn't passed make sure the dependency is actually
+	// dependency or running tools will fail
+	if !d.unusedDep {
+		var depIsUsed bool
+		for _, pkg := range pkgs {
+			if pkg.Module != nil && pkg.Module.Path == dep {
+				depIsUsed = true
+				break
+			}
+		}
+		if !depIsUsed {
+			return nil, fmt.Errorf("%s is not used in %s, run again with the -unused-dep flag", versionStr, modPath)
+		}
+	}
 
-	return <-capsCh, <-issuesCh, pkgsInspected, nil
+	return pkgs, nil
 }
 
 type changedDep struct {
@@ -377,22 +1214,94 @@ type changedDep struct {
 }
 
 func (d *depInspector) compareDepVersionsRecursively(ctx context.Context, dep, oldVer, newVer string) error {
+	changedDeps, err := d.findDepsToCompare(ctx, dep, oldVer, newVer)
+	if err != nil {
+		return err
+	}
+	return d.inspectChangedDeps(ctx, changedDeps)
+}
+
+// findDepsToCompare sets up the old and new go.mod backups with the
+// versions of dep and returns the dependencies that changed between
+// them, up to -depth levels away from dep.
+func (d *depInspector) findDepsToCompare(ctx context.Context, dep, oldVer, newVer string) ([]changedDep, error) {
 	if err := d.setupDepVersion(ctx, d.oldModBackupFiles, makeVersionStr(dep, oldVer), false); err != nil {
-		return fmt.Errorf("setting up dependency: %w", err)
+		return nil, fmt.Errorf("setting up dependency: %w", err)
 	}
 	oldModFile, err := d.parseAndBackupGoMod(d.oldModBackupFiles)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := d.setupDepVersion(ctx, d.newModBackupFiles, makeVersionStr(dep, newVer), true); err != nil {
-		return fmt.Errorf("setting up dependency: %w", err)
+		return nil, fmt.Errorf("setting up dependency: %w", err)
 	}
 	newModFile, err := d.parseAndBackupGoMod(d.newModBackupFiles)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for _, oldDep := range oldModFile.Require {
+		if oldDep.Mod.Path == dep && oldDep.Mod.Version != oldVer {
+			return nil, fmt.Errorf("cannot compare: after getting %s@%s and tidying the module version is %s", dep, oldVer, oldDep.Mod.Version)
+		}
+	}
+	for _, newDep := range newModFile.Require {
+		if newDep.Mod.Path == dep && newDep.Mod.Version != newVer {
+			return nil, fmt.Errorf("cannot compare: after getting %s@%s and tidying the module version is %s", dep, newVer, newDep.Mod.Version)
+		}
 	}
 
-	var depsToInspect []changedDep
+	changedDeps := findChangedDeps(oldModFile, newModFile)
+	if d.depth >= 0 {
+		return d.limitDepth(ctx, dep, newVer, changedDeps)
+	}
+	return changedDeps, nil
+}
+
+// limitDepth removes changed dependencies that are further than -depth
+// levels away from dep in the module graph. go.mod must be setup with
+// the new version of dep.
+func (d *depInspector) limitDepth(ctx context.Context, dep, newVer string, changedDeps []changedDep) ([]changedDep, error) {
+	maxDist := max(d.depth-1, 0)
+	dists := map[string]int{dep: 0}
+	if maxDist > 0 {
+		graph, err := d.moduleGraph(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// breadth first search of the requirements of dep
+		queue := []string{makeVersionStr(dep, newVer)}
+		for len(queue) != 0 {
+			node := queue[0]
+			queue = queue[1:]
+			nodeDep, _, _ := strings.Cut(node, "@")
+			if dists[nodeDep] == maxDist {
+				continue
+			}
+			for _, req := range graph[node] {
+				reqDep, _, _ := strings.Cut(req, "@")
+				if _, ok := dists[reqDep]; ok {
+					continue
+				}
+				dists[reqDep] = dists[nodeDep] + 1
+				queue = append(queue, req)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(changedDeps, func(c changedDep) bool {
+		if _, ok := dists[c.dep]; !ok {
+			log.Printf("not inspecting %s, it is deeper than -depth %d", c.dep, d.depth)
+			return true
+		}
+		return false
+	}), nil
+}
+
+// findChangedDeps returns the dependencies that were added or whose
+// versions changed between two versions of a go.mod file.
+func findChangedDeps(oldModFile, newModFile *modfile.File) []changedDep {
+	var changedDeps []changedDep
 	for _, newDep := range newModFile.Require {
 		var found bool
 		for _, oldDep := range oldModFile.Require {
@@ -400,17 +1309,9 @@ func (d *depInspector) compareDepVersionsRecursively(ctx context.Context, dep, o
 				continue
 			}
 
-			modPath := oldDep.Mod.Path
-			if modPath == dep && oldDep.Mod.Version != oldVer {
-				return fmt.Errorf("cannot compare: after getting %s@%s and tidying the module version is %s", dep, oldVer, oldDep.Mod.Version)
-			}
-			if modPath == dep && newDep.Mod.Version != newVer {
-				return fmt.Errorf("cannot compare: after getting %s@%s and tidying the module version is %s", dep, newVer, newDep.Mod.Version)
-			}
-
 			found = true
 			if oldDep.Mod.Version != newDep.Mod.Version {
-				depsToInspect = append(depsToInspect, changedDep{
+				changedDeps = append(changedDeps, changedDep{
 					dep:    oldDep.Mod.Path,
 					oldVer: oldDep.Mod.Version,
 					newVer: newDep.Mod.Version,
@@ -420,53 +1321,112 @@ func (d *depInspector) compareDepVersionsRecursively(ctx context.Context, dep, o
 		}
 
 		if !found {
-			depsToInspect = append(depsToInspect, changedDep{
+			changedDeps = append(changedDeps, changedDep{
 				dep:    newDep.Mod.Path,
 				newVer: newDep.Mod.Version,
 			})
 		}
 	}
 
+	return changedDeps
+}
+
+// inspectChangedDeps inspects newly added dependencies and compares
+// the versions of changed dependencies. The old and new go.mod backups
+// must be setup before calling. Reports are written once every
+// dependency is inspected so findings shared between dependencies are
+// only reported once, followed by a rollup report of every dependency.
+// If ctx is done before every dependency is inspected, reports of what
+// was found so far are written and an error is returned. The results of
+// every inspected dependency are persisted as they are found, so the
+// run can be resumed with -resume.
+func (d *depInspector) inspectChangedDeps(ctx context.Context, depsToInspect []changedDep) error {
+	if d.shard != "" {
+		// it was validated when flags were parsed
+		shard, _ := parseShard(d.shard)
+		depsToInspect = shard.deps(depsToInspect)
+		log.Printf("inspecting %d changed dependencies in shard %s", len(depsToInspect), d.shard)
+	}
+	d.multipleReports = len(depsToInspect) > 1
+	progress, err := d.loadProgress(depsToInspect)
+	if err != nil {
+		return err
+	}
+	var (
+		results      []*savedResults
+		notInspected []string
+	)
 	for _, depToInspect := range depsToInspect {
+		if res := progress.results(depToInspect.dep); res != nil {
+			log.Printf("reusing findings of %s from interrupted run", depToInspect.dep)
+			results = append(results, res)
+			continue
+		}
+		if ctx.Err() != nil {
+			notInspected = append(notInspected, depToInspect.dep)
+			continue
+		}
+
 		log.Printf("inspecting %s", depToInspect.dep)
+		var (
+			res = new(savedResults)
+			err error
+		)
 		if depToInspect.oldVer == "" {
-			err := d.inspectSingleDepVersion(ctx, depToInspect.dep, depToInspect.newVer)
+			res.New, err = d.inspectDep(ctx, d.newModBackupFiles, depToInspect.dep, depToInspect.newVer, true, nil)
 			if err != nil {
 				log.Printf("error inspecting newly added dep: %v", err)
 			}
 		} else {
-			err := d.compareDepVersions(ctx, depToInspect.dep, depToInspect.oldVer, depToInspect.newVer)
+			res.Old, res.New, err = d.inspectDepVersions(ctx, depToInspect.dep, depToInspect.oldVer, depToInspect.newVer)
 			if err != nil {
 				log.Printf("error comparing versions of dep: %v", err)
 			}
 		}
+		if res.New == nil {
+			if ctx.Err() != nil {
+				notInspected = append(notInspected, depToInspect.dep)
+			}
+			continue
+		}
+		results = append(results, res)
+		if err == nil {
+			if err := progress.add(res); err != nil {
+				log.Printf("error saving progress: %v", err)
+			}
+		}
 	}
-
-	return nil
-}
-
-func (d *depInspector) compareDepVersions(ctx context.Context, dep, oldVer, newVer string) error {
-	results, err := d.inspectDepVersions(ctx, dep, oldVer, newVer)
-	if err != nil {
-		return err
+	// a run that wasn't interrupted has nothing to resume
+	if ctx.Err() == nil {
+		if err := progress.remove(); err != nil {
+			log.Printf("error removing progress: %v", err)
+		}
 	}
 
-	r, err := d.compareDepsHTMLOutput(ctx, dep, oldVer, newVer, results)
-	if err != nil {
-		return err
+	outCtx := uninterruptedContext(ctx)
+	dedupeCaps(results)
+	for _, res := range results {
+		if err := d.outputResults(outCtx, res); err != nil {
+			log.Printf("error writing report of %s: %v", res.New.Dep, err)
+		}
 	}
-
-	if d.outputFile != "" {
-		outFile, err := os.Create(d.outputFile)
-		if err != nil {
-			return err
+	// pages of multiple reports need an index even if only one
+	// dependency could be inspected
+	if len(results) > 1 || d.pages && d.multipleReports {
+		if err := d.writeRollup(outCtx, results, notInspected); err != nil {
+			log.Printf("error writing rollup report: %v", err)
 		}
-		defer outFile.Close()
-		_, err = io.Copy(outFile, r)
-		return err
 	}
 
-	return browser.OpenReader(r)
+	if len(notInspected) != 0 {
+		return fmt.Errorf("%w, %s weren't inspected", context.Cause(ctx), strings.Join(notInspected, ", "))
+	}
+	for _, res := range results {
+		if res.New.Metadata.Incomplete != "" {
+			return fmt.Errorf("findings are incomplete: %w", context.Cause(ctx))
+		}
+	}
+	return nil
 }
 
 type inspectResults struct {
@@ -484,37 +1444,158 @@ type inspectResults struct {
 	oldPackages []string
 }
 
-func (d *depInspector) inspectDepVersions(ctx context.Context, dep, oldVer, newVer string) (*inspectResults, error) {
+// inspectDepVersions inspects two versions of a dependency. The old
+// version is inspected in a copy of the main module concurrently with
+// the new version if possible. Like inspectDep, incomplete findings
+// are returned along with an error if ctx is done before they are all
+// found.
+func (d *depInspector) inspectDepVersions(ctx context.Context, dep, oldVer, newVer string) (*depFindings, *depFindings, error) {
+	oldD, cleanup, err := d.newWorkspace(ctx)
+	if err != nil {
+		log.Printf("inspecting versions one at a time: %v", err)
+		return d.inspectDepVersionsSequentially(ctx, dep, oldVer, newVer)
+	}
+	defer cleanup()
+
+	var (
+		oldFindings *depFindings
+		oldErr      error
+		oldDone     = make(chan struct{})
+	)
+	go func() {
+		defer close(oldDone)
+		oldFindings, oldErr = oldD.inspectDep(ctx, d.oldModBackupFiles, dep, oldVer, false, nil)
+	}()
+	// linter issues of the old version are reused when linting the new
+	// version, so the new version waits for them before linting
+	newFindings, newErr := d.inspectDep(ctx, d.newModBackupFiles, dep, newVer, true, func() *depFindings {
+		<-oldDone
+		return oldFindings
+	})
+	<-oldDone
+
+	if oldErr != nil {
+		oldErr = fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, oldVer), oldErr)
+	}
+	if newErr != nil {
+		newErr = fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, newVer), newErr)
+	}
+	err = errors.Join(oldErr, newErr)
+	if err != nil && (oldFindings == nil || newFindings == nil) {
+		return nil, nil, err
+	}
+	// findings found before being interrupted are still compared
+	if reason := oldFindings.Metadata.Incomplete; reason != "" {
+		if newFindings.Metadata.Incomplete != "" {
+			reason += "; " + newFindings.Metadata.Incomplete
+		}
+		newFindings.Metadata.Incomplete = reason
+	}
+	if modified := oldFindings.Metadata.ModifiedModules; len(modified) != 0 {
+		modified = append(slices.Clip(modified), newFindings.Metadata.ModifiedModules...)
+		slices.Sort(modified)
+		newFindings.Metadata.ModifiedModules = slices.Compact(modified)
+	}
+
+	return oldFindings, newFindings, err
+}
+
+func (d *depInspector) inspectDepVersionsSequentially(ctx context.Context, dep, oldVer, newVer string) (*depFindings, *depFindings, error) {
 	// inspect old version
-	oldCaps, oldLintIssues, oldPackages, err := d.inspectDep(ctx, d.oldModBackupFiles, dep, oldVer, false)
+	oldFindings, err := d.inspectDep(ctx, d.oldModBackupFiles, dep, oldVer, false, nil)
 	if err != nil {
-		return nil, fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, oldVer), err)
+		return nil, nil, fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, oldVer), err)
 	}
 
 	// inspect new version
-	newCaps, newLintIssues, newPackages, err := d.inspectDep(ctx, d.newModBackupFiles, dep, newVer, true)
+	newFindings, err := d.inspectDep(ctx, d.newModBackupFiles, dep, newVer, true, knownFindings(oldFindings))
 	if err != nil {
-		return nil, fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, newVer), err)
+		err = fmt.Errorf("inspecting %s: %w", makeVersionStr(dep, newVer), err)
+		// findings found before being interrupted are still compared
+		if newFindings == nil {
+			return nil, nil, err
+		}
 	}
 
-	// process linter issues and capabilities
-	removedCaps, staleCaps, addedCaps := processFindings(oldCaps.CapabilityInfo, newCaps.CapabilityInfo, capsEqual)
-	fixedIssues, staleIssues, newIssues := processFindings(oldLintIssues, newLintIssues, func(a, b *lintIssue) bool {
-		return issuesEqual(dep, a, b)
-	})
+	return oldFindings, newFindings, err
+}
+
+// compareFindings processes the findings of two inspections of the
+// same dependency, determining which findings were removed, stayed the
+// same, and were added.
+func compareFindings(oldFindings, newFindings *depFindings) *inspectResults {
+	dep := newFindings.Dep
+	capKey := capFindingFingerprint
+	issueKey := func(issue *lintIssue) string {
+		return issueMatchKey(dep, issue)
+	}
+	// when comparing against a fork with a different module path,
+	// compare the fork's findings as if they were of the original module
+	if oldFindings.Dep != dep {
+		renamedCaps := make(map[*capability]*capability, len(newFindings.Caps.CapabilityInfo))
+		for _, c := range newFindings.Caps.CapabilityInfo {
+			renamedCaps[c] = renameCapModule(c, dep, oldFindings.Dep)
+		}
+		renamedIssues := make(map[*lintIssue]*lintIssue, len(newFindings.Issues))
+		for _, issue := range newFindings.Issues {
+			renamed := *issue
+			renamed.Text = renameModule(issue.Text, dep, oldFindings.Dep)
+			renamedIssues[issue] = &renamed
+		}
+
+		capKey = func(c *capability) string {
+			return capFindingFingerprint(lookupRenamed(renamedCaps, c))
+		}
+		issueKey = func(issue *lintIssue) string {
+			return issueMatchKey(dep, lookupRenamed(renamedIssues, issue))
+		}
+	}
+	removedCaps, staleCaps, addedCaps := processFindings(oldFindings.Caps.CapabilityInfo, newFindings.Caps.CapabilityInfo, capKey)
+	fixedIssues, staleIssues, newIssues := processFindings(oldFindings.Issues, newFindings.Issues, issueKey)
 
 	return &inspectResults{
-		oldCapMods:  oldCaps.ModuleInfo,
-		newCapMods:  newCaps.ModuleInfo,
+		oldCapMods:  oldFindings.Caps.ModuleInfo,
+		newCapMods:  newFindings.Caps.ModuleInfo,
 		removedCaps: removedCaps,
 		sameCaps:    staleCaps,
 		addedCaps:   addedCaps,
 		fixedIssues: fixedIssues,
 		staleIssues: staleIssues,
 		newIssues:   newIssues,
-		newPackages: newPackages,
-		oldPackages: oldPackages,
-	}, nil
+		newPackages: newFindings.Packages,
+		oldPackages: oldFindings.Packages,
+	}
+}
+
+// renameCapModule returns a copy of a capability with the module path
+// from replaced with to.
+func renameCapModule(c *capability, from, to string) *capability {
+	renamed := *c
+	renamed.PackageDir = renameModule(c.PackageDir, from, to)
+	renamed.Path = make([]functionCall, len(c.Path))
+	for i, call := range c.Path {
+		call.Name = renameModule(call.Name, from, to)
+		renamed.Path[i] = call
+	}
+	return &renamed
+}
+
+// renameModule replaces the module path from with to in package paths
+// and function names in s.
+func renameModule(s, from, to string) string {
+	if s == from {
+		return to
+	}
+	return strings.NewReplacer(from+"/", to+"/", from+".", to+".").Replace(s)
+}
+
+// lookupRenamed returns the renamed version of a finding if it was
+// renamed.
+func lookupRenamed[T any](renamed map[*T]*T, finding *T) *T {
+	if r, ok := renamed[finding]; ok {
+		return r
+	}
+	return finding
 }
 
 func (d *depInspector) parseAndBackupGoMod(modBackupFiles *modFilePair) (_ *modfile.File, ret error) {
@@ -615,6 +1696,25 @@ func (d *depInspector) closeFiles() error {
 	return errors.Join(errs...)
 }
 
+// resetModFiles restores the original go.mod and go.sum and discards
+// backups made when setting up dependency versions, so another
+// dependency can be inspected from a clean state.
+func (d *depInspector) resetModFiles() error {
+	if err := d.restoreGoMod(d.modBackupFiles); err != nil {
+		return err
+	}
+	for _, filePair := range []*modFilePair{d.oldModBackupFiles, d.newModBackupFiles} {
+		if filePair.modFile != nil && filePair.sumFile != nil {
+			if err := filePair.Close(); err != nil {
+				return err
+			}
+		}
+		*filePair = modFilePair{}
+	}
+
+	return nil
+}
+
 func trimNewline(s string) string {
 	if len(s) != 0 && s[len(s)-1] == '\n' {
 		return s[:len(s)-1]
@@ -636,6 +1736,24 @@ func (d *depInspector) getGoModCache(ctx context.Context) (string, error) {
 	return sb.String()[:sb.Len()-1], nil
 }
 
+// getGoEnv returns the effective values of the Go environment
+// variables in goEnvVars.
+func (d *depInspector) getGoEnv(ctx context.Context) (map[string]string, error) {
+	var output bytes.Buffer
+	cmd := append([]string{"go", "env", "-json"}, goEnvVars...)
+	err := d.runCommand(ctx, &output, cmd...)
+	if err != nil {
+		return nil, fmt.Errorf("getting Go environment: %w", err)
+	}
+
+	var goEnv map[string]string
+	if err := json.Unmarshal(output.Bytes(), &goEnv); err != nil {
+		return nil, fmt.Errorf("decoding Go environment: %w", err)
+	}
+
+	return goEnv, nil
+}
+
 func (d *depInspector) setupDepVersion(ctx context.Context, modBackupFiles *modFilePair, versionStr string, newDepVersion bool) error {
 	if modBackupFiles.modFile != nil && modBackupFiles.sumFile != nil {
 		return d.restoreGoMod(modBackupFiles)
@@ -661,7 +1779,10 @@ func (d *depInspector) setupDepVersion(ctx context.Context, modBackupFiles *modF
 	return nil
 }
 
-func processFindings[T any](oldVerFindings, newVerFindings []T, equal func(a, b T) bool) ([]T, []T, []T) {
+// processFindings determines which findings of an old version were
+// removed, stayed the same and were added in a new version. Findings
+// are the same if key returns the same string for them.
+func processFindings[T any](oldVerFindings, newVerFindings []T, key func(T) string) ([]T, []T, []T) {
 	var (
 		allFindingsLen = len(oldVerFindings) + len(newVerFindings)
 
@@ -670,21 +1791,32 @@ func processFindings[T any](oldVerFindings, newVerFindings []T, equal func(a, b
 		newFindings     = make([]T, 0, allFindingsLen/4)
 	)
 
+	// index the first finding with every key
+	oldIdx := make(map[string]int, len(oldVerFindings))
+	for i, finding := range oldVerFindings {
+		k := key(finding)
+		if _, ok := oldIdx[k]; !ok {
+			oldIdx[k] = i
+		}
+	}
+	newIdx := make(map[string]int, len(newVerFindings))
+	for i, finding := range newVerFindings {
+		k := key(finding)
+		if _, ok := newIdx[k]; !ok {
+			newIdx[k] = i
+		}
+	}
+
 	for _, finding := range oldVerFindings {
-		idx := slices.IndexFunc(newVerFindings, func(finding2 T) bool {
-			return equal(finding, finding2)
-		})
-		if idx == -1 {
+		idx, ok := newIdx[key(finding)]
+		if !ok {
 			removedFindings = append(removedFindings, finding)
 		} else {
 			staleFindings = append(staleFindings, newVerFindings[idx])
 		}
 	}
 	for _, finding := range newVerFindings {
-		idx := slices.IndexFunc(oldVerFindings, func(finding2 T) bool {
-			return equal(finding, finding2)
-		})
-		if idx == -1 {
+		if _, ok := oldIdx[key(finding)]; !ok {
 			newFindings = append(newFindings, finding)
 		}
 	}
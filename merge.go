@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+)
+
+func mergeCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dep-inspector [flags] merge results.json...")
+	}
+
+	var (
+		results      []*savedResults
+		notInspected []string
+	)
+	seen := make(map[string]bool)
+	for _, path := range args {
+		contents, err := d.readInput(ctx, path)
+		if err != nil {
+			return err
+		}
+		fileResults, fileNotInspected, err := decodeResults(bytes.NewReader(contents))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, res := range fileResults {
+			key := makeVersionStr(res.New.Dep, res.New.Version)
+			if res.Old != nil {
+				key = res.Old.Version + " " + key
+			}
+			// every shard inspects the dependency that was compared
+			// if it's the only one that changed
+			if seen[key] {
+				log.Printf("skipping results of %s in %s, they were already merged", makeVersionStr(res.New.Dep, res.New.Version), path)
+				continue
+			}
+			seen[key] = true
+			results = append(results, res)
+		}
+		for _, dep := range fileNotInspected {
+			if !slices.Contains(notInspected, dep) {
+				notInspected = append(notInspected, dep)
+			}
+		}
+	}
+	if len(results) == 0 {
+		return errors.New("no results to merge")
+	}
+	// source links are checked against the module cache
+	var err error
+	d.modCache, err = d.getGoModCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	// capabilities reported by dependencies in different shards are
+	// only reported once, like in a single run
+	dedupeCaps(results)
+	return d.outputAllResults(ctx, results, notInspected)
+}
+
+// decodeResults decodes the JSON results of one or more dependencies
+// from r, as written with -format json. Overviews of multiple results
+// are skipped, but the dependencies they list as not inspected are
+// returned.
+func decodeResults(r io.Reader) ([]*savedResults, []string, error) {
+	var (
+		results      []*savedResults
+		notInspected []string
+	)
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return results, notInspected, fmt.Errorf("decoding results: %w", err)
+		}
+
+		res := new(savedResults)
+		if err := json.Unmarshal(raw, res); err != nil {
+			return results, notInspected, fmt.Errorf("decoding results: %w", err)
+		}
+		if res.New != nil {
+			results = append(results, res)
+			continue
+		}
+		var rollup rollupReport
+		if err := json.Unmarshal(raw, &rollup); err != nil {
+			return results, notInspected, fmt.Errorf("decoding overview: %w", err)
+		}
+		notInspected = append(notInspected, rollup.NotInspected...)
+	}
+
+	return results, notInspected, nil
+}
+
+// outputAllResults outputs the results of multiple dependencies that
+// were inspected elsewhere, and an overview of them if there is more
+// than one.
+func (d *depInspector) outputAllResults(ctx context.Context, results []*savedResults, notInspected []string) error {
+	d.multipleReports = len(results) > 1
+	for _, res := range results {
+		if err := d.outputResults(ctx, res); err != nil {
+			log.Printf("error writing report of %s: %v", res.New.Dep, err)
+		}
+	}
+	if d.multipleReports || len(notInspected) != 0 {
+		if err := d.writeRollup(ctx, results, notInspected); err != nil {
+			log.Printf("error writing rollup report: %v", err)
+		}
+	}
+	if len(notInspected) != 0 {
+		return fmt.Errorf("results are incomplete, %s weren't inspected", strings.Join(notInspected, ", "))
+	}
+
+	return nil
+}
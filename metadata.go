@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io/fs"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// analysisTools are the external tools dep-inspector runs to find
+// capabilities and linter issues.
+var analysisTools = []string{"capslock", "golangci-lint", "staticcheck"}
+
+// redactedFlags are flags whose values may contain credentials, so
+// they aren't recorded in reports.
+var redactedFlags = []string{"webhook", "webhook-secret", "slack-webhook", "discord-webhook", "goauth"}
+
+// reportMetadata describes how a report was produced.
+type reportMetadata struct {
+	Time         time.Time
+	Version      string
+	ToolVersions map[string]string
+	GoEnv        map[string]string
+	// OS and Arch are the platform dep-inspector ran on
+	OS   string `json:",omitempty"`
+	Arch string `json:",omitempty"`
+	// ConfigHashes are the SHA-256 hashes of the capability maps and
+	// golangci-lint config findings were produced with
+	ConfigHashes map[string]string `json:",omitempty"`
+	// Flags are the flags dep-inspector was run with
+	Flags []string `json:",omitempty"`
+	// CapslockGranularity is the granularity capslock reported
+	// capabilities at, empty if it was capslock's default
+	CapslockGranularity string `json:",omitempty"`
+	// Incomplete is why findings are missing if dep-inspector was
+	// interrupted before it finished
+	Incomplete string `json:",omitempty"`
+	// ModifiedModules are the module versions whose copies in the
+	// module cache were modified since they were downloaded, only set
+	// if -allow-modified-cache was passed
+	ModifiedModules []string `json:",omitempty"`
+}
+
+func (d *depInspector) buildMetadata() reportMetadata {
+	return reportMetadata{
+		Time:                time.Now().UTC(),
+		Version:             version,
+		ToolVersions:        d.toolVersions,
+		GoEnv:               d.goEnv,
+		OS:                  runtime.GOOS,
+		Arch:                runtime.GOARCH,
+		ConfigHashes:        d.configHashes(),
+		Flags:               d.runFlags,
+		CapslockGranularity: d.granularity,
+	}
+}
+
+// configHashes returns the hashes of the embedded configs of the
+// analysis tools, keyed by their paths.
+var configHashes = sync.OnceValue(func() map[string]string {
+	hashes := make(map[string]string)
+	hash := func(contents []byte) string {
+		sum := sha256.Sum256(contents)
+		return hex.EncodeToString(sum[:])
+	}
+
+	hashes[golangciCfgEmbedPath] = hash(golangciCfgContents)
+	// the embedded file system can always be read
+	_ = fs.WalkDir(capMaps, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := fs.ReadFile(capMaps, path)
+		if err != nil {
+			return err
+		}
+		hashes[path] = hash(contents)
+		return nil
+	})
+
+	return hashes
+})
+
+// passedFlags returns the flags that were passed on the command line in
+// the form -name=value. Values of flags that may contain credentials
+// are redacted.
+func passedFlags() []string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		if slices.Contains(redactedFlags, f.Name) {
+			value = "REDACTED"
+		}
+		flags = append(flags, "-"+f.Name+"="+value)
+	})
+	return flags
+}
+
+// getToolVersions returns the versions of Go and the analysis tools
+// that are installed.
+func (d *depInspector) getToolVersions(ctx context.Context) map[string]string {
+	versions := make(map[string]string, len(analysisTools)+1)
+
+	var output bytes.Buffer
+	versions["go"] = "unknown"
+	if err := d.runCommand(ctx, &output, "go", "version"); err == nil {
+		// output is in the form 'go version go1.22.3 linux/amd64'
+		if fields := strings.Fields(output.String()); len(fields) >= 3 {
+			versions["go"] = fields[2]
+		}
+	}
+
+	for _, tool := range analysisTools {
+		versions[tool] = "unknown"
+		toolPath, err := exec.LookPath(tool)
+		if err != nil {
+			versions[tool] = "not installed"
+			continue
+		}
+
+		output.Reset()
+		if err := d.runCommand(ctx, &output, "go", "version", "-m", toolPath); err != nil {
+			continue
+		}
+		for _, line := range strings.Split(output.String(), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && fields[0] == "mod" {
+				versions[tool] = fields[2]
+				break
+			}
+		}
+	}
+
+	return versions
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "dep_inspector"
+
+// inspectorMetrics are Prometheus metrics of inspections. A nil
+// *inspectorMetrics is valid and records nothing, metrics are only
+// collected when running as a long-lived service.
+type inspectorMetrics struct {
+	registry *prometheus.Registry
+
+	inspections      *prometheus.CounterVec
+	analyzerDuration *prometheus.HistogramVec
+	modCacheLookups  *prometheus.CounterVec
+	capabilities     *prometheus.GaugeVec
+	issues           *prometheus.GaugeVec
+}
+
+func newInspectorMetrics() *inspectorMetrics {
+	m := &inspectorMetrics{
+		registry: prometheus.NewRegistry(),
+		inspections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "inspections_total",
+			Help:      "Number of dependency versions inspected, by result.",
+		}, []string{"result"}),
+		analyzerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "analyzer_duration_seconds",
+			Help:      "Time taken by each analyzer to analyze a dependency version.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+		}, []string{"analyzer"}),
+		modCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "module_cache_lookups_total",
+			Help:      "Number of dependency versions that were or weren't already in the module cache.",
+		}, []string{"result"}),
+		capabilities: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capabilities",
+			Help:      "Number of capabilities found in the last inspected version of a dependency.",
+		}, []string{"dep", "capability", "capability_type"}),
+		issues: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "issues",
+			Help:      "Number of linter issues found in the last inspected version of a dependency.",
+		}, []string{"dep", "linter"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.inspections,
+		m.analyzerDuration,
+		m.modCacheLookups,
+		m.capabilities,
+		m.issues,
+	)
+
+	return m
+}
+
+func (m *inspectorMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *inspectorMetrics) observeInspection(findings *depFindings, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.inspections.WithLabelValues("failure").Inc()
+		return
+	}
+	m.inspections.WithLabelValues("success").Inc()
+
+	dep := prometheus.Labels{"dep": findings.Dep}
+	m.capabilities.DeletePartialMatch(dep)
+	for _, c := range findings.Caps.CapabilityInfo {
+		m.capabilities.WithLabelValues(findings.Dep, c.Capability, strings.ToLower(capTypeName(c.CapabilityType))).Inc()
+	}
+	m.issues.DeletePartialMatch(dep)
+	for _, issue := range findings.Issues {
+		m.issues.WithLabelValues(findings.Dep, issue.FromLinter).Inc()
+	}
+}
+
+func (m *inspectorMetrics) observeAnalyzer(analyzer string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.analyzerDuration.WithLabelValues(analyzer).Observe(time.Since(start).Seconds())
+}
+
+// observeModCacheLookup records whether a dependency version has
+// already been downloaded to the module cache.
+func (m *inspectorMetrics) observeModCacheLookup(modCache, dep, version string) {
+	if m == nil {
+		return
+	}
+	zipPath, err := moduleZipPath(modCache, dep, version)
+	if err != nil {
+		return
+	}
+
+	result := "hit"
+	if _, err := os.Stat(zipPath); err != nil {
+		result = "miss"
+	}
+	m.modCacheLookups.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// moduleChanges are how the modules required by the main module
+// changed between compared versions of a dependency.
+type moduleChanges struct {
+	OldTotal int
+	NewTotal int
+	// Added and Removed are module versions that are newly required
+	// and no longer required
+	Added   []string
+	Removed []string
+}
+
+// requiredModules returns the module versions a go.mod file requires.
+func requiredModules(modFilePath string) ([]string, error) {
+	contents, err := os.ReadFile(modFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod: %w", err)
+	}
+	modFile, err := modfile.ParseLax(modFilePath, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod: %w", err)
+	}
+
+	mods := make([]string, 0, len(modFile.Require))
+	for _, req := range modFile.Require {
+		mods = append(mods, makeVersionStr(req.Mod.Path, req.Mod.Version))
+	}
+	slices.Sort(mods)
+
+	return mods, nil
+}
+
+// compareModules finds which modules are newly required and no longer
+// required. Modules whose versions changed are neither.
+func compareModules(oldMods, newMods []string) *moduleChanges {
+	if oldMods == nil || newMods == nil {
+		return nil
+	}
+
+	changes := &moduleChanges{
+		OldTotal: len(oldMods),
+		NewTotal: len(newMods),
+	}
+	hasPath := func(mods []string, mod string) bool {
+		path, _, _ := strings.Cut(mod, "@")
+		return slices.ContainsFunc(mods, func(m string) bool {
+			return strings.HasPrefix(m, path+"@")
+		})
+	}
+	for _, mod := range newMods {
+		if !hasPath(oldMods, mod) {
+			changes.Added = append(changes.Added, mod)
+		}
+	}
+	for _, mod := range oldMods {
+		if !hasPath(newMods, mod) {
+			changes.Removed = append(changes.Removed, mod)
+		}
+	}
+
+	return changes
+}
+
+// goSumModule is a module whose go.sum entries changed.
+type goSumModule struct {
+	Path        string
+	OldVersions []string
+	NewVersions []string
+	// AddedHashes and RemovedHashes are the number of module zip hashes
+	// of the module that were added and removed
+	AddedHashes   int
+	RemovedHashes int
+}
+
+// goSumChanges summarizes how go.sum changed between compared versions
+// of a dependency.
+type goSumChanges struct {
+	Added   []goSumModule
+	Removed []goSumModule
+	Changed []goSumModule
+	// Mismatched are module versions whose checksums differ between
+	// go.sum files. The contents of a module version should never
+	// change, so this means one of them was tampered with.
+	Mismatched []string
+}
+
+// goSumEntries returns the lines of the go.sum file next to a go.mod
+// file, or nil if there isn't one.
+func goSumEntries(modFilePath string) ([]string, error) {
+	contents, err := os.ReadFile(filepath.Join(filepath.Dir(modFilePath), "go.sum"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading go.sum: %w", err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		entries = append(entries, strings.Join(fields, " "))
+	}
+	slices.Sort(entries)
+
+	return entries, nil
+}
+
+// goSumHashes returns the module zip hashes of mods from go.sum
+// entries, keyed by module version. Only the hashes of required
+// modules are kept so findings don't include every line of go.sum.
+func goSumHashes(goSum, mods []string) map[string]string {
+	if goSum == nil {
+		return nil
+	}
+
+	required := make(map[string]bool, len(mods))
+	for _, mod := range mods {
+		required[mod] = true
+	}
+	hashes := make(map[string]string, len(mods))
+	for _, entry := range goSum {
+		path, rest, _ := strings.Cut(entry, " ")
+		version, hash, _ := strings.Cut(rest, " ")
+		// hashes of go.mod files have a '/go.mod' version suffix
+		if mod := makeVersionStr(path, version); required[mod] {
+			hashes[mod] = hash
+		}
+	}
+
+	return hashes
+}
+
+// moduleZipHash returns the hash of a module version's zip from go.sum
+// entries, or an empty string if there isn't one.
+func moduleZipHash(goSum []string, dep, version string) string {
+	prefix := dep + " " + version + " "
+	for _, entry := range goSum {
+		if hash, ok := strings.CutPrefix(entry, prefix); ok {
+			return hash
+		}
+	}
+	return ""
+}
+
+// contentChanged returns true if the module zip hash findings were
+// recorded with differs from the current one. Hashes that are unknown
+// are not considered changed.
+func contentChanged(recorded, current string) bool {
+	return recorded != "" && current != "" && recorded != current
+}
+
+// compareGoSums summarizes the differences between the module zip
+// hashes of two go.sum files.
+func compareGoSums(oldSums, newSums map[string]string) *goSumChanges {
+	if oldSums == nil || newSums == nil {
+		return nil
+	}
+
+	changes := &goSumChanges{}
+	mods := make(map[string]*goSumModule)
+	module := func(path string) *goSumModule {
+		mod, ok := mods[path]
+		if !ok {
+			mod = &goSumModule{Path: path}
+			mods[path] = mod
+		}
+		return mod
+	}
+	for modVer, hash := range oldSums {
+		path, version, _ := strings.Cut(modVer, "@")
+		mod := module(path)
+		mod.OldVersions = append(mod.OldVersions, version)
+		newHash, ok := newSums[modVer]
+		if !ok {
+			mod.RemovedHashes++
+		} else if newHash != hash {
+			changes.Mismatched = append(changes.Mismatched, modVer)
+		}
+	}
+	for modVer := range newSums {
+		path, version, _ := strings.Cut(modVer, "@")
+		mod := module(path)
+		mod.NewVersions = append(mod.NewVersions, version)
+		if _, ok := oldSums[modVer]; !ok {
+			mod.AddedHashes++
+		}
+	}
+
+	for _, mod := range mods {
+		if mod.AddedHashes == 0 && mod.RemovedHashes == 0 {
+			continue
+		}
+		slices.SortFunc(mod.OldVersions, semver.Compare)
+		slices.SortFunc(mod.NewVersions, semver.Compare)
+		switch {
+		case len(mod.OldVersions) == 0:
+			changes.Added = append(changes.Added, *mod)
+		case len(mod.NewVersions) == 0:
+			changes.Removed = append(changes.Removed, *mod)
+		default:
+			changes.Changed = append(changes.Changed, *mod)
+		}
+	}
+	byPath := func(a, b goSumModule) int {
+		return strings.Compare(a.Path, b.Path)
+	}
+	slices.SortFunc(changes.Added, byPath)
+	slices.SortFunc(changes.Removed, byPath)
+	slices.SortFunc(changes.Changed, byPath)
+	slices.Sort(changes.Mismatched)
+
+	return changes
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareGoSums(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldSums map[string]string
+		newSums map[string]string
+		want    *goSumChanges
+	}{
+		{
+			name:    "old hashes unknown",
+			newSums: map[string]string{"example.com/a@v1.0.0": "h1:a"},
+			want:    nil,
+		},
+		{
+			name:    "new hashes unknown",
+			oldSums: map[string]string{"example.com/a@v1.0.0": "h1:a"},
+			want:    nil,
+		},
+		{
+			name:    "unchanged",
+			oldSums: map[string]string{"example.com/a@v1.0.0": "h1:a"},
+			newSums: map[string]string{"example.com/a@v1.0.0": "h1:a"},
+			want:    &goSumChanges{},
+		},
+		{
+			name: "added and removed",
+			oldSums: map[string]string{
+				"example.com/a@v1.0.0":    "h1:a",
+				"example.com/gone@v0.1.0": "h1:gone",
+			},
+			newSums: map[string]string{
+				"example.com/a@v1.0.0":   "h1:a",
+				"example.com/new@v0.2.0": "h1:new",
+			},
+			want: &goSumChanges{
+				Added: []goSumModule{
+					{Path: "example.com/new", NewVersions: []string{"v0.2.0"}, AddedHashes: 1},
+				},
+				Removed: []goSumModule{
+					{Path: "example.com/gone", OldVersions: []string{"v0.1.0"}, RemovedHashes: 1},
+				},
+			},
+		},
+		{
+			name: "changed versions are sorted",
+			oldSums: map[string]string{
+				"example.com/a@v1.10.0": "h1:a10",
+				"example.com/a@v1.2.0":  "h1:a2",
+			},
+			newSums: map[string]string{
+				"example.com/a@v1.2.0":  "h1:a2",
+				"example.com/a@v1.11.0": "h1:a11",
+			},
+			want: &goSumChanges{
+				Changed: []goSumModule{
+					{
+						Path:          "example.com/a",
+						OldVersions:   []string{"v1.2.0", "v1.10.0"},
+						NewVersions:   []string{"v1.2.0", "v1.11.0"},
+						AddedHashes:   1,
+						RemovedHashes: 1,
+					},
+				},
+			},
+		},
+		{
+			name: "mismatched hash of the same version",
+			oldSums: map[string]string{
+				"example.com/a@v1.0.0": "h1:a",
+				"example.com/b@v1.0.0": "h1:b",
+			},
+			newSums: map[string]string{
+				"example.com/a@v1.0.0": "h1:tampered",
+				"example.com/b@v1.0.0": "h1:b",
+			},
+			want: &goSumChanges{
+				Mismatched: []string{"example.com/a@v1.0.0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareGoSums(tt.oldSums, tt.newSums)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareGoSums() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
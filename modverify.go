@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// modifiedSuffix is how 'go mod verify' reports module versions whose
+// extracted directory or zip changed since they were downloaded, such
+// as 'example.com/mod v1.0.0: dir has been modified (/path)'.
+const modifiedSuffix = " has been modified"
+
+// verifyModCache checks that the module cache's copies of a dependency
+// version and the other modules the main module requires weren't
+// modified since they were downloaded. Inspecting a modified copy
+// would report findings of code that isn't what go.sum covers. The
+// dependency's extracted directory is hashed and compared with go.sum
+// directly, as 'go mod verify' only compares it with hashes stored in
+// the module cache that could have been modified along with it.
+// Module versions that were modified are returned.
+func (d *depInspector) verifyModCache(ctx context.Context, dep, version string, goSum []string) ([]string, error) {
+	var modified []string
+
+	// dependencies replaced with local directories have no hash
+	if sum := moduleZipHash(goSum, dep, version); sum != "" {
+		dir, err := moduleDirPath(d.modCache, dep, version)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := dirhash.HashDir(dir, dep+"@"+version, dirhash.Hash1)
+		if err != nil {
+			return nil, fmt.Errorf("hashing module directory: %w", err)
+		}
+		if hash != sum {
+			modified = append(modified, makeVersionStr(dep, version))
+		}
+	}
+
+	cmd, errBuf := d.buildCommand(ctx, nil, "go", "mod", "verify")
+	if err := cmd.Run(); err != nil {
+		verifyModified := parseModVerifyOutput(errBuf.String())
+		if len(verifyModified) == 0 {
+			return nil, formatCmdErr(ctx, cmd, err, errBuf)
+		}
+		modified = append(modified, verifyModified...)
+	}
+
+	slices.Sort(modified)
+	return slices.Compact(modified), nil
+}
+
+// parseModVerifyOutput returns the module versions 'go mod verify'
+// reported were modified.
+func parseModVerifyOutput(output string) []string {
+	var modified []string
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		line := s.Text()
+		if !strings.Contains(line, modifiedSuffix) {
+			continue
+		}
+		mod, _, _ := strings.Cut(line, ":")
+		dep, version, ok := strings.Cut(mod, " ")
+		if !ok {
+			continue
+		}
+		modified = append(modified, makeVersionStr(dep, version))
+	}
+	return modified
+}
+
+// checkModCache verifies the module cache before a dependency version
+// is inspected. If copies of modules were modified an error is
+// returned, unless -allow-modified-cache was passed in which case the
+// modified module versions are returned so reports are annotated.
+func (d *depInspector) checkModCache(ctx context.Context, dep, version string, goSum []string) ([]string, error) {
+	modified, err := d.verifyModCache(ctx, dep, version, goSum)
+	if err != nil {
+		return nil, fmt.Errorf("verifying module cache: %w", err)
+	}
+	if len(modified) == 0 {
+		return nil, nil
+	}
+	if !d.allowModCache {
+		return nil, fmt.Errorf("module cache copies of %s were modified since they were downloaded, findings would not be of the code go.sum covers; run 'go clean -modcache' or pass -allow-modified-cache", strings.Join(modified, ", "))
+	}
+
+	log.Printf("WARNING: module cache copies of %s were modified since they were downloaded", strings.Join(modified, ", "))
+	return modified, nil
+}
+
+// moduleDirPath returns the directory a module version is extracted
+// to in the module cache.
+func moduleDirPath(modCache, dep, version string) (string, error) {
+	escPath, err := module.EscapePath(dep)
+	if err != nil {
+		return "", err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCache, escPath+"@"+escVer), nil
+}
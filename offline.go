@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// cacheOnlyEnv returns the Go environment that makes the go command
+// only use the module cache, or the file:// proxy -goproxy is set to.
+// Modules matching GOPRIVATE would be fetched directly even with
+// GOPROXY=off, and the checksum database and toolchain downloads need
+// network access too.
+func (d *depInspector) cacheOnlyEnv() []string {
+	goProxy := "off"
+	if isFileProxy(d.goProxy) {
+		goProxy = d.goProxy
+	}
+	return []string{
+		"GOPROXY=" + goProxy,
+		"GONOPROXY=none",
+		"GOSUMDB=off",
+		"GOVCS=*:off",
+		"GOTOOLCHAIN=local",
+	}
+}
+
+func isFileProxy(goProxy string) bool {
+	return strings.HasPrefix(goProxy, "file://") && !strings.ContainsAny(goProxy, ",|")
+}
+
+// cacheOnlyConflicts returns the flags that were passed that need
+// network access, which -cache-only doesn't allow.
+func (d *depInspector) cacheOnlyConflicts() []string {
+	var conflicts []string
+	set := func(name string, isSet bool) {
+		if isSet {
+			conflicts = append(conflicts, "-"+name)
+		}
+	}
+	set("goproxy", d.goProxy != "" && !isFileProxy(d.goProxy))
+	set("ownership", d.ownership)
+	set("check-proxies", d.checkProxies)
+	set("compare-proxies", d.compareProxies)
+	set("compare-vcs", d.compareVCS)
+	set("contributors", d.contributors)
+	set("release-notes", d.releaseNotes)
+	set("vulns", d.vulns)
+	set("git-credentials", d.gitCredentials)
+	set("pr-comment", d.prComment != "")
+	set("upload", d.upload != "")
+	set("webhook", len(d.webhooks) != 0)
+	set("slack-webhook", d.slackWebhook != "")
+	set("discord-webhook", d.discordWebhook != "")
+	set("approved-versions", isURL(d.approvedVersions))
+	for _, src := range d.reviews.sources {
+		if isURL(src) || strings.HasPrefix(src, "git+") {
+			set("review-source", true)
+			break
+		}
+	}
+
+	return conflicts
+}
+
+// checkCached returns an error listing the module versions that aren't
+// in the module cache or the file:// proxy. The inspected versions
+// need their module zips, while the modules the main module requires
+// only need their go.mod files to build the module graph.
+func (d *depInspector) checkCached(dep string, versions ...string) error {
+	var missing []string
+	has := func(modPath, version, ext string) error {
+		ok, err := d.isCached(modPath, version, ext)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, makeVersionStr(modPath, version)+" ("+ext+")")
+		}
+		return nil
+	}
+
+	for _, version := range versions {
+		if err := has(dep, version, ".zip"); err != nil {
+			return err
+		}
+	}
+	for _, req := range d.parsedModFile.Require {
+		if req.Mod.Path == dep {
+			continue
+		}
+		if err := has(req.Mod.Path, req.Mod.Version, ".mod"); err != nil {
+			return err
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("-cache-only was passed but these module versions aren't cached:\n\t%s", strings.Join(missing, "\n\t"))
+	}
+
+	return nil
+}
+
+// isCached returns true if a file of a module version with extension
+// ext is in the module cache or the file:// proxy.
+func (d *depInspector) isCached(modPath, version, ext string) (bool, error) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return false, err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return false, err
+	}
+	file := filepath.Join(escPath, "@v", escVer+ext)
+
+	dirs := []string{filepath.Join(d.modCache, "cache", "download")}
+	if isFileProxy(d.goProxy) {
+		u, err := url.Parse(d.goProxy)
+		if err != nil {
+			return false, fmt.Errorf("parsing -goproxy: %w", err)
+		}
+		dirs = append(dirs, filepath.FromSlash(u.Path))
+	}
+	for _, dir := range dirs {
+		_, err := os.Stat(filepath.Join(dir, file))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/browser"
+)
+
+const (
+	formatHTML     = "html"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatSARIF    = "sarif"
+)
+
+var outputFormats = []string{formatHTML, formatJSON, formatMarkdown, formatSARIF}
+
+var formatExts = map[string]string{
+	formatHTML:     ".html",
+	formatJSON:     ".json",
+	formatMarkdown: ".md",
+	formatSARIF:    ".sarif",
+}
+
+var contentTypes = map[string]string{
+	formatHTML:     "text/html; charset=utf-8",
+	formatJSON:     "application/json",
+	formatMarkdown: "text/markdown; charset=utf-8",
+	formatSARIF:    "application/sarif+json",
+}
+
+// outputResults renders results and writes them, along with any
+// summaries that were requested.
+func (d *depInspector) outputResults(ctx context.Context, res *savedResults) error {
+	// only findings are attested, not rendered reports
+	if d.sign && d.format != formatJSON {
+		return errors.New("-sign requires -format json")
+	}
+
+	res = d.prepareResults(res)
+	if res.Old != nil {
+		if sums := compareGoSums(res.Old.GoSumHashes, res.New.GoSumHashes); sums != nil {
+			for _, mod := range sums.Mismatched {
+				log.Printf("WARNING: checksum of %s differs between go.sum files of compared versions", mod)
+			}
+		}
+		if change := compareLicenses(res.Old.Licenses, res.New.Licenses); change != nil {
+			log.Printf("WARNING: license of %s changed from %s to %s",
+				res.New.Dep,
+				strings.Join(change.Old, ", "),
+				strings.Join(change.New, ", "),
+			)
+		}
+		for _, change := range compareOwnership(res.Old.Ownership, res.New.Ownership) {
+			log.Printf("WARNING: %s of %s changed from %s to %s", strings.ToLower(change.Signal), res.New.Dep, change.Old, change.New)
+		}
+		if changes := compareEnvVars(res.Old.Source, res.New.Source); changes != nil {
+			for _, env := range changes.Added {
+				if env.Sensitive {
+					log.Printf("WARNING: %s now reads the environment variable %s which may hold credentials", res.New.Dep, env.Name)
+				}
+			}
+		}
+		if changes := comparePaths(res.Old.Source, res.New.Source); changes != nil {
+			for _, access := range changes.Added {
+				if access.Sensitive {
+					log.Printf("WARNING: %s now accesses the sensitive path %s", res.New.Dep, access.Path)
+				}
+			}
+		}
+		if changes := compareLowLevelAccess(res.Old.Source, res.New.Source); changes != nil {
+			for _, access := range changes.Added {
+				log.Printf("WARNING: %s now has low level system access: %s %s", res.New.Dep, access.Kind, access.Detail)
+			}
+		}
+		if changes := compareDownloadExec(res.Old.Source, res.New.Source); changes != nil {
+			for _, flow := range changes.Added {
+				log.Printf("WARNING: %s now has network data from %s that is %s in %s", res.New.Dep, flow.Source, flow.Sink, flow.Func)
+			}
+		}
+		if changes := compareGating(res.Old.Source, res.New.Source); changes != nil {
+			for _, gc := range changes.Added {
+				log.Printf("WARNING: %s has a new condition that depends on the %s: %s", res.New.Dep, gc.Kind, gc.Condition)
+			}
+		}
+	}
+	r, err := d.renderResults(ctx, d.format, res)
+	if err != nil {
+		return err
+	}
+	if d.upload != "" {
+		report, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if _, err := d.uploadResults(ctx, res, report); err != nil {
+			return err
+		}
+		r = bytes.NewReader(report)
+	}
+	if d.pages && d.sourceDiff && res.Old != nil {
+		// the report links to the diff if it exists
+		if err := d.writeSourceDiff(ctx, res); err != nil {
+			return err
+		}
+	}
+	if reportPath := d.reportPath(res.New.Dep); isPDFPath(reportPath) {
+		r, err = d.printPDF(ctx, r)
+		if err != nil {
+			return err
+		}
+	} else if isZipPath(reportPath) {
+		r, err = d.bundleReport(ctx, reportPath, res, r)
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.writeReport(res.New.Dep, r); err != nil {
+		return err
+	}
+	// runs with multiple reports write an overview of them all when
+	// they're done
+	if d.pages && !d.multipleReports {
+		if err := d.writeRollup(ctx, []*savedResults{res}, nil); err != nil {
+			return err
+		}
+	}
+	if d.jsonSidecar {
+		if err := writeSidecar(d.reportPath(res.New.Dep), res); err != nil {
+			return err
+		}
+	}
+	if d.sign {
+		pred := d.newFindingsPredicate(res.New.Metadata, res.Old, res.New)
+		if err := d.attestFindings(ctx, d.reportPath(res.New.Dep), pred); err != nil {
+			return err
+		}
+	}
+	if d.ghaSummary {
+		if err := writeGHASummary(res); err != nil {
+			return err
+		}
+	}
+	if d.prComment != "" {
+		if err := d.postPRComment(ctx, res); err != nil {
+			return err
+		}
+	}
+	for _, violation := range d.checkPolicy(res) {
+		log.Printf("policy violation: %s", violation)
+		d.policyViolated = true
+	}
+
+	return nil
+}
+
+// prepareResults sets the severities, risk scores and classifications
+// of findings, marks findings in generated files and removes findings that were filtered out.
+func (d *depInspector) prepareResults(res *savedResults) *savedResults {
+	d.severities.apply(res.Old)
+	d.severities.apply(res.New)
+	markGenerated(res.Old, d.generatedCode)
+	markGenerated(res.New, d.generatedCode)
+	d.risk.apply(res.Old)
+	d.risk.apply(res.New)
+	d.triage.apply(res.Old)
+	d.triage.apply(res.New)
+	d.comments.apply(res.Old)
+	d.comments.apply(res.New)
+	if d.classify {
+		classify(res.Old)
+		classify(res.New)
+	}
+	return d.filter.filterResults(res)
+}
+
+// renderResults renders results in an output format.
+func (d *depInspector) renderResults(ctx context.Context, format string, res *savedResults) (io.Reader, error) {
+	res = d.prepareResults(res)
+	if d.summary {
+		return d.summaryOutput(format, res)
+	}
+
+	switch format {
+	case formatJSON:
+		return jsonOutput(res)
+	case formatSARIF:
+		return sarifOutput(res)
+	}
+
+	extras := d.buildReportExtras(ctx, res)
+	if format == formatMarkdown {
+		return markdownOutput(res, d.onlyChanges, d.stdlibFrames, extras)
+	}
+	if res.Old == nil {
+		return d.singleDepHTMLOutput(ctx, res.New, extras)
+	}
+	return d.compareDepsHTMLOutput(ctx, res.Old, res.New, extras)
+}
+
+// reportExtras is information shown in HTML and Markdown reports that
+// isn't part of findings.
+type reportExtras struct {
+	Violations   []policyViolation
+	Contributors *contributorChanges
+	ReleaseNotes []releaseNotes
+	// DiffStat is how much the source code of compared versions
+	// differs, only set if their source code is in the module cache
+	DiffStat *diffStat
+	// Reviews are reviews of the new version by trusted reviewers
+	Reviews []verifiedReview
+	// Approval is whether the new version is approved, only set if
+	// -approved-versions was passed
+	Approval *approvalStatus
+}
+
+func (d *depInspector) buildReportExtras(ctx context.Context, res *savedResults) *reportExtras {
+	extras := &reportExtras{
+		Violations: d.checkPolicy(res),
+		Approval:   d.approvalStatus(res.New),
+	}
+	reviews, err := d.findReviews(ctx, res.New)
+	if err != nil {
+		log.Printf("error finding reviews of %s: %v", makeVersionStr(res.New.Dep, res.New.Version), err)
+	} else {
+		extras.Reviews = reviews
+	}
+	if res.Old != nil && (res.Old.Dep != res.New.Dep || res.Old.Version != res.New.Version) {
+		stat, err := d.moduleDiffStat(ctx, res.Old, res.New)
+		if err != nil {
+			// the source code of versions may not be in the module
+			// cache if results were loaded from files
+			log.Printf("not showing diff statistics: %v", err)
+		} else {
+			extras.DiffStat = stat
+		}
+	}
+	// contributors and release notes can only be found when comparing
+	// different versions of the same module
+	if res.Old == nil || res.Old.Dep != res.New.Dep || res.Old.Version == res.New.Version {
+		return extras
+	}
+	if d.contributors {
+		contributors, err := d.findContributors(ctx, res.New.Dep, res.Old.Version, res.New.Version)
+		if err != nil {
+			log.Printf("error finding contributors of %s: %v", res.New.Dep, err)
+		} else {
+			extras.Contributors = contributors
+		}
+	}
+	if d.releaseNotes {
+		notes, err := d.findReleaseNotes(ctx, res.New.Dep, res.Old.Version, res.New.Version)
+		if err != nil {
+			log.Printf("error finding release notes of %s: %v", res.New.Dep, err)
+		} else {
+			extras.ReleaseNotes = notes
+		}
+	}
+
+	return extras
+}
+
+// writeReport writes a report of dep to the output file if one was
+// specified. Otherwise HTML reports are opened in a browser and reports
+// of other formats are written to stdout.
+func (d *depInspector) writeReport(dep string, r io.Reader) error {
+	if outputFile := d.reportPath(dep); outputFile != "" {
+		// assets of zip archives are bundled in them
+		if d.htmlAssets && !isZipPath(outputFile) {
+			if err := writeHTMLAssets(filepath.Dir(outputFile)); err != nil {
+				return err
+			}
+		}
+		if d.pages {
+			return writePage(outputFile, r)
+		}
+		outFile, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		_, err = io.Copy(outFile, r)
+		return err
+	}
+	if d.format != formatHTML {
+		_, err := io.Copy(os.Stdout, r)
+		return err
+	}
+
+	return browser.OpenReader(r)
+}
+
+// reportPath returns the path a report of dep should be written to.
+// When multiple dependencies are reported on in one run, each report
+// is written to a separate file named after its dependency.
+func (d *depInspector) reportPath(dep string) string {
+	if d.pages {
+		return filepath.Join(d.outputFile, pagesDepsDir, pageName(dep)+formatExts[formatHTML])
+	}
+	if d.outputFile == "" || !d.multipleReports {
+		return d.outputFile
+	}
+
+	ext := filepath.Ext(d.outputFile)
+	name := strings.TrimSuffix(d.outputFile, ext)
+	return name + "-" + strings.ReplaceAll(dep, "/", "_") + ext
+}
+
+func jsonOutput(res *savedResults) (io.Reader, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return nil, fmt.Errorf("encoding results: %w", err)
+	}
+
+	return &buf, nil
+}
+
+// markdownOutput renders results as Markdown. If onlyChanges is true
+// findings that are the same between compared versions are omitted.
+// stdlibFrames is how calls through the standard library are shown.
+func markdownOutput(res *savedResults, onlyChanges bool, stdlibFrames string, extras *reportExtras) (io.Reader, error) {
+	tmplPath := "output/single-dep.md.tmpl"
+	var data any
+	if res.Old == nil {
+		data = &singleDepResult{
+			Dep:        res.New.Dep,
+			VersionStr: makeVersionStr(res.New.Dep, res.New.Version),
+			Findings:   prepareFindingResult(res.New.Dep, res.New.Caps.CapabilityInfo, res.New.Issues, nil, nil),
+			Packages:   res.New.Packages,
+			Licenses:   res.New.Licenses,
+			Risk:       res.New.Risk,
+			Vulns:      res.New.Vulns,
+			Proxies:    res.New.Proxies,
+			VCSDiff:    res.New.VCSDiff,
+			ProxyDiff:  res.New.ProxyDiff,
+			CopiedCode: res.New.CopiedCode,
+			Source:     res.New.Source,
+			Violations: extras.Violations,
+			Reviews:    extras.Reviews,
+			Approval:   extras.Approval,
+			Metadata:   res.New.Metadata,
+		}
+	} else {
+		tmplPath = "output/compare-deps.md.tmpl"
+		compared := prepareCompareDepsResult(res.Old, res.New)
+		compared.OnlyChanges = compared.OnlyChanges || onlyChanges
+		compared.Violations = extras.Violations
+		compared.Contributors = extras.Contributors
+		compared.DiffStat = extras.DiffStat
+		compared.ReleaseNotes = extras.ReleaseNotes
+		compared.Reviews = extras.Reviews
+		compared.Approval = extras.Approval
+		data = compared
+	}
+
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"capType":     capTypeName,
+		"formatDelta": formatDelta,
+		"codeFence":   codeFence,
+		"cellCode":    cellCode,
+		"collapsedFrames": func(calls []functionCall, i int) int {
+			return collapsedFrames(calls, i, stdlibFrames)
+		},
+	}).ParseFS(tmplFS, tmplPath, "output/totals.md.tmpl", "output/findings.md.tmpl", "output/go-sum.md.tmpl", "output/vcs-diff.md.tmpl", "output/proxy-diff.md.tmpl", "output/copied-code.md.tmpl", "output/proxies.md.tmpl", "output/env-vars.md.tmpl", "output/paths.md.tmpl", "output/fatal-calls.md.tmpl", "output/platforms.md.tmpl", "output/low-level.md.tmpl", "output/gating.md.tmpl", "output/download-exec.md.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing output template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, path.Base(tmplPath), data); err != nil {
+		return nil, fmt.Errorf("error executing output template: %w", err)
+	}
+
+	return &buf, nil
+}
+
+// cellCode formats text as a code span that can be put in a Markdown
+// table cell.
+func cellCode(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	delim := strings.Repeat("`", longest+1)
+	text = strings.ReplaceAll(text, "|", `\|`)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return delim + text + delim
+}
+
+// prepareCompareDepsResult compares findings and prepares them for
+// output formats that don't link to source code.
+func prepareCompareDepsResult(oldFindings, newFindings *depFindings) *compareDepsResult {
+	dep := newFindings.Dep
+	results := compareFindings(oldFindings, newFindings)
+	res := buildCompareDepsResult(oldFindings, newFindings, results)
+	res.OldFindings = prepareFindingResult(oldFindings.Dep, results.removedCaps, results.fixedIssues, nil, nil)
+	res.SameFindings = prepareFindingResult(dep, results.sameCaps, results.staleIssues, nil, nil)
+	res.NewFindings = prepareFindingResult(dep, results.addedCaps, results.newIssues, nil, nil)
+	buildCombinedTotals(res)
+
+	return res
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxOverviewItems is how many kinds of capabilities or linter issues
+// are named in an overview, the rest are counted together.
+const maxOverviewItems = 3
+
+// changeOverview returns a sentence summarizing the most important
+// changes between compared versions. It is shown at the top of reports
+// for readers who don't read further.
+func changeOverview(oldFindings, newFindings *depFindings, results *inspectResults, oldVer, newVer string) string {
+	var changes []string
+	if added := capChanges(results.addedCaps); added != "" {
+		changes = append(changes, "adds "+added)
+	}
+	if removed := capChanges(results.removedCaps); removed != "" {
+		changes = append(changes, "removes "+removed)
+	}
+	oldCgo, newCgo := usesCgo(oldFindings), usesCgo(newFindings)
+	if newCgo && !oldCgo {
+		changes = append(changes, "introduces cgo")
+	} else if oldCgo && !newCgo {
+		changes = append(changes, "no longer uses cgo")
+	}
+	if added := issueChanges(results.newIssues); added != "" {
+		changes = append(changes, "introduces "+added)
+	}
+	if fixed := issueChanges(results.fixedIssues); fixed != "" {
+		changes = append(changes, "fixes "+fixed)
+	}
+	if size := sizeChange(oldFindings, newFindings); size != "" {
+		changes = append(changes, size)
+	}
+
+	if len(changes) == 0 {
+		return fmt.Sprintf("%s → %s changes no capabilities or linter issues.", oldVer, newVer)
+	}
+	return fmt.Sprintf("%s → %s %s.", oldVer, newVer, joinWords(changes))
+}
+
+// capChanges describes how many capabilities of each kind there are
+// and which package has them, or how many packages if there are more
+// than one, most common first.
+func capChanges(caps []*capability) string {
+	type capCount struct {
+		name  string
+		count int
+		pkgs  []string
+	}
+	var counts []*capCount
+	for _, c := range caps {
+		name := strings.TrimPrefix(c.Capability, "CAPABILITY_")
+		i := slices.IndexFunc(counts, func(cc *capCount) bool {
+			return cc.name == name
+		})
+		if i == -1 {
+			i = len(counts)
+			counts = append(counts, &capCount{name: name})
+		}
+		counts[i].count++
+		if !slices.Contains(counts[i].pkgs, c.PackageDir) {
+			counts[i].pkgs = append(counts[i].pkgs, c.PackageDir)
+		}
+	}
+	slices.SortFunc(counts, func(a, b *capCount) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	var items []string
+	for i, cc := range counts {
+		if i == maxOverviewItems {
+			var rest int
+			for _, cc := range counts[i:] {
+				rest += cc.count
+			}
+			items = append(items, pluralize(rest, "other capability", "other capabilities"))
+			break
+		}
+		item := pluralize(cc.count, cc.name+" capability", cc.name+" capabilities")
+		if len(cc.pkgs) > 1 {
+			item += fmt.Sprintf(" in %d packages", len(cc.pkgs))
+		} else {
+			item += " in " + cc.pkgs[0]
+		}
+		items = append(items, item)
+	}
+	return joinWords(items)
+}
+
+// issueChanges describes how many linter issues each linter reported,
+// most common first.
+func issueChanges(issues []*lintIssue) string {
+	counts := make(map[string]int)
+	var linters []string
+	for _, issue := range issues {
+		linter := linterName(issue)
+		if counts[linter] == 0 {
+			linters = append(linters, linter)
+		}
+		counts[linter]++
+	}
+	slices.SortFunc(linters, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	var items []string
+	for i, linter := range linters {
+		if i == maxOverviewItems {
+			var rest int
+			for _, linter := range linters[i:] {
+				rest += counts[linter]
+			}
+			items = append(items, pluralize(rest, "other issue", "other issues"))
+			break
+		}
+		items = append(items, pluralize(counts[linter], linter+" issue", linter+" issues"))
+	}
+	return joinWords(items)
+}
+
+// usesCgo returns true if findings have cgo capabilities.
+func usesCgo(findings *depFindings) bool {
+	return slices.ContainsFunc(findings.Caps.CapabilityInfo, func(c *capability) bool {
+		return c.Capability == "CAPABILITY_CGO"
+	})
+}
+
+// sizeChange describes how many lines of code the packages of the
+// dependency grew or shrank by. It is empty if the size didn't change
+// or the packages of either version weren't described.
+func sizeChange(oldFindings, newFindings *depFindings) string {
+	if len(oldFindings.PackageInfo) == 0 || len(newFindings.PackageInfo) == 0 {
+		return ""
+	}
+	var oldLines, newLines int
+	for _, info := range oldFindings.PackageInfo {
+		oldLines += info.Lines
+	}
+	for _, info := range newFindings.PackageInfo {
+		newLines += info.Lines
+	}
+
+	switch delta := newLines - oldLines; {
+	case delta > 0:
+		return "grows by " + formatLineCount(delta) + " lines of code"
+	case delta < 0:
+		return "shrinks by " + formatLineCount(-delta) + " lines of code"
+	}
+	return ""
+}
+
+// formatLineCount formats a number of lines, abbreviating thousands.
+func formatLineCount(lines int) string {
+	if lines < 1000 {
+		return strconv.Itoa(lines)
+	}
+	thousands := strconv.FormatFloat(float64(lines)/1000, 'f', 1, 64)
+	return strings.TrimSuffix(thousands, ".0") + "k"
+}
+
+// pluralize formats a count followed by the singular or plural form
+// of a noun.
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
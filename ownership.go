@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// signingKeyNone is the signing key of versions whose tags aren't
+	// signed
+	signingKeyNone = "unsigned"
+	// signingKeyUnknown is the signing key of versions whose tags are
+	// signed in a way that isn't understood
+	signingKeyUnknown = "unknown"
+)
+
+// ownershipSignals are signals of who controls a module version.
+type ownershipSignals struct {
+	// Repository is the repository the module path resolves to
+	Repository string `json:",omitempty"`
+	// MovedTo is the repository Repository redirects to if it was
+	// transferred or renamed
+	MovedTo string `json:",omitempty"`
+	// SigningKey is the fingerprint of the key the version's tag was
+	// signed with. It is empty if the version isn't tagged.
+	SigningKey string `json:",omitempty"`
+}
+
+// ownershipChange is an ownership signal that changed between compared
+// versions.
+type ownershipChange struct {
+	Signal string
+	Old    string
+	New    string
+}
+
+// findOwnership finds the ownership signals of a module version.
+func (d *depInspector) findOwnership(ctx context.Context, dep, version string) (*ownershipSignals, error) {
+	tmpDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	modURL, err := d.findModuleURL(ctx, dep, version, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	repoURL, subdir := repoRoot(modURL)
+	signals := &ownershipSignals{Repository: repoURL}
+
+	if modURL.url.Host == "github.com" {
+		movedTo, err := githubRepoLocation(ctx, repoURL)
+		if err != nil {
+			log.Printf("error checking if %s was moved: %v", repoURL, err)
+		} else if !strings.EqualFold(movedTo, repoURL) {
+			signals.MovedTo = movedTo
+		}
+	}
+	// pseudo-versions aren't tagged
+	if !modURL.verIsCommit {
+		key, err := d.tagSigningKey(ctx, repoURL, moduleRev(modURL, subdir), tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		signals.SigningKey = key
+	}
+
+	return signals, nil
+}
+
+// githubRepoLocation returns the current URL of a GitHub repository,
+// which differs from repoURL if the repository was transferred or
+// renamed.
+func githubRepoLocation(ctx context.Context, repoURL string) (string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	var repo struct {
+		HTMLURL string `json:"html_url"`
+	}
+	// the API follows redirects of moved repositories
+	if err := githubRequest(ctx, os.Getenv("GITHUB_TOKEN"), http.MethodGet, "/repos"+u.Path, nil, &repo); err != nil {
+		return "", err
+	}
+
+	return repo.HTMLURL, nil
+}
+
+// tagSigningKey returns the fingerprint of the key a tag was signed
+// with, or signingKeyNone if it isn't signed.
+func (d *depInspector) tagSigningKey(ctx context.Context, repoURL, tag, tmpDir string) (string, error) {
+	gitDir := tmpDir + "/tags.git"
+	if err := d.runCommand(ctx, nil, "git", "init", "--quiet", "--bare", gitDir); err != nil {
+		return "", fmt.Errorf("creating repository: %w", err)
+	}
+	ref := "refs/tags/" + tag
+	if err := d.runCommand(ctx, nil, "git", "--git-dir", gitDir, "fetch", "--quiet", "--depth", "1", "--filter=blob:none", repoURL, ref+":"+ref); err != nil {
+		return "", fmt.Errorf("fetching tag %s: %w", tag, err)
+	}
+
+	// annotated tags are signed in the tag object, lightweight tags
+	// are the commit object which may be signed
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "git", "--git-dir", gitDir, "cat-file", "-p", ref); err != nil {
+		return "", fmt.Errorf("reading tag %s: %w", tag, err)
+	}
+
+	return signatureKey(output.String()), nil
+}
+
+// signatureKey returns the fingerprint of the key of the PGP or SSH
+// signature in a git tag or commit object.
+func signatureKey(obj string) string {
+	start := strings.Index(obj, "-----BEGIN ")
+	if start == -1 {
+		return signingKeyNone
+	}
+	obj = obj[start:]
+	end := strings.Index(obj, "-----END ")
+	if end == -1 {
+		return signingKeyUnknown
+	}
+
+	lines := strings.Split(obj[:end], "\n")
+	var armored strings.Builder
+	for _, line := range lines[1:] {
+		// signatures of commits are indented
+		line = strings.TrimSpace(line)
+		// skip armor headers and checksums
+		if strings.Contains(line, ": ") || strings.HasPrefix(line, "=") {
+			continue
+		}
+		armored.WriteString(line)
+	}
+	sig, err := base64.StdEncoding.DecodeString(armored.String())
+	if err != nil {
+		return signingKeyUnknown
+	}
+
+	var (
+		key string
+		ok  bool
+	)
+	switch {
+	case strings.Contains(lines[0], "PGP SIGNATURE"):
+		key, ok = pgpIssuer(sig)
+	case strings.Contains(lines[0], "SSH SIGNATURE"):
+		key, ok = sshSignatureKey(sig)
+	}
+	if !ok {
+		return signingKeyUnknown
+	}
+	return key
+}
+
+// pgpIssuer returns the fingerprint or key ID of the issuer of a
+// version 4 OpenPGP signature packet.
+func pgpIssuer(sig []byte) (string, bool) {
+	if len(sig) < 2 {
+		return "", false
+	}
+	// skip the packet header, the body is the rest of the signature
+	var body []byte
+	if sig[0]&0x40 != 0 {
+		switch l := sig[1]; {
+		case l < 192:
+			body = sig[2:]
+		case l < 224:
+			body = sig[3:]
+		case l == 255:
+			body = sig[6:]
+		default:
+			return "", false
+		}
+	} else {
+		switch sig[0] & 0x3 {
+		case 0:
+			body = sig[2:]
+		case 1:
+			body = sig[3:]
+		case 2:
+			body = sig[5:]
+		default:
+			body = sig[1:]
+		}
+	}
+	if len(body) < 6 || body[0] != 4 {
+		return "", false
+	}
+
+	// the issuer may be in the hashed or unhashed subpackets
+	var keyID string
+	subpackets := body[4:]
+	for i := 0; i < 2; i++ {
+		if len(subpackets) < 2 {
+			return "", false
+		}
+		n := int(binary.BigEndian.Uint16(subpackets))
+		if len(subpackets) < 2+n {
+			return "", false
+		}
+		for area := subpackets[2 : 2+n]; len(area) != 0; {
+			var l int
+			switch {
+			case area[0] < 192:
+				l, area = int(area[0]), area[1:]
+			case area[0] < 255 && len(area) >= 2:
+				l, area = (int(area[0])-192)<<8+int(area[1])+192, area[2:]
+			case len(area) >= 5:
+				l, area = int(binary.BigEndian.Uint32(area[1:])), area[5:]
+			default:
+				return "", false
+			}
+			if l == 0 || l > len(area) {
+				return "", false
+			}
+			data := area[1:l]
+			switch area[0] & 0x7f {
+			case 33: // issuer fingerprint
+				if len(data) > 1 {
+					return "PGP " + strings.ToUpper(hex.EncodeToString(data[1:])), true
+				}
+			case 16: // issuer key ID
+				keyID = "PGP " + strings.ToUpper(hex.EncodeToString(data))
+			}
+			area = area[l:]
+		}
+		subpackets = subpackets[2+n:]
+	}
+
+	return keyID, keyID != ""
+}
+
+// sshSignatureKey returns the SHA256 fingerprint of the public key of
+// an SSH signature.
+func sshSignatureKey(sig []byte) (string, bool) {
+	const magic = "SSHSIG"
+	// the magic preamble is followed by a version and the public key
+	if len(sig) < len(magic)+8 || string(sig[:len(magic)]) != magic {
+		return "", false
+	}
+	rest := sig[len(magic)+4:]
+	n := int(binary.BigEndian.Uint32(rest))
+	if len(rest) < 4+n {
+		return "", false
+	}
+	sum := sha256.Sum256(rest[4 : 4+n])
+
+	return "SSH SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), true
+}
+
+// compareOwnership returns the ownership signals that changed between
+// compared versions.
+func compareOwnership(oldSignals, newSignals *ownershipSignals) []ownershipChange {
+	if newSignals == nil {
+		return nil
+	}
+
+	var changes []ownershipChange
+	if newSignals.MovedTo != "" {
+		changes = append(changes, ownershipChange{
+			Signal: "Repository moved",
+			Old:    newSignals.Repository,
+			New:    newSignals.MovedTo,
+		})
+	}
+	if oldSignals == nil {
+		return changes
+	}
+	if oldSignals.Repository != "" && newSignals.Repository != "" && oldSignals.Repository != newSignals.Repository {
+		changes = append(changes, ownershipChange{
+			Signal: "Repository",
+			Old:    oldSignals.Repository,
+			New:    newSignals.Repository,
+		})
+	}
+	if oldSignals.SigningKey != "" && newSignals.SigningKey != "" && oldSignals.SigningKey != newSignals.SigningKey {
+		changes = append(changes, ownershipChange{
+			Signal: "Release signing key",
+			Old:    oldSignals.SigningKey,
+			New:    newSignals.SigningKey,
+		})
+	}
+
+	return changes
+}
@@ -1,19 +1,141 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"golang.org/x/exp/maps"
 	"golang.org/x/tools/go/packages"
 )
 
+// maxCachedPkgGraphs is how many loaded package graphs are cached,
+// enough for the old and new versions of a comparison.
+const maxCachedPkgGraphs = 2
+
 type loadedPackages map[string]*packages.Package
 
-func listPackages(modName string) (loadedPackages, error) {
-	mode := packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedModule | packages.NeedEmbedFiles
-	cfg := &packages.Config{Mode: mode}
+// pkgLoadCache caches loaded package graphs by the go.mod and go.sum
+// they were loaded with, so when several dependencies are inspected
+// with the same module requirements packages are only loaded once.
+type pkgLoadCache struct {
+	mu     sync.Mutex
+	graphs map[string]*pkgGraph
+	// keys are the keys of graphs, oldest first
+	keys []string
+}
+
+// pkgGraph is a package graph that is being or was loaded. Loads of
+// the same graph wait for the first one instead of loading it again.
+type pkgGraph struct {
+	// done is closed when the graph is loaded
+	done chan struct{}
+	pkgs loadedPackages
+	err  error
+}
+
+func newPkgLoadCache() *pkgLoadCache {
+	return &pkgLoadCache{
+		graphs: make(map[string]*pkgGraph),
+	}
+}
+
+// loadPackages loads the packages of a module in dir, or the directory
+// of the main module if dir is empty. Packages are only loaded again if
+// the module's requirements changed since they were last loaded.
+func (d *depInspector) loadPackages(modName, dir string) (loadedPackages, error) {
+	env := d.commandEnv()
+	if dir == "" {
+		dir = d.workDir
+	}
+	c := d.pkgCache
+	if c == nil {
+		return listPackages(modName, dir, env)
+	}
+
+	modDir := dir
+	if modDir == "" {
+		modDir = filepath.Dir(d.modFilePath)
+	}
+	key, err := pkgGraphKey(modName, modDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// only hold the lock while looking up and adding graphs so
+	// different graphs can be loaded concurrently
+	c.mu.Lock()
+	graph, ok := c.graphs[key]
+	if !ok {
+		graph = &pkgGraph{done: make(chan struct{})}
+		c.graphs[key] = graph
+		c.keys = append(c.keys, key)
+		if len(c.keys) > maxCachedPkgGraphs {
+			delete(c.graphs, c.keys[0])
+			c.keys = c.keys[1:]
+		}
+	}
+	c.mu.Unlock()
+	if ok {
+		<-graph.done
+		return graph.pkgs, graph.err
+	}
+
+	graph.pkgs, graph.err = listPackages(modName, dir, env)
+	close(graph.done)
+	if graph.err != nil {
+		// don't cache failed loads so they can be retried
+		c.mu.Lock()
+		if c.graphs[key] == graph {
+			delete(c.graphs, key)
+			c.keys = slices.DeleteFunc(c.keys, func(k string) bool {
+				return k == key
+			})
+		}
+		c.mu.Unlock()
+	}
+
+	return graph.pkgs, graph.err
+}
+
+// pkgGraphKey identifies the package graph of a module by the contents
+// of its go.mod and go.sum and the environment of the go command.
+func pkgGraphKey(modName, modDir string, env []string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", modName, modDir)
+	for _, name := range []string{"go.mod", "go.sum"} {
+		contents, err := os.ReadFile(filepath.Join(modDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("reading %s: %w", name, err)
+		}
+		h.Write(contents)
+		h.Write([]byte{0})
+	}
+	for _, kv := range env {
+		fmt.Fprintf(h, "%s\x00", kv)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// listPackages loads the packages of a module in dir, or the current
+// directory if dir is empty. The packages are compiled so the export
+// data of every package is in the build cache. The analysis tools run
+// as separate processes and still load and type check packages
+// themselves, only the builds they run reuse the cached compilation.
+func listPackages(modName, dir string, env []string) (loadedPackages, error) {
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule | packages.NeedEmbedFiles | packages.NeedExportFile
+	cfg := &packages.Config{
+		Mode: mode,
+		Dir:  dir,
+		Env:  env,
+	}
 	pkgs, err := packages.Load(cfg, modName+"/...")
 	if err != nil {
 		return nil, fmt.Errorf("loading packages: %w", err)
@@ -73,3 +195,48 @@ func listImportedPackages(dep string, pkgs loadedPackages) ([]string, error) {
 
 	return importsToCheck, nil
 }
+
+// packageInfo describes a package of an inspected module.
+type packageInfo struct {
+	Path  string
+	Files int
+	Lines int
+	// Imports are the paths of the packages the package imports
+	Imports []string `json:",omitempty"`
+	// Generated are the names of the package's files that were
+	// generated by a tool
+	Generated []string `json:",omitempty"`
+}
+
+// describePackages describes the loaded packages of a module.
+func describePackages(modPath string, pkgs loadedPackages) ([]packageInfo, error) {
+	var infos []packageInfo
+	for _, pkg := range pkgs {
+		if pkg.Module == nil || pkg.Module.Path != modPath {
+			continue
+		}
+
+		info := packageInfo{
+			Path:    pkg.PkgPath,
+			Files:   len(pkg.GoFiles),
+			Imports: maps.Keys(pkg.Imports),
+		}
+		for _, file := range pkg.GoFiles {
+			contents, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("counting lines of package %s: %w", pkg.PkgPath, err)
+			}
+			info.Lines += bytes.Count(contents, []byte("\n"))
+			if isGeneratedFile(file, contents) {
+				info.Generated = append(info.Generated, filepath.Base(file))
+			}
+		}
+		slices.Sort(info.Imports)
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b packageInfo) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return infos, nil
+}
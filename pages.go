@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// When -pages is passed, reports are written as a directory of
+// interlinked pages:
+//
+//	index.html                 overview of every dependency
+//	deps/<dep>.html            report of a dependency
+//	packages/<package>.html    details and findings of a package
+//	diffs/<dep>.diff           source diff of compared versions
+const (
+	pagesIndex    = "index.html"
+	pagesDepsDir  = "deps"
+	pagesPkgsDir  = "packages"
+	pagesDiffsDir = "diffs"
+)
+
+// pageLinks are links to related pages shown at the top of a page.
+type pageLinks struct {
+	Index  string
+	Report string
+	Diff   string
+}
+
+// packagePage is the page of a single package.
+type packagePage struct {
+	VersionStr string
+	Pane       packagePane
+	Links      *pageLinks
+}
+
+// pageName returns the file name of the page of a dependency or
+// package without an extension.
+func pageName(path string) string {
+	return strings.ReplaceAll(path, "/", "_")
+}
+
+// depPageLink returns the link to the report of dep relative to other
+// dependency and package pages.
+func depPageLink(dep string) string {
+	return "../" + pagesDepsDir + "/" + pageName(dep) + ".html"
+}
+
+// packagePageLink returns the link to the page of pkg relative to
+// dependency and other package pages.
+func packagePageLink(pkg string) string {
+	return "../" + pagesPkgsDir + "/" + pageName(pkg) + ".html"
+}
+
+// diffPath returns the path of the source diff of dep.
+func (d *depInspector) diffPath(dep string) string {
+	return filepath.Join(d.outputFile, pagesDiffsDir, pageName(dep)+".diff")
+}
+
+// reportPageLinks returns the links shown on the report of dep. The
+// source diff is only linked if it was written.
+func (d *depInspector) reportPageLinks(dep string) *pageLinks {
+	links := &pageLinks{
+		Index: "../" + pagesIndex,
+	}
+	if _, err := os.Stat(d.diffPath(dep)); err == nil {
+		links.Diff = "../" + pagesDiffsDir + "/" + pageName(dep) + ".diff"
+	}
+	return links
+}
+
+// writePackagePages writes a page for every package pane of a report,
+// and sets the panes' links to them.
+func (d *depInspector) writePackagePages(tmpl *template.Template, findings *depFindings, panes []packagePane) error {
+	pageTmpl := tmpl.Lookup("package-page.tmpl")
+	links := &pageLinks{
+		Index:  "../" + pagesIndex,
+		Report: depPageLink(findings.Dep),
+	}
+	for i := range panes {
+		panes[i].Page = packagePageLink(panes[i].Path)
+		r, err := d.executeTemplate(pageTmpl, &packagePage{
+			VersionStr: makeVersionStr(findings.Dep, findings.Version),
+			Pane:       panes[i],
+			Links:      links,
+		})
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(d.outputFile, pagesPkgsDir, pageName(panes[i].Path)+".html")
+		if err := writePage(path, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSourceDiff writes a diff of the source code of compared
+// versions to the diffs directory.
+func (d *depInspector) writeSourceDiff(ctx context.Context, res *savedResults) error {
+	var diff bytes.Buffer
+	if err := d.diffModuleSource(ctx, &diff, res.Old, res.New); err != nil {
+		// the source code of versions may not be in the module cache
+		// if results were loaded from files
+		log.Printf("not writing source diff: %v", err)
+		return nil
+	}
+	return writePage(d.diffPath(res.New.Dep), &diff)
+}
+
+// writePage writes a page, creating its directory if necessary.
+func writePage(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	return writeFile(path, r)
+}
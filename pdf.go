@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pdfRenderers are the browsers that can print HTML reports to PDFs,
+// in order of preference.
+var pdfRenderers = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable"}
+
+// detailsRe matches the opening tags of sections.
+var detailsRe = regexp.MustCompile(`<details(\s[^>]*)?>`)
+
+// isPDFPath returns true if an HTML report should be printed to a PDF.
+func isPDFPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".pdf")
+}
+
+// printPDF prints an HTML report to a PDF with a headless browser.
+// Every section of the report is expanded first, as collapsed sections
+// would be missing from the PDF.
+func (d *depInspector) printPDF(ctx context.Context, report io.Reader) (io.Reader, error) {
+	renderer, err := findPDFRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := io.ReadAll(report)
+	if err != nil {
+		return nil, err
+	}
+	html = detailsRe.ReplaceAllFunc(html, func(tag []byte) []byte {
+		if bytes.Contains(tag, []byte(" open")) {
+			return tag
+		}
+		return append([]byte("<details open"), tag[len("<details"):]...)
+	})
+
+	tempDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	htmlPath := filepath.Join(tempDir, "report.html")
+	if err := os.WriteFile(htmlPath, html, 0o644); err != nil {
+		return nil, fmt.Errorf("writing report: %w", err)
+	}
+	pdfPath := filepath.Join(tempDir, "report.pdf")
+	cmd, errBuf := d.buildCommand(ctx, nil,
+		renderer,
+		"--headless",
+		"--disable-gpu",
+		"--no-pdf-header-footer",
+		"--print-to-pdf="+pdfPath,
+		"file://"+htmlPath,
+	)
+	if err := cmd.Run(); err != nil {
+		return nil, formatCmdErr(ctx, cmd, err, errBuf)
+	}
+
+	pdf, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading PDF: %w", err)
+	}
+	return bytes.NewReader(pdf), nil
+}
+
+func findPDFRenderer() (string, error) {
+	for _, renderer := range pdfRenderers {
+		if path, err := exec.LookPath(renderer); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("printing PDFs requires Chromium or Google Chrome to be installed")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+func packagesCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector [flags] packages path/of/module@version")
+	}
+	dep, version, ok := strings.Cut(args[0], "@")
+	if !ok || dep == "" || version == "" {
+		return fmt.Errorf("%q is not a module version, must be path/of/module@version", args[0])
+	}
+	version, err := d.checkVersion(dep, version)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := d.setupDep(ctx, d.newModBackupFiles, dep, version, true)
+	if err != nil {
+		return err
+	}
+	capslockPkgs, err := d.capslockPackages(dep, pkgs)
+	if err != nil {
+		return err
+	}
+	targets, err := d.findLintTargets(dep, version, pkgs, nil)
+	if err != nil {
+		return err
+	}
+	allPkgs, err := d.modulePackages(ctx, dep)
+	if err != nil {
+		// packages that aren't imported just won't be listed
+		log.Printf("error listing every package of %s: %v", dep, err)
+	}
+	for path, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Path == dep && !slices.Contains(allPkgs, path) {
+			allPkgs = append(allPkgs, path)
+		}
+	}
+	slices.Sort(allPkgs)
+	chains := importChains(pkgs)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprint(tw, "Package\tcapslock\tLinters\tImported via\n")
+	for _, pkg := range allPkgs {
+		var capslock, linters string
+		switch {
+		case analyzesPkg(capslockPkgs, pkg):
+			capslock = "root"
+		case chains[pkg] != nil:
+			// capslock analyzes the packages roots import too
+			capslock = "yes"
+		default:
+			capslock = "no"
+		}
+		linters = "no"
+		if analyzesPkg(targets.staticcheckPkgs, pkg) {
+			linters = "yes"
+		}
+
+		importedVia := strings.Join(chains[pkg], " → ")
+		if importedVia == "" {
+			importedVia = "not imported, pass -a to analyze it"
+			if d.inspectAllPkgs || d.unusedDep {
+				importedVia = "not imported"
+			}
+		}
+		if d.filter.ignored(pkg, "") {
+			importedVia += " (findings ignored by -ignore-pkg)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", pkg, capslock, linters, importedVia)
+	}
+
+	return tw.Flush()
+}
+
+// modulePackages returns the paths of every package of a module that
+// is required by the main module, including ones that aren't imported.
+func (d *depInspector) modulePackages(ctx context.Context, dep string) ([]string, error) {
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "go", "list", "-e", dep+"/..."); err != nil {
+		return nil, err
+	}
+	return strings.Fields(output.String()), nil
+}
+
+// analyzesPkg returns true if a list of packages or patterns an analysis
+// tool is run on includes pkg.
+func analyzesPkg(pkgs []string, pkg string) bool {
+	for _, p := range pkgs {
+		if p == pkg {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "/..."); ok && (pkg == prefix || strings.HasPrefix(pkg, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// importChains returns the shortest chain of imports from a package of
+// the main module to every loaded package.
+func importChains(pkgs loadedPackages) map[string][]string {
+	importedBy := make(map[string]string)
+	var queue []string
+	for path, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Main {
+			importedBy[path] = ""
+			queue = append(queue, path)
+		}
+	}
+	slices.Sort(queue)
+
+	for len(queue) != 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		var imports []string
+		for _, imported := range pkgs[path].Imports {
+			imports = append(imports, imported.PkgPath)
+		}
+		slices.Sort(imports)
+		for _, imported := range imports {
+			if _, ok := importedBy[imported]; ok {
+				continue
+			}
+			importedBy[imported] = path
+			queue = append(queue, imported)
+		}
+	}
+
+	chains := make(map[string][]string, len(importedBy))
+	for path := range importedBy {
+		chain := []string{path}
+		for by := importedBy[path]; by != ""; by = importedBy[by] {
+			chain = append(chain, by)
+		}
+		slices.Reverse(chain)
+		chains[path] = chain
+	}
+	return chains
+}
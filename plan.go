@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// depPlan is what inspecting a dependency version would do.
+type depPlan struct {
+	VersionStr       string
+	Packages         []packageInfo
+	CapslockPkgs     []string
+	GolangciLintDirs []string
+	StaticcheckPkgs  []string
+	// ReusedPkgs is how many packages wouldn't be linted as they
+	// didn't change since the old version
+	ReusedPkgs int
+}
+
+// dryRunSingle prints what inspecting a dependency version would do.
+// go.mod is set up and packages are loaded to find out, but no analysis
+// tools are run.
+func (d *depInspector) dryRunSingle(ctx context.Context, w io.Writer, dep, version string) error {
+	versionStr := makeVersionStr(dep, version)
+	fmt.Fprintf(w, "Would inspect %s\n", versionStr)
+	d.printDownloads(w, versionStr)
+
+	pkgs, err := d.setupDep(ctx, d.newModBackupFiles, dep, version, true)
+	if err != nil {
+		return err
+	}
+	modFile, err := parseModFile(d.modFilePath)
+	if err != nil {
+		return err
+	}
+	printModChanges(w, "go.mod changes", d.parsedModFile, modFile)
+
+	plan, err := d.planDep(dep, version, pkgs, nil)
+	if err != nil {
+		return err
+	}
+	plan.print(w)
+
+	return nil
+}
+
+// dryRunCompare prints what comparing two versions of a dependency
+// would do, including for every dependency that changed as a result.
+func (d *depInspector) dryRunCompare(ctx context.Context, w io.Writer, dep, oldVer, newVer string) error {
+	oldVerStr := makeVersionStr(dep, oldVer)
+	newVerStr := makeVersionStr(dep, newVer)
+	fmt.Fprintf(w, "Would compare %s and %s\n", oldVerStr, newVerStr)
+	d.printDownloads(w, oldVerStr, newVerStr)
+
+	changedDeps, err := d.findDepsToCompare(ctx, dep, oldVer, newVer)
+	if err != nil {
+		return err
+	}
+	oldModFile, err := d.backedUpModFile(d.oldModBackupFiles)
+	if err != nil {
+		return err
+	}
+	newModFile, err := d.backedUpModFile(d.newModBackupFiles)
+	if err != nil {
+		return err
+	}
+	printModChanges(w, "go.mod changes of old version", d.parsedModFile, oldModFile)
+	printModChanges(w, "go.mod changes of new version", d.parsedModFile, newModFile)
+
+	fmt.Fprintf(w, "\nchanged dependencies that would be inspected (%d):\n", len(changedDeps))
+	for _, c := range changedDeps {
+		if c.oldVer == "" {
+			fmt.Fprintf(w, "  %s added at %s\n", c.dep, c.newVer)
+		} else {
+			fmt.Fprintf(w, "  %s %s → %s\n", c.dep, c.oldVer, c.newVer)
+		}
+	}
+
+	var totalPkgs, totalLines int
+	for _, c := range changedDeps {
+		var prev *depFindings
+		if c.oldVer != "" {
+			oldPkgs, err := d.setupDep(ctx, d.oldModBackupFiles, c.dep, c.oldVer, false)
+			if err != nil {
+				return err
+			}
+			oldPlan, err := d.planDep(c.dep, c.oldVer, oldPkgs, nil)
+			if err != nil {
+				return err
+			}
+			oldPlan.print(w)
+			totalPkgs, totalLines = oldPlan.addScope(totalPkgs, totalLines)
+			// the new version's lint targets depend on which packages
+			// changed since the old version
+			prev = &depFindings{
+				Dep:      c.dep,
+				Version:  c.oldVer,
+				Metadata: d.buildMetadata(),
+			}
+		}
+
+		newPkgs, err := d.setupDep(ctx, d.newModBackupFiles, c.dep, c.newVer, true)
+		if err != nil {
+			return err
+		}
+		newPlan, err := d.planDep(c.dep, c.newVer, newPkgs, prev)
+		if err != nil {
+			return err
+		}
+		newPlan.print(w)
+		totalPkgs, totalLines = newPlan.addScope(totalPkgs, totalLines)
+	}
+	fmt.Fprintf(w, "\nIn total %d packages with %d lines would be inspected\n", totalPkgs, totalLines)
+
+	return nil
+}
+
+// planDep returns what inspecting a dependency version would do. go.mod
+// must be set up with the version and pkgs loaded with it.
+func (d *depInspector) planDep(dep, version string, pkgs loadedPackages, prev *depFindings) (*depPlan, error) {
+	pkgInfo, err := describePackages(dep, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	capslockPkgs, err := d.capslockPackages(dep, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := d.findLintTargets(dep, version, pkgs, prev)
+	if err != nil {
+		return nil, err
+	}
+	// packages are loaded in no particular order
+	slices.Sort(capslockPkgs)
+	slices.Sort(targets.golangciLintDirs)
+	slices.Sort(targets.staticcheckPkgs)
+
+	return &depPlan{
+		VersionStr:       makeVersionStr(dep, version),
+		Packages:         pkgInfo,
+		CapslockPkgs:     capslockPkgs,
+		GolangciLintDirs: targets.golangciLintDirs,
+		StaticcheckPkgs:  targets.staticcheckPkgs,
+		ReusedPkgs:       targets.reusedPkgs,
+	}, nil
+}
+
+// addScope adds how many packages and lines would be inspected to
+// running totals.
+func (p *depPlan) addScope(pkgs, lines int) (int, int) {
+	pkgs += len(p.Packages)
+	for _, pkg := range p.Packages {
+		lines += pkg.Lines
+	}
+	return pkgs, lines
+}
+
+func (p *depPlan) print(w io.Writer) {
+	var files, lines int
+	for _, pkg := range p.Packages {
+		files += pkg.Files
+		lines += pkg.Lines
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", p.VersionStr)
+	fmt.Fprintf(w, "  %d used packages, %d files, %d lines\n", len(p.Packages), files, lines)
+	printList(w, "capslock packages", p.CapslockPkgs)
+	printList(w, "golangci-lint directories", p.GolangciLintDirs)
+	printList(w, "staticcheck packages", p.StaticcheckPkgs)
+	if p.ReusedPkgs != 0 {
+		fmt.Fprintf(w, "  linter issues of %d packages unchanged since the old version would be reused\n", p.ReusedPkgs)
+	}
+}
+
+func printList(w io.Writer, title string, items []string) {
+	fmt.Fprintf(w, "  %s (%d):\n", title, len(items))
+	for _, item := range items {
+		fmt.Fprintf(w, "    %s\n", item)
+	}
+}
+
+// printDownloads prints which module versions aren't in the module
+// cache and would be downloaded.
+func (d *depInspector) printDownloads(w io.Writer, versionStrs ...string) {
+	for _, versionStr := range versionStrs {
+		dep, version, _ := strings.Cut(versionStr, "@")
+		zipPath, err := moduleZipPath(d.modCache, dep, version)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(zipPath); err != nil {
+			fmt.Fprintf(w, "%s would be downloaded\n", versionStr)
+		} else {
+			fmt.Fprintf(w, "%s is in the module cache\n", versionStr)
+		}
+	}
+}
+
+// printModChanges prints how the requirements of go.mod would change.
+func printModChanges(w io.Writer, title string, before, after *modfile.File) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	var changed bool
+	for _, c := range findChangedDeps(before, after) {
+		changed = true
+		if c.oldVer == "" {
+			fmt.Fprintf(w, "  %s added at %s\n", c.dep, c.newVer)
+		} else {
+			fmt.Fprintf(w, "  %s %s → %s\n", c.dep, c.oldVer, c.newVer)
+		}
+	}
+	for _, req := range before.Require {
+		var found bool
+		for _, afterReq := range after.Require {
+			if afterReq.Mod.Path == req.Mod.Path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			changed = true
+			fmt.Fprintf(w, "  %s removed\n", req.Mod.Path)
+		}
+	}
+	if !changed {
+		fmt.Fprintln(w, "  none")
+	}
+}
+
+// backedUpModFile restores go.mod from a backup and parses it.
+func (d *depInspector) backedUpModFile(modBackupFiles *modFilePair) (*modfile.File, error) {
+	if err := d.restoreGoMod(modBackupFiles); err != nil {
+		return nil, err
+	}
+	return parseModFile(d.modFilePath)
+}
+
+func parseModFile(path string) (*modfile.File, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod: %w", err)
+	}
+	modFile, err := modfile.ParseLax(path, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod: %w", err)
+	}
+	return modFile, nil
+}
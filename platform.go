@@ -0,0 +1,282 @@
+package main
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/tools/go/packages"
+)
+
+var (
+	// knownOS and knownArch are the values of GOOS and GOARCH build
+	// constraints and file name suffixes can refer to
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips",
+		"mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le",
+		"riscv", "riscv64", "s390", "s390x", "sparc", "sparc64", "wasm",
+	}
+
+	// importCaps are capabilities a package has if it imports the
+	// package of the key, used to find the capabilities of files that
+	// aren't analyzed on the target platform
+	importCaps = map[string]string{
+		"C":                                 "CAPABILITY_CGO",
+		"crypto/tls":                        "CAPABILITY_NETWORK",
+		"golang.org/x/sys/plan9":            "CAPABILITY_SYSTEM_CALLS",
+		"golang.org/x/sys/unix":             "CAPABILITY_SYSTEM_CALLS",
+		"golang.org/x/sys/windows":          "CAPABILITY_SYSTEM_CALLS",
+		"golang.org/x/sys/windows/registry": "CAPABILITY_MODIFY_SYSTEM_STATE",
+		"golang.org/x/sys/windows/svc":      "CAPABILITY_MODIFY_SYSTEM_STATE",
+		"net":                               "CAPABILITY_NETWORK",
+		"net/http":                          "CAPABILITY_NETWORK",
+		"net/rpc":                           "CAPABILITY_NETWORK",
+		"net/smtp":                          "CAPABILITY_NETWORK",
+		"os/exec":                           "CAPABILITY_EXEC",
+		"os/signal":                         "CAPABILITY_OPERATING_SYSTEM",
+		"os/user":                           "CAPABILITY_READ_SYSTEM_STATE",
+		"plugin":                            "CAPABILITY_ARBITRARY_EXECUTION",
+		"reflect":                           "CAPABILITY_REFLECT",
+		"syscall":                           "CAPABILITY_SYSTEM_CALLS",
+		"unsafe":                            "CAPABILITY_UNSAFE_POINTER",
+	}
+)
+
+// platformSummary is the packages of a dependency that have code that
+// is only built for some platforms. Capslock and the linters only
+// analyze the code built for the platform dep-inspector runs on.
+type platformSummary struct {
+	// Platform is the GOOS/GOARCH the dependency was analyzed for
+	Platform string
+	Packages []platformPackage
+}
+
+// platformPackage is a package with files that are only built for some
+// platforms.
+type platformPackage struct {
+	Path  string
+	Files []platformFile
+	// OtherPlatformCaps are capabilities only files that weren't
+	// analyzed have, found from their imports
+	OtherPlatformCaps []string `json:",omitempty"`
+}
+
+// platformFile is a file that is only built for some platforms.
+type platformFile struct {
+	Name string
+	// Constraint is the file's build constraint, or the constraint its
+	// name implies, such as 'windows && arm64'
+	Constraint string
+	// Analyzed is true if the file is built for the platform the
+	// dependency was analyzed for
+	Analyzed bool `json:",omitempty"`
+}
+
+// OtherPlatformCapNames returns the names of OtherPlatformCaps as they
+// are shown in reports.
+func (p platformPackage) OtherPlatformCapNames() []string {
+	names := make([]string, len(p.OtherPlatformCaps))
+	for i, c := range p.OtherPlatformCaps {
+		names[i] = capDisplayName(c)
+	}
+	return names
+}
+
+// AnalyzedFiles returns how many of the package's platform specific
+// files were analyzed.
+func (p platformPackage) AnalyzedFiles() int {
+	var n int
+	for _, file := range p.Files {
+		if file.Analyzed {
+			n++
+		}
+	}
+	return n
+}
+
+// newPlatforms returns the platform summary of source facts, or nil if
+// there are none.
+func newPlatforms(facts *sourceFacts) *platformSummary {
+	if facts == nil {
+		return nil
+	}
+	return facts.Platforms
+}
+
+// OtherPlatformCaps returns the packages that have capabilities that
+// are only in files that weren't analyzed along with the capabilities,
+// such as 'example.com/pkg: Exec, Network'.
+func (p *platformSummary) OtherPlatformCaps() []string {
+	var pkgCaps []string
+	for _, pp := range p.Packages {
+		if len(pp.OtherPlatformCaps) != 0 {
+			pkgCaps = append(pkgCaps, pp.Path+": "+strings.Join(pp.OtherPlatformCapNames(), ", "))
+		}
+	}
+	return pkgCaps
+}
+
+// targetPlatform returns the GOOS/GOARCH packages are loaded for, as
+// resolved by the go command.
+func (d *depInspector) targetPlatform() string {
+	return d.goEnv["GOOS"] + "/" + d.goEnv["GOARCH"]
+}
+
+// scanPlatforms finds the files of a package that are only built for
+// some platforms. Files that weren't built for platform, the platform
+// packages were loaded for, are parsed to find capabilities that
+// capslock couldn't.
+func (s *sourceFacts) scanPlatforms(pkg *packages.Package, analyzed []*sourceFile, platform string) {
+	pp := platformPackage{Path: pkg.PkgPath}
+	analyzedCaps := make(map[string]bool)
+	for _, f := range analyzed {
+		for c := range fileImportCaps(f.file) {
+			analyzedCaps[c] = true
+		}
+		if c := fileConstraint(filepath.Base(f.path), f.file); c != "" {
+			pp.Files = append(pp.Files, platformFile{
+				Name:       filepath.Base(f.path),
+				Constraint: c,
+				Analyzed:   true,
+			})
+		}
+	}
+
+	otherCaps := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range pkg.IgnoredFiles {
+		name := filepath.Base(file)
+		if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			// files that are never built may not be valid Go
+			continue
+		}
+		c := fileConstraint(name, f)
+		if c == "" {
+			continue
+		}
+		pp.Files = append(pp.Files, platformFile{
+			Name:       name,
+			Constraint: c,
+		})
+		for c := range fileImportCaps(f) {
+			if !analyzedCaps[c] {
+				otherCaps[c] = true
+			}
+		}
+	}
+	if len(pp.Files) == 0 {
+		return
+	}
+
+	slices.SortFunc(pp.Files, func(a, b platformFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	pp.OtherPlatformCaps = maps.Keys(otherCaps)
+	slices.Sort(pp.OtherPlatformCaps)
+	if s.Platforms == nil {
+		s.Platforms = &platformSummary{Platform: platform}
+	}
+	s.Platforms.Packages = append(s.Platforms.Packages, pp)
+}
+
+// fileImportCaps returns the capabilities a file has from the packages
+// it imports.
+func fileImportCaps(f *ast.File) map[string]bool {
+	caps := make(map[string]bool)
+	for _, imp := range f.Imports {
+		impPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if c, ok := importCaps[impPath]; ok {
+			caps[c] = true
+		}
+	}
+	return caps
+}
+
+// fileConstraint returns the build constraint of a file if it refers
+// to GOOS or GOARCH, otherwise the constraint the file's name implies.
+// An empty string is returned if the file is built for every platform.
+func fileConstraint(name string, f *ast.File) string {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(comment.Text)
+			if err == nil && hasPlatformTag(expr) {
+				return expr.String()
+			}
+		}
+	}
+
+	goos, goarch := nameConstraint(name)
+	switch {
+	case goos != "" && goarch != "":
+		return goos + " && " + goarch
+	case goos != "":
+		return goos
+	default:
+		return goarch
+	}
+}
+
+// hasPlatformTag returns true if a build constraint refers to GOOS or
+// GOARCH.
+func hasPlatformTag(expr constraint.Expr) bool {
+	switch e := expr.(type) {
+	case *constraint.TagExpr:
+		return e.Tag == "unix" || slices.Contains(knownOS, e.Tag) || slices.Contains(knownArch, e.Tag)
+	case *constraint.NotExpr:
+		return hasPlatformTag(e.X)
+	case *constraint.AndExpr:
+		return hasPlatformTag(e.X) || hasPlatformTag(e.Y)
+	case *constraint.OrExpr:
+		return hasPlatformTag(e.X) || hasPlatformTag(e.Y)
+	}
+	return false
+}
+
+// nameConstraint returns the GOOS and GOARCH a file's name restricts
+// it to, such as 'windows' and 'amd64' for 'sys_windows_amd64.go'.
+func nameConstraint(name string) (string, string) {
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "_test")
+	// the part before the first '_' is never a constraint, so 'linux.go'
+	// is built for every platform
+	_, name, ok := strings.Cut(name, "_")
+	if !ok {
+		return "", ""
+	}
+
+	parts := strings.Split(name, "_")
+	last := parts[len(parts)-1]
+	if len(parts) >= 2 && slices.Contains(knownArch, last) && slices.Contains(knownOS, parts[len(parts)-2]) {
+		return parts[len(parts)-2], last
+	}
+	if slices.Contains(knownOS, last) {
+		return last, ""
+	}
+	if slices.Contains(knownArch, last) {
+		return "", last
+	}
+	return "", ""
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// exitPolicyViolation is the exit code when findings violate a policy
+// rule passed with -fail-on.
+const exitPolicyViolation = 3
+
+var policyRuleRe = regexp.MustCompile(`^\s*(added|removed|delta|total)\.(caps|issues)(?:\.([\w-]+))?\s*(>=|<=|==|!=|>|<)\s*(-?\d+)\s*$`)
+
+// policyRule is a threshold on the number of findings, such as
+// 'added.caps.NETWORK > 0' or 'added.issues > 5'.
+type policyRule struct {
+	raw string
+	// scope is added, removed, delta or total
+	scope string
+	// kind is caps or issues
+	kind string
+	// name is the capability or linter name, all findings of kind are
+	// counted if empty
+	name      string
+	op        string
+	threshold int
+}
+
+// policyViolation is a policy rule that findings violated.
+type policyViolation struct {
+	Dep   string `json:"dep"`
+	Rule  string `json:"rule"`
+	Value int    `json:"value"`
+	// License is the disallowed license of license policy violations
+	License string `json:"license,omitempty"`
+	// Version is the unapproved version of approval policy violations
+	Version string `json:"version,omitempty"`
+}
+
+func (v policyViolation) String() string {
+	if v.License != "" || v.Version != "" {
+		return fmt.Sprintf("%s: %s", v.Dep, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s, was %d", v.Dep, v.Rule, v.Value)
+}
+
+func parsePolicyRules(rules []string) ([]policyRule, error) {
+	parsed := make([]policyRule, 0, len(rules))
+	for _, rule := range rules {
+		m := policyRuleRe.FindStringSubmatch(rule)
+		if m == nil {
+			return nil, fmt.Errorf("invalid policy rule %q: must be in the form 'added|removed|delta|total.caps|issues[.NAME] OP N'", rule)
+		}
+		threshold, err := strconv.Atoi(m[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy rule %q: %w", rule, err)
+		}
+		parsed = append(parsed, policyRule{
+			raw:       strings.TrimSpace(rule),
+			scope:     m[1],
+			kind:      m[2],
+			name:      m[3],
+			op:        m[4],
+			threshold: threshold,
+		})
+	}
+
+	return parsed, nil
+}
+
+// policyTotals are the totals of findings policy rules are evaluated
+// against.
+type policyTotals struct {
+	added   findingTotals
+	removed findingTotals
+	// total also holds the deltas of findings when comparing
+	total findingTotals
+}
+
+func newPolicyTotals(res *savedResults) policyTotals {
+	if res.Old == nil {
+		totals := calculateTotals(res.New.Caps.CapabilityInfo, res.New.Issues)
+		return policyTotals{
+			added: totals,
+			total: totals,
+		}
+	}
+
+	compared := prepareCompareDepsResult(res.Old, res.New)
+	return policyTotals{
+		added:   compared.NewFindings.Totals,
+		removed: compared.OldFindings.Totals,
+		total:   compared.Totals,
+	}
+}
+
+// checkPolicy evaluates policy rules and the license policy against
+// results, returning the rules that were violated.
+func (d *depInspector) checkPolicy(res *savedResults) []policyViolation {
+	violations := append(d.checkLicenses(res), d.checkApproval(res)...)
+	if len(d.policyRules) == 0 {
+		return violations
+	}
+
+	totals := newPolicyTotals(d.prepareResults(res))
+	for _, rule := range d.policyRules {
+		value := rule.value(totals)
+		if rule.violated(value) {
+			violations = append(violations, policyViolation{
+				Dep:   res.New.Dep,
+				Rule:  rule.raw,
+				Value: value,
+			})
+		}
+	}
+
+	return violations
+}
+
+func (r policyRule) value(totals policyTotals) int {
+	var (
+		t      findingTotals
+		counts map[string]int
+		total  int
+	)
+	switch r.scope {
+	case "added":
+		t = totals.added
+	case "removed":
+		t = totals.removed
+	case "total", "delta":
+		t = totals.total
+	}
+	if r.kind == "caps" {
+		counts, total = t.Caps, t.TotalCaps
+	} else {
+		counts, total = t.Issues, t.TotalIssues
+	}
+
+	// without an old version there are no deltas, every finding is new
+	if r.scope == "delta" && t.HasDeltas {
+		if r.kind == "caps" {
+			counts = t.CapDeltas
+		} else {
+			counts = t.IssueDeltas
+		}
+		total = 0
+		for _, delta := range counts {
+			total += delta
+		}
+	}
+	if r.name == "" {
+		return total
+	}
+
+	var value int
+	for name, count := range counts {
+		if r.matches(name) {
+			value += count
+		}
+	}
+	return value
+}
+
+// matches returns true if a name of findings totals is the capability
+// or linter the rule is about.
+func (r policyRule) matches(name string) bool {
+	if r.kind == "issues" {
+		return name == r.name
+	}
+	// capability names of totals are title cased without the
+	// CAPABILITY_ prefix
+	capName := strings.TrimPrefix(strings.ToUpper(r.name), "CAPABILITY_")
+	return strings.ToUpper(strings.ReplaceAll(name, " ", "_")) == capName
+}
+
+func (r policyRule) violated(value int) bool {
+	switch r.op {
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case "==":
+		return value == r.threshold
+	default:
+		return value != r.threshold
+	}
+}
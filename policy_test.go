@@ -0,0 +1,89 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePolicyRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    policyRule
+		wantErr bool
+	}{
+		{
+			name: "all capabilities",
+			rule: "added.caps > 0",
+			want: policyRule{raw: "added.caps > 0", scope: "added", kind: "caps", op: ">", threshold: 0},
+		},
+		{
+			name: "named capability",
+			rule: "added.caps.NETWORK > 0",
+			want: policyRule{raw: "added.caps.NETWORK > 0", scope: "added", kind: "caps", name: "NETWORK", op: ">", threshold: 0},
+		},
+		{
+			name: "linter name with dash",
+			rule: "total.issues.go-critic >= 10",
+			want: policyRule{raw: "total.issues.go-critic >= 10", scope: "total", kind: "issues", name: "go-critic", op: ">=", threshold: 10},
+		},
+		{
+			name: "negative threshold",
+			rule: "delta.caps<-2",
+			want: policyRule{raw: "delta.caps<-2", scope: "delta", kind: "caps", op: "<", threshold: -2},
+		},
+		{
+			name: "surrounding space is trimmed",
+			rule: "  removed.issues != 3 ",
+			want: policyRule{raw: "removed.issues != 3", scope: "removed", kind: "issues", op: "!=", threshold: 3},
+		},
+		{
+			name: "equality",
+			rule: "added.issues == 1",
+			want: policyRule{raw: "added.issues == 1", scope: "added", kind: "issues", op: "==", threshold: 1},
+		},
+		{
+			name:    "unknown scope",
+			rule:    "new.caps > 0",
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			rule:    "added.vulns > 0",
+			wantErr: true,
+		},
+		{
+			name:    "missing threshold",
+			rule:    "added.caps >",
+			wantErr: true,
+		},
+		{
+			name:    "unknown operator",
+			rule:    "added.caps => 1",
+			wantErr: true,
+		},
+		{
+			name:    "threshold out of range",
+			rule:    "added.caps > 99999999999999999999",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parsePolicyRules([]string{tt.rule})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePolicyRules(%q) = %+v, want error", tt.rule, rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePolicyRules(%q) returned error: %v", tt.rule, err)
+			}
+			if want := []policyRule{tt.want}; !slices.Equal(rules, want) {
+				t.Errorf("parsePolicyRules(%q) = %+v, want %+v", tt.rule, rules, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// progressIgnoredFlags are flags that don't change findings, so a run
+// can be resumed with different values of them.
+var progressIgnoredFlags = []string{"resume", "timeout", "jobs", "v"}
+
+// inspectProgress records the results of changed dependencies that
+// were inspected, so a run that was interrupted before inspecting all
+// of them can be resumed with -resume.
+type inspectProgress struct {
+	path string
+	// Inspected are the results of every dependency that was
+	// completely inspected, keyed by dependency
+	Inspected map[string]*savedResults
+}
+
+// loadProgress returns the progress of inspecting depsToInspect. If
+// -resume was passed, the progress of a previous run that inspected the
+// same dependencies with the same flags and tools is loaded.
+func (d *depInspector) loadProgress(depsToInspect []changedDep) (*inspectProgress, error) {
+	path, err := d.progressPath(depsToInspect)
+	if err != nil {
+		return nil, err
+	}
+	progress := &inspectProgress{
+		path:      path,
+		Inspected: make(map[string]*savedResults),
+	}
+	if !d.resume {
+		return progress, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Println("no interrupted run to resume found, inspecting every dependency")
+		return progress, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading progress: %w", err)
+	}
+	if err := json.Unmarshal(contents, progress); err != nil {
+		return nil, fmt.Errorf("decoding progress: %w", err)
+	}
+	log.Printf("resuming interrupted run, %d of %d dependencies were already inspected", len(progress.Inspected), len(depsToInspect))
+
+	return progress, nil
+}
+
+// progressPath returns the path progress of inspecting depsToInspect is
+// persisted to, in the user's cache directory. It is unique to the main
+// module, the changed dependencies, the passed flags and the versions of
+// the analysis tools so results are only reused by a run that would
+// have found the same ones.
+func (d *depInspector) progressPath(depsToInspect []changedDep) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	modPath, err := filepath.Abs(d.modFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintln(hash, modPath)
+	for _, c := range depsToInspect {
+		fmt.Fprintln(hash, c.dep, c.oldVer, c.newVer)
+	}
+	for _, f := range d.runFlags {
+		name, _, _ := strings.Cut(strings.TrimPrefix(f, "-"), "=")
+		if slices.Contains(progressIgnoredFlags, name) {
+			continue
+		}
+		fmt.Fprintln(hash, f)
+	}
+	tools := make([]string, 0, len(d.toolVersions))
+	for tool, version := range d.toolVersions {
+		tools = append(tools, tool+" "+version)
+	}
+	slices.Sort(tools)
+	for _, tool := range tools {
+		fmt.Fprintln(hash, tool)
+	}
+
+	name := hex.EncodeToString(hash.Sum(nil)[:8]) + ".json"
+	return filepath.Join(cacheDir, "dep-inspector", "progress", name), nil
+}
+
+// results returns the results of dep if it was already inspected.
+func (p *inspectProgress) results(dep string) *savedResults {
+	return p.Inspected[dep]
+}
+
+// add records that a dependency was inspected and persists the
+// progress.
+func (p *inspectProgress) add(res *savedResults) error {
+	p.Inspected[res.New.Dep] = res
+
+	contents, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding progress: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	// exiting immediately while writing shouldn't corrupt progress
+	tempPath := p.path + ".tmp"
+	if err := writeFile(tempPath, bytes.NewReader(contents)); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, p.path)
+}
+
+// remove removes persisted progress once every dependency was
+// inspected.
+func (p *inspectProgress) remove() error {
+	err := os.Remove(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// publicProxy is the public Go module proxy, which serves every public
+// module and so is where same-named modules are looked for
+const publicProxy = "https://proxy.golang.org"
+
+// proxyAvailability is where a module is served from, recorded for
+// modules that are private or served by a private proxy. A public
+// module with the same path and a higher version than a private one
+// can be picked up instead of it by anyone without the same GOPRIVATE
+// or GOPROXY settings, which is known as dependency confusion.
+type proxyAvailability struct {
+	Module  string
+	Version string
+	// Private is true if the module matches GONOPROXY, so the go
+	// command fetches it directly instead of from a proxy
+	Private bool `json:",omitempty"`
+	// Proxy is the configured proxy that serves the module, empty if
+	// the module is private or no configured proxy serves it
+	Proxy string `json:",omitempty"`
+	// PublicLatest is the highest version of the module path the public
+	// proxy serves, empty if it doesn't serve the module path
+	PublicLatest string `json:",omitempty"`
+}
+
+// Confusable returns true if the public proxy serves a version of the
+// module path that is higher than the version used, so it would be
+// selected if the module was resolved from the public proxy.
+func (p proxyAvailability) Confusable() bool {
+	return p.PublicLatest != "" && semver.Compare(p.PublicLatest, p.Version) > 0
+}
+
+// ServedBy returns where the go command fetches the module from.
+func (p proxyAvailability) ServedBy() string {
+	switch {
+	case p.Private:
+		return "direct (GONOPROXY)"
+	case p.Proxy != "":
+		return p.Proxy
+	}
+	return "no configured proxy"
+}
+
+// findProxies finds where a dependency and the modules the main
+// module requires are served from. Modules only the public proxy
+// serves aren't recorded.
+func (d *depInspector) findProxies(ctx context.Context, dep, version string, modules []string) ([]proxyAvailability, error) {
+	mods := []string{makeVersionStr(dep, version)}
+	for _, mod := range modules {
+		if !strings.HasPrefix(mod, dep+"@") {
+			mods = append(mods, mod)
+		}
+	}
+
+	var proxies []proxyAvailability
+	for _, mod := range mods {
+		modPath, modVer, _ := strings.Cut(mod, "@")
+		avail, err := d.proxyAvailability(ctx, modPath, modVer)
+		if err != nil {
+			return nil, fmt.Errorf("checking where %s is served from: %w", mod, err)
+		}
+		if avail != nil {
+			proxies = append(proxies, *avail)
+		}
+	}
+	slices.SortFunc(proxies, func(a, b proxyAvailability) int {
+		return strings.Compare(a.Module, b.Module)
+	})
+
+	return proxies, nil
+}
+
+// proxyAvailability finds where a module is served from. nil is
+// returned if the module isn't private and the first configured proxy
+// that serves it is the public proxy.
+func (d *depInspector) proxyAvailability(ctx context.Context, modPath, version string) (*proxyAvailability, error) {
+	avail := &proxyAvailability{
+		Module:  modPath,
+		Version: version,
+		Private: module.MatchPrefixPatterns(d.goEnv["GONOPROXY"], modPath),
+	}
+	if !avail.Private {
+		for _, proxyURL := range d.proxyURLs() {
+			if proxyURL == publicProxy {
+				return nil, nil
+			}
+			versions, err := d.proxyVersions(ctx, proxyURL, modPath)
+			if err != nil {
+				return nil, err
+			}
+			if versions != nil {
+				avail.Proxy = proxyURL
+				break
+			}
+		}
+	}
+
+	versions, err := d.proxyVersions(ctx, publicProxy, modPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) != 0 {
+		avail.PublicLatest = versions[len(versions)-1]
+	}
+	if avail.Confusable() {
+		log.Printf("WARNING: %s has version %s on %s which is higher than the version used, %s", modPath, avail.PublicLatest, publicProxy, version)
+	}
+
+	return avail, nil
+}
+
+// proxyEntry is an entry of GOPROXY.
+type proxyEntry struct {
+	// URL is the URL of the proxy, or 'direct' or 'off'
+	URL string
+	// fallBackOnError is true if the entry is followed by '|', so the
+	// next entry is tried after any error. Otherwise it is only tried
+	// if this entry doesn't serve the module.
+	fallBackOnError bool
+}
+
+// errProxyOff is returned when a module is looked up and GOPROXY
+// disallows it.
+var errProxyOff = errors.New("module lookup disabled by GOPROXY=off")
+
+// proxyList returns the entries of GOPROXY that are used to look up a
+// module. Modules that match GONOPROXY are only looked up directly.
+func (d *depInspector) proxyList(modPath string) []proxyEntry {
+	if module.MatchPrefixPatterns(d.goEnv["GONOPROXY"], modPath) {
+		return []proxyEntry{{URL: sourceDirect}}
+	}
+	return d.goProxyEntries()
+}
+
+// goProxyEntries parses GOPROXY.
+func (d *depInspector) goProxyEntries() []proxyEntry {
+	goProxy := d.goEnv["GOPROXY"]
+	if goProxy == "" {
+		goProxy = publicProxy + "," + sourceDirect
+	}
+
+	var entries []proxyEntry
+	for goProxy != "" {
+		var entry proxyEntry
+		end := strings.IndexAny(goProxy, ",|")
+		if end == -1 {
+			entry.URL, goProxy = goProxy, ""
+		} else {
+			entry.URL = goProxy[:end]
+			entry.fallBackOnError = goProxy[end] == '|'
+			goProxy = goProxy[end+1:]
+		}
+		entry.URL = strings.TrimSuffix(strings.TrimSpace(entry.URL), "/")
+		if entry.URL == "" {
+			continue
+		}
+		entries = append(entries, entry)
+		// entries after 'off' are never reached
+		if entry.URL == "off" {
+			break
+		}
+	}
+	return entries
+}
+
+// proxyURLs returns the proxies of GOPROXY a module may be fetched
+// from, without the 'direct' and 'off' keywords.
+func (d *depInspector) proxyURLs() []string {
+	var urls []string
+	for _, entry := range d.goProxyEntries() {
+		if entry.URL != sourceDirect && entry.URL != "off" {
+			urls = append(urls, entry.URL)
+		}
+	}
+	return urls
+}
+
+// tryProxies looks up a module with the entries of GOPROXY in order
+// like the go command does. fromProxy is called with the URLs of
+// proxies and returns false if the proxy doesn't serve the module,
+// and direct is called for the 'direct' keyword. The next entry is
+// tried if a proxy doesn't serve the module, or after any error if the
+// entry is followed by '|'.
+func (d *depInspector) tryProxies(modPath string, fromProxy func(proxyURL string) (bool, error), direct func() error) error {
+	var lastErr error
+	for _, entry := range d.proxyList(modPath) {
+		var (
+			err      error
+			notFound bool
+		)
+		switch entry.URL {
+		case "off":
+			if lastErr != nil {
+				return lastErr
+			}
+			return errProxyOff
+		case sourceDirect:
+			err = direct()
+		default:
+			var found bool
+			found, err = fromProxy(entry.URL)
+			if err == nil && !found {
+				err = fmt.Errorf("%s is not served by %s", modPath, entry.URL)
+				notFound = true
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !notFound && !entry.fallBackOnError {
+			return err
+		}
+	}
+	if lastErr == nil {
+		return fmt.Errorf("no proxies to look up %s with in GOPROXY", modPath)
+	}
+	return lastErr
+}
+
+// moduleVersions returns the tagged versions of a module sorted by
+// semver, looking them up like the go command does.
+func (d *depInspector) moduleVersions(ctx context.Context, modPath string) ([]string, error) {
+	var versions []string
+	err := d.tryProxies(modPath,
+		func(proxyURL string) (bool, error) {
+			escPath, err := module.EscapePath(modPath)
+			if err != nil {
+				return false, err
+			}
+			list, found, err := d.proxyGet(ctx, proxyURL, escPath+"/@v/list")
+			versions = strings.Fields(string(list))
+			return found, err
+		},
+		func() error {
+			var mod struct {
+				Versions []string
+			}
+			if err := d.goListModule(ctx, &mod, "-versions", modPath); err != nil {
+				return err
+			}
+			versions = mod.Versions
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	versions = slices.DeleteFunc(versions, func(v string) bool {
+		return !semver.IsValid(v)
+	})
+	semver.Sort(versions)
+
+	return versions, nil
+}
+
+// moduleInfo is the .info file of a module version.
+type moduleInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// queryModule resolves a version or 'latest' of a module like the go
+// command does. The latest version is the highest release version, or
+// the highest pre-release version if there are no releases, or the
+// latest pseudo-version if the module has no tagged versions.
+func (d *depInspector) queryModule(ctx context.Context, modPath, query string) (*moduleInfo, error) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	version := query
+	var info moduleInfo
+	err = d.tryProxies(modPath,
+		func(proxyURL string) (bool, error) {
+			if query == "latest" {
+				list, found, err := d.proxyGet(ctx, proxyURL, escPath+"/@v/list")
+				if err != nil || !found {
+					return found, err
+				}
+				version = latestTaggedVersion(strings.Fields(string(list)))
+			}
+			file := escPath + "/@latest"
+			if version != "" && version != "latest" {
+				escVer, err := module.EscapeVersion(version)
+				if err != nil {
+					return false, err
+				}
+				file = escPath + "/@v/" + escVer + ".info"
+			}
+			body, found, err := d.proxyGet(ctx, proxyURL, file)
+			if err != nil || !found {
+				return found, err
+			}
+			if err := json.Unmarshal(body, &info); err != nil {
+				return false, fmt.Errorf("decoding %s from %s: %w", file, proxyURL, err)
+			}
+			return true, nil
+		},
+		func() error {
+			return d.goListModule(ctx, &info, makeVersionStr(modPath, query))
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// latestTaggedVersion returns the highest release version of versions,
+// or the highest pre-release version if there are no releases.
+func latestTaggedVersion(versions []string) string {
+	var latest, latestPre string
+	for _, v := range versions {
+		switch {
+		case !semver.IsValid(v):
+		case semver.Prerelease(v) == "":
+			if semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		default:
+			if semver.Compare(v, latestPre) > 0 {
+				latestPre = v
+			}
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+	return latestPre
+}
+
+// goListModule looks up a module directly from its repository with
+// 'go list -m' and decodes its JSON output into v.
+func (d *depInspector) goListModule(ctx context.Context, v any, args ...string) error {
+	var output bytes.Buffer
+	cmd, errBuf := d.buildCommand(ctx, &output, append([]string{"go", "list", "-m", "-json"}, args...)...)
+	cmd.Env = setEnv(cmd.Env, "GOPROXY="+sourceDirect)
+	if err := cmd.Run(); err != nil {
+		return formatCmdErr(ctx, cmd, err, errBuf)
+	}
+	if err := json.Unmarshal(output.Bytes(), v); err != nil {
+		return fmt.Errorf("decoding module info: %w", err)
+	}
+	return nil
+}
+
+// proxyVersions returns the versions of a module a proxy serves,
+// sorted by semver. nil is returned if the proxy doesn't serve the
+// module, and an empty slice if it only serves pseudo-versions.
+func (d *depInspector) proxyVersions(ctx context.Context, proxyURL, modPath string) ([]string, error) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	list, found, err := d.proxyGet(ctx, proxyURL, escPath+"/@v/list")
+	if err != nil || !found {
+		return nil, err
+	}
+
+	versions := strings.Fields(string(list))
+	if len(versions) == 0 {
+		// modules without tagged versions are only served at @latest
+		latest, found, err := d.proxyGet(ctx, proxyURL, escPath+"/@latest")
+		if err != nil || !found {
+			return []string{}, err
+		}
+		var info struct {
+			Version string
+		}
+		if err := json.Unmarshal(latest, &info); err != nil {
+			return nil, fmt.Errorf("decoding %s/@latest from %s: %w", modPath, proxyURL, err)
+		}
+		versions = append(versions, info.Version)
+	}
+	semver.Sort(versions)
+
+	return versions, nil
+}
+
+// proxyGet fetches a file from a module proxy. false is returned if
+// the proxy doesn't have the file.
+func (d *depInspector) proxyGet(ctx context.Context, proxyURL, file string) ([]byte, bool, error) {
+	r, found, err := d.proxyOpen(ctx, proxyURL, file)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s from %s: %w", file, proxyURL, err)
+	}
+	return body, true, nil
+}
+
+// proxyOpen requests a file from a module proxy, authenticating the
+// request if credentials for the proxy's host are available. false is
+// returned if the proxy doesn't have the file.
+func (d *depInspector) proxyOpen(ctx context.Context, proxyURL, file string) (io.ReadCloser, bool, error) {
+	reqURL := proxyURL + "/" + file
+	// module mirrors on disk have the same layout as proxies
+	if isFileProxy(proxyURL) {
+		u, err := url.Parse(reqURL)
+		if err != nil {
+			return nil, false, err
+		}
+		f, err := os.Open(filepath.FromSlash(u.Path))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return f, true, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	host := req.URL.Hostname()
+	if user, pass, ok := d.credentialsFor(ctx, host, d.isPrivate(host)); ok {
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("fetching %s: %w", reqURL, err)
+	}
+	// proxies respond with 404 or 410 for modules they don't serve
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, false, fmt.Errorf("fetching %s: %s returned %s", file, proxyURL, resp.Status)
+	}
+
+	return resp.Body, true, nil
+}
+
+// proxyChanges are the modules that became confusable with public
+// modules between two versions of a dependency, and that no longer are.
+type proxyChanges struct {
+	Added   []proxyAvailability
+	Removed []proxyAvailability
+}
+
+// compareProxies compares the confusable modules of two versions of a
+// dependency. Modules that are newly required and confusable are
+// included.
+func compareProxies(oldProxies, newProxies []proxyAvailability) *proxyChanges {
+	if oldProxies == nil && newProxies == nil {
+		return nil
+	}
+
+	confusable := func(proxies []proxyAvailability) []proxyAvailability {
+		return slices.DeleteFunc(slices.Clone(proxies), func(p proxyAvailability) bool {
+			return !p.Confusable()
+		})
+	}
+	removed, _, added := processFindings(confusable(oldProxies), confusable(newProxies), func(p proxyAvailability) string {
+		return p.Module
+	})
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &proxyChanges{
+		Added:   added,
+		Removed: removed,
+	}
+}
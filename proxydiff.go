@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// sourceDirect is the source of module versions fetched directly from
+// their repositories
+const sourceDirect = "direct"
+
+// proxyDiff is how a module version is served by the configured
+// proxies, the public proxy and its repository. Every source should
+// serve the same version, but only the hashes of public modules are
+// checked against the checksum database. A private proxy or repository
+// serving different contents than another source is the only sign that
+// one was tampered with.
+type proxyDiff struct {
+	// GoSumHash is the hash of the module zip in go.sum
+	GoSumHash string `json:",omitempty"`
+	Sources   []proxySource
+}
+
+// proxySource is a module version as one source serves it.
+type proxySource struct {
+	// Source is the URL of the proxy, or 'direct'
+	Source string
+	// NotServed is true if the source doesn't serve the version
+	NotServed bool `json:",omitempty"`
+	// Time is the time of the version from its .info file
+	Time      time.Time
+	GoModHash string `json:",omitempty"`
+	ZipHash   string `json:",omitempty"`
+	// Error is why the version couldn't be fetched from the source
+	Error string `json:",omitempty"`
+}
+
+// Differs returns true if any sources serve the version differently
+// from each other or from go.sum.
+func (p *proxyDiff) Differs() bool {
+	zipHash := p.GoSumHash
+	var (
+		goModHash string
+		verTime   time.Time
+	)
+	for _, src := range p.Sources {
+		if src.NotServed || src.Error != "" {
+			continue
+		}
+		if zipHash == "" {
+			zipHash = src.ZipHash
+		}
+		if goModHash == "" {
+			goModHash = src.GoModHash
+		}
+		if verTime.IsZero() {
+			verTime = src.Time
+		}
+		if src.ZipHash != zipHash || src.GoModHash != goModHash || !src.Time.Equal(verTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareProxyFetches fetches a module version from every configured
+// proxy, the public proxy if the module isn't private, and directly
+// from its repository, and records how each serves it.
+func (d *depInspector) compareProxyFetches(ctx context.Context, dep, version, goSumHash string) (*proxyDiff, error) {
+	tmpDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	proxies := d.proxyURLs()
+	if !module.MatchPrefixPatterns(d.goEnv["GONOPROXY"], dep) && !slices.Contains(proxies, publicProxy) {
+		proxies = append(proxies, publicProxy)
+	}
+
+	diff := &proxyDiff{GoSumHash: goSumHash}
+	for _, proxyURL := range proxies {
+		src, err := d.fetchFromProxy(ctx, proxyURL, dep, version, tmpDir)
+		if err != nil {
+			src = &proxySource{Source: proxyURL, Error: err.Error()}
+		}
+		diff.Sources = append(diff.Sources, *src)
+	}
+	src, err := d.fetchDirect(ctx, dep, version, tmpDir)
+	if err != nil {
+		src = &proxySource{Source: sourceDirect, Error: err.Error()}
+	}
+	diff.Sources = append(diff.Sources, *src)
+
+	return diff, nil
+}
+
+// fetchFromProxy fetches the .info, .mod and .zip files of a module
+// version from a proxy and hashes them.
+func (d *depInspector) fetchFromProxy(ctx context.Context, proxyURL, dep, version, tmpDir string) (*proxySource, error) {
+	escPath, err := module.EscapePath(dep)
+	if err != nil {
+		return nil, err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	verPath := escPath + "/@v/" + escVer
+	src := &proxySource{Source: proxyURL}
+
+	info, found, err := d.proxyGet(ctx, proxyURL, verPath+".info")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		src.NotServed = true
+		return src, nil
+	}
+	src.Time, err = infoTime(info)
+	if err != nil {
+		return nil, err
+	}
+	goMod, found, err := d.proxyGet(ctx, proxyURL, verPath+".mod")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s doesn't have %s.mod", proxyURL, verPath)
+	}
+	src.GoModHash, err = goModHash(goMod)
+	if err != nil {
+		return nil, err
+	}
+
+	zipPath := filepath.Join(tmpDir, "proxy.zip")
+	if err := d.proxyDownload(ctx, proxyURL, verPath+".zip", zipPath); err != nil {
+		return nil, err
+	}
+	src.ZipHash, err = dirhash.HashZip(zipPath, dirhash.Hash1)
+	if err != nil {
+		return nil, fmt.Errorf("hashing module zip: %w", err)
+	}
+
+	return src, nil
+}
+
+// fetchDirect downloads a module version from its repository into a
+// temporary module cache. The checksum database isn't consulted so
+// versions that differ from it are still fetched and reported.
+func (d *depInspector) fetchDirect(ctx context.Context, dep, version, tmpDir string) (*proxySource, error) {
+	var output bytes.Buffer
+	cmd, errBuf := d.buildCommand(ctx, &output, "go", "mod", "download", "-json", makeVersionStr(dep, version))
+	// outside of a module go.sum isn't checked either
+	cmd.Dir = tmpDir
+	cmd.Env = setEnv(cmd.Env,
+		"GOPROXY="+sourceDirect,
+		"GOSUMDB=off",
+		"GOFLAGS=-modcacherw",
+		"GOMODCACHE="+filepath.Join(tmpDir, "modcache"),
+	)
+	// 'go mod download -json' reports errors in its output
+	runErr := cmd.Run()
+
+	var mod struct {
+		Info     string
+		Sum      string
+		GoModSum string
+		Error    string
+	}
+	if err := json.Unmarshal(output.Bytes(), &mod); err != nil {
+		if runErr != nil {
+			return nil, formatCmdErr(ctx, cmd, runErr, errBuf)
+		}
+		return nil, fmt.Errorf("decoding module download: %w", err)
+	}
+	if mod.Error != "" {
+		return nil, fmt.Errorf("downloading module: %s", mod.Error)
+	}
+
+	info, err := os.ReadFile(mod.Info)
+	if err != nil {
+		return nil, fmt.Errorf("reading module info: %w", err)
+	}
+	verTime, err := infoTime(info)
+	if err != nil {
+		return nil, err
+	}
+	return &proxySource{
+		Source:    sourceDirect,
+		Time:      verTime,
+		GoModHash: mod.GoModSum,
+		ZipHash:   mod.Sum,
+	}, nil
+}
+
+// proxyDownload downloads a file from a module proxy to path.
+func (d *depInspector) proxyDownload(ctx context.Context, proxyURL, file, path string) error {
+	r, found, err := d.proxyOpen(ctx, proxyURL, file)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s doesn't have %s", proxyURL, file)
+	}
+	defer r.Close()
+
+	return writeFile(path, r)
+}
+
+// infoTime returns the time of a module version from its .info file.
+func infoTime(info []byte) (time.Time, error) {
+	var verInfo struct {
+		Time time.Time
+	}
+	if err := json.Unmarshal(info, &verInfo); err != nil {
+		return time.Time{}, fmt.Errorf("decoding module info: %w", err)
+	}
+	return verInfo.Time, nil
+}
+
+// goModHash returns the hash of a go.mod file as go.sum records it.
+func goModHash(goMod []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(goMod)), nil
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// maxReleasePages is the maximum number of pages of releases that
+	// are fetched
+	maxReleasePages = 10
+	// maxReleaseNotesLen is the maximum length of the notes of a
+	// release that are included in reports
+	maxReleaseNotesLen = 20_000
+)
+
+// releaseNotes are the notes of a release of a dependency.
+type releaseNotes struct {
+	Version string
+	Name    string
+	URL     string
+	Body    string
+}
+
+// findReleaseNotes fetches the release notes of every version of a
+// dependency after oldVer up to and including newVer from GitHub or
+// GitLab, newest first.
+func (d *depInspector) findReleaseNotes(ctx context.Context, dep, oldVer, newVer string) ([]releaseNotes, error) {
+	tmpDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	modURL, err := d.findModuleURL(ctx, dep, newVer, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	repoURL, subdir := repoRoot(modURL)
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []releaseNotes
+	switch u.Host {
+	case "github.com":
+		releases, err = githubReleases(ctx, u.Path)
+	case "gitlab.com":
+		releases, err = gitlabReleases(ctx, u.Path)
+	default:
+		return nil, fmt.Errorf("release notes of %s repositories are not supported", u.Host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// tags of modules in subdirectories are prefixed with the
+	// subdirectory
+	var notes []releaseNotes
+	for _, release := range releases {
+		version := release.Version
+		if subdir != "" {
+			var ok bool
+			version, ok = strings.CutPrefix(version, subdir+"/")
+			if !ok {
+				continue
+			}
+		}
+		if !semver.IsValid(version) || semver.Compare(version, oldVer) <= 0 || semver.Compare(version, newVer) > 0 {
+			continue
+		}
+		release.Version = version
+		release.Body = sanitizeReleaseNotes(release.Body)
+		notes = append(notes, release)
+	}
+	slices.SortFunc(notes, func(a, b releaseNotes) int {
+		return semver.Compare(b.Version, a.Version)
+	})
+
+	return notes, nil
+}
+
+func githubReleases(ctx context.Context, repoPath string) ([]releaseNotes, error) {
+	const perPage = 100
+
+	var releases []releaseNotes
+	for page := 1; page <= maxReleasePages; page++ {
+		path := fmt.Sprintf("/repos%s/releases?per_page=%d&page=%d", repoPath, perPage, page)
+		var pageReleases []struct {
+			TagName string `json:"tag_name"`
+			Name    string `json:"name"`
+			HTMLURL string `json:"html_url"`
+			Body    string `json:"body"`
+			Draft   bool   `json:"draft"`
+		}
+		if err := githubRequest(ctx, os.Getenv("GITHUB_TOKEN"), http.MethodGet, path, nil, &pageReleases); err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		for _, release := range pageReleases {
+			if release.Draft {
+				continue
+			}
+			releases = append(releases, releaseNotes{
+				Version: release.TagName,
+				Name:    release.Name,
+				URL:     release.HTMLURL,
+				Body:    release.Body,
+			})
+		}
+		if len(pageReleases) < perPage {
+			break
+		}
+	}
+
+	return releases, nil
+}
+
+func gitlabReleases(ctx context.Context, repoPath string) ([]releaseNotes, error) {
+	const perPage = 100
+
+	project := url.PathEscape(strings.TrimPrefix(repoPath, "/"))
+	var releases []releaseNotes
+	for page := 1; page <= maxReleasePages; page++ {
+		reqURL := fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/releases?per_page=%d&page=%d", project, perPage, page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		var pageReleases []struct {
+			TagName     string `json:"tag_name"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Links       struct {
+				Self string `json:"self"`
+			} `json:"_links"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing releases: %s returned %s: %s", reqURL, resp.Status, msg)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&pageReleases)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding releases: %w", err)
+		}
+
+		for _, release := range pageReleases {
+			releases = append(releases, releaseNotes{
+				Version: release.TagName,
+				Name:    release.Name,
+				URL:     release.Links.Self,
+				Body:    release.Description,
+			})
+		}
+		if len(pageReleases) < perPage {
+			break
+		}
+	}
+
+	return releases, nil
+}
+
+// sanitizeReleaseNotes removes control characters from release notes
+// and truncates them. Notes are always shown as plain text, so markup
+// in them isn't rendered.
+func sanitizeReleaseNotes(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && (unicode.IsControl(r) || unicode.Is(unicode.Cf, r)) {
+			return -1
+		}
+		return r
+	}, body)
+	body = strings.TrimSpace(body)
+	if len(body) > maxReleaseNotesLen {
+		body = strings.ToValidUTF8(body[:maxReleaseNotesLen], "") + "\n... (truncated)"
+	}
+
+	return body
+}
+
+// codeFence returns a Markdown code fence that can enclose text, it is
+// longer than any run of backticks in text.
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
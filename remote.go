@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// syncToRemote copies go.mod, go.sum and -capability-map files to a new
+// temporary directory on a remote host and returns its path and the
+// paths of the capability maps in it. Nothing else of the main module
+// is copied.
+func (d *depInspector) syncToRemote(ctx context.Context, host string) (string, []string, error) {
+	var output strings.Builder
+	if err := d.runCommand(ctx, &output, sshCommand(host, "mktemp -d")...); err != nil {
+		return "", nil, fmt.Errorf("creating remote directory: %w", err)
+	}
+	remoteDir := trimNewline(output.String())
+
+	copyFile := func(src, dst string) error {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		cmd, errBuf := d.buildCommand(ctx, nil, sshCommand(host, "cat > "+shellQuote(dst))...)
+		cmd.Stdin = f
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("copying %s to %s: %w", src, host, formatCmdErr(ctx, cmd, err, errBuf))
+		}
+		return nil
+	}
+	for _, src := range []string{d.modFilePath, d.sumFilePath} {
+		if err := copyFile(src, path.Join(remoteDir, filepath.Base(src))); err != nil {
+			d.removeRemoteDir(ctx, host, remoteDir)
+			return "", nil, err
+		}
+	}
+	var capMapPaths []string
+	for i, src := range d.capMapPaths {
+		// capability maps may have the same names
+		dst := path.Join(remoteDir, strconv.Itoa(i)+"-"+filepath.Base(src))
+		if err := copyFile(src, dst); err != nil {
+			d.removeRemoteDir(ctx, host, remoteDir)
+			return "", nil, err
+		}
+		capMapPaths = append(capMapPaths, dst)
+	}
+
+	return remoteDir, capMapPaths, nil
+}
+
+// removeRemoteDir removes a directory syncToRemote created.
+func (d *depInspector) removeRemoteDir(ctx context.Context, host, remoteDir string) {
+	if err := d.runCommand(ctx, nil, sshCommand(host, "rm -rf "+shellQuote(remoteDir))...); err != nil {
+		log.Printf("error removing remote directory: %v", err)
+	}
+}
+
+// remoteCommand returns the command that runs dep-inspector on a
+// remote host in a directory syncToRemote created. Only go.mod and
+// go.sum of the main module are there, so which packages of the
+// dependency are used can't be found and all of them are inspected.
+func (d *depInspector) remoteCommand(ctx context.Context, host, remoteDir string, capMapPaths, depArgs []string) (*exec.Cmd, *bytes.Buffer) {
+	args := append([]string{"dep-inspector", "-unused-dep"}, d.runnerArgs(capMapPaths, true)...)
+	if d.cacheOnly {
+		args = append(args, "-cache-only")
+	}
+	args = append(args, depArgs...)
+
+	var remoteCmd strings.Builder
+	remoteCmd.WriteString("cd " + shellQuote(remoteDir) + " &&")
+	for _, arg := range args {
+		remoteCmd.WriteString(" " + shellQuote(arg))
+	}
+	return d.buildCommand(ctx, nil, sshCommand(host, remoteCmd.String())...)
+}
+
+// sshCommand returns the arguments to run a shell command on a remote
+// host. ssh never prompts for passwords or host keys as its output is
+// read by dep-inspector.
+func sshCommand(host, remoteCmd string) []string {
+	return []string{runnerSSH, "-o", "BatchMode=yes", host, remoteCmd}
+}
+
+// shellQuote quotes s so a POSIX shell treats it as a single word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func reportCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector [flags] report results.json|inspection-id")
+	}
+
+	res, err := d.loadResults(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	// source links are checked against the module cache
+	d.modCache, err = d.getGoModCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.outputResults(ctx, res)
+}
+
+func diffCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: dep-inspector [flags] diff old-results.json new-results.json")
+	}
+
+	oldRes, err := d.loadResults(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("loading old results: %w", err)
+	}
+	newRes, err := d.loadResults(ctx, args[1])
+	if err != nil {
+		return fmt.Errorf("loading new results: %w", err)
+	}
+	if oldRes.New.Dep != newRes.New.Dep {
+		return fmt.Errorf("cannot compare: results are of different dependencies %s and %s", oldRes.New.Dep, newRes.New.Dep)
+	}
+	d.modCache, err = d.getGoModCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	// if results of a comparison were saved, compare the newest
+	// findings of each
+	return d.outputResults(ctx, &savedResults{
+		Old: oldRes.New,
+		New: newRes.New,
+	})
+}
+
+// loadResults loads results from a saved results file, or from a
+// result store entry if a store is in use and an inspection ID is
+// passed.
+func (d *depInspector) loadResults(ctx context.Context, source string) (*savedResults, error) {
+	if d.store != nil {
+		if id, err := strconv.ParseInt(source, 10, 64); err == nil {
+			inspection, err := d.store.get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return &savedResults{New: inspection.Findings}, nil
+		}
+	}
+
+	return d.loadSavedResults(ctx, source)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// depFindings are the findings of inspecting a single dependency
+// version. They are what is recorded in result stores and saved
+// results files, reports can be rendered from them at any time.
+type depFindings struct {
+	Dep      string
+	Version  string
+	Caps     *capslockResult
+	Issues   []*lintIssue
+	Packages []string
+	// PackageInfo describes the packages of the dependency that were
+	// loaded
+	PackageInfo []packageInfo `json:",omitempty"`
+	// Modules are the module versions the main module required when
+	// the dependency was inspected
+	Modules []string `json:",omitempty"`
+	// GoSumHashes are the module zip hashes from the main module's
+	// go.sum file of the modules in Modules, keyed by module version
+	GoSumHashes map[string]string `json:",omitempty"`
+	// Licenses are the SPDX identifiers of the dependency's licenses
+	Licenses []string `json:",omitempty"`
+	// Ownership are signals of who controls the dependency, only set
+	// if -ownership was passed
+	Ownership *ownershipSignals `json:",omitempty"`
+	// Proxies are where the dependency and the modules the main module
+	// required are served from if they are private or served by a
+	// private proxy, only set if -check-proxies was passed
+	Proxies []proxyAvailability `json:",omitempty"`
+	// VCSDiff is how the dependency's module zip differs from its
+	// repository, only set if -compare-vcs was passed
+	VCSDiff *vcsDiff `json:",omitempty"`
+	// ProxyDiff is how the configured proxies, the public proxy and the
+	// dependency's repository serve its module version, only set if
+	// -compare-proxies was passed
+	ProxyDiff *proxyDiff `json:",omitempty"`
+	// CopiedCode are directories of the dependency that are copies of
+	// other modules, only set if -find-copies was passed
+	CopiedCode []copiedCode `json:",omitempty"`
+	// Source are facts about the dependency's behavior found by parsing
+	// its source code
+	Source *sourceFacts `json:",omitempty"`
+	// Size is the size in bytes of the dependency's module zip
+	Size int64 `json:",omitempty"`
+	// ZipHash is the hash of the dependency's module zip from go.sum.
+	// Findings are bound to the content that was inspected, a version
+	// whose hash changed must be inspected again
+	ZipHash string `json:",omitempty"`
+	// Vulns are the dependency's known vulnerabilities, only set if
+	// -vulns was passed
+	Vulns *vulnFindings `json:",omitempty"`
+	// Risk is the dependency's risk score, it is computed when results
+	// are output
+	Risk     *riskScore `json:",omitempty"`
+	Metadata reportMetadata
+}
+
+// savedResults are the results of inspecting a single dependency
+// version, or comparing two versions of a dependency if Old is set.
+type savedResults struct {
+	Old *depFindings `json:",omitempty"`
+	New *depFindings
+}
+
+// loadSavedResults reads and, if -verify was passed, verifies saved
+// results.
+func (d *depInspector) loadSavedResults(ctx context.Context, path string) (*savedResults, error) {
+	contents, err := d.readInput(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res savedResults
+	if err := json.Unmarshal(contents, &res); err != nil {
+		return nil, fmt.Errorf("decoding saved results: %w", err)
+	}
+	if res.New == nil || res.New.Caps == nil {
+		return nil, fmt.Errorf("%s does not contain any findings", path)
+	}
+	if res.Old != nil && res.Old.Caps == nil {
+		return nil, fmt.Errorf("%s does not contain any old findings", path)
+	}
+
+	return &res, nil
+}
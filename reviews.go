@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+const (
+	verdictPositive = "positive"
+	verdictNeutral  = "neutral"
+	verdictNegative = "negative"
+)
+
+var reviewVerdicts = []string{verdictPositive, verdictNeutral, verdictNegative}
+
+var errUntrustedReviewer = errors.New("reviewer is not trusted")
+
+// review is a reviewer's verdict on a dependency version. Reviews are
+// signed and published so other teams can reuse them instead of
+// auditing the same versions again.
+type review struct {
+	Dep     string `json:"dep"`
+	Version string `json:"version"`
+	// Reviewer is the identity of the reviewer's signing certificate,
+	// usually an email address
+	Reviewer string `json:"reviewer"`
+	Verdict  string `json:"verdict"`
+	// FindingsDigest identifies the findings that were reviewed
+	FindingsDigest string    `json:"findingsDigest"`
+	Notes          string    `json:"notes,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// signedReview is an encoded review and the sigstore bundle of its
+// signature. The review is kept encoded so the signed bytes don't
+// change when review files are reformatted.
+type signedReview struct {
+	Payload []byte          `json:"payload"`
+	Bundle  json.RawMessage `json:"bundle"`
+}
+
+// verifiedReview is a review signed by a trusted reviewer.
+type verifiedReview struct {
+	review
+	// Current is true if the reviewed findings are the same as the
+	// findings being reported
+	Current bool
+}
+
+type reviewsConfig struct {
+	// Sources are directories, HTTP endpoints or git repositories
+	// prefixed with 'git+' that reviews are published to
+	Sources []string          `yaml:"sources"`
+	Trusted []trustedReviewer `yaml:"trusted"`
+}
+
+// trustedReviewer is the certificate identity and OIDC issuer reviews
+// of a trusted party are signed with.
+type trustedReviewer struct {
+	Identity string `yaml:"identity"`
+	Issuer   string `yaml:"issuer"`
+}
+
+// reviewSources fetches reviews from where they are published.
+type reviewSources struct {
+	sources []string
+	trusted []trustedReviewer
+
+	mu sync.Mutex
+	// repos are the local clones of git repositories of reviews that
+	// were updated this run
+	repos map[string]string
+}
+
+func newReviewSources(sources []string, cfg reviewsConfig) (*reviewSources, error) {
+	sources = append(sources, cfg.Sources...)
+	for _, trusted := range cfg.Trusted {
+		if trusted.Identity == "" || trusted.Issuer == "" {
+			return nil, errors.New("trusted reviewers require an identity and an issuer")
+		}
+	}
+	if len(sources) != 0 && len(cfg.Trusted) == 0 {
+		return nil, errors.New("review sources were set but no reviewers are trusted")
+	}
+
+	return &reviewSources{
+		sources: sources,
+		trusted: cfg.Trusted,
+		repos:   make(map[string]string),
+	}, nil
+}
+
+// reviewPath returns the path of the reviews of a dependency version
+// relative to a review source. Paths mirror module proxy paths.
+func reviewPath(dep, version string) (string, error) {
+	escPath, err := module.EscapePath(dep)
+	if err != nil {
+		return "", err
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(escPath, "@v", escVer+".reviews.json"), nil
+}
+
+// findingsDigest hashes the fingerprints of findings so reviews can
+// record what was reviewed.
+func findingsDigest(findings *depFindings) string {
+	var fps []string
+	for _, fp := range findingFingerprints(findings.Dep, findings.Caps.CapabilityInfo, findings.Issues, "") {
+		fps = append(fps, fp.Fingerprint)
+	}
+	slices.Sort(fps)
+
+	h := sha256.New()
+	for _, fp := range fps {
+		fmt.Fprintln(h, fp)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findReviews returns the reviews of trusted reviewers of a dependency
+// version from every review source. Reviews that can't be verified are
+// skipped.
+func (d *depInspector) findReviews(ctx context.Context, findings *depFindings) ([]verifiedReview, error) {
+	if d.reviews == nil || len(d.reviews.sources) == 0 {
+		return nil, nil
+	}
+	relPath, err := reviewPath(findings.Dep, findings.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := findingsDigest(findings)
+	var reviews []verifiedReview
+	// the same review may be published to multiple sources
+	seen := make(map[string]bool)
+	for _, source := range d.reviews.sources {
+		signed, err := d.readReviews(ctx, source, relPath)
+		if err != nil {
+			log.Printf("error reading reviews from %s: %v", source, err)
+			continue
+		}
+		for _, sr := range signed {
+			if seen[string(sr.Payload)] {
+				continue
+			}
+			r, err := d.verifyReview(ctx, sr)
+			if errors.Is(err, errUntrustedReviewer) && !d.verbose {
+				continue
+			}
+			if err != nil {
+				log.Printf("skipping review of %s from %s: %v", makeVersionStr(findings.Dep, findings.Version), source, err)
+				continue
+			}
+			if r.Dep != findings.Dep || r.Version != findings.Version {
+				log.Printf("skipping review from %s: it is of %s", source, makeVersionStr(r.Dep, r.Version))
+				continue
+			}
+			seen[string(sr.Payload)] = true
+			reviews = append(reviews, verifiedReview{
+				review:  *r,
+				Current: r.FindingsDigest == digest,
+			})
+		}
+	}
+
+	return reviews, nil
+}
+
+// readReviews reads the signed reviews at relPath of a review source.
+// A source without reviews of a version is not an error.
+func (d *depInspector) readReviews(ctx context.Context, source, relPath string) ([]signedReview, error) {
+	var contents []byte
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(source, "/")+"/"+relPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned %s", req.URL, resp.Status)
+		}
+		contents, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		dir := source
+		if repo, ok := strings.CutPrefix(source, "git+"); ok {
+			var err error
+			dir, err = d.syncReviewRepo(ctx, repo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		contents, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var signed []signedReview
+	if err := json.Unmarshal(contents, &signed); err != nil {
+		return nil, fmt.Errorf("decoding reviews: %w", err)
+	}
+	return signed, nil
+}
+
+// syncReviewRepo clones or updates a git repository of reviews in the
+// user's cache directory, once per run.
+func (d *depInspector) syncReviewRepo(ctx context.Context, repo string) (string, error) {
+	d.reviews.mu.Lock()
+	defer d.reviews.mu.Unlock()
+
+	if dir, ok := d.reviews.repos[repo]; ok {
+		return dir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(repo))
+	dir := filepath.Join(cacheDir, "dep-inspector", "reviews", hex.EncodeToString(hash[:8]))
+
+	if _, err := os.Stat(dir); err == nil {
+		err = d.runCommand(ctx, nil, "git", "-C", dir, "pull", "--ff-only", "--quiet")
+	} else {
+		err = d.runCommand(ctx, nil, "git", "clone", "--depth", "1", "--quiet", repo, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("updating reviews from %s: %w", repo, err)
+	}
+	d.reviews.repos[repo] = dir
+
+	return dir, nil
+}
+
+// verifyReview verifies a review was signed by the trusted reviewer it
+// claims to be from with cosign.
+func (d *depInspector) verifyReview(ctx context.Context, sr signedReview) (*review, error) {
+	var r review
+	if err := json.Unmarshal(sr.Payload, &r); err != nil {
+		return nil, fmt.Errorf("decoding review: %w", err)
+	}
+	i := slices.IndexFunc(d.reviews.trusted, func(t trustedReviewer) bool {
+		return t.Identity == r.Reviewer
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("%w: %s", errUntrustedReviewer, r.Reviewer)
+	}
+	trusted := d.reviews.trusted[i]
+
+	tempDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	payloadPath := filepath.Join(tempDir, "review.json")
+	bundlePath := payloadPath + bundleExt
+	if err := os.WriteFile(payloadPath, sr.Payload, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(bundlePath, sr.Bundle, 0o644); err != nil {
+		return nil, err
+	}
+
+	err = d.runCommand(ctx, nil,
+		"cosign", "verify-blob",
+		"--bundle", bundlePath,
+		"--certificate-identity", trusted.Identity,
+		"--certificate-oidc-issuer", trusted.Issuer,
+		payloadPath,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("verifying signature of review by %s: %w", r.Reviewer, err)
+	}
+
+	return &r, nil
+}
+
+func reviewCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("review", flag.ContinueOnError)
+	reviewer := fs.String("reviewer", "", "identity of the certificate the review will be signed with, usually an email address")
+	verdict := fs.String("verdict", verdictPositive, "verdict of the review: "+strings.Join(reviewVerdicts, ", "))
+	notes := fs.String("notes", "", "notes about the review")
+	reviewsDir := fs.String("reviews-dir", ".", "directory to write the review to, such as a clone of a git repository of reviews")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: dep-inspector [flags] review [-reviewer identity] [-verdict verdict] [-notes notes] findings.json")
+	}
+	if *reviewer == "" {
+		return errors.New("-reviewer is required")
+	}
+	if !slices.Contains(reviewVerdicts, *verdict) {
+		return fmt.Errorf("unknown verdict %q, must be one of %s", *verdict, strings.Join(reviewVerdicts, ", "))
+	}
+
+	findingsPath := fs.Arg(0)
+	res, err := d.loadSavedResults(ctx, findingsPath)
+	if err != nil {
+		return err
+	}
+	// digest the findings reports would show
+	res = d.prepareResults(res)
+	r := review{
+		Dep:            res.New.Dep,
+		Version:        res.New.Version,
+		Reviewer:       *reviewer,
+		Verdict:        *verdict,
+		FindingsDigest: findingsDigest(res.New),
+		Notes:          *notes,
+		Time:           time.Now().UTC(),
+	}
+	sr, err := d.signReview(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	relPath, err := reviewPath(r.Dep, r.Version)
+	if err != nil {
+		return err
+	}
+	reviewsFile := filepath.Join(*reviewsDir, filepath.FromSlash(relPath))
+	if err := addReview(reviewsFile, r.Reviewer, sr); err != nil {
+		return err
+	}
+	log.Printf("wrote review of %s to %s, publish it to share it", makeVersionStr(r.Dep, r.Version), reviewsFile)
+
+	return nil
+}
+
+// signReview signs a review with cosign, keyless via sigstore.
+func (d *depInspector) signReview(ctx context.Context, r review) (signedReview, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return signedReview{}, fmt.Errorf("encoding review: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return signedReview{}, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	payloadPath := filepath.Join(tempDir, "review.json")
+	bundlePath := payloadPath + bundleExt
+	if err := os.WriteFile(payloadPath, payload, 0o644); err != nil {
+		return signedReview{}, err
+	}
+
+	log.Printf("signing review of %s", makeVersionStr(r.Dep, r.Version))
+	err = d.runCommand(ctx, nil, "cosign", "sign-blob", "--yes", "--bundle", bundlePath, payloadPath)
+	if err != nil {
+		return signedReview{}, fmt.Errorf("signing review: %w", err)
+	}
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return signedReview{}, fmt.Errorf("reading signature bundle: %w", err)
+	}
+
+	return signedReview{
+		Payload: payload,
+		Bundle:  bundle,
+	}, nil
+}
+
+// addReview adds a signed review to the reviews file at path,
+// replacing any previous review of the same reviewer.
+func addReview(path, reviewer string, sr signedReview) error {
+	var signed []signedReview
+	contents, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(contents, &signed); err != nil {
+			return fmt.Errorf("decoding reviews: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	signed = slices.DeleteFunc(signed, func(other signedReview) bool {
+		var r review
+		return json.Unmarshal(other.Payload, &r) == nil && r.Reviewer == reviewer
+	})
+	signed = append(signed, sr)
+
+	contents, err = json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding reviews: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	return os.WriteFile(path, append(contents, '\n'), 0o644)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+const (
+	riskCapabilities    = "capabilities"
+	riskIssues          = "issues"
+	riskUnsafe          = "unsafe"
+	riskSize            = "size"
+	riskVulnerabilities = "vulnerabilities"
+	riskHealth          = "health"
+)
+
+// riskSignals are the signals risk scores are computed from.
+var riskSignals = []string{riskCapabilities, riskIssues, riskUnsafe, riskSize, riskVulnerabilities, riskHealth}
+
+// defaultRiskWeights are how much each signal contributes to the risk
+// score of a dependency.
+var defaultRiskWeights = map[string]float64{
+	riskCapabilities:    40,
+	riskIssues:          15,
+	riskUnsafe:          15,
+	riskSize:            5,
+	riskVulnerabilities: 20,
+	riskHealth:          5,
+}
+
+// defaultCapRiskPoints are how many points each distinct capability of
+// a severity adds by default to the capabilities signal.
+var defaultCapRiskPoints = map[string]float64{
+	severityLow:      1,
+	severityMedium:   5,
+	severityHigh:     10,
+	severityCritical: 20,
+}
+
+const (
+	// maxCapRiskPoints is the number of capability points at which the
+	// capabilities signal is at its maximum
+	maxCapRiskPoints = 40
+	// maxIssueDensity is the number of issues per package at which the
+	// issues signal is at its maximum
+	maxIssueDensity = 5
+	// maxRiskSize is the size in bytes of a module zip at which the
+	// size signal is at its maximum
+	maxRiskSize = 10 << 20
+	// maxRiskVulns is the number of known vulnerabilities at which the
+	// vulnerabilities signal is at its maximum
+	maxRiskVulns = 3
+)
+
+// riskScore is a score from 0 to 100 of how risky a dependency is.
+type riskScore struct {
+	Score int
+	// Signals are how many points each signal contributed to the score
+	Signals map[string]int
+}
+
+// riskConfig configures how risk scores are computed.
+type riskConfig struct {
+	// Signals maps signal names to how much they contribute to risk
+	// scores relative to each other
+	Signals map[string]float64 `yaml:"signals"`
+	// Severities maps severities to how many points each distinct
+	// capability of that severity adds to the capabilities signal
+	Severities map[string]float64 `yaml:"severities"`
+	// Capabilities maps capability names, with or without the
+	// CAPABILITY_ prefix, to how many points they add to the
+	// capabilities signal, overriding points by severity
+	Capabilities map[string]float64 `yaml:"capabilities"`
+}
+
+// riskModel computes risk scores of findings.
+type riskModel struct {
+	weights   map[string]float64
+	sevPoints map[string]float64
+	capPoints map[string]float64
+}
+
+func newRiskModel(cfg riskConfig) (*riskModel, error) {
+	m := &riskModel{
+		weights:   maps.Clone(defaultRiskWeights),
+		sevPoints: maps.Clone(defaultCapRiskPoints),
+		capPoints: make(map[string]float64),
+	}
+	for signal, weight := range cfg.Signals {
+		if !slices.Contains(riskSignals, signal) {
+			return nil, fmt.Errorf("unknown risk signal %q: must be one of %s", signal, strings.Join(riskSignals, ", "))
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of risk signal %q is negative", signal)
+		}
+		m.weights[signal] = weight
+	}
+	for sev, points := range cfg.Severities {
+		if err := checkSeverity(sev); err != nil {
+			return nil, err
+		}
+		if points < 0 {
+			return nil, fmt.Errorf("risk points of severity %q are negative", sev)
+		}
+		m.sevPoints[sev] = points
+	}
+	for name, points := range cfg.Capabilities {
+		if points < 0 {
+			return nil, fmt.Errorf("risk points of capability %q are negative", name)
+		}
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "CAPABILITY_") {
+			name = "CAPABILITY_" + name
+		}
+		m.capPoints[name] = points
+	}
+
+	var totalWeight float64
+	for _, weight := range m.weights {
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return nil, errors.New("at least one risk signal must have a weight above 0")
+	}
+
+	return m, nil
+}
+
+// capabilityPoints returns how many points a capability adds to the
+// capabilities signal.
+func (m *riskModel) capabilityPoints(c *capability) float64 {
+	if points, ok := m.capPoints[c.Capability]; ok {
+		return points
+	}
+	return m.sevPoints[c.Severity]
+}
+
+// apply sets the risk score of findings. Severities must already be
+// set.
+func (m *riskModel) apply(findings *depFindings) {
+	if findings == nil {
+		return
+	}
+	findings.Risk = m.score(findings)
+}
+
+// score computes the risk score of findings from signals that are
+// scaled from 0 to 1 and weighted. Signals that weren't collected, such
+// as vulnerabilities when -vulns wasn't passed, aren't included.
+func (m *riskModel) score(findings *depFindings) *riskScore {
+	signals := make(map[string]float64)
+
+	var (
+		capNames  []string
+		capPoints float64
+		cgo       bool
+		unsafe    bool
+	)
+	for _, c := range findings.Caps.CapabilityInfo {
+		switch c.Capability {
+		case "CAPABILITY_CGO":
+			cgo = true
+		case "CAPABILITY_UNSAFE_POINTER":
+			unsafe = true
+		}
+		if slices.Contains(capNames, c.Capability) {
+			continue
+		}
+		capNames = append(capNames, c.Capability)
+		capPoints += m.capabilityPoints(c)
+	}
+	signals[riskCapabilities] = math.Min(1, capPoints/maxCapRiskPoints)
+
+	pkgs := max(len(findings.Packages), 1)
+	signals[riskIssues] = math.Min(1, float64(len(findings.Issues))/float64(pkgs)/maxIssueDensity)
+
+	signals[riskUnsafe] = 0
+	if cgo {
+		signals[riskUnsafe] += 0.5
+	}
+	if unsafe {
+		signals[riskUnsafe] += 0.5
+	}
+
+	if findings.Size > 0 {
+		signals[riskSize] = math.Min(1, float64(findings.Size)/maxRiskSize)
+	}
+	if findings.Vulns != nil {
+		signals[riskVulnerabilities] = math.Min(1, float64(len(findings.Vulns.IDs))/maxRiskVulns)
+	}
+	if findings.Licenses != nil || findings.Ownership != nil {
+		signals[riskHealth] = 0
+		if slices.Contains(findings.Licenses, licenseNone) || slices.Contains(findings.Licenses, licenseUnknown) {
+			signals[riskHealth] += 0.5
+		}
+		if findings.Ownership != nil && findings.Ownership.MovedTo != "" {
+			signals[riskHealth] += 0.5
+		}
+	}
+
+	var totalWeight float64
+	for name := range signals {
+		totalWeight += m.weights[name]
+	}
+	risk := &riskScore{
+		Signals: make(map[string]int, len(signals)),
+	}
+	if totalWeight == 0 {
+		return risk
+	}
+	var score float64
+	for name, value := range signals {
+		points := 100 * value * m.weights[name] / totalWeight
+		risk.Signals[name] = int(math.Round(points))
+		score += points
+	}
+	risk.Score = int(math.Round(score))
+
+	return risk
+}
+
+// riskDelta returns the change of a risk score formatted with a sign,
+// or an empty string if either score is missing.
+func riskDelta(oldRisk, newRisk *riskScore) string {
+	if oldRisk == nil || newRisk == nil {
+		return ""
+	}
+	return formatDelta(newRisk.Score - oldRisk.Score)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// rollupReport is an overview of every dependency that changed in a
+// recursive comparison, or every dependency of the main module. Rows
+// are ranked by risk score.
+type rollupReport struct {
+	Title string
+	// Compared is true if any dependency was compared against an old
+	// version
+	Compared bool
+	Rows     []rollupRow
+	// NotInspected are dependencies that weren't inspected because
+	// dep-inspector was interrupted
+	NotInspected []string `json:",omitempty"`
+}
+
+type rollupRow struct {
+	Dep        string
+	OldVersion string `json:",omitempty"`
+	NewVersion string
+
+	OldRisk *int `json:",omitempty"`
+	NewRisk int
+
+	Caps   int
+	Issues int
+
+	AddedCaps   int
+	RemovedCaps int
+	NewIssues   int
+	FixedIssues int
+
+	// Report is the file name of the dependency's report, if reports
+	// are written to files
+	Report string `json:",omitempty"`
+	// Incomplete is true if not all findings of the dependency were
+	// found
+	Incomplete bool `json:",omitempty"`
+}
+
+func (d *depInspector) buildRollup(title string, results []*savedResults) *rollupReport {
+	rollup := &rollupReport{
+		Title: title,
+		Rows:  make([]rollupRow, 0, len(results)),
+	}
+	for _, res := range results {
+		res = d.prepareResults(res)
+		row := rollupRow{
+			Dep:        res.New.Dep,
+			NewVersion: res.New.Version,
+			NewRisk:    res.New.Risk.Score,
+			Caps:       len(res.New.Caps.CapabilityInfo),
+			Issues:     len(res.New.Issues),
+			Incomplete: res.New.Metadata.Incomplete != "",
+		}
+		if res.Old == nil {
+			row.AddedCaps = len(res.New.Caps.CapabilityInfo)
+			row.NewIssues = len(res.New.Issues)
+		} else {
+			compared := compareFindings(res.Old, res.New)
+			row.OldVersion = res.Old.Version
+			row.OldRisk = &res.Old.Risk.Score
+			rollup.Compared = true
+			row.AddedCaps = len(compared.addedCaps)
+			row.RemovedCaps = len(compared.removedCaps)
+			row.NewIssues = len(compared.newIssues)
+			row.FixedIssues = len(compared.fixedIssues)
+		}
+		if d.pages {
+			row.Report = pagesDepsDir + "/" + pageName(res.New.Dep) + formatExts[formatHTML]
+		} else if reportPath := d.reportPath(res.New.Dep); reportPath != "" && d.multipleReports {
+			// reports are written next to each other, link relatively
+			row.Report = filepath.Base(reportPath)
+		}
+		rollup.Rows = append(rollup.Rows, row)
+	}
+	// the riskiest dependencies should be looked at first
+	slices.SortStableFunc(rollup.Rows, func(a, b rollupRow) int {
+		if c := cmp.Compare(b.NewRisk, a.NewRisk); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Dep, b.Dep)
+	})
+
+	return rollup
+}
+
+// writeRollup writes an overview of the reports of multiple changed
+// dependencies. notInspected are changed dependencies that weren't
+// inspected because dep-inspector was interrupted.
+func (d *depInspector) writeRollup(ctx context.Context, results []*savedResults, notInspected []string) error {
+	rollup := d.buildRollup("Changed dependencies", results)
+	rollup.NotInspected = notInspected
+	return d.writeDashboard(ctx, "rollup", rollup)
+}
+
+// writeDashboard writes a rollup report as the report of name.
+func (d *depInspector) writeDashboard(ctx context.Context, name string, rollup *rollupReport) error {
+	var r io.Reader
+	switch d.format {
+	case formatJSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rollup); err != nil {
+			return fmt.Errorf("encoding rollup: %w", err)
+		}
+		r = &buf
+	case formatMarkdown:
+		tmpl, err := template.ParseFS(tmplFS, "output/rollup.md.tmpl")
+		if err != nil {
+			return fmt.Errorf("error parsing output template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, rollup); err != nil {
+			return fmt.Errorf("error executing output template: %w", err)
+		}
+		r = &buf
+	case formatHTML:
+		tmpl, err := htmltemplate.ParseFS(tmplFS, "output/rollup.tmpl", "output/print.tmpl", "output/sort.tmpl", "output/style.tmpl")
+		if err != nil {
+			return fmt.Errorf("error parsing output template: %w", err)
+		}
+		r, err = d.executeTemplate(tmpl, rollup)
+		if err != nil {
+			return err
+		}
+		if isPDFPath(d.reportPath(name)) {
+			r, err = d.printPDF(ctx, r)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		log.Printf("not writing a %s report, %s output is not supported", name, d.format)
+		return nil
+	}
+
+	if d.pages {
+		return writePage(filepath.Join(d.outputFile, pagesIndex), r)
+	}
+	return d.writeReport(name, r)
+}
+
+func dashboardCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: dep-inspector [flags] dashboard [baseline.json]")
+	}
+
+	// findings of a saved baseline can be used instead of inspecting
+	// every dependency again
+	var (
+		base *baselineFindings
+		err  error
+	)
+	if len(args) == 1 {
+		base, err = d.loadBaseline(ctx, args[0], d.parsedModFile.Module.Mod.Path)
+		if err != nil {
+			return err
+		}
+	} else {
+		base = d.inspectRequiredDeps(ctx)
+	}
+
+	results := make([]*savedResults, 0, len(base.Deps))
+	for _, findings := range base.Deps {
+		results = append(results, &savedResults{New: findings})
+	}
+	// the dashboard is the index of pages, write the pages it links to
+	if d.pages {
+		d.multipleReports = true
+		for _, res := range results {
+			if err := d.outputResults(ctx, res); err != nil {
+				return err
+			}
+		}
+	}
+	rollup := d.buildRollup("Dependencies of "+base.Module, results)
+
+	return d.writeDashboard(ctx, "dashboard", rollup)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	runnerDocker = "docker"
+	runnerSSH    = "ssh"
+	// defaultRunnerImage is the image built from the Dockerfile in this
+	// repository, it has dep-inspector, Go and the analysis tools
+	defaultRunnerImage = "ghcr.io/capnspacehook/dep-inspector:latest"
+)
+
+// parseRunner returns the kind of runner and what it runs the analysis
+// on from a -runner value in the form 'docker[:image]' or
+// 'ssh:[user@]host'.
+func parseRunner(runner string) (string, string, error) {
+	kind, target, _ := strings.Cut(runner, ":")
+	switch kind {
+	case runnerDocker:
+		if target == "" {
+			target = defaultRunnerImage
+		}
+	case runnerSSH:
+		if target == "" {
+			return "", "", errors.New("the ssh runner requires a host such as ssh:builder.example.com")
+		}
+		// it would be parsed as an option by ssh
+		if strings.HasPrefix(target, "-") {
+			return "", "", fmt.Errorf("invalid ssh host %q", target)
+		}
+	default:
+		return "", "", fmt.Errorf("unknown runner %q, must be %s[:image] or %s:host", kind, runnerDocker, runnerSSH)
+	}
+	return kind, target, nil
+}
+
+// runnerConflicts returns the flags that were passed that need network
+// access while analyzing, which containers the analysis runs in don't
+// have.
+func (d *depInspector) runnerConflicts() []string {
+	var conflicts []string
+	set := func(name string, isSet bool) {
+		if isSet {
+			conflicts = append(conflicts, "-"+name)
+		}
+	}
+	set("ownership", d.ownership)
+	set("check-proxies", d.checkProxies)
+	set("compare-proxies", d.compareProxies)
+	set("compare-vcs", d.compareVCS)
+	set("contributors", d.contributors)
+	set("release-notes", d.releaseNotes)
+	set("vulns", d.vulns)
+	set("git-credentials", d.gitCredentials)
+
+	return conflicts
+}
+
+// inspectWithRunner inspects or compares versions of a dependency with
+// the runner passed with -runner. dep-inspector is run by the runner
+// and the findings it outputs are streamed back and reported as if they
+// were found here. The module's go.mod and go.sum are never modified,
+// runners only get copies of them.
+func (d *depInspector) inspectWithRunner(ctx context.Context, dep string, versions ...string) error {
+	kind, target, err := parseRunner(d.runner)
+	if err != nil {
+		return err
+	}
+	depArgs := []string{makeVersionStr(dep, versions[0])}
+	if len(versions) == 2 {
+		depArgs = []string{dep, versions[0], versions[1]}
+	}
+
+	var (
+		cmd    *exec.Cmd
+		errBuf *bytes.Buffer
+	)
+	switch kind {
+	case runnerDocker:
+		tmpDir, err := os.MkdirTemp("", tempPrefix)
+		if err != nil {
+			return fmt.Errorf("creating temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		cmd, errBuf, err = d.containerCommand(ctx, target, tmpDir, dep, versions, depArgs)
+		if err != nil {
+			return err
+		}
+	case runnerSSH:
+		remoteDir, capMapPaths, err := d.syncToRemote(ctx, target)
+		if err != nil {
+			return err
+		}
+		defer d.removeRemoteDir(uninterruptedContext(ctx), target, remoteDir)
+
+		cmd, errBuf = d.remoteCommand(ctx, target, remoteDir, capMapPaths, depArgs)
+	}
+
+	return d.outputRunnerResults(ctx, cmd, errBuf)
+}
+
+// containerCommand fetches every version into the module cache in
+// disposable containers with network access, unless -cache-only was
+// passed, and returns the command that runs dep-inspector in a
+// container without network access that has the module cache mounted
+// read-only.
+func (d *depInspector) containerCommand(ctx context.Context, image, tmpDir, dep string, versions, depArgs []string) (*exec.Cmd, *bytes.Buffer, error) {
+	if !d.cacheOnly {
+		for _, version := range versions {
+			if err := d.fetchInContainer(ctx, image, tmpDir, dep, version); err != nil {
+				return nil, nil, fmt.Errorf("fetching %s: %w", makeVersionStr(dep, version), err)
+			}
+		}
+	}
+
+	mounts, err := d.containerMounts(filepath.Join(tmpDir, "analyze"), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	capMapPaths := make([]string, len(d.capMapPaths))
+	for i, path := range d.capMapPaths {
+		// errors were returned when mounting the file
+		capMapPaths[i], _ = filepath.Abs(path)
+	}
+	args := append(d.containerRunArgs(mounts), "--network=none", image, "-cache-only")
+	args = append(args, d.runnerArgs(capMapPaths, false)...)
+	args = append(args, depArgs...)
+
+	cmd, errBuf := d.buildCommand(ctx, nil, args...)
+	// docker forwards the interrupt to dep-inspector in the container,
+	// which writes the findings it found so far
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	return cmd, errBuf, nil
+}
+
+// outputRunnerResults runs a command that runs dep-inspector with a
+// runner, and outputs the results it streams back.
+func (d *depInspector) outputRunnerResults(ctx context.Context, cmd *exec.Cmd, errBuf *bytes.Buffer) error {
+	// show what's being inspected as it happens
+	cmd.Stderr = io.MultiWriter(os.Stderr, errBuf)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return formatCmdErr(ctx, cmd, err, errBuf)
+	}
+
+	results, notInspected, decodeErr := decodeResults(stdout)
+	if decodeErr != nil {
+		// don't block the runner writing output
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	runErr := cmd.Wait()
+	if runErr != nil {
+		runErr = formatCmdErr(ctx, cmd, runErr, errBuf)
+	}
+
+	outCtx := uninterruptedContext(ctx)
+	for _, res := range results {
+		if err := d.recordRunnerResults(outCtx, res); err != nil {
+			return err
+		}
+	}
+	outputErr := d.outputAllResults(outCtx, results, notInspected)
+
+	return errors.Join(decodeErr, runErr, outputErr)
+}
+
+// recordRunnerResults records complete findings found by a runner in
+// the result store, and adds the last recorded findings
+// of the inspected version to compare against if -diff-last was
+// passed.
+func (d *depInspector) recordRunnerResults(ctx context.Context, res *savedResults) error {
+	if d.store == nil {
+		return nil
+	}
+	found := []*depFindings{res.Old, res.New}
+	if d.diffLast {
+		lastInspection, ok, err := d.store.latest(ctx, res.New.Dep, res.New.Version)
+		if err != nil {
+			return err
+		}
+		if ok {
+			res.Old = lastInspection.Findings
+		} else {
+			log.Printf("no previous inspection of %s recorded, reporting all findings", makeVersionStr(res.New.Dep, res.New.Version))
+		}
+	}
+	for _, findings := range found {
+		if findings == nil || findings.Metadata.Incomplete != "" {
+			continue
+		}
+		if err := d.store.record(ctx, findings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetchInContainer downloads a dependency version and every module
+// that is needed to build with it into the module cache, in containers
+// that have network access but only copies of go.mod and go.sum.
+func (d *depInspector) fetchInContainer(ctx context.Context, image, tmpDir, dep, version string) error {
+	mounts, err := d.containerMounts(filepath.Join(tmpDir, "fetch-"+version), true)
+	if err != nil {
+		return err
+	}
+	getArgs := []string{"get"}
+	if d.upgradeTransDeps {
+		getArgs = append(getArgs, "-u")
+	}
+	getArgs = append(getArgs, makeVersionStr(dep, version))
+
+	for _, goArgs := range [][]string{getArgs, {"mod", "download", "all"}} {
+		args := append(d.containerRunArgs(mounts), "--entrypoint=go")
+		for _, name := range goEnvVars {
+			if val := d.goEnv[name]; val != "" {
+				args = append(args, "--env="+name+"="+val)
+			}
+		}
+		if d.netrcPath != "" {
+			netrcPath, err := filepath.Abs(d.netrcPath)
+			if err != nil {
+				return err
+			}
+			args = append(args, bindMount(netrcPath, true), "--env=NETRC="+netrcPath)
+		}
+		args = append(args, image)
+		args = append(args, goArgs...)
+		if err := d.runCommand(ctx, nil, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// containerRunArgs returns the arguments to run a disposable container
+// with. Containers run as the current user so files they add to the
+// module cache are owned by it, and can only write to mounts and /tmp.
+func (d *depInspector) containerRunArgs(mounts []string) []string {
+	workDir := d.workDir
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+	args := []string{
+		runnerDocker, "run", "--rm",
+		"--read-only",
+		"--tmpfs=/tmp",
+		"--cap-drop=ALL",
+		"--security-opt=no-new-privileges",
+		"--env=HOME=/tmp",
+		"--env=GOCACHE=/tmp/go-build",
+		"--env=GOMODCACHE=" + d.modCache,
+		"--workdir=" + workDir,
+	}
+	// there are no user IDs on Windows
+	if uid := os.Getuid(); uid != -1 {
+		args = append(args, "--user="+strconv.Itoa(uid)+":"+strconv.Itoa(os.Getgid()))
+	}
+
+	return append(args, mounts...)
+}
+
+// containerMounts copies go.mod and go.sum to dir and returns the
+// arguments to mount them over the read-only main module, along with
+// the module cache and files flags refer to. Mounts have the same paths
+// in containers so paths in findings are the same as when inspecting
+// without a runner.
+func (d *depInspector) containerMounts(dir string, fetch bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	modDir := filepath.Dir(d.modFilePath)
+	mounts := []string{
+		bindMount(modDir, true),
+		bindMount(d.modCache, !fetch),
+	}
+	for _, src := range []string{d.modFilePath, d.sumFilePath} {
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		dst := filepath.Join(dir, filepath.Base(src))
+		if err := os.WriteFile(dst, contents, 0o644); err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, "--mount=type=bind,src="+dst+",dst="+src)
+	}
+	if isFileProxy(d.goProxy) {
+		mounts = append(mounts, bindMount(filepath.FromSlash(strings.TrimPrefix(d.goProxy, "file://")), true))
+	}
+	if !fetch {
+		for _, path := range d.capMapPaths {
+			path, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			mounts = append(mounts, bindMount(path, true))
+		}
+	}
+
+	return mounts, nil
+}
+
+func bindMount(path string, readOnly bool) string {
+	mount := "--mount=type=bind,src=" + path + ",dst=" + path
+	if readOnly {
+		mount += ",readonly"
+	}
+	return mount
+}
+
+// runnerArgs returns the arguments dep-inspector is run with by a
+// runner. Only flags that change how dependencies are analyzed are
+// passed, findings are filtered and reported here. capMapPaths are the
+// paths of the -capability-map files where the runner can read them.
+// If network is true the runner has network access and flags that need
+// it are passed as well.
+func (d *depInspector) runnerArgs(capMapPaths []string, network bool) []string {
+	args := []string{"-format=" + formatJSON}
+	boolArg := func(name string, set bool) {
+		if set {
+			args = append(args, "-"+name)
+		}
+	}
+	stringArg := func(name, val string) {
+		if val != "" {
+			args = append(args, "-"+name+"="+val)
+		}
+	}
+	boolArg("a", d.inspectAllPkgs)
+	boolArg("unused-dep", d.unusedDep)
+	boolArg("u", d.upgradeTransDeps)
+	boolArg("find-copies", d.findCopies)
+	boolArg("find-download-exec", d.downloadExec)
+	boolArg("allow-modified-cache", d.allowModCache)
+	boolArg("capslock-noinitsummary", d.noInitSummary)
+	boolArg("confine", d.confine)
+	boolArg("v", d.verbose)
+	stringArg("capabilities", d.collectCaps)
+	stringArg("capslock-granularity", d.granularity)
+	stringArg("capslock-buildtags", d.buildTags)
+	stringArg("goflags", d.goFlags)
+	stringArg("shard", d.shard)
+	if d.depth >= 0 {
+		args = append(args, "-depth="+strconv.Itoa(d.depth))
+	}
+	if d.jobs > 0 {
+		args = append(args, "-jobs="+strconv.Itoa(d.jobs))
+	}
+	for _, path := range capMapPaths {
+		args = append(args, "-capability-map="+path)
+	}
+	if isFileProxy(d.goProxy) == !network {
+		stringArg("goproxy", d.goProxy)
+	}
+	if network {
+		stringArg("goprivate", d.goPrivate)
+		boolArg("ownership", d.ownership)
+		boolArg("check-proxies", d.checkProxies)
+		boolArg("compare-proxies", d.compareProxies)
+		boolArg("compare-vcs", d.compareVCS)
+		boolArg("contributors", d.contributors)
+		boolArg("release-notes", d.releaseNotes)
+		boolArg("vulns", d.vulns)
+	}
+
+	return args
+}
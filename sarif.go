@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SARIF baseline states of results when comparing versions
+	baselineNew       = "new"
+	baselineUnchanged = "unchanged"
+	baselineAbsent    = "absent"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool            `json:"tool"`
+	Taxonomies []sarifToolComponent `json:"taxonomies,omitempty"`
+	Results    []sarifResult        `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string           `json:"id"`
+	ShortDescription sarifMessage     `json:"shortDescription"`
+	Properties       *sarifProperties `json:"properties,omitempty"`
+}
+
+// sarifToolComponent is a taxonomy findings are classified by.
+type sarifToolComponent struct {
+	Name         string      `json:"name"`
+	Organization string      `json:"organization,omitempty"`
+	Taxa         []sarifRule `json:"taxa"`
+}
+
+type sarifResult struct {
+	RuleID        string                     `json:"ruleId"`
+	Level         string                     `json:"level"`
+	Message       sarifMessage               `json:"message"`
+	Locations     []sarifLocation            `json:"locations,omitempty"`
+	BaselineState string                     `json:"baselineState,omitempty"`
+	Taxa          []sarifDescriptorReference `json:"taxa,omitempty"`
+	Properties    *sarifProperties           `json:"properties,omitempty"`
+}
+
+type sarifDescriptorReference struct {
+	ID            string             `json:"id"`
+	ToolComponent sarifComponentName `json:"toolComponent"`
+}
+
+type sarifComponentName struct {
+	Name string `json:"name"`
+}
+
+type sarifProperties struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func sarifOutput(res *savedResults) (io.Reader, error) {
+	dep := res.New.Dep
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "dep-inspector",
+				Version:        res.New.Metadata.Version,
+				InformationURI: "https://github.com/capnspacehook/dep-inspector",
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	if res.Old == nil {
+		for _, c := range res.New.Caps.CapabilityInfo {
+			run.Results = append(run.Results, capToSARIF(dep, c, ""))
+		}
+		for _, issue := range res.New.Issues {
+			run.Results = append(run.Results, issueToSARIF(issue, ""))
+		}
+	} else {
+		results := compareFindings(res.Old, res.New)
+		for _, c := range results.addedCaps {
+			run.Results = append(run.Results, capToSARIF(dep, c, baselineNew))
+		}
+		for _, c := range results.sameCaps {
+			run.Results = append(run.Results, capToSARIF(dep, c, baselineUnchanged))
+		}
+		for _, c := range results.removedCaps {
+			run.Results = append(run.Results, capToSARIF(dep, c, baselineAbsent))
+		}
+		for _, issue := range results.newIssues {
+			run.Results = append(run.Results, issueToSARIF(issue, baselineNew))
+		}
+		for _, issue := range results.staleIssues {
+			run.Results = append(run.Results, issueToSARIF(issue, baselineUnchanged))
+		}
+		for _, issue := range results.fixedIssues {
+			run.Results = append(run.Results, issueToSARIF(issue, baselineAbsent))
+		}
+	}
+
+	// rules are tagged with the classifications of all their results
+	ruleTags := make(map[string][]string)
+	var cwes []string
+	for _, result := range run.Results {
+		tags := ruleTags[result.RuleID]
+		if result.Properties != nil {
+			tags = append(tags, result.Properties.Tags...)
+		}
+		ruleTags[result.RuleID] = tags
+		for _, taxon := range result.Taxa {
+			cwes = append(cwes, taxon.ID)
+		}
+	}
+	ruleIDs := maps.Keys(ruleTags)
+	slices.Sort(ruleIDs)
+	for _, id := range ruleIDs {
+		rule := sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: id},
+		}
+		if tags := ruleTags[id]; len(tags) != 0 {
+			slices.Sort(tags)
+			rule.Properties = &sarifProperties{Tags: slices.Compact(tags)}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	if len(cwes) != 0 {
+		slices.Sort(cwes)
+		cwe := sarifToolComponent{Name: "CWE", Organization: "MITRE"}
+		for _, id := range slices.Compact(cwes) {
+			cwe.Taxa = append(cwe.Taxa, sarifRule{
+				ID:               id,
+				ShortDescription: sarifMessage{Text: id},
+			})
+		}
+		run.Taxonomies = []sarifToolComponent{cwe}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding SARIF: %w", err)
+	}
+
+	return &buf, nil
+}
+
+func capToSARIF(dep string, c *capability, baselineState string) sarifResult {
+	calls := make([]string, len(c.Path))
+	for i, call := range c.Path {
+		calls[i] = call.Name
+	}
+	result := sarifResult{
+		RuleID:        c.Capability,
+		Level:         sarifLevel(c.Severity, "note"),
+		Message:       sarifMessage{Text: fmt.Sprintf("%s (%s): %s", c.Capability, capTypeName(c.CapabilityType), strings.Join(calls, " -> "))},
+		BaselineState: baselineState,
+	}
+	classifySARIF(&result, c.Classification)
+
+	// the first call site is in the package of the dependency the
+	// capability was found in
+	if len(c.Path) > 1 && c.Path[1].Site.Filename != "" {
+		pkgDir := strings.TrimPrefix(strings.TrimPrefix(c.PackageDir, dep), "/")
+		result.Locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: path.Join(pkgDir, c.Path[1].Site.Filename)},
+				Region: sarifRegion{
+					StartLine:   atoiOrZero(c.Path[1].Site.Line),
+					StartColumn: atoiOrZero(c.Path[1].Site.Column),
+				},
+			},
+		}}
+	}
+
+	return result
+}
+
+func issueToSARIF(issue *lintIssue, baselineState string) sarifResult {
+	result := sarifResult{
+		RuleID:  issue.FromLinter,
+		Level:   sarifLevel(issue.Severity, "warning"),
+		Message: sarifMessage{Text: issue.Text},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: issue.Pos.Filename},
+				Region: sarifRegion{
+					StartLine:   issue.Pos.Line,
+					StartColumn: issue.Pos.Column,
+				},
+			},
+		}},
+		BaselineState: baselineState,
+	}
+	classifySARIF(&result, issue.Classification)
+
+	return result
+}
+
+// classifySARIF adds the CWE weaknesses of a classified finding to its
+// SARIF result.
+func classifySARIF(result *sarifResult, class *classification) {
+	if class == nil {
+		return
+	}
+	for _, cwe := range class.CWE {
+		result.Taxa = append(result.Taxa, sarifDescriptorReference{
+			ID:            cwe,
+			ToolComponent: sarifComponentName{Name: "CWE"},
+		})
+	}
+	result.Properties = &sarifProperties{Tags: class.sarifTags()}
+}
+
+// sarifLevel converts a severity to a SARIF level, findings without a
+// severity have the default level.
+func sarifLevel(severity, defaultLevel string) string {
+	switch severity {
+	case severityCritical, severityHigh:
+		return "error"
+	case severityMedium:
+		return "warning"
+	case severityLow:
+		return "note"
+	}
+	return defaultLevel
+}
+
+// atoiOrZero converts a capslock line or column to an int, unknown
+// positions are 0 which is omitted from SARIF output.
+func atoiOrZero(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
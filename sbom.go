@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	cycloneDXSpecVersion = "1.5"
+
+	// prefix of CycloneDX component properties set by dep-inspector
+	sbomPropPrefix = "dep-inspector:"
+)
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber,omitempty"`
+	Version      int             `json:"version"`
+	Metadata     *cdxMetadata    `json:"metadata,omitempty"`
+	Components   []*cdxComponent `json:"components,omitempty"`
+	Dependencies []cdxDependency `json:"dependencies,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp,omitempty"`
+	Tools     *cdxTools     `json:"tools,omitempty"`
+	Component *cdxComponent `json:"component,omitempty"`
+}
+
+type cdxTools struct {
+	Components []*cdxComponent `json:"components,omitempty"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseID `json:"license"`
+}
+
+// cdxLicenseID is an SPDX license identifier, or a name for licenses
+// that aren't identified by one.
+type cdxLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func sbomCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: dep-inspector [flags] sbom [baseline.json]")
+	}
+
+	// findings of a saved baseline can be used instead of inspecting
+	// every dependency again
+	var (
+		base *baselineFindings
+		err  error
+	)
+	if len(args) == 1 {
+		base, err = d.loadBaseline(ctx, args[0], d.parsedModFile.Module.Mod.Path)
+		if err != nil {
+			return err
+		}
+	} else {
+		base = d.inspectRequiredDeps(ctx)
+	}
+
+	bom, err := d.buildSBOM(ctx, base)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if d.outputFile != "" {
+		f, err := os.Create(d.outputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bom); err != nil {
+		return fmt.Errorf("encoding SBOM: %w", err)
+	}
+
+	return nil
+}
+
+// buildSBOM builds a CycloneDX SBOM of the module graph of the main
+// module. Components that were inspected have their findings added as
+// properties.
+func (d *depInspector) buildSBOM(ctx context.Context, base *baselineFindings) (*cdxBOM, error) {
+	graph, err := d.moduleGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	modPath := d.parsedModFile.Module.Mod.Path
+	mainComponent := &cdxComponent{
+		Type:   "application",
+		BOMRef: modulePURL(modPath, ""),
+		Name:   modPath,
+		PURL:   modulePURL(modPath, ""),
+	}
+	bom := &cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: &cdxMetadata{
+			Timestamp: base.Metadata.Time.UTC().Format(time.RFC3339),
+			Tools: &cdxTools{
+				Components: []*cdxComponent{{
+					Type:    "application",
+					Name:    "dep-inspector",
+					Version: base.Metadata.Version,
+				}},
+			},
+			Component: mainComponent,
+		},
+	}
+
+	findings := make(map[string]*depFindings, len(base.Deps))
+	for _, f := range base.Deps {
+		d.severities.apply(f)
+		d.risk.apply(f)
+		findings[makeVersionStr(f.Dep, f.Version)] = f
+	}
+
+	nodes := maps.Keys(graph)
+	slices.Sort(nodes)
+	for _, node := range nodes {
+		dep, ver, _ := strings.Cut(node, "@")
+		ref := modulePURL(dep, ver)
+		if dep == modPath && ver == "" {
+			ref = mainComponent.BOMRef
+		} else {
+			bom.Components = append(bom.Components, &cdxComponent{
+				Type:       "library",
+				BOMRef:     ref,
+				Name:       dep,
+				Version:    ver,
+				PURL:       ref,
+				Licenses:   componentLicenses(findings[node]),
+				Properties: findingProperties(findings[node]),
+			})
+		}
+
+		var dependsOn []string
+		for _, req := range graph[node] {
+			reqDep, reqVer, _ := strings.Cut(req, "@")
+			dependsOn = append(dependsOn, modulePURL(reqDep, reqVer))
+		}
+		bom.Dependencies = append(bom.Dependencies, cdxDependency{
+			Ref:       ref,
+			DependsOn: dependsOn,
+		})
+	}
+
+	return bom, nil
+}
+
+// findingProperties converts findings to CycloneDX properties. Nil
+// findings of dependencies that weren't inspected have no properties.
+func findingProperties(findings *depFindings) []cdxProperty {
+	if findings == nil {
+		return nil
+	}
+
+	props := []cdxProperty{
+		{Name: sbomPropPrefix + "capabilities", Value: strconv.Itoa(len(findings.Caps.CapabilityInfo))},
+		{Name: sbomPropPrefix + "issues", Value: strconv.Itoa(len(findings.Issues))},
+	}
+	capCounts := make(map[string]int)
+	for _, c := range findings.Caps.CapabilityInfo {
+		capCounts[strings.TrimPrefix(c.Capability, "CAPABILITY_")]++
+	}
+	capNames := maps.Keys(capCounts)
+	slices.Sort(capNames)
+	for _, name := range capNames {
+		props = append(props, cdxProperty{
+			Name:  sbomPropPrefix + "capability:" + name,
+			Value: strconv.Itoa(capCounts[name]),
+		})
+	}
+	for _, license := range findings.Licenses {
+		props = append(props, cdxProperty{
+			Name:  sbomPropPrefix + "license",
+			Value: license,
+		})
+	}
+	if findings.Risk != nil {
+		props = append(props, cdxProperty{
+			Name:  sbomPropPrefix + "risk-score",
+			Value: strconv.Itoa(findings.Risk.Score),
+		})
+	}
+	props = append(props, cdxProperty{
+		Name:  sbomPropPrefix + "inspected",
+		Value: findings.Metadata.Time.UTC().Format(time.RFC3339),
+	})
+
+	return props
+}
+
+// componentLicenses converts the detected licenses of a dependency to
+// CycloneDX licenses. NONE and NOASSERTION aren't license identifiers,
+// so they are set as names.
+func componentLicenses(findings *depFindings) []cdxLicense {
+	if findings == nil {
+		return nil
+	}
+
+	var licenses []cdxLicense
+	for _, license := range findings.Licenses {
+		if license == licenseNone || license == licenseUnknown {
+			licenses = append(licenses, cdxLicense{License: cdxLicenseID{Name: license}})
+			continue
+		}
+		licenses = append(licenses, cdxLicense{License: cdxLicenseID{ID: license}})
+	}
+	return licenses
+}
+
+// moduleGraph returns the module requirement graph of the main module
+// as a map of module versions to the module versions they require.
+func (d *depInspector) moduleGraph(ctx context.Context) (map[string][]string, error) {
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "go", "mod", "graph"); err != nil {
+		return nil, fmt.Errorf("listing module graph: %w", err)
+	}
+
+	graph := make(map[string][]string)
+	sc := bufio.NewScanner(&output)
+	for sc.Scan() {
+		from, to, ok := strings.Cut(sc.Text(), " ")
+		if !ok {
+			continue
+		}
+		// the go and toolchain directives are listed as requirements
+		if isToolchainNode(from) || isToolchainNode(to) {
+			continue
+		}
+		graph[from] = append(graph[from], to)
+		if _, ok := graph[to]; !ok {
+			graph[to] = nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading module graph: %w", err)
+	}
+
+	return graph, nil
+}
+
+func isToolchainNode(node string) bool {
+	return strings.HasPrefix(node, "go@") || strings.HasPrefix(node, "toolchain@")
+}
+
+// modulePURL returns the package URL of a Go module version.
+func modulePURL(modPath, version string) string {
+	purl := golangPURLPrefix + modPath
+	if version != "" {
+		purl += "@" + version
+	}
+	return purl
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/mod/modfile"
+)
+
+// develVersion is the version of the main module when it can't be
+// determined from git.
+const develVersion = "(devel)"
+
+// selfCmd inspects the packages of the main module itself instead of
+// a dependency, or compares them between two git refs.
+func selfCmd(ctx context.Context, d *depInspector, args []string) error {
+	switch len(args) {
+	case 0:
+		modDir := filepath.Dir(d.modFilePath)
+		findings, err := d.inspectMainModule(ctx, modDir, d.mainModuleVersion(ctx))
+		if err != nil {
+			return err
+		}
+		return d.outputResults(ctx, &savedResults{New: findings})
+	case 2:
+		oldFindings, err := d.inspectMainModuleAtRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		newFindings, err := d.inspectMainModuleAtRef(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return d.outputResults(ctx, &savedResults{
+			Old: oldFindings,
+			New: newFindings,
+		})
+	default:
+		return errors.New("usage: dep-inspector [flags] self [old-ref new-ref]")
+	}
+}
+
+// mainModuleVersion returns the commit the main module is checked out
+// at so reports can link to its source.
+func (d *depInspector) mainModuleVersion(ctx context.Context) string {
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "git", "rev-parse", "HEAD"); err != nil {
+		if d.verbose {
+			log.Printf("error finding commit of main module: %v", err)
+		}
+		return develVersion
+	}
+	return trimNewline(output.String())
+}
+
+// inspectMainModuleAtRef inspects the main module as it is at a git
+// ref. The ref is checked out in a temporary worktree so the working
+// tree of the main module isn't modified.
+func (d *depInspector) inspectMainModuleAtRef(ctx context.Context, ref string) (_ *depFindings, ret error) {
+	modDir := filepath.Dir(d.modFilePath)
+
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "git", "-C", modDir, "rev-parse", "--verify", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	commit := trimNewline(output.String())
+	// the main module may be in a subdirectory of the repository
+	output.Reset()
+	if err := d.runCommand(ctx, &output, "git", "-C", modDir, "rev-parse", "--show-prefix"); err != nil {
+		return nil, fmt.Errorf("finding directory of main module in repository: %w", err)
+	}
+	prefix := trimNewline(output.String())
+
+	worktree, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	log.Printf("checking out %s in a temporary worktree", ref)
+	if err := d.runCommand(ctx, nil, "git", "-C", modDir, "worktree", "add", "--detach", worktree, commit); err != nil {
+		os.RemoveAll(worktree)
+		return nil, fmt.Errorf("checking out %s: %w", ref, err)
+	}
+	defer func() {
+		// use a new context so the worktree is removed even if the
+		// inspection was canceled
+		err := d.runCommand(context.Background(), nil, "git", "-C", modDir, "worktree", "remove", "--force", worktree)
+		if err != nil {
+			ret = errors.Join(ret, fmt.Errorf("removing worktree: %w", err))
+		}
+		os.RemoveAll(worktree)
+	}()
+
+	wtModDir := filepath.Join(worktree, filepath.FromSlash(prefix))
+	d.workDir = wtModDir
+	defer func() {
+		d.workDir = ""
+	}()
+
+	return d.inspectMainModule(ctx, wtModDir, commit)
+}
+
+// inspectMainModule finds the capabilities and linter issues of every
+// package in the main module in modDir.
+func (d *depInspector) inspectMainModule(ctx context.Context, modDir, version string) (findings *depFindings, ret error) {
+	defer func() {
+		d.metrics.observeInspection(findings, ret)
+	}()
+
+	modContents, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod: %w", err)
+	}
+	// the module path may differ from the current one when inspecting
+	// other refs
+	modPath := modfile.ModulePath(modContents)
+	if modPath == "" {
+		return nil, fmt.Errorf("module path not found in %s", filepath.Join(modDir, "go.mod"))
+	}
+	versionStr := makeVersionStr(modPath, version)
+	modules, err := requiredModules(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	goSum, err := goSumEntries(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	licenses, err := dirLicenses(modDir)
+	if err != nil {
+		return nil, fmt.Errorf("detecting licenses: %w", err)
+	}
+
+	pkgs, err := d.loadPackages(modPath, modDir)
+	if err != nil {
+		return nil, err
+	}
+	var pkgsInspected []string
+	for _, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Path == modPath {
+			pkgsInspected = append(pkgsInspected, pkg.PkgPath)
+		}
+	}
+	slices.Sort(pkgsInspected)
+	pkgInfo, err := describePackages(modPath, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	caps, err := d.runCapslock(ctx, modPath, versionStr, []string{modPath + "/..."})
+	if err != nil {
+		return nil, fmt.Errorf("finding capabilities of main module: %w", err)
+	}
+	// the main module isn't always listed by capslock, but reports need
+	// it to link to its source
+	if !slices.ContainsFunc(caps.ModuleInfo, func(m capModule) bool {
+		return m.Path == modPath
+	}) {
+		caps.ModuleInfo = append(caps.ModuleInfo, capModule{Path: modPath, Version: version})
+	}
+
+	issues, err := d.runLinters(ctx, modPath, versionStr,
+		[]string{modDir + string(filepath.Separator) + "..."},
+		[]string{modPath + "/..."},
+		func(filename string) (string, error) {
+			rel, err := filepath.Rel(modDir, filename)
+			if err != nil {
+				return "", err
+			}
+			return filepath.ToSlash(rel), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("linting main module: %w", err)
+	}
+
+	findings = &depFindings{
+		Dep:         modPath,
+		Version:     version,
+		Caps:        caps,
+		Issues:      issues,
+		Packages:    pkgsInspected,
+		PackageInfo: pkgInfo,
+		Modules:     modules,
+		GoSumHashes: goSumHashes(goSum, modules),
+		Licenses:    licenses,
+		Metadata:    d.buildMetadata(),
+	}
+	if d.store != nil {
+		if err := d.store.record(ctx, findings); err != nil {
+			return nil, err
+		}
+	}
+
+	return findings, nil
+}
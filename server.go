@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+const (
+	jobQueued   = "queued"
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobFailed   = "failed"
+	jobQueueLen = 64
+
+	// serverTokenEnv is the environment variable the bearer token
+	// clients must send is read from if -token isn't passed
+	serverTokenEnv = "DEP_INSPECTOR_TOKEN"
+)
+
+// inspectRequest is the body of a request to inspect a dependency. If
+// OldVer is empty only NewVer is inspected.
+type inspectRequest struct {
+	Module string `json:"module"`
+	OldVer string `json:"oldVer,omitempty"`
+	NewVer string `json:"newVer"`
+}
+
+// triageRequest is the body of a request to triage a finding.
+type triageRequest struct {
+	Module      string `json:"module"`
+	Fingerprint string `json:"fingerprint"`
+	Status      string `json:"status"`
+	Note        string `json:"note,omitempty"`
+	TriagedBy   string `json:"triagedBy"`
+}
+
+type inspectJob struct {
+	ID string `json:"id"`
+	inspectRequest
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// results are the JSON encoded results of a finished job. Rendering
+	// results modifies them, so every request decodes its own copy
+	results []byte
+}
+
+// inspectServer runs inspection jobs submitted over HTTP. Inspecting
+// a dependency modifies go.mod and go.sum of the main module, so jobs
+// are run one at a time in the order they were submitted.
+type inspectServer struct {
+	d *depInspector
+	// externalURL is the URL the server can be reached at, used to link
+	// to results in notifications
+	externalURL string
+	// token is the bearer token clients must send, if it's empty
+	// requests aren't authenticated
+	token string
+
+	mu     sync.Mutex
+	jobs   map[string]*inspectJob
+	lastID int
+	queue  chan *inspectJob
+	// finished are the IDs of finished jobs, oldest first. Only
+	// maxFinished finished jobs are kept so results don't accumulate
+	finished    []string
+	maxFinished int
+
+	// triageMu serializes recording triage decisions so concurrent
+	// requests don't overwrite each other's changes to the -triage file
+	triageMu sync.Mutex
+}
+
+func serveCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listenAddr := fs.String("listen", "127.0.0.1:8080", "address to listen on")
+	externalURL := fs.String("url", "", "URL the server is reachable at, used to link to results in webhook notifications")
+	maxFinished := fs.Int("max-finished-jobs", 100, "how many finished jobs and their results are kept, older ones are forgotten")
+	token := fs.String("token", os.Getenv(serverTokenEnv), "bearer token clients must send, required unless listening on a loopback address (default $"+serverTokenEnv+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: dep-inspector [flags] serve [-listen addr] [-url url] [-max-finished-jobs n] [-token token]")
+	}
+	if *maxFinished < 1 {
+		return errors.New("-max-finished-jobs must be at least 1")
+	}
+	if *token == "" && !isLoopbackAddr(*listenAddr) {
+		return fmt.Errorf("-token or $%s is required when not listening on a loopback address", serverTokenEnv)
+	}
+
+	d.metrics = newInspectorMetrics()
+	// reports link to the triage endpoint so reviewers can triage
+	// findings as they read them
+	if d.store != nil || d.triagePath != "" {
+		d.triageURL = strings.TrimSuffix(*externalURL, "/") + "/triage"
+		if d.triage == nil {
+			d.triage = &triageList{}
+		}
+	}
+	s := &inspectServer{
+		d:           d,
+		externalURL: strings.TrimSuffix(*externalURL, "/"),
+		token:       *token,
+		jobs:        make(map[string]*inspectJob),
+		queue:       make(chan *inspectJob, jobQueueLen),
+		maxFinished: *maxFinished,
+	}
+	srv := &http.Server{
+		Addr:              *listenAddr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.runJobs(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("error shutting down server: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", *listenAddr)
+	err := srv.ListenAndServe()
+	// wait for the running job to finish so go.mod and go.sum can be
+	// restored
+	wg.Wait()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// isLoopbackAddr returns true if addr is a host and port that only
+// accepts connections from the local machine.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *inspectServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/inspect", s.handleInspect)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	mux.HandleFunc("/triage", s.handleTriage)
+	mux.Handle("/metrics", s.d.metrics.handler())
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests that don't have the server's bearer
+// token in their Authorization header, if a token is set.
+func (s *inspectServer) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			httpError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *inspectServer) handleInspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req inspectRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("decoding request: %v", err))
+		return
+	}
+	if err := s.validateRequest(&req); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.lastID++
+	job := &inspectJob{
+		ID:             strconv.Itoa(s.lastID),
+		inspectRequest: req,
+		Status:         jobQueued,
+		Created:        time.Now(),
+	}
+	select {
+	case s.queue <- job:
+		s.jobs[job.ID] = job
+	default:
+		s.mu.Unlock()
+		httpError(w, http.StatusServiceUnavailable, "too many queued jobs")
+		return
+	}
+	resp := *job
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+func (s *inspectServer) validateRequest(req *inspectRequest) error {
+	if req.Module == "" {
+		return errors.New("module is required")
+	}
+	if req.NewVer == "" {
+		return errors.New("newVer is required")
+	}
+
+	var err error
+	req.NewVer, err = s.d.checkVersion(req.Module, req.NewVer)
+	if err != nil {
+		return fmt.Errorf("checking new version: %w", err)
+	}
+	if req.OldVer == "" {
+		return nil
+	}
+	req.OldVer, err = s.d.checkVersion(req.Module, req.OldVer)
+	if err != nil {
+		return fmt.Errorf("checking old version: %w", err)
+	}
+	if req.OldVer == req.NewVer {
+		return errors.New("cannot compare: old version and new version are the same")
+	}
+	if semver.Compare(req.OldVer, req.NewVer) == 1 {
+		return fmt.Errorf("cannot compare: %q is greater than %q. old version must be less than new version", req.OldVer, req.NewVer)
+	}
+
+	return nil
+}
+
+// handleJob handles requests for the status of a job at /jobs/{id},
+// and for the results of a job at /jobs/{id}/results.
+func (s *inspectServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	var jobCopy inspectJob
+	if ok {
+		jobCopy = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		httpError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	switch rest {
+	case "":
+		writeJSON(w, http.StatusOK, jobCopy)
+	case "results":
+		s.writeResults(w, r, &jobCopy)
+	default:
+		httpError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *inspectServer) writeResults(w http.ResponseWriter, r *http.Request, job *inspectJob) {
+	if job.Status != jobDone {
+		httpError(w, http.StatusConflict, fmt.Sprintf("job is %s", job.Status))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+	if !slices.Contains(outputFormats, format) {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q", format))
+		return
+	}
+
+	var res savedResults
+	if err := json.Unmarshal(job.results, &res); err != nil {
+		httpError(w, http.StatusInternalServerError, fmt.Sprintf("decoding results: %v", err))
+		return
+	}
+	out, err := s.d.renderResults(r.Context(), format, &res)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, fmt.Sprintf("rendering results: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	if _, err := io.Copy(w, out); err != nil {
+		log.Printf("error writing results of job %s: %v", job.ID, err)
+	}
+}
+
+// handleTriage handles requests to list triage decisions, and to
+// triage a finding either with a JSON body or a form submitted from a
+// served HTML report.
+func (s *inspectServer) handleTriage(w http.ResponseWriter, r *http.Request) {
+	if s.d.triageURL == "" {
+		httpError(w, http.StatusNotFound, "triage requires -store or a -triage file to record decisions in")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.d.triage.list())
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("reading request: %v", err))
+		return
+	}
+	// curl sends JSON bodies as forms unless told otherwise, so only
+	// bodies that aren't JSON objects are decoded as forms
+	var req triageRequest
+	fromForm := r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" && !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+	if fromForm {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Sprintf("decoding request: %v", err))
+			return
+		}
+		req = triageRequest{
+			Module:      form.Get("module"),
+			Fingerprint: form.Get("fingerprint"),
+			Status:      form.Get("status"),
+			Note:        form.Get("note"),
+			TriagedBy:   form.Get("triaged-by"),
+		}
+	} else if err := json.Unmarshal(body, &req); err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("decoding request: %v", err))
+		return
+	}
+
+	t := triageDecision{
+		Module:      req.Module,
+		Fingerprint: strings.ToLower(req.Fingerprint),
+		Status:      req.Status,
+		Note:        req.Note,
+		TriagedBy:   req.TriagedBy,
+		Date:        time.Now().UTC().Format(time.DateOnly),
+	}
+	if err := t.validate(); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if t.TriagedBy == "" {
+		httpError(w, http.StatusBadRequest, "triagedBy is required")
+		return
+	}
+
+	s.triageMu.Lock()
+	err = s.d.recordTriage(r.Context(), t)
+	s.triageMu.Unlock()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, fmt.Sprintf("recording triage decision: %v", err))
+		return
+	}
+	log.Printf("%s marked finding %s of %s as %s", t.TriagedBy, t.Fingerprint, t.Module, t.Status)
+
+	// send reviewers back to the report they triaged the finding from,
+	// which now shows the decision
+	if referer := r.Referer(); fromForm && referer != "" {
+		http.Redirect(w, r, referer, http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *inspectServer) runJobs(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.setJobStatus(job, jobRunning, nil, nil)
+			log.Printf("running job %s: inspecting %s", job.ID, job.Module)
+
+			res, err := s.runJob(ctx, job)
+			if err != nil {
+				log.Printf("job %s failed: %v", job.ID, err)
+				s.setJobStatus(job, jobFailed, nil, err)
+				continue
+			}
+			encoded, err := json.Marshal(res)
+			if err != nil {
+				err = fmt.Errorf("encoding results: %w", err)
+				log.Printf("job %s failed: %v", job.ID, err)
+				s.setJobStatus(job, jobFailed, nil, err)
+				continue
+			}
+			log.Printf("job %s finished", job.ID)
+			s.setJobStatus(job, jobDone, encoded, nil)
+
+			var reportURL string
+			if s.externalURL != "" {
+				reportURL = fmt.Sprintf("%s/jobs/%s/results?format=html", s.externalURL, job.ID)
+			}
+			if err := s.d.notifyInspection(ctx, res, reportURL); err != nil {
+				log.Printf("error notifying webhooks of job %s: %v", job.ID, err)
+			}
+			if err := s.d.notifyChat(ctx, res, reportURL); err != nil {
+				log.Printf("error sending chat notifications of job %s: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+func (s *inspectServer) runJob(ctx context.Context, job *inspectJob) (*savedResults, error) {
+	d := s.d
+	// start from the original go.mod and go.sum, previous jobs may
+	// have changed them
+	if err := d.resetModFiles(); err != nil {
+		return nil, fmt.Errorf("restoring go.mod: %w", err)
+	}
+
+	if job.OldVer == "" {
+		findings, err := d.inspectDep(ctx, d.newModBackupFiles, job.Module, job.NewVer, true, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &savedResults{New: findings}, nil
+	}
+
+	oldFindings, newFindings, err := d.inspectDepVersions(ctx, job.Module, job.OldVer, job.NewVer)
+	if err != nil {
+		return nil, err
+	}
+	return &savedResults{
+		Old: oldFindings,
+		New: newFindings,
+	}, nil
+}
+
+func (s *inspectServer) setJobStatus(job *inspectJob, status string, res []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.Status = status
+	job.results = res
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status == jobDone || status == jobFailed {
+		now := time.Now()
+		job.Finished = &now
+
+		s.finished = append(s.finished, job.ID)
+		if len(s.finished) > s.maxFinished {
+			delete(s.jobs, s.finished[0])
+			s.finished = s.finished[1:]
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+func httpError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{Error: msg})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+const (
+	severityLow      = "low"
+	severityMedium   = "medium"
+	severityHigh     = "high"
+	severityCritical = "critical"
+)
+
+// severities are the valid severities, from least to most severe.
+var severities = []string{severityLow, severityMedium, severityHigh, severityCritical}
+
+// defaultCapSeverities are the severities of capabilities that aren't
+// configured. Capabilities that let a dependency run arbitrary code or
+// talk to the outside world are the most severe.
+var defaultCapSeverities = map[string]string{
+	"CAPABILITY_ARBITRARY_EXECUTION": severityHigh,
+	"CAPABILITY_CGO":                 severityHigh,
+	"CAPABILITY_EXEC":                severityHigh,
+	"CAPABILITY_NETWORK":             severityHigh,
+	"CAPABILITY_SYSTEM_CALLS":        severityHigh,
+	"CAPABILITY_FILES":               severityMedium,
+	"CAPABILITY_MODIFY_SYSTEM_STATE": severityMedium,
+	"CAPABILITY_OPERATING_SYSTEM":    severityMedium,
+	"CAPABILITY_UNANALYZED":          severityMedium,
+	"CAPABILITY_UNSAFE_POINTER":      severityMedium,
+	"CAPABILITY_READ_SYSTEM_STATE":   severityLow,
+	"CAPABILITY_REFLECT":             severityLow,
+	"CAPABILITY_RUNTIME":             severityLow,
+	"CAPABILITY_UNSPECIFIED":         severityLow,
+	"CAPABILITY_SAFE":                severityLow,
+}
+
+// defaultIssueSeverities are the severities of linter issues that
+// aren't configured, keyed by patterns matched against linter names
+// and staticcheck check codes.
+var defaultIssueSeverities = map[string]string{
+	"SA2*":  severityHigh,
+	"SA5*":  severityHigh,
+	"gosec": severityHigh,
+	"SA1*":  severityMedium,
+	"SA4*":  severityMedium,
+	"SA9*":  severityMedium,
+	"*":     severityLow,
+}
+
+// severityConfig configures the severities of findings.
+type severityConfig struct {
+	// Capabilities maps capability names, with or without the
+	// CAPABILITY_ prefix, to severities
+	Capabilities map[string]string `yaml:"capabilities"`
+	// Issues maps patterns matched against linter names or staticcheck
+	// check codes, such as 'SA2*' or 'errcheck', to severities
+	Issues map[string]string `yaml:"issues"`
+}
+
+// severityModel assigns severities to findings.
+type severityModel struct {
+	caps map[string]string
+	// issuePatterns are sorted so the most specific patterns are
+	// matched first
+	issuePatterns []string
+	issues        map[string]string
+}
+
+func newSeverityModel(cfg severityConfig) (*severityModel, error) {
+	m := &severityModel{
+		caps:   maps.Clone(defaultCapSeverities),
+		issues: maps.Clone(defaultIssueSeverities),
+	}
+	for name, sev := range cfg.Capabilities {
+		if err := checkSeverity(sev); err != nil {
+			return nil, err
+		}
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "CAPABILITY_") {
+			name = "CAPABILITY_" + name
+		}
+		m.caps[name] = sev
+	}
+	for pattern, sev := range cfg.Issues {
+		if err := checkSeverity(sev); err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid issue severity pattern %q: %w", pattern, err)
+		}
+		m.issues[pattern] = sev
+	}
+
+	m.issuePatterns = maps.Keys(m.issues)
+	slices.SortFunc(m.issuePatterns, func(a, b string) int {
+		// patterns with fewer wildcards and more literal characters are
+		// more specific
+		if c := strings.Count(a, "*") - strings.Count(b, "*"); c != 0 {
+			return c
+		}
+		if c := len(b) - len(a); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	return m, nil
+}
+
+func checkSeverity(sev string) error {
+	if !slices.Contains(severities, sev) {
+		return fmt.Errorf("unknown severity %q: must be one of %s", sev, strings.Join(severities, ", "))
+	}
+	return nil
+}
+
+// compareSeverity compares severities by how severe they are.
+func compareSeverity(a, b string) int {
+	return slices.Index(severities, a) - slices.Index(severities, b)
+}
+
+func (m *severityModel) capSeverity(c *capability) string {
+	if sev, ok := m.caps[c.Capability]; ok {
+		return sev
+	}
+	return severityMedium
+}
+
+func (m *severityModel) issueSeverity(issue *lintIssue) string {
+	// staticcheck issues are from 'staticcheck SA1234'
+	linter, code, _ := strings.Cut(issue.FromLinter, " ")
+	for _, pattern := range m.issuePatterns {
+		if ok, _ := path.Match(pattern, issue.FromLinter); ok {
+			return m.issues[pattern]
+		}
+		if ok, _ := path.Match(pattern, linter); ok {
+			return m.issues[pattern]
+		}
+		if ok, _ := path.Match(pattern, code); ok && code != "" {
+			return m.issues[pattern]
+		}
+	}
+	return severityLow
+}
+
+// apply sets the severity of every finding.
+func (m *severityModel) apply(findings *depFindings) {
+	if findings == nil {
+		return
+	}
+	for _, c := range findings.Caps.CapabilityInfo {
+		c.Severity = m.capSeverity(c)
+	}
+	for _, issue := range findings.Issues {
+		issue.Severity = m.issueSeverity(issue)
+	}
+}
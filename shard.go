@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// shard is the part of the changed dependencies a worker inspects when
+// they are split between multiple workers.
+type shard struct {
+	// index is which part, starting at 1
+	index int
+	count int
+}
+
+// parseShard parses a -shard value in the form 'i/n'.
+func parseShard(s string) (shard, error) {
+	indexStr, countStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return shard{}, fmt.Errorf("malformed shard %q, must be in the form i/n", s)
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil {
+		return shard{}, fmt.Errorf("malformed shard index %q: %w", indexStr, err)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return shard{}, fmt.Errorf("malformed shard count %q: %w", countStr, err)
+	}
+	if count < 1 || index < 1 || index > count {
+		return shard{}, fmt.Errorf("invalid shard %q, must be from 1/n to n/n", s)
+	}
+
+	return shard{index: index, count: count}, nil
+}
+
+// deps returns the changed dependencies that are in the shard. They are
+// sorted by module path first so every worker splits them the same way
+// and all of them are inspected by exactly one worker.
+func (s shard) deps(changedDeps []changedDep) []changedDep {
+	sorted := slices.Clone(changedDeps)
+	slices.SortFunc(sorted, func(a, b changedDep) int {
+		return strings.Compare(a.dep, b.dep)
+	})
+
+	var deps []changedDep
+	for i, dep := range sorted {
+		if i%s.count == s.index-1 {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
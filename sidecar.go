@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	findingNew      = "new"
+	findingSame     = "same"
+	findingResolved = "resolved"
+)
+
+// reportSidecar is a machine-readable summary of a report, written
+// next to HTML and Markdown reports so CI can check findings without
+// parsing the report or inspecting dependencies again.
+type reportSidecar struct {
+	*findingsSummary
+	// Report is the file name of the report the sidecar describes
+	Report       string
+	Metadata     reportMetadata
+	Fingerprints []findingFingerprint
+}
+
+// findingFingerprint identifies a finding across runs and versions.
+type findingFingerprint struct {
+	Fingerprint string
+	// Kind is 'capability' or 'issue'
+	Kind string
+	// Name is the capability or the linter that found the issue
+	Name string
+	// Status is 'new', 'same' or 'resolved' if versions were compared
+	Status string `json:",omitempty"`
+}
+
+// writeSidecar writes a JSON summary of results next to the report at
+// reportPath.
+func writeSidecar(reportPath string, res *savedResults) error {
+	sidecar := &reportSidecar{
+		findingsSummary: summarizeResults(res),
+		Report:          filepath.Base(reportPath),
+		Metadata:        res.New.Metadata,
+	}
+	dep := res.New.Dep
+	if res.Old == nil {
+		sidecar.Fingerprints = findingFingerprints(dep, res.New.Caps.CapabilityInfo, res.New.Issues, "")
+	} else {
+		compared := compareFindings(res.Old, res.New)
+		sidecar.Fingerprints = append(sidecar.Fingerprints, findingFingerprints(dep, compared.addedCaps, compared.newIssues, findingNew)...)
+		sidecar.Fingerprints = append(sidecar.Fingerprints, findingFingerprints(dep, compared.sameCaps, compared.staleIssues, findingSame)...)
+		sidecar.Fingerprints = append(sidecar.Fingerprints, findingFingerprints(res.Old.Dep, compared.removedCaps, compared.fixedIssues, findingResolved)...)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sidecar); err != nil {
+		return fmt.Errorf("encoding summary: %w", err)
+	}
+	path := sidecarPath(reportPath)
+	if err := writeFile(path, &buf); err != nil {
+		return err
+	}
+	log.Printf("wrote summary to %s", path)
+
+	return nil
+}
+
+// sidecarPath returns the path of the JSON summary of the report at
+// reportPath, which is the report's path with a .json extension.
+func sidecarPath(reportPath string) string {
+	path := strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + ".json"
+	if path == reportPath {
+		path = strings.TrimSuffix(reportPath, ".json") + ".summary.json"
+	}
+	return path
+}
+
+func findingFingerprints(dep string, caps []*capability, issues []*lintIssue, status string) []findingFingerprint {
+	fps := make([]findingFingerprint, 0, len(caps)+len(issues))
+	for _, c := range caps {
+		fps = append(fps, findingFingerprint{
+			Fingerprint: capFindingFingerprint(c),
+			Kind:        "capability",
+			Name:        c.Capability,
+			Status:      status,
+		})
+	}
+	for _, issue := range issues {
+		fps = append(fps, findingFingerprint{
+			Fingerprint: issueFingerprint(dep, issue),
+			Kind:        "issue",
+			Name:        issue.FromLinter,
+			Status:      status,
+		})
+	}
+
+	return fps
+}
+
+// capFindingFingerprint hashes the parts of a capability that are
+// compared between versions. Call sites aren't included so the
+// fingerprint doesn't change when unrelated code moves.
+func capFindingFingerprint(c *capability) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", c.PackageDir, c.PackageName, c.Capability, c.CapabilityType)
+	for _, call := range c.Path {
+		fmt.Fprintln(h, call.Name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// issueFingerprint hashes the parts of a linter issue that are
+// compared between versions. Line numbers aren't included so the
+// fingerprint doesn't change when unrelated code moves.
+func issueFingerprint(dep string, issue *lintIssue) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", issue.FromLinter, issue.Text, getDepRelPath(dep, issue.Pos.Filename))
+	for _, line := range issue.SourceLines {
+		fmt.Fprintln(h, strings.TrimSpace(line))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
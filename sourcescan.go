@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sourceFacts are facts about a dependency's behavior found by parsing
+// its source code, that capslock and the linters don't report.
+type sourceFacts struct {
+	// EnvVars are the environment variables the dependency reads
+	EnvVars []envVarRead `json:",omitempty"`
+	// DynamicEnvReads is how many times environment variables are read
+	// with names that aren't constant
+	DynamicEnvReads int `json:",omitempty"`
+	// Paths are the constant filesystem paths the dependency accesses
+	Paths []pathAccess `json:",omitempty"`
+	// FatalCalls are the calls of library packages that crash or exit
+	// the process
+	FatalCalls []fatalCall `json:",omitempty"`
+	// Platforms are the packages with code that is only built for some
+	// platforms
+	Platforms *platformSummary `json:",omitempty"`
+	// LowLevel is system access that bypasses the standard library,
+	// such as raw syscalls
+	LowLevel []lowLevelAccess `json:",omitempty"`
+	// GatedConditions are conditions that depend on the date, host, CI
+	// environment or locale
+	GatedConditions []gatedCondition `json:",omitempty"`
+	// DownloadExec are flows of data read from the network into code
+	// that executes it, only set if -find-download-exec was passed
+	DownloadExec []downloadExec `json:",omitempty"`
+}
+
+// sourcePos is a position in a dependency's source code, relative to
+// its root.
+type sourcePos struct {
+	File string
+	Line int
+}
+
+func (p sourcePos) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// sourceFile is a parsed Go file of a dependency.
+type sourceFile struct {
+	// path is relative to the root of the dependency
+	path string
+	fset *token.FileSet
+	file *ast.File
+	// imports maps the names packages are imported as to their paths
+	imports map[string]string
+	// consts are the package level string constants of the file's
+	// package
+	consts map[string]string
+}
+
+// scanDepSource parses the Go files of the loaded packages of a
+// dependency and finds facts about its behavior. platform is the
+// GOOS/GOARCH the packages were loaded for.
+func scanDepSource(dep, platform string, pkgs loadedPackages) (*sourceFacts, error) {
+	facts := &sourceFacts{}
+	for _, pkg := range pkgs {
+		if pkg.Module == nil || pkg.Module.Path != dep {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		files := make([]*sourceFile, 0, len(pkg.GoFiles))
+		consts := make(map[string]string)
+		for _, file := range pkg.GoFiles {
+			f, err := parser.ParseFile(fset, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", file, err)
+			}
+			relPath, err := filepath.Rel(pkg.Module.Dir, file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &sourceFile{
+				path:    filepath.ToSlash(relPath),
+				fset:    fset,
+				file:    f,
+				imports: fileImports(f),
+				consts:  consts,
+			})
+			collectStringConsts(f, consts)
+		}
+		for _, f := range files {
+			facts.scan(f)
+		}
+		facts.scanPlatforms(pkg, files, platform)
+	}
+
+	facts.addDeviceAccess()
+	facts.sort()
+	return facts, nil
+}
+
+// scan adds the facts found in a file.
+func (s *sourceFacts) scan(f *sourceFile) {
+	s.scanEnvVars(f)
+	s.scanPaths(f)
+	s.scanFatalCalls(f)
+	s.scanLowLevelAccess(f)
+	s.scanGating(f)
+}
+
+// sort sorts facts so they are the same every time a dependency is
+// inspected.
+func (s *sourceFacts) sort() {
+	slices.SortFunc(s.EnvVars, func(a, b envVarRead) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, env := range s.EnvVars {
+		sortPositions(env.Uses)
+	}
+	slices.SortFunc(s.Paths, func(a, b pathAccess) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, access := range s.Paths {
+		sortPositions(access.Uses)
+	}
+	slices.SortFunc(s.FatalCalls, func(a, b fatalCall) int {
+		return strings.Compare(a.Func, b.Func)
+	})
+	for _, call := range s.FatalCalls {
+		sortPositions(call.Uses)
+	}
+	slices.SortFunc(s.LowLevel, func(a, b lowLevelAccess) int {
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Detail, b.Detail)
+	})
+	for _, access := range s.LowLevel {
+		sortPositions(access.Uses)
+	}
+	slices.SortFunc(s.GatedConditions, func(a, b gatedCondition) int {
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Condition, b.Condition)
+	})
+	for _, gc := range s.GatedConditions {
+		sortPositions(gc.Uses)
+	}
+	if s.Platforms != nil {
+		slices.SortFunc(s.Platforms.Packages, func(a, b platformPackage) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+	}
+}
+
+func sortPositions(positions []sourcePos) {
+	slices.SortFunc(positions, func(a, b sourcePos) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+}
+
+// fileImports returns the names packages are imported as in a file,
+// mapped to their paths. Packages are assumed to be named after the
+// last element of their path, ignoring major version suffixes.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string, len(f.Imports))
+	for _, imp := range f.Imports {
+		impPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(impPath)
+		if v2PlusRe.MatchString(name) {
+			name = path.Base(path.Dir(impPath))
+		}
+		if i := strings.LastIndexAny(name, ".-"); i != -1 {
+			// gopkg.in/yaml.v3 is yaml, go-isatty is isatty
+			if v2PlusRe.MatchString(name[i+1:]) {
+				name = name[:i]
+			} else {
+				name = name[i+1:]
+			}
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = impPath
+	}
+	return imports
+}
+
+// collectStringConsts adds the package level string constants of a
+// file to consts.
+func collectStringConsts(f *ast.File, consts map[string]string) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					break
+				}
+				if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if s, err := strconv.Unquote(lit.Value); err == nil {
+						consts[name.Name] = s
+					}
+				}
+			}
+		}
+	}
+}
+
+// callee returns the import path of the package and the name of a
+// function called as pkg.Func, or false if the call isn't of a
+// function of an imported package.
+func (f *sourceFile) callee(call *ast.CallExpr) (string, string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	pkgPath, ok := f.imports[x.Name]
+	if !ok {
+		return "", "", false
+	}
+	return pkgPath, sel.Sel.Name, true
+}
+
+// stringValue returns the value of a constant string expression, or
+// false if it isn't constant. Only string literals, package level
+// constants and concatenations of them are understood.
+func (f *sourceFile) stringValue(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		s, ok := f.consts[e.Name]
+		return s, ok
+	case *ast.ParenExpr:
+		return f.stringValue(e.X)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := f.stringValue(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := f.stringValue(e.Y)
+		return x + y, ok
+	}
+	return "", false
+}
+
+// pos returns the position of a node relative to the root of the
+// dependency.
+func (f *sourceFile) pos(node ast.Node) sourcePos {
+	return sourcePos{
+		File: f.path,
+		Line: f.fset.Position(node.Pos()).Line,
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS inspections (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	dep           TEXT NOT NULL,
+	version       TEXT NOT NULL,
+	inspected_at  TEXT NOT NULL,
+	total_caps    INTEGER NOT NULL,
+	total_issues  INTEGER NOT NULL,
+	totals        TEXT NOT NULL,
+	metadata      TEXT NOT NULL,
+	tool_versions TEXT NOT NULL,
+	findings      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS inspections_dep_version ON inspections (dep, version);
+CREATE TABLE IF NOT EXISTS approvals (
+	dep             TEXT NOT NULL,
+	version         TEXT NOT NULL,
+	approved_by     TEXT NOT NULL,
+	approved_at     TEXT NOT NULL,
+	notes           TEXT NOT NULL,
+	findings_digest TEXT NOT NULL,
+	zip_hash        TEXT NOT NULL,
+	PRIMARY KEY (dep, version)
+);
+CREATE TABLE IF NOT EXISTS triage (
+	dep         TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	note        TEXT NOT NULL,
+	triaged_by  TEXT NOT NULL,
+	triaged_at  TEXT NOT NULL,
+	PRIMARY KEY (dep, fingerprint)
+);
+CREATE TABLE IF NOT EXISTS comments (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	dep          TEXT NOT NULL,
+	fingerprint  TEXT NOT NULL,
+	author       TEXT NOT NULL,
+	commented_at TEXT NOT NULL,
+	text         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS comments_dep_fingerprint ON comments (dep, fingerprint);
+`
+
+// resultStore records the findings of every inspection so they can be
+// queried later without re-running analysis.
+type resultStore struct {
+	db *sql.DB
+}
+
+type storedInspection struct {
+	ID          int64
+	Dep         string
+	Version     string
+	InspectedAt time.Time
+	TotalCaps   int
+	TotalIssues int
+	Findings    *depFindings
+}
+
+func openStore(ctx context.Context, path string) (*resultStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening result store: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, storeSchema); err != nil {
+		return nil, errors.Join(
+			fmt.Errorf("creating result store tables: %w", err),
+			db.Close(),
+		)
+	}
+
+	return &resultStore{db: db}, nil
+}
+
+func (s *resultStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *resultStore) record(ctx context.Context, findings *depFindings) error {
+	totals := calculateTotals(findings.Caps.CapabilityInfo, findings.Issues)
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("encoding totals: %w", err)
+	}
+	metadataJSON, err := json.Marshal(findings.Metadata)
+	if err != nil {
+		return fmt.Errorf("encoding metadata: %w", err)
+	}
+	toolsJSON, err := json.Marshal(findings.Metadata.ToolVersions)
+	if err != nil {
+		return fmt.Errorf("encoding tool versions: %w", err)
+	}
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		return fmt.Errorf("encoding findings: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO inspections (dep, version, inspected_at, total_caps, total_issues, totals, metadata, tool_versions, findings)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		findings.Dep,
+		findings.Version,
+		time.Now().UTC().Format(time.RFC3339),
+		totals.TotalCaps,
+		totals.TotalIssues,
+		string(totalsJSON),
+		string(metadataJSON),
+		string(toolsJSON),
+		string(findingsJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("recording inspection: %w", err)
+	}
+
+	return nil
+}
+
+// history returns all recorded inspections of dep, newest first.
+func (s *resultStore) history(ctx context.Context, dep string) ([]*storedInspection, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, dep, version, inspected_at, total_caps, total_issues, findings
+		FROM inspections WHERE dep = ? ORDER BY id DESC`,
+		dep,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	var inspections []*storedInspection
+	for rows.Next() {
+		inspection, err := scanInspection(rows)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, inspection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+
+	return inspections, nil
+}
+
+// latest returns the most recently recorded inspection of a dependency
+// version. false is returned if it has never been recorded.
+func (s *resultStore) latest(ctx context.Context, dep, version string) (*storedInspection, bool, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, dep, version, inspected_at, total_caps, total_issues, findings
+		FROM inspections WHERE dep = ? AND version = ? ORDER BY id DESC LIMIT 1`,
+		dep,
+		version,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, false, fmt.Errorf("querying result store: %w", err)
+		}
+		return nil, false, nil
+	}
+
+	inspection, err := scanInspection(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	return inspection, true, nil
+}
+
+// latestAll returns the most recently recorded inspection of every
+// dependency version, newest first.
+func (s *resultStore) latestAll(ctx context.Context) ([]*storedInspection, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, dep, version, inspected_at, total_caps, total_issues, findings
+		FROM inspections WHERE id IN (SELECT MAX(id) FROM inspections GROUP BY dep, version)
+		ORDER BY id DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	var inspections []*storedInspection
+	for rows.Next() {
+		inspection, err := scanInspection(rows)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, inspection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+
+	return inspections, nil
+}
+
+// get returns a recorded inspection by its ID.
+func (s *resultStore) get(ctx context.Context, id int64) (*storedInspection, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, dep, version, inspected_at, total_caps, total_issues, findings
+		FROM inspections WHERE id = ?`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("querying result store: %w", err)
+		}
+		return nil, fmt.Errorf("no inspection with ID %d is recorded", id)
+	}
+
+	return scanInspection(rows)
+}
+
+// recordApproval records that a dependency version was approved,
+// replacing any previous approval of it.
+func (s *resultStore) recordApproval(ctx context.Context, a approval) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO approvals (dep, version, approved_by, approved_at, notes, findings_digest, zip_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.Module,
+		a.Version,
+		a.ApprovedBy,
+		a.Date,
+		a.Notes,
+		a.FindingsDigest,
+		a.ZipHash,
+	)
+	if err != nil {
+		return fmt.Errorf("recording approval: %w", err)
+	}
+
+	return nil
+}
+
+// approvals returns every recorded approval.
+func (s *resultStore) approvals(ctx context.Context) ([]approval, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT dep, version, approved_by, approved_at, notes, findings_digest, zip_hash
+		FROM approvals ORDER BY dep, version`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []approval
+	for rows.Next() {
+		var a approval
+		if err := rows.Scan(&a.Module, &a.Version, &a.ApprovedBy, &a.Date, &a.Notes, &a.FindingsDigest, &a.ZipHash); err != nil {
+			return nil, fmt.Errorf("reading result store: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// recordTriage records a decision about a finding, replacing any
+// previous decision about it.
+func (s *resultStore) recordTriage(ctx context.Context, t triageDecision) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO triage (dep, fingerprint, status, note, triaged_by, triaged_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		t.Module,
+		t.Fingerprint,
+		t.Status,
+		t.Note,
+		t.TriagedBy,
+		t.Date,
+	)
+	if err != nil {
+		return fmt.Errorf("recording triage decision: %w", err)
+	}
+
+	return nil
+}
+
+// triageDecisions returns every recorded triage decision.
+func (s *resultStore) triageDecisions(ctx context.Context) ([]triageDecision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT dep, fingerprint, status, note, triaged_by, triaged_at
+		FROM triage ORDER BY dep, fingerprint`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	var decisions []triageDecision
+	for rows.Next() {
+		var t triageDecision
+		if err := rows.Scan(&t.Module, &t.Fingerprint, &t.Status, &t.Note, &t.TriagedBy, &t.Date); err != nil {
+			return nil, fmt.Errorf("reading result store: %w", err)
+		}
+		decisions = append(decisions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+
+	return decisions, nil
+}
+
+// recordComment records a comment about a finding.
+func (s *resultStore) recordComment(ctx context.Context, c findingComment) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO comments (dep, fingerprint, author, commented_at, text)
+		VALUES (?, ?, ?, ?, ?)`,
+		c.Module,
+		c.Fingerprint,
+		c.Author,
+		c.Date,
+		c.Text,
+	)
+	if err != nil {
+		return fmt.Errorf("recording comment: %w", err)
+	}
+
+	return nil
+}
+
+// comments returns every recorded comment, oldest first.
+func (s *resultStore) comments(ctx context.Context) ([]findingComment, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT dep, fingerprint, author, commented_at, text
+		FROM comments ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []findingComment
+	for rows.Next() {
+		var c findingComment
+		if err := rows.Scan(&c.Module, &c.Fingerprint, &c.Author, &c.Date, &c.Text); err != nil {
+			return nil, fmt.Errorf("reading result store: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying result store: %w", err)
+	}
+
+	return comments, nil
+}
+
+func scanInspection(rows *sql.Rows) (*storedInspection, error) {
+	var (
+		inspection   storedInspection
+		inspectedAt  string
+		findingsJSON string
+	)
+	err := rows.Scan(
+		&inspection.ID,
+		&inspection.Dep,
+		&inspection.Version,
+		&inspectedAt,
+		&inspection.TotalCaps,
+		&inspection.TotalIssues,
+		&findingsJSON,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading result store: %w", err)
+	}
+	inspection.InspectedAt, err = time.Parse(time.RFC3339, inspectedAt)
+	if err != nil {
+		return nil, fmt.Errorf("parsing inspection time: %w", err)
+	}
+	inspection.Findings = new(depFindings)
+	if err := json.Unmarshal([]byte(findingsJSON), inspection.Findings); err != nil {
+		return nil, fmt.Errorf("decoding stored findings: %w", err)
+	}
+
+	return &inspection, nil
+}
+
+func historyCmd(ctx context.Context, d *depInspector, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dep-inspector -store path.db history path/of/module")
+	}
+	if d.store == nil {
+		return errors.New("a result store must be specified with -store")
+	}
+
+	inspections, err := d.store.history(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if len(inspections) == 0 {
+		fmt.Printf("no inspections of %s have been recorded\n", args[0])
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprint(tw, "ID\tInspected At\tVersion\tCapabilities\tIssues\tdep-inspector Version\n")
+	for _, inspection := range inspections {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%s\n",
+			inspection.ID,
+			inspection.InspectedAt.Local().Format(time.DateTime),
+			inspection.Version,
+			inspection.TotalCaps,
+			inspection.TotalIssues,
+			inspection.Findings.Metadata.Version,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,38 @@
+package main
+
+import "context"
+
+type subcommand struct {
+	// needsModule is true if the subcommand inspects dependencies of
+	// the main module, which requires go.mod and go.sum to be backed
+	// up and restored
+	needsModule bool
+	run         func(ctx context.Context, d *depInspector, args []string) error
+}
+
+var subcommands = map[string]subcommand{
+	"advise":           {needsModule: true, run: adviseCmd},
+	"annotate-sbom":    {needsModule: true, run: annotateSBOMCmd},
+	"approve":          {run: approveCmd},
+	"audit":            {needsModule: true, run: auditCmd},
+	"baseline":         {needsModule: true, run: baselineCmd},
+	"capmap":           {needsModule: true, run: capmapCmd},
+	"comment":          {run: commentCmd},
+	"compare-baseline": {needsModule: true, run: compareBaselineCmd},
+	"dashboard":        {needsModule: true, run: dashboardCmd},
+	"diff":             {run: diffCmd},
+	"explain":          {run: explainCmd},
+	"fork":             {needsModule: true, run: forkCmd},
+	"git-diff":         {needsModule: true, run: gitDiffCmd},
+	"history":          {run: historyCmd},
+	"merge":            {run: mergeCmd},
+	"packages":         {needsModule: true, run: packagesCmd},
+	"report":           {run: reportCmd},
+	"review":           {run: reviewCmd},
+	"sbom":             {needsModule: true, run: sbomCmd},
+	"self":             {needsModule: true, run: selfCmd},
+	"serve":            {needsModule: true, run: serveCmd},
+	"triage":           {run: triageCmd},
+	"verify":           {run: verifyCmd},
+	"watch":            {needsModule: true, run: watchCmd},
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+)
+
+// findingsSummary is the totals of findings and how they changed,
+// without the findings themselves.
+type findingsSummary struct {
+	Title      string `json:"-"`
+	Dep        string
+	OldVersion string `json:",omitempty"`
+	NewVersion string
+	OldRisk    *riskScore `json:",omitempty"`
+	NewRisk    *riskScore `json:",omitempty"`
+	Totals     findingTotals
+}
+
+// RiskDelta returns how much the risk score changed between compared
+// versions.
+func (s *findingsSummary) RiskDelta() string {
+	return riskDelta(s.OldRisk, s.NewRisk)
+}
+
+func summarizeResults(res *savedResults) *findingsSummary {
+	if res.Old == nil {
+		return &findingsSummary{
+			Title:      "Findings for " + makeVersionStr(res.New.Dep, res.New.Version),
+			Dep:        res.New.Dep,
+			NewVersion: res.New.Version,
+			NewRisk:    res.New.Risk,
+			Totals:     calculateTotals(res.New.Caps.CapabilityInfo, res.New.Issues),
+		}
+	}
+
+	compared := prepareCompareDepsResult(res.Old, res.New)
+	return &findingsSummary{
+		Title:      fmt.Sprintf("Comparing %s and %s", compared.OldVersionStr, compared.NewVersionStr),
+		Dep:        res.New.Dep,
+		OldVersion: res.Old.Version,
+		NewVersion: res.New.Version,
+		OldRisk:    res.Old.Risk,
+		NewRisk:    res.New.Risk,
+		Totals:     compared.Totals,
+	}
+}
+
+// summaryOutput renders only the totals of findings in an output
+// format. SARIF has no way to represent totals so it isn't supported.
+func (d *depInspector) summaryOutput(format string, res *savedResults) (io.Reader, error) {
+	summary := summarizeResults(res)
+
+	var buf bytes.Buffer
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(summary); err != nil {
+			return nil, fmt.Errorf("encoding summary: %w", err)
+		}
+	case formatMarkdown:
+		tmpl, err := template.New("").Funcs(template.FuncMap{
+			"formatDelta": formatDelta,
+		}).ParseFS(tmplFS, "output/summary.md.tmpl", "output/totals.md.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("error parsing output template: %w", err)
+		}
+		if err := tmpl.ExecuteTemplate(&buf, "summary.md.tmpl", summary); err != nil {
+			return nil, fmt.Errorf("error executing output template: %w", err)
+		}
+	case formatHTML:
+		tmpl, err := htmltemplate.New("summary.tmpl").Funcs(htmltemplate.FuncMap{
+			"formatDelta": formatDelta,
+		}).ParseFS(tmplFS, "output/summary.tmpl", "output/totals.tmpl", "output/style.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("error parsing output template: %w", err)
+		}
+		return d.executeTemplate(tmpl, summary)
+	default:
+		return nil, fmt.Errorf("-summary does not support %s output", format)
+	}
+
+	return &buf, nil
+}
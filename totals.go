@@ -32,15 +32,19 @@ func calculateTotals(caps []*capability, issues []*lintIssue) findingTotals {
 		// it is fine
 		return strings.Title(strings.ToLower(capName))
 	})
-	t.Issues = lo.CountValuesBy(issues, func(issue *lintIssue) string {
-		if strings.HasPrefix(issue.FromLinter, "staticcheck") {
-			return "staticcheck"
-		}
-		return issue.FromLinter
-	})
+	t.Issues = lo.CountValuesBy(issues, linterName)
 	return t
 }
 
+// linterName returns the name of the linter that reported an issue.
+// Issues of every staticcheck analyzer are reported as staticcheck.
+func linterName(issue *lintIssue) string {
+	if strings.HasPrefix(issue.FromLinter, "staticcheck") {
+		return "staticcheck"
+	}
+	return issue.FromLinter
+}
+
 func buildCombinedTotals(r *compareDepsResult) {
 	totalCaps, capTotals, capDeltas := currentTotals(
 		r.OldFindings.Totals.Caps,
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	triageAccepted      = "accepted"
+	triageNeedsFix      = "needs-fix"
+	triageFalsePositive = "false-positive"
+)
+
+var triageStatuses = []string{triageAccepted, triageNeedsFix, triageFalsePositive}
+
+// triageDecision is what reviewers decided about a finding. Decisions
+// are keyed by the finding's fingerprint, which doesn't change between
+// versions, so findings don't have to be reviewed again every upgrade.
+type triageDecision struct {
+	Module      string `yaml:"module"`
+	Fingerprint string `yaml:"fingerprint"`
+	// Status is 'accepted', 'needs-fix' or 'false-positive'
+	Status    string `yaml:"status"`
+	Note      string `yaml:"note,omitempty"`
+	TriagedBy string `yaml:"triaged-by"`
+	Date      string `yaml:"date,omitempty"`
+}
+
+// triageList is the triage decisions of an organization. It is YAML or
+// JSON, and is usually committed next to the main module so decisions
+// are shared.
+type triageList struct {
+	Decisions []triageDecision `yaml:"decisions"`
+
+	// mu guards Decisions, the server records decisions while
+	// reports are rendered
+	mu sync.Mutex
+}
+
+// triageForm is the triage decision of a finding shown in HTML reports,
+// and what is needed to triage it when reports are served.
+type triageForm struct {
+	// URL is where triage decisions are posted, only set in server
+	// mode
+	URL         string
+	Module      string
+	Fingerprint string
+	Statuses    []string
+	Decision    *triageDecision
+}
+
+// loadTriage reads triage decisions from a file.
+func loadTriage(path string) (*triageList, error) {
+	contents, err := os.ReadFile(path)
+	// the file is created when the first finding is triaged
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("triage decisions file %s doesn't exist yet", path)
+		return &triageList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading triage decisions: %w", err)
+	}
+
+	var list triageList
+	if err := yaml.Unmarshal(contents, &list); err != nil {
+		return nil, fmt.Errorf("parsing triage decisions: %w", err)
+	}
+	for _, t := range list.Decisions {
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("parsing triage decisions: %w", err)
+		}
+	}
+
+	return &list, nil
+}
+
+func (t *triageDecision) validate() error {
+	if t.Module == "" || t.Fingerprint == "" {
+		return errors.New("triage decisions require a module and fingerprint")
+	}
+	if !slices.Contains(triageStatuses, t.Status) {
+		return fmt.Errorf("unknown triage status %q, must be one of %s", t.Status, strings.Join(triageStatuses, ", "))
+	}
+	return nil
+}
+
+// find returns the decision about a finding of dep, or nil if it
+// hasn't been triaged. l.mu must be held.
+func (l *triageList) find(dep, fingerprint string) *triageDecision {
+	for i, t := range l.Decisions {
+		if t.Module == dep && t.Fingerprint == fingerprint {
+			return &l.Decisions[i]
+		}
+	}
+	return nil
+}
+
+// add adds a decision, replacing any previous decision about the same
+// finding.
+func (l *triageList) add(t triageDecision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if existing := l.find(t.Module, t.Fingerprint); existing != nil {
+		*existing = t
+	} else {
+		l.Decisions = append(l.Decisions, t)
+	}
+}
+
+// list returns a copy of every decision.
+func (l *triageList) list() []triageDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]triageDecision{}, l.Decisions...)
+}
+
+// apply sets the triage decision of every finding that was triaged.
+func (l *triageList) apply(findings *depFindings) {
+	if l == nil || findings == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, c := range findings.Caps.CapabilityInfo {
+		c.Triage = l.copyOf(findings.Dep, capFindingFingerprint(c))
+	}
+	for _, issue := range findings.Issues {
+		issue.Triage = l.copyOf(findings.Dep, issueFingerprint(findings.Dep, issue))
+	}
+}
+
+// copyOf returns a copy of the decision about a finding so later
+// decisions don't change findings that were already rendered.
+func (l *triageList) copyOf(dep, fingerprint string) *triageDecision {
+	t := l.find(dep, fingerprint)
+	if t == nil {
+		return nil
+	}
+	tCopy := *t
+	return &tCopy
+}
+
+// recordTriage records a triage decision in the result store and the
+// -triage file, whichever are configured, and applies it to reports
+// rendered afterwards.
+func (d *depInspector) recordTriage(ctx context.Context, t triageDecision) error {
+	if d.store == nil && d.triagePath == "" {
+		return errors.New("triage requires -store or a -triage file to record the decision in")
+	}
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	if d.store != nil {
+		if err := d.store.recordTriage(ctx, t); err != nil {
+			return err
+		}
+	}
+	if d.triagePath != "" {
+		if err := addTriage(d.triagePath, t); err != nil {
+			return err
+		}
+	}
+	if d.triage == nil {
+		d.triage = &triageList{}
+	}
+	d.triage.add(t)
+
+	return nil
+}
+
+// addTriage adds a decision to the triage decisions file at path.
+func addTriage(path string, t triageDecision) error {
+	list, err := loadTriage(path)
+	if err != nil {
+		return err
+	}
+	list.add(t)
+
+	contents, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encoding triage decisions: %w", err)
+	}
+	return writeFile(path, bytes.NewReader(contents))
+}
+
+func triageCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("triage", flag.ContinueOnError)
+	note := fs.String("note", "", "why the decision was made")
+	triagedBy := fs.String("triaged-by", "", "who triaged the finding, defaults to the email address git is configured with")
+	findingsPath := fs.String("findings", "", "results or inspection ID to search for the finding, defaults to the latest findings of every version in the result store")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: dep-inspector [flags] triage [-note note] [-triaged-by name] [-findings results.json|inspection-id] fingerprint %s", strings.Join(triageStatuses, "|"))
+	}
+
+	sources, err := d.findingSources(ctx, *findingsPath)
+	if err != nil {
+		return err
+	}
+	f, err := findFinding(sources, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *triagedBy == "" {
+		*triagedBy = d.approverName(ctx)
+	}
+	if *triagedBy == "" {
+		return errors.New("-triaged-by is required")
+	}
+
+	t := triageDecision{
+		Module:      f.findings.Dep,
+		Fingerprint: f.fingerprint,
+		Status:      fs.Arg(1),
+		Note:        *note,
+		TriagedBy:   *triagedBy,
+		Date:        time.Now().UTC().Format(time.DateOnly),
+	}
+	if err := d.recordTriage(ctx, t); err != nil {
+		return err
+	}
+	log.Printf("marked finding %s of %s as %s", f.fingerprint, f.findings.Dep, t.Status)
+
+	return nil
+}
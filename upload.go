@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// uploadDest is an object storage location reports are uploaded to.
+type uploadDest struct {
+	// scheme is s3, gs or az
+	scheme string
+	// bucket is the bucket name for S3 and GCS, and the storage
+	// account name for Azure
+	bucket string
+	// container is the Azure blob container
+	container string
+	prefix    string
+}
+
+// parseUploadDest parses an object storage URL in the form
+// s3://bucket/prefix, gs://bucket/prefix or
+// az://account/container/prefix.
+func parseUploadDest(rawURL string) (uploadDest, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return uploadDest{}, fmt.Errorf("parsing upload URL: %w", err)
+	}
+	dest := uploadDest{
+		scheme: u.Scheme,
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}
+	if dest.bucket == "" {
+		return uploadDest{}, fmt.Errorf("upload URL %q has no bucket", rawURL)
+	}
+
+	switch dest.scheme {
+	case "s3", "gs":
+	case "az":
+		dest.container, dest.prefix, _ = strings.Cut(dest.prefix, "/")
+		if dest.container == "" {
+			return uploadDest{}, fmt.Errorf("upload URL %q has no container: must be in the form az://account/container/prefix", rawURL)
+		}
+	default:
+		return uploadDest{}, fmt.Errorf("unsupported upload URL scheme %q: must be s3, gs or az", dest.scheme)
+	}
+
+	return dest, nil
+}
+
+// publicURL returns the HTTPS URL of an uploaded object.
+func (u uploadDest) publicURL(key string) string {
+	switch u.scheme {
+	case "s3":
+		return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", u.bucket, key)
+	case "gs":
+		return fmt.Sprintf("https://storage.googleapis.com/%s/%s", u.bucket, key)
+	default:
+		return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", u.bucket, u.container, key)
+	}
+}
+
+// uploadResults uploads a rendered report and the JSON results it was
+// rendered from to object storage. Objects are named after the SHA-256
+// hash of their contents, so identical reports are only stored once and
+// uploaded reports are never overwritten. The URL of the report is
+// returned.
+func (d *depInspector) uploadResults(ctx context.Context, res *savedResults, report []byte) (string, error) {
+	dest, err := parseUploadDest(d.upload)
+	if err != nil {
+		return "", err
+	}
+
+	reportURL, err := d.uploadObject(ctx, dest, report, formatExts[d.format], contentTypes[d.format])
+	if err != nil {
+		return "", fmt.Errorf("uploading report: %w", err)
+	}
+	log.Printf("uploaded report to %s", reportURL)
+	if d.format == formatJSON {
+		return reportURL, nil
+	}
+
+	r, err := jsonOutput(res)
+	if err != nil {
+		return "", err
+	}
+	results, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	resultsURL, err := d.uploadObject(ctx, dest, results, formatExts[formatJSON], contentTypes[formatJSON])
+	if err != nil {
+		return "", fmt.Errorf("uploading results: %w", err)
+	}
+	log.Printf("uploaded results to %s", resultsURL)
+
+	return reportURL, nil
+}
+
+func (d *depInspector) uploadObject(ctx context.Context, dest uploadDest, contents []byte, ext, contentType string) (string, error) {
+	hash := sha256.Sum256(contents)
+	key := path.Join(dest.prefix, hex.EncodeToString(hash[:])+ext)
+
+	// the cloud CLIs don't all support reading from stdin, so write the
+	// object to a file first
+	f, err := os.CreateTemp("", tempPrefix)
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return "", fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	// objects are only written if they don't exist yet. An object that
+	// already exists has the same contents, so failing because of it
+	// is reported with existsErr and isn't an error
+	var (
+		args      []string
+		existsErr string
+	)
+	switch dest.scheme {
+	case "s3":
+		args = []string{
+			"aws", "s3api", "put-object", "--if-none-match", "*",
+			"--bucket", dest.bucket,
+			"--key", key,
+			"--content-type", contentType,
+			"--body", f.Name(),
+		}
+		existsErr = "PreconditionFailed"
+	case "gs":
+		// existing objects are skipped without failing
+		args = []string{"gcloud", "storage", "cp", "--no-clobber", "--content-type", contentType, f.Name(), fmt.Sprintf("gs://%s/%s", dest.bucket, key)}
+	case "az":
+		args = []string{
+			"az", "storage", "blob", "upload", "--only-show-errors", "--overwrite", "false",
+			"--account-name", dest.bucket,
+			"--container-name", dest.container,
+			"--name", key,
+			"--content-type", contentType,
+			"--file", f.Name(),
+		}
+		existsErr = "BlobAlreadyExists"
+	}
+	cmd, errBuf := d.buildCommand(ctx, nil, args...)
+	if err := cmd.Run(); err != nil {
+		if existsErr == "" || !strings.Contains(errBuf.String(), existsErr) {
+			return "", formatCmdErr(ctx, cmd, err, errBuf)
+		}
+		if d.verbose {
+			log.Printf("%s already exists, not uploading it again", dest.publicURL(key))
+		}
+	}
+
+	return dest.publicURL(key), nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// vcsDiff is how a module version's zip differs from the contents of
+// its repository at the revision the version was made from. A zip that
+// doesn't match its repository was either published from a modified
+// checkout or tampered with, and reviewing the repository won't show
+// the code that is actually built.
+type vcsDiff struct {
+	Repository string
+	Revision   string
+	// Added are files in the module zip that aren't in the repository
+	Added []string `json:",omitempty"`
+	// Modified are files in the module zip whose contents differ from
+	// the repository
+	Modified []string `json:",omitempty"`
+	// Missing are Go files in the repository that aren't in the module
+	// zip. Other files are expected to be missing, as module zips
+	// don't include files of nested modules or vendor directories.
+	Missing []string `json:",omitempty"`
+}
+
+// Differs returns true if the module zip doesn't match the repository.
+func (v *vcsDiff) Differs() bool {
+	return len(v.Added) != 0 || len(v.Modified) != 0 || len(v.Missing) != 0
+}
+
+// compareZipToVCS compares the files of a module version's zip in the
+// module cache with the files of its repository at the tag or commit
+// the version was made from.
+func (d *depInspector) compareZipToVCS(ctx context.Context, dep, version string) (*vcsDiff, error) {
+	tmpDir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	modURL, err := d.findModuleURL(ctx, dep, version, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	repoURL, subdir := repoRoot(modURL)
+	rev := moduleRev(modURL, subdir)
+
+	gitDir, commit, err := d.fetchRevision(ctx, repoURL, rev, modURL.verIsCommit, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	repoFiles, err := d.repoBlobHashes(ctx, gitDir, commit)
+	if err != nil {
+		return nil, err
+	}
+	zipPath, err := moduleZipPath(d.modCache, dep, version)
+	if err != nil {
+		return nil, err
+	}
+	zipFiles, err := zipBlobHashes(zipPath, dep+"@"+version+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffModuleFiles(zipFiles, repoFiles, subdir)
+	diff.Repository = repoURL
+	diff.Revision = rev
+	return diff, nil
+}
+
+// fetchRevision fetches the commit of a tag or commit hash of a
+// repository into a new bare repository without the contents of files,
+// as only their hashes are compared. The path of the repository and
+// the fetched commit are returned.
+func (d *depInspector) fetchRevision(ctx context.Context, repoURL, rev string, isCommit bool, tmpDir string) (string, string, error) {
+	gitDir := tmpDir + "/repo.git"
+	if isCommit {
+		// pseudo-versions only have a prefix of the commit hash, which
+		// can't be fetched directly
+		if err := d.runCommand(ctx, nil, "git", "clone", "--quiet", "--bare", "--filter=blob:none", repoURL, gitDir); err != nil {
+			return "", "", fmt.Errorf("cloning %s: %w", repoURL, err)
+		}
+	} else {
+		if err := d.runCommand(ctx, nil, "git", "init", "--quiet", "--bare", gitDir); err != nil {
+			return "", "", fmt.Errorf("creating repository: %w", err)
+		}
+		ref := "refs/tags/" + rev
+		if err := d.runCommand(ctx, nil, "git", "--git-dir", gitDir, "fetch", "--quiet", "--depth", "1", "--filter=blob:none", repoURL, ref+":"+ref); err != nil {
+			return "", "", fmt.Errorf("fetching tag %s: %w", rev, err)
+		}
+		rev = ref
+	}
+
+	var output strings.Builder
+	if err := d.runCommand(ctx, &output, "git", "--git-dir", gitDir, "rev-parse", "--verify", rev+"^{commit}"); err != nil {
+		return "", "", fmt.Errorf("resolving %s: %w", rev, err)
+	}
+	return gitDir, strings.TrimSpace(output.String()), nil
+}
+
+// repoBlobHashes returns the git blob hashes of the regular files of a
+// commit, keyed by their paths.
+func (d *depInspector) repoBlobHashes(ctx context.Context, gitDir, commit string) (map[string]string, error) {
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "git", "--git-dir", gitDir, "ls-tree", "-r", "-z", commit); err != nil {
+		return nil, fmt.Errorf("listing files of %s: %w", commit, err)
+	}
+
+	files := make(map[string]string)
+	for _, entry := range bytes.Split(output.Bytes(), []byte{0}) {
+		// entries are '<mode> <type> <hash>\t<path>'
+		info, file, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(info)
+		// symlinks and submodules aren't included in module zips
+		if len(fields) != 3 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		files[file] = fields[2]
+	}
+	return files, nil
+}
+
+// zipBlobHashes returns the git blob hashes of the files of a module
+// zip, keyed by their paths relative to the module root.
+func zipBlobHashes(zipPath, prefix string) (map[string]string, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening module zip: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]string, len(zr.File))
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || f.FileInfo().IsDir() {
+			continue
+		}
+		hash, err := zipFileBlobHash(f)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", name, err)
+		}
+		files[name] = hash
+	}
+	return files, nil
+}
+
+// zipFileBlobHash returns the hash git would give the contents of a
+// file in a zip.
+func zipFileBlobHash(f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", f.UncompressedSize64)
+	if _, err := io.Copy(h, bufio.NewReader(r)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// diffModuleFiles compares the files of a module zip with the files of
+// the repository it was made from. Module zips of modules in
+// subdirectories include the repository's root LICENSE file if the
+// module has none of its own.
+func diffModuleFiles(zipFiles, repoFiles map[string]string, subdir string) *vcsDiff {
+	repoPath := func(file string) string {
+		return path.Join(subdir, file)
+	}
+
+	diff := &vcsDiff{}
+	for file, hash := range zipFiles {
+		repoHash, ok := repoFiles[repoPath(file)]
+		if !ok && file == "LICENSE" && subdir != "" {
+			repoHash, ok = repoFiles["LICENSE"]
+		}
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, file)
+		case hash != repoHash:
+			diff.Modified = append(diff.Modified, file)
+		}
+	}
+
+	// Go files of nested modules and vendor directories are excluded
+	// from module zips
+	var excludedDirs []string
+	for file := range repoFiles {
+		rel, ok := moduleRelPath(file, subdir)
+		if !ok {
+			continue
+		}
+		if dir := path.Dir(rel); path.Base(rel) == "go.mod" && dir != "." {
+			excludedDirs = append(excludedDirs, dir)
+		}
+	}
+	for file := range repoFiles {
+		rel, ok := moduleRelPath(file, subdir)
+		if !ok || path.Ext(rel) != ".go" {
+			continue
+		}
+		if _, ok := zipFiles[rel]; ok {
+			continue
+		}
+		if rel == "vendor" || strings.HasPrefix(rel, "vendor/") || strings.Contains(rel, "/vendor/") {
+			continue
+		}
+		if slices.ContainsFunc(excludedDirs, func(dir string) bool {
+			return strings.HasPrefix(rel, dir+"/")
+		}) {
+			continue
+		}
+		diff.Missing = append(diff.Missing, rel)
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Modified)
+	slices.Sort(diff.Missing)
+	return diff
+}
+
+// moduleRelPath returns the path of a file of a repository relative to
+// the module in subdir, or false if the file isn't in the module.
+func moduleRelPath(file, subdir string) (string, bool) {
+	if subdir == "" {
+		return file, true
+	}
+	return strings.CutPrefix(file, subdir+"/")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const osvQueryURL = "https://api.osv.dev/v1/query"
+
+// vulnFindings are the known vulnerabilities of a dependency version.
+type vulnFindings struct {
+	// IDs are the OSV IDs of the vulnerabilities
+	IDs []string `json:",omitempty"`
+}
+
+// findVulns queries OSV for known vulnerabilities of a module version.
+func findVulns(ctx context.Context, dep, version string) (*vulnFindings, error) {
+	query := map[string]any{
+		"package": map[string]string{
+			"name":      dep,
+			"ecosystem": "Go",
+		},
+		// OSV versions of Go modules don't have a 'v' prefix
+		"version": strings.TrimPrefix(version, "v"),
+	}
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("encoding query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, osvQueryURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying OSV: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("querying OSV: %s returned %s: %s", osvQueryURL, resp.Status, msg)
+	}
+
+	var result struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding OSV response: %w", err)
+	}
+
+	vulns := &vulnFindings{}
+	for _, vuln := range result.Vulns {
+		vulns.IDs = append(vulns.IDs, vuln.ID)
+	}
+	slices.Sort(vulns.IDs)
+
+	return vulns, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+type watcher struct {
+	d *depInspector
+	// reportDir is the directory reports are written to
+	reportDir string
+	// reportBaseURL is the URL reportDir is served at, used to link to
+	// reports in notifications
+	reportBaseURL string
+	// feed is an Atom feed of completed inspections, only set if
+	// -feed was passed
+	feed *atomFeed
+	// versions are the latest versions of watched dependencies that
+	// were inspected
+	versions map[string]string
+}
+
+func watchCmd(ctx context.Context, d *depInspector, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Hour, "how often to check for new dependency versions")
+	reportDir := fs.String("report-dir", ".", "directory to write reports to")
+	reportBaseURL := fs.String("report-base-url", "", "URL the report directory is served at, used to link to reports in notifications")
+	metricsAddr := fs.String("metrics-listen", "", "address to serve Prometheus metrics on")
+	feed := fs.Bool("feed", false, "publish an Atom feed of completed inspections and policy violations to "+feedFile+" in the report directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w := &watcher{
+		d:             d,
+		reportDir:     *reportDir,
+		reportBaseURL: strings.TrimSuffix(*reportBaseURL, "/"),
+		versions:      make(map[string]string),
+	}
+	// watch the passed dependencies, or every direct dependency
+	for _, req := range d.parsedModFile.Require {
+		if fs.NArg() == 0 && !req.Indirect {
+			w.versions[req.Mod.Path] = req.Mod.Version
+		}
+	}
+	for _, dep := range fs.Args() {
+		ver, err := d.checkVersion(dep, curVersion)
+		if err != nil {
+			return err
+		}
+		w.versions[dep] = ver
+	}
+	if len(w.versions) == 0 {
+		return errors.New("no dependencies to watch")
+	}
+	if *feed {
+		var err error
+		w.feed, err = readFeed(filepath.Join(w.reportDir, feedFile), d.parsedModFile.Module.Mod.Path, w.reportBaseURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *metricsAddr != "" {
+		d.metrics = newInspectorMetrics()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", d.metrics.handler())
+		srv := &http.Server{
+			Addr:              *metricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("error serving metrics: %v", err)
+			}
+		}()
+		defer srv.Close()
+	}
+
+	log.Printf("watching %d dependencies for new versions every %s", len(w.versions), *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		w.checkVersions(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkVersions inspects new versions of watched dependencies,
+// comparing them to the last inspected versions.
+func (w *watcher) checkVersions(ctx context.Context) {
+	for dep, oldVer := range w.versions {
+		if ctx.Err() != nil {
+			return
+		}
+
+		newVer, err := w.d.latestVersion(ctx, dep)
+		if err != nil {
+			log.Printf("error finding latest version of %s: %v", dep, err)
+			continue
+		}
+		if semver.Compare(newVer, oldVer) <= 0 {
+			continue
+		}
+
+		log.Printf("new version of %s found: %s", dep, newVer)
+		if err := w.inspectNewVersion(ctx, dep, oldVer, newVer); err != nil {
+			log.Printf("error inspecting %s: %v", makeVersionStr(dep, newVer), err)
+			continue
+		}
+		w.versions[dep] = newVer
+	}
+}
+
+func (w *watcher) inspectNewVersion(ctx context.Context, dep, oldVer, newVer string) error {
+	d := w.d
+	if err := d.resetModFiles(); err != nil {
+		return fmt.Errorf("restoring go.mod: %w", err)
+	}
+	oldFindings, newFindings, err := d.inspectDepVersions(ctx, dep, oldVer, newVer)
+	if err != nil {
+		return err
+	}
+	res := &savedResults{
+		Old: oldFindings,
+		New: newFindings,
+	}
+
+	r, err := d.renderResults(ctx, d.format, res)
+	if err != nil {
+		return err
+	}
+	name := strings.ReplaceAll(dep, "/", "_") + "@" + newVer + formatExts[d.format]
+	reportPath := filepath.Join(w.reportDir, name)
+	if err := writeFile(reportPath, r); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	log.Printf("wrote report to %s", reportPath)
+
+	var reportURL string
+	if w.reportBaseURL != "" {
+		reportURL = w.reportBaseURL + "/" + name
+	}
+	if d.upload != "" {
+		report, err := os.ReadFile(reportPath)
+		if err != nil {
+			return err
+		}
+		// link to the durable uploaded report instead
+		reportURL, err = d.uploadResults(ctx, res, report)
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.notifyInspection(ctx, res, reportURL); err != nil {
+		log.Printf("error notifying webhooks: %v", err)
+	}
+	if err := d.notifyChat(ctx, res, reportURL); err != nil {
+		log.Printf("error sending chat notifications: %v", err)
+	}
+	if w.feed != nil {
+		w.feed.addEntry(res, reportURL, d.checkPolicy(res))
+		if err := w.feed.write(filepath.Join(w.reportDir, feedFile)); err != nil {
+			log.Printf("error writing feed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// latestVersion queries the module proxies for the latest version of
+// a dependency.
+func (d *depInspector) latestVersion(ctx context.Context, dep string) (string, error) {
+	info, err := d.queryModule(ctx, dep, "latest")
+	if err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	eventInspectionCompleted = "inspection.completed"
+	eventPolicyViolation     = "policy.violation"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// stringsFlag is a flag that can be passed multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+// webhookPayload is the JSON body posted to webhook endpoints.
+type webhookPayload struct {
+	Event      string          `json:"event"`
+	Dep        string          `json:"dep"`
+	OldVersion string          `json:"oldVersion,omitempty"`
+	NewVersion string          `json:"newVersion"`
+	Totals     webhookTotals   `json:"totals"`
+	Deltas     *webhookTotals  `json:"deltas,omitempty"`
+	ReportURL  string          `json:"reportURL,omitempty"`
+	Metadata   *reportMetadata `json:"metadata,omitempty"`
+	// Violations are the policy rules that were violated, only set for
+	// policy violation events
+	Violations []policyViolation `json:"violations,omitempty"`
+}
+
+type webhookTotals struct {
+	Capabilities int            `json:"capabilities"`
+	CapsByName   map[string]int `json:"capabilitiesByName"`
+	Issues       int            `json:"issues"`
+	IssuesByName map[string]int `json:"issuesByLinter"`
+}
+
+func newWebhookPayload(event string, res *savedResults, reportURL string) *webhookPayload {
+	payload := &webhookPayload{
+		Event:      event,
+		Dep:        res.New.Dep,
+		NewVersion: res.New.Version,
+		ReportURL:  reportURL,
+		Metadata:   &res.New.Metadata,
+	}
+
+	if res.Old == nil {
+		totals := calculateTotals(res.New.Caps.CapabilityInfo, res.New.Issues)
+		payload.Totals = webhookTotals{
+			Capabilities: totals.TotalCaps,
+			CapsByName:   totals.Caps,
+			Issues:       totals.TotalIssues,
+			IssuesByName: totals.Issues,
+		}
+		return payload
+	}
+
+	payload.OldVersion = res.Old.Version
+	totals := prepareCompareDepsResult(res.Old, res.New).Totals
+	payload.Totals = webhookTotals{
+		Capabilities: totals.TotalCaps,
+		CapsByName:   totals.Caps,
+		Issues:       totals.TotalIssues,
+		IssuesByName: totals.Issues,
+	}
+	deltas := &webhookTotals{
+		CapsByName:   totals.CapDeltas,
+		IssuesByName: totals.IssueDeltas,
+	}
+	for _, delta := range totals.CapDeltas {
+		deltas.Capabilities += delta
+	}
+	for _, delta := range totals.IssueDeltas {
+		deltas.Issues += delta
+	}
+	payload.Deltas = deltas
+
+	return payload
+}
+
+// notifyWebhooks posts payload to every configured webhook endpoint.
+// Failing to notify an endpoint doesn't stop others from being
+// notified.
+func (d *depInspector) notifyWebhooks(ctx context.Context, payload *webhookPayload) error {
+	if len(d.webhooks) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	var errs []error
+	for _, endpoint := range d.webhooks {
+		if err := d.postWebhook(ctx, endpoint, payload.Event, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyInspection notifies webhook endpoints that an inspection
+// completed, and that it violated policy rules if it did.
+func (d *depInspector) notifyInspection(ctx context.Context, res *savedResults, reportURL string) error {
+	err := d.notifyWebhooks(ctx, newWebhookPayload(eventInspectionCompleted, res, reportURL))
+	if violations := d.checkPolicy(res); len(violations) != 0 {
+		payload := newWebhookPayload(eventPolicyViolation, res, reportURL)
+		payload.Violations = violations
+		err = errors.Join(err, d.notifyWebhooks(ctx, payload))
+	}
+
+	return err
+}
+
+func (d *depInspector) postWebhook(ctx context.Context, endpoint, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", projectName)
+	req.Header.Set("X-Dep-Inspector-Event", event)
+	// let receivers verify the payload was sent by us
+	if d.webhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(d.webhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Dep-Inspector-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting webhook to %s: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("posting webhook to %s: returned %s: %s", req.URL.Redacted(), resp.Status, msg)
+	}
+	if d.verbose {
+		log.Printf("notified webhook %s", req.URL.Redacted())
+	}
+
+	return nil
+}
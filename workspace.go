@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newWorkspace copies the main module to a temporary directory so a
+// dependency version can be set up and inspected in it while another
+// version is inspected in the main module. It returns a copy of d that
+// runs commands in the workspace and a function that removes it.
+func (d *depInspector) newWorkspace(ctx context.Context) (*depInspector, func(), error) {
+	if err := d.checkWorkspaceable(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", tempPrefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("error removing workspace %s: %v", dir, err)
+		}
+	}
+	if err := copyModule(filepath.Dir(d.modFilePath), dir); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("copying main module: %w", err)
+	}
+
+	wd := *d
+	wd.workDir = dir
+	wd.modFilePath = filepath.Join(dir, "go.mod")
+	wd.sumFilePath = filepath.Join(dir, "go.sum")
+	return &wd, cleanup, nil
+}
+
+// checkWorkspaceable returns an error if the main module would build
+// differently if it was copied elsewhere.
+func (d *depInspector) checkWorkspaceable(ctx context.Context) error {
+	var output bytes.Buffer
+	if err := d.runCommand(ctx, &output, "go", "env", "GOWORK"); err != nil {
+		return fmt.Errorf("finding GOWORK: %w", err)
+	}
+	if gowork := trimNewline(output.String()); gowork != "" && gowork != "off" {
+		return errors.New("the main module is part of a workspace")
+	}
+	for _, replace := range d.parsedModFile.Replace {
+		if replace.New.Version == "" && !filepath.IsAbs(replace.New.Path) {
+			return fmt.Errorf("%s is replaced with a relative path", replace.Old.Path)
+		}
+	}
+
+	return nil
+}
+
+// copyModule copies the files of a module to dst. Directories the go
+// command ignores, such as testdata and hidden directories, vendor
+// directories and nested modules are not copied.
+func copyModule(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case entry.IsDir():
+			if rel == "." {
+				return nil
+			}
+			if ignoredDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return os.Mkdir(target, 0o755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// ignoredDir returns true if the go command ignores directories named
+// name when matching packages. Vendor directories are ignored too, the
+// dependency versions set up in the workspace wouldn't match them.
+func ignoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "_") ||
+		name == "testdata" ||
+		name == "vendor"
+}
+
+// copyFile copies the contents of a file to a new file with mode perm.
+func copyFile(src, dst string, perm fs.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}